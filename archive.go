@@ -0,0 +1,263 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+// archiveSep separates the path of an archive from the path of an entry within it,
+// e.g. "/opt/app.jar!/META-INF/MANIFEST.MF".
+const archiveSep = "!"
+
+// errArchiveBudget is returned by budgetReader once more than max_hash_file_size bytes were read.
+var errArchiveBudget = errors.New("read more than max_hash_file_size bytes")
+
+// budgetReader fails with errArchiveBudget once more than left bytes were read from r.
+type budgetReader struct {
+	r    io.Reader
+	left int64
+}
+
+func (b *budgetReader) Read(p []byte) (int, error) {
+	if b.left < 0 {
+		return 0, errArchiveBudget
+	}
+	n, err := b.r.Read(p)
+	if b.left -= int64(n); b.left < 0 {
+		return n, errArchiveBudget
+	}
+	return n, err
+}
+
+// archiveEntry is the metadata of a file within an archive.
+type archiveEntry struct {
+	name     string
+	mode     os.FileMode
+	size     int64
+	modTime  time.Time
+	linkname string
+}
+
+// archiveFormat returns the format of the archive at p, i.e. its extension in archive_formats
+// of the policy, or "" if its contents aren't to be inspected.
+func (w *Walker) archiveFormat(p string, info os.FileInfo) string {
+	if !w.pol.InspectArchives || !info.Mode().IsRegular() || info.Size() > w.maxHashFileSize() {
+		return ""
+	}
+	name := strings.ToLower(filepath.Base(p))
+	for _, f := range w.pol.InspectedArchiveFormats() {
+		if strings.HasSuffix(name, "."+f) {
+			return f
+		}
+	}
+	return ""
+}
+
+// inspectArchive records the entries of the archive at p as files with virtual paths below it.
+// Archives which can't be read are noted in the Walk but don't fail it.
+func (w *Walker) inspectArchive(p, format string) {
+	var err error
+	switch format {
+	case "tar", "tar.gz", "tgz":
+		err = w.inspectTar(p, format != "tar")
+	default:
+		err = w.inspectZip(p)
+	}
+	if err != nil {
+		msg := fmt.Sprintf("unable to inspect archive %q: %v", p, err)
+		log.Print(msg)
+		w.addNotificationToWalk(fspb.Notification_WARNING, p, msg)
+		if w.Counter != nil {
+			w.Counter.Add(1, countArchiveErr)
+		}
+	}
+}
+
+// archiveTruncated notes that not all of the archive at p was inspected.
+func (w *Walker) archiveTruncated(p string) {
+	w.addNotificationToWalk(fspb.Notification_WARNING, p, fmt.Sprintf("archive %q: only the first %d bytes of its contents were inspected (max_hash_file_size)", p, w.maxHashFileSize()))
+}
+
+// archiveFile converts an entry of the archive at p. It returns nil for entries which are
+// excluded by the policy or don't name a file.
+func (w *Walker) archiveFile(p string, e archiveEntry) *fspb.File {
+	name := path.Clean("/" + e.name) // also resolves ".." which would escape the archive.
+	if name == "/" {
+		return nil
+	}
+	vp := p + archiveSep + name
+	if w.isExcluded(vp) || w.excludeRule(vp) != "" {
+		return nil
+	}
+	mts, _ := ptypes.TimestampProto(e.modTime) // ignoring the error and using default
+	f := &fspb.File{
+		Version: fileVersion,
+		Path:    vp,
+		Info: &fspb.FileInfo{
+			Name:     path.Base(name),
+			Size:     e.size,
+			Mode:     uint32(e.mode),
+			Modified: mts,
+			IsDir:    e.mode.IsDir(),
+		},
+	}
+	if e.mode&os.ModeSymlink != 0 {
+		f.SymlinkTarget = e.linkname
+	}
+	return f
+}
+
+// hashArchiveEntry sets the fingerprint of f over the content read from r.
+func (w *Walker) hashArchiveEntry(f *fspb.File, r io.Reader) error {
+	method := w.hashMethod()
+	h, err := w.newHash()
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return err
+	}
+	atomic.AddInt64(&w.bytesHashed, n)
+	f.Fingerprint = []*fspb.Fingerprint{{Method: method, Value: hex.EncodeToString(h.Sum(nil))}}
+	return nil
+}
+
+// addArchiveFile adds an entry of an archive to the Walk.
+func (w *Walker) addArchiveFile(f *fspb.File) {
+	w.addFileToWalk(f)
+	if w.Counter != nil {
+		w.Counter.Add(1, countArchived)
+		if len(f.Fingerprint) > 0 {
+			w.Counter.Add(1, countHashes)
+		}
+	}
+}
+
+// inspectZip records the entries of a zip archive, e.g. a jar. Entries are hashed if the
+// archive is to be hashed, up to max_hash_file_size bytes in total.
+func (w *Walker) inspectZip(p string) error {
+	zr, err := zip.OpenReader(p)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	hash := w.wantHashing(p)
+	left := w.maxHashFileSize()
+	truncated := false
+	for _, zf := range zr.File {
+		f := w.archiveFile(p, archiveEntry{
+			name:    zf.Name,
+			mode:    zf.Mode(),
+			size:    int64(zf.UncompressedSize64),
+			modTime: zf.Modified,
+		})
+		if f == nil {
+			continue
+		}
+		if hash && zf.Mode().IsRegular() {
+			if int64(zf.UncompressedSize64) > left {
+				truncated = true
+			} else {
+				rc, err := zf.Open()
+				if err != nil {
+					return err
+				}
+				err = w.hashArchiveEntry(f, rc)
+				rc.Close()
+				if err != nil {
+					return err
+				}
+				left -= int64(zf.UncompressedSize64)
+			}
+		}
+		w.addArchiveFile(f)
+	}
+	if truncated {
+		w.archiveTruncated(p)
+	}
+	return nil
+}
+
+// inspectTar records the entries of a tar archive, gzip compressed if gz is set. At most
+// max_hash_file_size uncompressed bytes are read. Entries are hashed if the archive is to be hashed.
+func (w *Walker) inspectTar(p string, gz bool) error {
+	fh, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+	var r io.Reader = fh
+	if gz {
+		zr, err := gzip.NewReader(fh)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+	tr := tar.NewReader(&budgetReader{r: r, left: w.maxHashFileSize()})
+	hash := w.wantHashing(p)
+	for {
+		hdr, err := tr.Next()
+		switch {
+		case err == io.EOF:
+			return nil
+		case errors.Is(err, errArchiveBudget):
+			w.archiveTruncated(p)
+			return nil
+		case err != nil:
+			return err
+		}
+		fi := hdr.FileInfo()
+		f := w.archiveFile(p, archiveEntry{
+			name:     hdr.Name,
+			mode:     fi.Mode(),
+			size:     hdr.Size,
+			modTime:  hdr.ModTime,
+			linkname: hdr.Linkname,
+		})
+		if f == nil {
+			continue
+		}
+		if hash && fi.Mode().IsRegular() {
+			if err := w.hashArchiveEntry(f, tr); errors.Is(err, errArchiveBudget) {
+				w.archiveTruncated(p)
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+		w.addArchiveFile(f)
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+func sha256Hex(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+// writeTestArchives writes app.jar and data.tar.gz into dir.
+func writeTestArchives(t *testing.T, dir string) {
+	t.Helper()
+	var zb bytes.Buffer
+	zw := zip.NewWriter(&zb)
+	for _, e := range []struct{ name, content string }{
+		{name: "META-INF/"},
+		{name: "META-INF/MANIFEST.MF", content: "Manifest-Version: 1.0\n"},
+		{name: "../escape.txt", content: "escape"},
+	} {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: e.name, Modified: time.Unix(1500000000, 0)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "app.jar"), zb.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var tb bytes.Buffer
+	gw := gzip.NewWriter(&tb)
+	tw := tar.NewWriter(gw)
+	for _, h := range []*tar.Header{
+		{Name: "./sub/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "./sub/a.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len("hello"))},
+		{Name: "./link", Typeflag: tar.TypeSymlink, Linkname: "sub/a.txt", Mode: 0777},
+	} {
+		h.ModTime = time.Unix(1500000000, 0)
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		if h.Size > 0 {
+			if _, err := tw.Write([]byte("hello")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "data.tar.gz"), tb.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInspectArchives(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "archives")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	writeTestArchives(t, tmpdir)
+	jar := filepath.Join(tmpdir, "app.jar")
+	tgz := filepath.Join(tmpdir, "data.tar.gz")
+
+	testCases := []struct {
+		desc      string
+		pol       *fspb.Policy
+		wantFiles map[string]string // virtual path => fingerprint
+		wantNotes []string
+	}{
+		{
+			desc: "disabled",
+			pol:  &fspb.Policy{},
+		}, {
+			desc: "all formats",
+			pol:  &fspb.Policy{InspectArchives: true},
+			wantFiles: map[string]string{
+				jar + "!/META-INF":             "",
+				jar + "!/META-INF/MANIFEST.MF": sha256Hex("Manifest-Version: 1.0\n"),
+				jar + "!/escape.txt":           sha256Hex("escape"),
+				tgz + "!/sub":                  "",
+				tgz + "!/sub/a.txt":            sha256Hex("hello"),
+				tgz + "!/link":                 "",
+			},
+		}, {
+			desc: "jar only with excludes",
+			pol: &fspb.Policy{
+				InspectArchives: true,
+				ArchiveFormats:  []string{".JAR"},
+				ExcludePfx:      []string{jar + "!/escape"},
+			},
+			wantFiles: map[string]string{
+				jar + "!/META-INF":             "",
+				jar + "!/META-INF/MANIFEST.MF": sha256Hex("Manifest-Version: 1.0\n"),
+			},
+		}, {
+			// The tar stream is larger than the compressed file, so it is only partly inspected.
+			desc:      "size limit",
+			pol:       &fspb.Policy{InspectArchives: true, ArchiveFormats: []string{"tgz", "tar.gz"}, MaxHashFileSize: 1024},
+			wantFiles: map[string]string{tgz + "!/sub": ""},
+			wantNotes: []string{tgz},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			tc.pol.Include = []string{tmpdir}
+			if tc.pol.MaxHashFileSize == 0 {
+				tc.pol.MaxHashFileSize = 1048576
+			}
+			tc.pol.HashPfx = []string{tmpdir}
+			wlkr := &Walker{pol: tc.pol, DryRun: true}
+			if err := wlkr.Run(ctx); err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+			gotFiles := map[string]string{}
+			for _, f := range wlkr.walk.File {
+				if !strings.Contains(f.Path, archiveSep+"/") {
+					continue
+				}
+				gotFiles[f.Path] = ""
+				if len(f.Fingerprint) > 0 {
+					gotFiles[f.Path] = f.Fingerprint[0].Value
+				}
+			}
+			if tc.wantFiles == nil {
+				tc.wantFiles = map[string]string{}
+			}
+			if diff := cmp.Diff(tc.wantFiles, gotFiles); diff != "" {
+				t.Errorf("Run(): diff (-want +got):\n%s", diff)
+			}
+			var gotNotes []string
+			for _, n := range wlkr.walk.Notification {
+				gotNotes = append(gotNotes, n.Path)
+			}
+			sort.Strings(gotNotes)
+			if diff := cmp.Diff(tc.wantNotes, gotNotes); diff != "" {
+				t.Errorf("Run() notifications: diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestArchiveFile(t *testing.T) {
+	wlkr := &Walker{pol: &fspb.Policy{}}
+	mod := time.Unix(1500000000, 0)
+	f := wlkr.archiveFile("/opt/app.tar", archiveEntry{name: "./bin/tool", mode: os.ModeSymlink | 0777, modTime: mod, linkname: "../lib/tool"})
+	if got, want := f.Path, "/opt/app.tar!/bin/tool"; got != want {
+		t.Errorf("archiveFile().Path = %q; want %q", got, want)
+	}
+	if got, want := f.Info.Name, "tool"; got != want {
+		t.Errorf("archiveFile().Info.Name = %q; want %q", got, want)
+	}
+	if got, want := f.SymlinkTarget, "../lib/tool"; got != want {
+		t.Errorf("archiveFile().SymlinkTarget = %q; want %q", got, want)
+	}
+	if f := wlkr.archiveFile("/opt/app.tar", archiveEntry{name: "./"}); f != nil {
+		t.Errorf("archiveFile() of the archive root = %v; want nil", f)
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"golang.org/x/sys/unix"
+
+	tspb "github.com/golang/protobuf/ptypes/timestamp"
+)
+
+// birthTime returns the birth time of the given path using statx. Symlinks are not followed.
+// If the kernel or the file system doesn't support birth times, nil and no error are returned.
+func birthTime(path string) (*tspb.Timestamp, error) {
+	var stx unix.Statx_t
+	err := unix.Statx(unix.AT_FDCWD, path, unix.AT_SYMLINK_NOFOLLOW, unix.STATX_BTIME, &stx)
+	if err == unix.ENOSYS {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stx.Mask&unix.STATX_BTIME == 0 {
+		return nil, nil
+	}
+	return &tspb.Timestamp{
+		Seconds: stx.Btime.Sec,
+		Nanos:   int32(stx.Btime.Nsec),
+	}, nil
+}
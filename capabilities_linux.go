@@ -0,0 +1,125 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+const (
+	// capabilityXattr is the extended attribute holding the capabilities of a file.
+	capabilityXattr = "security.capability"
+
+	// Layout of struct vfs_cap_data, see linux/capability.h.
+	vfsCapRevisionMask     = 0xFF000000
+	vfsCapRevision1        = 0x01000000
+	vfsCapRevision2        = 0x02000000
+	vfsCapRevision3        = 0x03000000
+	vfsCapFlagsEffective   = 0x000001
+	vfsCapRevision1Entries = 1
+	vfsCapRevision2Entries = 2
+)
+
+// capabilityNames are the names of the capabilities by number, see capabilities(7).
+var capabilityNames = []string{
+	"cap_chown", "cap_dac_override", "cap_dac_read_search", "cap_fowner", "cap_fsetid",
+	"cap_kill", "cap_setgid", "cap_setuid", "cap_setpcap", "cap_linux_immutable",
+	"cap_net_bind_service", "cap_net_broadcast", "cap_net_admin", "cap_net_raw", "cap_ipc_lock",
+	"cap_ipc_owner", "cap_sys_module", "cap_sys_rawio", "cap_sys_chroot", "cap_sys_ptrace",
+	"cap_sys_pacct", "cap_sys_admin", "cap_sys_boot", "cap_sys_nice", "cap_sys_resource",
+	"cap_sys_time", "cap_sys_tty_config", "cap_mknod", "cap_lease", "cap_audit_write",
+	"cap_audit_control", "cap_setfcap", "cap_mac_override", "cap_mac_admin", "cap_syslog",
+	"cap_wake_alarm", "cap_block_suspend", "cap_audit_read", "cap_perfmon", "cap_bpf",
+	"cap_checkpoint_restore",
+}
+
+// capabilityName returns the name of the capability with the given number.
+func capabilityName(n int) string {
+	if n < len(capabilityNames) {
+		return capabilityNames[n]
+	}
+	return fmt.Sprintf("cap_%d", n)
+}
+
+// fileCapabilities returns the capabilities of the given path in the text form of setcap(8),
+// like cap_get_file(3) and cap_to_text(3) do. Symlinks are followed. If the file has no
+// capabilities or the system doesn't support them, "" and no error are returned.
+func fileCapabilities(path string) (string, error) {
+	buf := make([]byte, 24) // large enough for all revisions of vfs_cap_data.
+	n, err := syscall.Getxattr(path, capabilityXattr, buf)
+	switch err {
+	case nil:
+	case syscall.ENODATA, syscall.ENOTSUP, syscall.ENOSYS:
+		return "", nil
+	default:
+		return "", err
+	}
+	return decodeCapabilities(buf[:n])
+}
+
+// decodeCapabilities converts a vfs_cap_data structure into the text form of setcap(8), e.g.
+// "cap_net_admin,cap_net_raw=ep". Capabilities with the same flags are listed together.
+func decodeCapabilities(data []byte) (string, error) {
+	if len(data) < 4 {
+		return "", fmt.Errorf("capability data too short: %d bytes", len(data))
+	}
+	magic := binary.LittleEndian.Uint32(data)
+	var entries int
+	switch magic & vfsCapRevisionMask {
+	case vfsCapRevision1:
+		entries = vfsCapRevision1Entries
+	case vfsCapRevision2, vfsCapRevision3:
+		entries = vfsCapRevision2Entries
+	default:
+		return "", fmt.Errorf("unknown capability revision %#x", magic&vfsCapRevisionMask)
+	}
+	if len(data) < 4+entries*8 {
+		return "", fmt.Errorf("capability data too short: %d bytes", len(data))
+	}
+	effective := magic&vfsCapFlagsEffective != 0
+
+	byFlags := map[string][]string{}
+	for i := 0; i < entries; i++ {
+		permitted := binary.LittleEndian.Uint32(data[4+i*8:])
+		inheritable := binary.LittleEndian.Uint32(data[8+i*8:])
+		for bit := 0; bit < 32; bit++ {
+			p, inh := permitted&(1<<uint(bit)) != 0, inheritable&(1<<uint(bit)) != 0
+			if !p && !inh {
+				continue
+			}
+			var flags string
+			if effective {
+				flags += "e"
+			}
+			if inh {
+				flags += "i"
+			}
+			if p {
+				flags += "p"
+			}
+			byFlags[flags] = append(byFlags[flags], capabilityName(i*32+bit))
+		}
+	}
+	var groups []string
+	for flags, names := range byFlags {
+		groups = append(groups, strings.Join(names, ",")+"="+flags)
+	}
+	sort.Strings(groups)
+	return strings.Join(groups, " "), nil
+}
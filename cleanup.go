@@ -0,0 +1,146 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// CleanupOptions selects the Walk files deleted by CleanupWalks. A Walk file is deleted if
+// either of the retention limits applies to it.
+type CleanupOptions struct {
+	// RetentionDays deletes Walks taken more than this many days ago. 0 disables it.
+	RetentionDays int
+	// RetentionCount keeps only this many of the most recent Walks of each host. 0 disables it.
+	RetentionCount int
+	// DryRun only determines the Walk files to delete without deleting them.
+	DryRun bool
+	// Now is the time RetentionDays is relative to. time.Now() is used if not set.
+	Now time.Time
+	// FilenameFormat is the layout of the Walk file names, see WalkFilenameWithFormat.
+	// DefaultWalkFilenameFormat is used if not set.
+	FilenameFormat string
+}
+
+// CleanupResult lists the Walk files deleted by CleanupWalks.
+type CleanupResult struct {
+	// Deleted are the names of the deleted Walk files, or the ones which would be deleted in a
+	// dry run, sorted by name.
+	Deleted []string
+	// Bytes is the total size of the deleted Walk files.
+	Bytes int64
+}
+
+// CleanupWalks deletes old Walk files of all hosts in walkPath and its subdirectories. The time
+// and host of each Walk are taken from its file name, so only files named in the FilenameFormat
+// of opts are considered. Delta Walks are not taken into account, i.e. deleting an old Walk may
+// leave delta Walks based on it unreadable.
+func CleanupWalks(ctx context.Context, walkPath string, opts CleanupOptions) (*CleanupResult, error) {
+	return cleanupWalks(ctx, storeForPath(walkPath), walkPath, opts)
+}
+
+// cleanupWalks is CleanupWalks using the given Walk store.
+func cleanupWalks(ctx context.Context, store WalkStore, walkPath string, opts CleanupOptions) (*CleanupResult, error) {
+	if opts.RetentionDays < 0 || opts.RetentionCount < 0 {
+		return nil, fmt.Errorf("retention limits must not be negative")
+	}
+	if opts.RetentionDays == 0 && opts.RetentionCount == 0 {
+		return nil, fmt.Errorf("either a retention in days or a retention count needs to be specified")
+	}
+	ds, ok := store.(DeletableWalkStore)
+	if !ok && !opts.DryRun {
+		return nil, fmt.Errorf("deleting Walks in %q is not supported by its Walk store", walkPath)
+	}
+	if err := ValidateWalkFilenameFormat(opts.FilenameFormat); err != nil {
+		return nil, err
+	}
+	names, err := findWalkFiles(ctx, store, "", opts.FilenameFormat, walkPath, true)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list Walks in %q: %w", walkPath, err)
+	}
+
+	type walkFile struct {
+		name string
+		t    time.Time
+	}
+	hosts := map[string][]walkFile{}
+	for _, n := range names {
+		host, t, err := parseWalkFilename(opts.FilenameFormat, "", n)
+		if err != nil {
+			continue
+		}
+		hosts[host] = append(hosts[host], walkFile{name: n, t: t})
+	}
+
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	cutoff := now.AddDate(0, 0, -opts.RetentionDays)
+	var del []string
+	for _, files := range hosts {
+		sort.Slice(files, func(i, j int) bool {
+			if !files[i].t.Equal(files[j].t) {
+				return files[i].t.Before(files[j].t)
+			}
+			return files[i].name < files[j].name
+		})
+		for i, f := range files {
+			tooOld := opts.RetentionDays > 0 && f.t.Before(cutoff)
+			tooMany := opts.RetentionCount > 0 && i < len(files)-opts.RetentionCount
+			if tooOld || tooMany {
+				del = append(del, f.name)
+			}
+		}
+	}
+	sort.Strings(del)
+
+	res := &CleanupResult{}
+	for _, n := range del {
+		size, err := walkFileSize(ctx, store, n)
+		if err != nil {
+			return res, &WalkIOError{Path: n, Err: fmt.Errorf("unable to determine size: %w", err)}
+		}
+		if !opts.DryRun {
+			if err := ds.Delete(ctx, n); err != nil {
+				return res, &WalkIOError{Path: n, Err: fmt.Errorf("unable to delete old Walk: %w", err)}
+			}
+		}
+		res.Deleted = append(res.Deleted, n)
+		res.Bytes += size
+	}
+	return res, nil
+}
+
+// walkFileSize returns the size of a Walk file. Files on the local file system are only
+// stat'ed, others are read.
+func walkFileSize(ctx context.Context, store WalkStore, name string) (int64, error) {
+	if _, ok := store.(LocalWalkStore); ok {
+		fi, err := os.Stat(name)
+		if err != nil {
+			return 0, err
+		}
+		return fi.Size(), nil
+	}
+	data, err := store.Read(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(data)), nil
+}
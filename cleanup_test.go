@@ -0,0 +1,123 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCleanupWalks(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2018, 12, 10, 12, 0, 0, 0, time.Local)
+	day := 24 * time.Hour
+	files := []string{
+		WalkFilename("a", now.Add(-10*day)),
+		WalkFilename("a", now.Add(-5*day)),
+		WalkFilename("a", now.Add(-1*day)),
+		filepath.Join("2018", WalkFilename("b", now.Add(-8*day))),
+		filepath.Join("2018", WalkFilename("b", now.Add(-2*day))),
+		"notes.txt",
+	}
+
+	testCases := []struct {
+		desc      string
+		opts      CleanupOptions
+		wantNames []string
+		wantErr   bool
+	}{
+		{
+			desc:      "days",
+			opts:      CleanupOptions{RetentionDays: 7},
+			wantNames: []string{files[3], files[0]},
+		}, {
+			desc:      "count",
+			opts:      CleanupOptions{RetentionCount: 1},
+			wantNames: []string{files[3], files[0], files[1]},
+		}, {
+			desc:      "days or count",
+			opts:      CleanupOptions{RetentionDays: 9, RetentionCount: 2},
+			wantNames: []string{files[0]},
+		}, {
+			desc:      "dry run",
+			opts:      CleanupOptions{RetentionDays: 7, DryRun: true},
+			wantNames: []string{files[3], files[0]},
+		}, {
+			desc:    "no limits",
+			wantErr: true,
+		}, {
+			desc:    "negative",
+			opts:    CleanupOptions{RetentionDays: -1},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			tmpdir, err := ioutil.TempDir("", "cleanup")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(tmpdir) // clean up
+			for i, f := range files {
+				p := filepath.Join(tmpdir, f)
+				if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(p, make([]byte, i+1), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			tc.opts.Now = now
+			res, err := CleanupWalks(ctx, tmpdir, tc.opts)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("CleanupWalks() error = %v; want error: %t", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var want []string
+			var wantBytes int64
+			wantDeleted := map[string]bool{}
+			for _, n := range tc.wantNames {
+				want = append(want, filepath.Join(tmpdir, n))
+				wantDeleted[n] = !tc.opts.DryRun
+				for i, f := range files {
+					if f == n {
+						wantBytes += int64(i + 1)
+					}
+				}
+			}
+			if diff := cmp.Diff(want, res.Deleted); diff != "" {
+				t.Errorf("CleanupWalks(): diff (-want +got):\n%s", diff)
+			}
+			if res.Bytes != wantBytes {
+				t.Errorf("CleanupWalks() freed %d bytes; want %d", res.Bytes, wantBytes)
+			}
+			for _, f := range files {
+				_, err := os.Stat(filepath.Join(tmpdir, f))
+				if deleted := os.IsNotExist(err); deleted != wantDeleted[f] {
+					t.Errorf("CleanupWalks() deleted %s: %t; want %t", f, deleted, wantDeleted[f])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Paginator pages the output of the reporter, e.g. through less.
+type Paginator interface {
+	// Paginate calls fn with the writer to write the output to and returns once the output
+	// was paged, i.e. the pager exited.
+	Paginate(ctx context.Context, fn func(w io.Writer) error) error
+}
+
+// LessPaginator pipes the output into $PAGER if it is set, or else into less found in $PATH.
+type LessPaginator struct {
+	// Stdout is where the pager writes to.
+	Stdout io.Writer
+}
+
+// command returns the pager to run along with its arguments, e.g. PAGER="less -R".
+func (LessPaginator) command() ([]string, error) {
+	if args := strings.Fields(os.Getenv("PAGER")); len(args) > 0 {
+		return args, nil
+	}
+	less, err := exec.LookPath("less")
+	if err != nil {
+		return nil, fmt.Errorf("no $PAGER set and %v", err)
+	}
+	return []string{less}, nil
+}
+
+// Paginate runs the pager and has fn write into its input.
+func (p LessPaginator) Paginate(ctx context.Context, fn func(w io.Writer) error) error {
+	args, err := p.command()
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = p.Stdout      // so the pager writes into stdout
+	in, err := cmd.StdinPipe() // so we write into the pager's input
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("unable to start %q: %v", args[0], err)
+	}
+	err = fn(in)
+	in.Close()
+	return errors.Join(err, cmd.Wait())
+}
+
+// NoopPaginator writes the output to W as is.
+type NoopPaginator struct {
+	W io.Writer
+}
+
+// Paginate calls fn with W.
+func (p NoopPaginator) Paginate(ctx context.Context, fn func(w io.Writer) error) error {
+	return fn(p.W)
+}
+
+// newPaginator returns the Paginator for the -paginate flag, writing to w if not paginating.
+func newPaginator(w io.Writer) Paginator {
+	if *paginate {
+		return LessPaginator{Stdout: os.Stdout}
+	}
+	return NoopPaginator{W: w}
+}
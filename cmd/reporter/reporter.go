@@ -16,34 +16,72 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
-	"os/exec"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/fswalker"
 )
 
 var (
-	configFile = flag.String("configFile", "", "required report config file to use")
-	walkPath   = flag.String("walkPath", "", "path to search for Walks")
-	reviewFile = flag.String("reviewFile", "", "path to the file containing a list of last-known-good states - this needs to be writeable")
-	hostname   = flag.String("hostname", "", "host to review the differences for")
-	beforeFile = flag.String("beforeFile", "", "path to the file to compare against (last known good typically)")
-	afterFile  = flag.String("afterFile", "", "path to the file to compare with the before state")
-	paginate   = flag.Bool("paginate", false, "pipe output into $PAGER in order to paginate and make reviews easier")
-	verbose    = flag.Bool("verbose", false, "print additional output for each file which changed")
+	configFile   = flag.String("configFile", "", "required report config file to use")
+	walkPath     = flag.String("walkPath", "", "path to search for Walks - may also be a glob pattern like /walks/*/ to search several local directories, or a gcs://bucket/prefix or s3://bucket/prefix URI")
+	reviewFile   = flag.String("reviewFile", "", "path to the file containing a list of last-known-good states - this needs to be writeable and may also be a gcs:// or s3:// URI")
+	hostname     = flag.String("hostname", "", "host to review the differences for")
+	beforeFile   = flag.String("beforeFile", "", "path to the file to compare against (last known good typically) - may also be a gcs:// or s3:// URI")
+	afterFile    = flag.String("afterFile", "", "path to the file to compare with the before state - may also be a gcs:// or s3:// URI, or - to read the Walk from stdin")
+	paginate     = flag.Bool("paginate", false, "pipe output into $PAGER in order to paginate and make reviews easier")
+	verbose      = flag.Bool("verbose", false, "print additional output for each file which changed")
+	outputFormat = flag.String("outputFormat", outputText, "format of the diff output: text, json or html")
+	autoUpdate   = flag.Bool("autoUpdate", false, "update the reviews file without asking for confirmation")
+	noUpdate     = flag.Bool("noUpdate", false, "never update the reviews file and don't ask for confirmation")
+	allHosts     = flag.Bool("allHosts", false, "compare the Walks of all hosts found in walkPath, one after another")
+	since        = flag.Duration("since", 0, "only consider Walks in walkPath written within this duration, e.g. 24h")
+	startTime    = flag.String("startTime", "", "compare all Walks of hostname in walkPath written at or after this RFC 3339 time, e.g. 2018-09-21T00:00:00Z, step by step")
+	endTime      = flag.String("endTime", "", "compare all Walks of hostname in walkPath written at or before this RFC 3339 time, step by step")
+	golden       = flag.Bool("compareGolden", false, "compare the latest Walk of hostname, or of all hosts in walkPath, against the latest Walk of golden_hostname of the config instead of the last known good")
+	recursive    = flag.Bool("recursive", false, "search subdirectories of walkPath too, e.g. for Walks written with the walker's -outputDirLayout")
+	nameFormat   = flag.String("filenameFormat", fswalker.DefaultWalkFilenameFormat, "layout of the names of the Walk files to search walkPath for, as set with the walker's -filenameFormat")
+	changeTypes  = flag.String("filterChangeTypes", "", "comma separated change types to report, e.g. PERMISSION_CHANGED,OWNER_CHANGED - overrides change_type_filter of the config if set")
+	diffContext  = flag.Int("diffContext", 0, "list up to this many neighbouring paths of the Walk around each changed path in the text output, like diff -C")
+	ignoreMtime  = flag.Bool("ignoreMtimeOnly", false, "don't report files whose only change is their mtime, e.g. log files touched without changing their content")
+	minChanges   = flag.Int("minChangeCount", 0, "print nothing and don't update the reviews file if fewer changes than this are found - privilege changes failing the report are always reported")
+	minChangesRC = flag.Int("minChangeCountExit", 0, "exit code to use if changes were found but fewer than minChangeCount")
+	verify       = flag.Bool("verify", false, "only verify the checksum of the Walk in afterFile without comparing anything")
+	metricsAddr  = flag.String("metricsAddr", "", "address (e.g. :9100) of an HTTP server to start exposing metrics to Prometheus at /metrics while the reporter runs")
+	concurrency  = flag.Int("concurrency", 1, "number of hosts to compare in parallel with allHosts - requires autoUpdate or noUpdate if above 1")
+	saveDiffPfx  = flag.String("saveDiffPfx", "", "directory or gcs:// or s3:// prefix to save the comparison of each host to as a WalkDiff proto file, e.g. next to the Walks for a history of changes")
+	explainRule  = flag.String("explainRule", "", "only list the files of the latest Walk covered by this rule of the rule summary along with their metadata, e.g. 'include: \"/etc\"'")
+	statsOnly    = flag.Bool("statsOnly", false, "only print the report summary and metrics without the per-file diff, e.g. for monitoring - requires autoUpdate or noUpdate")
 )
 
 const (
-	lessCmd = "/usr/bin/less"
+	// Supported formats of the diff output.
+	outputText = "text"
+	outputJSON = "json"
+	outputHTML = "html"
+
+	// exitChanges is the exit code used when differences were found.
+	// It differs from the exit code of log.Fatal so scripts can tell drift from failure.
+	exitChanges = 2
 )
 
 func updateReviews() bool {
+	switch {
+	case *autoUpdate:
+		return true
+	case *noUpdate, *afterFile == fswalker.StdioPath:
+		// Walks read from stdin can't be reviewed and stdin can't be prompted anymore.
+		return false
+	}
 	fmt.Print("Do you want to update the \"last known good\" to this [y/N]: ")
 	var input string
 	fmt.Scanln(&input)
@@ -53,65 +91,352 @@ func updateReviews() bool {
 	return false
 }
 
+// reportResult is the outcome of reporting on the Walks of a single host.
+type reportResult int
+
+const (
+	resultClean          reportResult = iota // no changes.
+	resultBelowMin                           // changes, but fewer than minChangeCount; nothing printed.
+	resultNeedsAttention                     // changes to look into.
+)
+
+// printText writes the text report of the loaded Walks to out. With statsOnly, the Walks are
+// still compared to count all metrics but only the summaries are printed.
+func printText(rptr *fswalker.Reporter, out io.Writer) {
+	if *statsOnly {
+		rptr.Compare(ioutil.Discard)
+		rptr.PrintReportSummary(out)
+		rptr.PrintRuleSummary(out)
+		return
+	}
+	rptr.PrintReportSummary(out)
+	rptr.PrintRuleSummary(out)
+	rptr.Compare(out)
+}
+
+// report loads, compares and optionally reviews the Walks of a single host and returns
+// whether there were changes and whether they need attention. All output is written to w.
+func report(ctx context.Context, rptr *fswalker.Reporter, host string, w io.Writer) reportResult {
+	rptr.SetOutput(w)
+	if err := rptr.LoadWalks(ctx, host, *reviewFile, *walkPath, *afterFile, *beforeFile); err != nil {
+		log.Fatal(err)
+	}
+	if err := rptr.Validate(ctx); err != nil {
+		log.Fatalf("walks failed validation: %v", err)
+	}
+	if *minChanges > 0 && !rptr.FailOnPrivilegeChange() {
+		s, err := rptr.Summary()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if n := s.ChangeCount(); n < *minChanges {
+			if n == 0 {
+				return resultClean
+			}
+			return resultBelowMin
+		}
+	}
+
+	// Processing and output, paginated via $PAGER if requested.
+	err := newPaginator(w).Paginate(ctx, func(out io.Writer) error {
+		switch *outputFormat {
+		case outputJSON:
+			return rptr.CompareJSON(out)
+		case outputHTML:
+			return rptr.CompareHTML(out)
+		}
+		printText(rptr, out)
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *saveDiffPfx != "" {
+		saveDiff(ctx, rptr, w)
+	}
+
+	// Update reviews file if desired.
+	if updateReviews() {
+		if err := rptr.UpdateReviewProto(ctx); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		fmt.Fprintln(w, "not updating reviews file")
+	}
+
+	if rptr.ChangeCount() > 0 || rptr.FailOnPrivilegeChange() {
+		return resultNeedsAttention
+	}
+	return resultClean
+}
+
+// saveDiff writes the comparison of the loaded Walks below saveDiffPfx, named after the host
+// and the current time.
+func saveDiff(ctx context.Context, rptr *fswalker.Reporter, w io.Writer) {
+	s, err := rptr.Summary()
+	if err != nil {
+		log.Fatal(err)
+	}
+	p := strings.TrimSuffix(*saveDiffPfx, "/") + "/" + fswalker.WalkDiffFilename(s.Hostname, time.Now())
+	if err := rptr.SaveDiff(ctx, p); err != nil {
+		log.Fatalf("unable to save diff: %v", err)
+	}
+	fmt.Fprintf(w, "Saved diff to %q\n", p)
+}
+
+// reportConcurrently runs report for up to n hosts in parallel, each with its own clone of rptr
+// sharing its Counter and reviews. The output of each host is buffered and printed in the
+// order of hosts once it is complete, so the sections of different hosts never interleave.
+func reportConcurrently(ctx context.Context, rptr *fswalker.Reporter, hosts []string, n int) reportResult {
+	if rptr.Reviews == nil {
+		// Clones need to share the reviews so they don't overwrite each other's baselines.
+		rptr.Reviews = fswalker.ReviewManagerFromFile(*reviewFile)
+	}
+	outs := make([]bytes.Buffer, len(hosts))
+	results := make([]reportResult, len(hosts))
+	done := make([]chan struct{}, len(hosts))
+	sem := make(chan struct{}, n)
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		done[i] = make(chan struct{})
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			defer close(done[i])
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = report(ctx, rptr.Clone(), host, &outs[i])
+		}(i, host)
+	}
+
+	result := resultClean
+	for i, host := range hosts {
+		<-done[i]
+		fmt.Printf("=== %s ===\n", host)
+		os.Stdout.Write(outs[i].Bytes())
+		fmt.Println()
+		if results[i] > result {
+			result = results[i]
+		}
+	}
+	wg.Wait()
+	return result
+}
+
+// parseTimeFlag parses an RFC 3339 time flag. An empty value results in the zero time.
+func parseTimeFlag(name, v string) time.Time {
+	if v == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", name, err)
+	}
+	return t
+}
+
+// reportRange compares each consecutive pair of Walks of the host written between startTime and
+// endTime to follow changes over time. The reviews file is neither read nor updated.
+// It returns whether any changes were found.
+func reportRange(ctx context.Context, rptr *fswalker.Reporter) bool {
+	start, end := parseTimeFlag("startTime", *startTime), parseTimeFlag("endTime", *endTime)
+	names, err := rptr.LoadWalksByTimeRange(ctx, *hostname, *walkPath, start, end)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Found %d Walks of %s in the time range.\n", len(names), *hostname)
+	changes := false
+	for i := 1; i < len(names); i++ {
+		if err := rptr.LoadWalks(ctx, "", "", "", names[i], names[i-1]); err != nil {
+			log.Fatal(err)
+		}
+		if err := rptr.Validate(ctx); err != nil {
+			log.Fatalf("walks failed validation: %v", err)
+		}
+		fmt.Printf("\n=== %s => %s ===\n", names[i-1], names[i])
+		printText(rptr, os.Stdout)
+		if rptr.ChangeCount() > 0 || rptr.FailOnPrivilegeChange() {
+			changes = true
+		}
+	}
+	return changes
+}
+
+// verifyWalk checks the checksum of the Walk file at path.
+func verifyWalk(ctx context.Context, path string) {
+	wlk, err := fswalker.ReadWalk(ctx, path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if wlk.Checksum == nil {
+		log.Fatalf("%q has no checksum to verify, it was written by an older walker", path)
+	}
+	fmt.Printf("%q: checksum %s(%s) OK\n", path, wlk.Checksum.Method, wlk.Checksum.Value)
+}
+
 func main() {
 	ctx := context.Background()
+	flag.Parse()
+
+	if *verify {
+		if *afterFile == "" {
+			log.Fatal("verify requires afterFile")
+		}
+		verifyWalk(ctx, *afterFile)
+		return
+	}
 
 	// Loading configs and walks.
 	if *configFile == "" {
 		log.Fatal("configFile needs to be specified")
 	}
+	if *outputFormat != outputText && *outputFormat != outputJSON && *outputFormat != outputHTML {
+		log.Fatalf("unknown outputFormat %q", *outputFormat)
+	}
+	if *autoUpdate && *noUpdate {
+		log.Fatal("autoUpdate and noUpdate are mutually exclusive")
+	}
+	if *autoUpdate && *afterFile == fswalker.StdioPath {
+		log.Fatal("autoUpdate can't be used when reading the Walk from stdin")
+	}
+	if *statsOnly {
+		if *outputFormat != outputText {
+			log.Fatal("statsOnly only supports the text outputFormat")
+		}
+		if !*autoUpdate && !*noUpdate && *startTime == "" && *endTime == "" {
+			log.Fatal("statsOnly requires autoUpdate or noUpdate as the changes to review aren't printed")
+		}
+	}
+	if *concurrency < 1 {
+		log.Fatal("concurrency needs to be at least 1")
+	}
+	if *concurrency > 1 {
+		if !*allHosts {
+			log.Fatal("concurrency requires allHosts")
+		}
+		if !*autoUpdate && !*noUpdate {
+			log.Fatal("concurrency requires autoUpdate or noUpdate as parallel comparisons can't prompt")
+		}
+		if *paginate {
+			log.Fatal("concurrency can't be combined with paginate")
+		}
+	}
+	if err := fswalker.ValidateWalkFilenameFormat(*nameFormat); err != nil {
+		log.Fatal(err)
+	}
 	rptr, err := fswalker.ReporterFromConfigFile(ctx, *configFile, *verbose)
 	if err != nil {
 		log.Fatal(err)
 	}
-	if err := rptr.LoadWalks(ctx, *hostname, *reviewFile, *walkPath, *afterFile, *beforeFile); err != nil {
-		log.Fatal(err)
+	if rptr.Reviews != nil && *reviewFile != "" {
+		log.Printf("warning: ignoring reviewFile, reviews are kept in %s as set by review_backend of the config", rptr.Reviews)
+	}
+	if *metricsAddr != "" {
+		if err := rptr.Counter.ServePrometheus(*metricsAddr, "fswalker_reporter"); err != nil {
+			log.Fatalf("unable to serve metrics: %v", err)
+		}
+	}
+	rptr.Since = *since
+	rptr.Recursive = *recursive
+	rptr.FilenameFormat = *nameFormat
+	rptr.IgnoreMtimeOnly = *ignoreMtime
+	rptr.DiffContext = *diffContext
+	if rptr.ChangeTypeFilter, err = fswalker.ParseChangeTypes(*changeTypes); err != nil {
+		log.Fatalf("invalid filterChangeTypes: %v", err)
 	}
 
-	// Processing and output.
-	// Note that we do some trickery here to allow pagination via $PAGER if requested.
-	out := io.WriteCloser(os.Stdout)
-	var cmd *exec.Cmd
-	if *paginate {
-		// Use $PAGER if it is set - if not, revert to using less.
-		cmdpath := os.Getenv("PAGER")
-		if cmdpath == "" {
-			cmdpath = lessCmd
+	if *startTime != "" || *endTime != "" {
+		if *hostname == "" || *walkPath == "" || *allHosts || *afterFile != "" || *beforeFile != "" {
+			log.Fatal("startTime and endTime require hostname and walkPath and can't be combined with allHosts, afterFile or beforeFile")
+		}
+		if *outputFormat != outputText {
+			log.Fatal("startTime and endTime only support the text outputFormat")
+		}
+		if reportRange(ctx, rptr) {
+			os.Exit(exitChanges)
 		}
+		return
+	}
 
-		var err error
-		cmd = exec.Command(lessCmd)
-		cmd.Stdout = os.Stdout     // so less writes into stdout
-		out, err = cmd.StdinPipe() // so we write into less' input
-		if err != nil {
+	if *golden {
+		if *walkPath == "" || *allHosts || *afterFile != "" || *beforeFile != "" {
+			log.Fatal("compareGolden requires walkPath and can't be combined with allHosts, afterFile or beforeFile")
+		}
+		if *outputFormat != outputText {
+			log.Fatal("compareGolden only supports the text outputFormat")
+		}
+		var hosts []string
+		if *hostname != "" {
+			hosts = []string{*hostname}
+		}
+		if err := rptr.CompareAgainstGolden(ctx, "", *walkPath, hosts); err != nil {
 			log.Fatal(err)
 		}
-		if err := cmd.Start(); err != nil {
-			log.Fatal(fmt.Errorf("unable to start %q: %v", lessCmd, err))
+		if rptr.ChangeCount() > 0 {
+			os.Exit(exitChanges)
 		}
+		return
 	}
-	rptr.PrintReportSummary(out)
-	rptr.PrintRuleSummary(out)
-	rptr.Compare(out)
 
-	if *paginate {
-		out.Close()
-		cmd.Wait()
+	if *explainRule != "" {
+		if *allHosts {
+			log.Fatal("explainRule can't be combined with allHosts")
+		}
+		if err := rptr.LoadWalks(ctx, *hostname, *reviewFile, *walkPath, *afterFile, *beforeFile); err != nil {
+			log.Fatal(err)
+		}
+		err := newPaginator(os.Stdout).Paginate(ctx, func(out io.Writer) error {
+			return rptr.ExplainRule(*explainRule, out)
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
-	// Update reviews file if desired.
-	if updateReviews() {
-		if err := rptr.UpdateReviewProto(ctx); err != nil {
+	hosts := []string{*hostname}
+	if *allHosts {
+		if *hostname != "" || (*reviewFile == "" && rptr.Reviews == nil) || *walkPath == "" {
+			log.Fatal("allHosts requires reviewFile (or review_backend of the config) and walkPath and can't be combined with hostname")
+		}
+		if *outputFormat != outputText {
+			log.Fatal("allHosts only supports the text outputFormat")
+		}
+		if hosts, err = rptr.WalkHosts(ctx, *walkPath); err != nil {
 			log.Fatal(err)
 		}
+		if len(hosts) == 0 {
+			log.Fatalf("no Walks found in %q", *walkPath)
+		}
+		rptr.PrefixHostname = true
+	}
+
+	result := resultClean
+	if *concurrency > 1 && len(hosts) > 1 {
+		result = reportConcurrently(ctx, rptr, hosts, *concurrency)
 	} else {
-		fmt.Println("not updating reviews file")
+		for _, host := range hosts {
+			if r := report(ctx, rptr, host, os.Stdout); r > result {
+				result = r
+			}
+		}
+	}
+	// With minChangeCount, hosts without changes to report print nothing at all.
+	switch {
+	case result == resultBelowMin:
+		os.Exit(*minChangesRC)
+	case result == resultClean && *minChanges > 0:
+		return
 	}
 
 	fmt.Println()
 	fmt.Println("Metrics:")
-	for _, k := range rptr.Counter.Metrics() {
-		v, _ := rptr.Counter.Get(k)
+	for k, v := range rptr.Counter.Export() {
 		fmt.Printf("[%-30s] = %6d\n", k, v)
 	}
+
+	if result == resultNeedsAttention {
+		os.Exit(exitChanges)
+	}
 }
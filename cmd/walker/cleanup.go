@@ -0,0 +1,68 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/google/fswalker"
+)
+
+// runCleanup implements "walker cleanup", which deletes old Walk files of all hosts in a
+// directory and its subdirectories.
+func runCleanup(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("cleanup", flag.ExitOnError)
+	walkPath := fs.String("walkPath", "", "required directory to delete old Walks from, including its subdirectories - may also be a gcs:// or s3:// URI")
+	retentionDays := fs.Int("retentionDays", 0, "deletes Walks taken more than this many days ago")
+	retentionCount := fs.Int("retentionCount", 0, "keeps only this many of the most recent Walks of each host")
+	dryRun := fs.Bool("dryRun", false, "when set to true, only prints the Walks which would be deleted instead of deleting them")
+	filenameFormat := fs.String("filenameFormat", fswalker.DefaultWalkFilenameFormat, "layout of the names of the Walk files, as set with -filenameFormat of the walk")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s cleanup -walkPath=<dir> [-retentionDays=<n>] [-retentionCount=<n>] [-dryRun]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *walkPath == "" {
+		log.Fatal("walkPath needs to be specified")
+	}
+	res, err := fswalker.CleanupWalks(ctx, *walkPath, fswalker.CleanupOptions{
+		RetentionDays:  *retentionDays,
+		RetentionCount: *retentionCount,
+		DryRun:         *dryRun,
+		FilenameFormat: *filenameFormat,
+	})
+	if res != nil {
+		for _, n := range res.Deleted {
+			if *dryRun {
+				fmt.Printf("Would delete %s\n", n)
+			} else {
+				fmt.Printf("Deleted %s\n", n)
+			}
+		}
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *dryRun {
+		fmt.Printf("Would delete %d Walk files, freeing %d bytes.\n", len(res.Deleted), res.Bytes)
+	} else {
+		fmt.Printf("Deleted %d Walk files, freed %d bytes.\n", len(res.Deleted), res.Bytes)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/google/fswalker"
+)
+
+// runCSV implements "walker csv", which writes the files of an existing Walk file as CSV.
+func runCSV(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("csv", flag.ExitOnError)
+	in := fs.String("in", "", "required Walk file to convert - may also be a gcs:// or s3:// URI")
+	out := fs.String("out", "", "path of the CSV file to write - stdout if unset")
+	columns := fs.String("columns", strings.Join(fswalker.DefaultCSVColumns, ","), "comma separated columns to write: path, size, mode, uid, gid, mtime, sha256")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s csv -in=<walk> [-out=<csv>] [-columns=path,size,...]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *in == "" {
+		log.Fatal("in needs to be specified")
+	}
+	wlk, err := fswalker.ReadWalk(ctx, *in)
+	if err != nil {
+		log.Fatalf("unable to read Walk: %v", err)
+	}
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	if err := fswalker.WalkToCSV(wlk, bw, strings.Split(*columns, ",")); err != nil {
+		log.Fatalf("unable to write CSV: %v", err)
+	}
+	if err := bw.Flush(); err != nil {
+		log.Fatalf("unable to write CSV: %v", err)
+	}
+}
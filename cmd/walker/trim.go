@@ -0,0 +1,69 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/google/fswalker"
+)
+
+// stringList is a flag which can be given multiple times.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// runTrim implements "walker trim", which removes files matching exclude patterns from an
+// existing Walk file.
+func runTrim(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("trim", flag.ExitOnError)
+	in := fs.String("in", "", "required Walk file to trim - may also be a gcs:// or s3:// URI")
+	out := fs.String("out", "", "required path of the trimmed Walk file to write - the format is determined by its extension")
+	var excludes stringList
+	fs.Var(&excludes, "exclude", "glob pattern (see path.Match) of files to remove along with anything below them - may be given multiple times")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s trim -in=<walk> -out=<walk> -exclude=<pattern>...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *in == "" || *out == "" {
+		log.Fatal("in and out need to be specified")
+	}
+	wlk, err := fswalker.ReadWalk(ctx, *in)
+	if err != nil {
+		log.Fatalf("unable to read Walk: %v", err)
+	}
+	trimmed, err := fswalker.TrimWalk(wlk, excludes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := fswalker.WriteWalk(ctx, *out, trimmed); err != nil {
+		log.Fatalf("unable to write Walk: %v", err)
+	}
+	fmt.Printf("Removed %d of %d files.\n", len(wlk.File)-len(trimmed.File), len(wlk.File))
+}
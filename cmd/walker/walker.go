@@ -17,59 +17,361 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strings"
 	"time"
 
 	"github.com/google/fswalker"
 )
 
 var (
-	maxHashFileSize = flag.Int64("maxHashFileSize", 1024*1024, "max size of a file in bytes up to which a hash is generated")
-	policyFile      = flag.String("policyFile", "", "required policy file to use")
-	outputFilePfx   = flag.String("outputFilePfx", "", "path prefix for the output file to write (when a path is set)")
+	maxHashFileSize = flag.Int64("maxHashFileSize", 1024*1024, "max size of a file in bytes up to which a hash is generated - overrides max_hash_file_size of the policy if set")
+	policyFile      = flag.String("policyFile", "", "required policy file or http(s):// URL to use - note that walks stay on the file system of each include path unless walk_cross_device is set")
+	policyTimeout   = flag.Duration("policyTimeout", 30*time.Second, "timeout for fetching the policy when policyFile is a URL")
+	insecureTLS     = flag.Bool("insecureSkipVerify", false, "when set to true, skips TLS certificate verification when fetching the policy from an https:// URL")
+	outputFilePfx   = flag.String("outputFilePfx", "", "path prefix for the output file to write (when a path is set) - may also be a gcs://bucket/prefix or s3://bucket/prefix URI, or - to write the Walk to stdout")
+	filenameFormat  = flag.String("filenameFormat", fswalker.DefaultWalkFilenameFormat, "layout of the output file name without extension - a Go time layout in which %h is replaced by the hostname")
+	outputDirLayout = flag.String("outputDirLayout", "", "subdirectories of outputFilePfx to write the output file to - {year}, {month}, {day} and {host} are replaced, e.g. {year}/{month}/{day}/{host} - delta walks and max_walk_retention of the policy consider the Walks in all subdirectories")
+	outputFormat    = flag.String("outputFormat", string(fswalker.OutputFormatProto), "format of the output file: proto, json or textproto")
+	hostnameOvr     = flag.String("hostnameOverride", "", "hostname to record in the Walk and its file name instead of the one of this machine, e.g. the service name in a container")
+	compress        = flag.Bool("compress", false, "when set to true, gzip compresses the output file")
 	verbose         = flag.Bool("verbose", false, "when set to true, prints all discovered files including a metadata summary")
+	maxErrors       = flag.Uint("maxErrors", 0, "abort the walk after this many unreadable files or directories - overrides max_errors of the policy if non-zero")
+	checkPolicy     = flag.Bool("checkPolicy", false, "only validate the policy and print any problems found without walking - exits non-zero if the policy is invalid")
+	requireIncludes = flag.Bool("requireIncludes", false, "when set to true, treats include paths of the policy missing on this host as an invalid policy, also with checkPolicy, instead of skipping them")
+	probe           = flag.Bool("probe", false, "only estimate the number of files, their size and the duration of the walk by sampling directories, without walking")
+	probeDirs       = flag.Int("probeDirs", 100, "number of directories to sample from each include path with probe")
+	dryRun          = flag.Bool("dryRun", false, "when set to true, walks the file system without writing the output file - exits non-zero if any file could not be walked")
+	dryRunCleanup   = flag.Bool("dryRunCleanup", false, "when set to true, only prints the old Walks which max_walk_retention of the policy would delete instead of deleting them")
+	metricsAddr     = flag.String("metricsAddr", "", "address (e.g. :9100) of an HTTP server to start exposing metrics to Prometheus at /metrics while the walker runs")
+	progressEvery   = flag.Duration("progressInterval", 10*time.Second, "interval at which walk progress is printed to stderr when verbose is set")
+	walkTimeout     = flag.Duration("timeout", 0, "stop walking after this duration and write the files walked until then as a partial Walk - exits non-zero")
+	cpuProfile      = flag.String("cpuProfile", "", "file to write a pprof CPU profile of the walk to")
+	memProfile      = flag.String("memProfile", "", "file to write a pprof heap profile to once the walk is done")
 )
 
-func outputPath(pfx string) (string, error) {
-	if pfx == "" {
-		return "", nil
+// startCPUProfile starts writing a CPU profile to path if non-empty. The returned function
+// stops profiling and closes the file.
+func startCPUProfile(path string) (func(), error) {
+	if path == "" {
+		return func() {}, nil
 	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create CPU profile: %v", err)
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unable to start CPU profile: %v", err)
+	}
+	return func() {
+		pprof.StopCPUProfile()
+		if err := f.Close(); err != nil {
+			log.Printf("unable to write CPU profile: %v", err)
+		}
+	}, nil
+}
+
+// writeMemProfile writes a heap profile to path if non-empty.
+func writeMemProfile(path string) error {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("unable to create memory profile: %v", err)
+	}
+	runtime.GC() // get up-to-date statistics
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to write memory profile: %v", err)
+	}
+	return f.Close()
+}
 
+// printProgress prints the latest progress received on the channel to stderr once per interval
+// until the channel is closed.
+func printProgress(progress <-chan fswalker.WalkProgress, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last fswalker.WalkProgress
+	for {
+		select {
+		case p, ok := <-progress:
+			if !ok {
+				return
+			}
+			last = p
+		case <-ticker.C:
+			if last.Dir != "" {
+				fmt.Fprintf(os.Stderr, "progress: %d files, %d bytes hashed, reading %s\n", last.Files, last.BytesHashed, last.Dir)
+			}
+		}
+	}
+}
+
+// flagSet returns whether the flag with the given name was set on the command line.
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// walkHostname returns the hostname to record in the Walk, hostnameOverride if set.
+func walkHostname() (string, error) {
+	if *hostnameOvr != "" {
+		return *hostnameOvr, nil
+	}
 	hn, err := os.Hostname()
 	if err != nil {
 		return "", fmt.Errorf("unable to determine hostname: %v", err)
 	}
-	return filepath.Join(pfx, fswalker.WalkFilename(hn, time.Now())), nil
+	return hn, nil
+}
+
+// outputPath builds the path of the output file below pfx. Directories of dirLayout which
+// don't exist yet are created on the local file system.
+func outputPath(pfx, dirLayout, layout string, format fswalker.OutputFormat, compress bool) (string, error) {
+	if pfx == "" || pfx == fswalker.StdioPath {
+		return pfx, nil
+	}
+
+	hn, err := walkHostname()
+	if err != nil {
+		return "", err
+	}
+	return outputPathAt(pfx, dirLayout, layout, format, compress, hn, time.Now())
+}
+
+// outputPathAt is outputPath for the Walk of host hn taken at now. On the local file system, an
+// existing Walk file of the same name, e.g. written within the same second, is never
+// overwritten (see fswalker.WalkFilenameUniqueWithFormat).
+func outputPathAt(pfx, dirLayout, layout string, format fswalker.OutputFormat, compress bool, hn string, now time.Time) (string, error) {
+	dir, err := fswalker.OutputDir(dirLayout, hn, now)
+	if err != nil {
+		return "", err
+	}
+	nameLayout := fswalker.WalkFilenameLayout(layout, format, compress)
+	if strings.HasPrefix(pfx, "gcs://") || strings.HasPrefix(pfx, "s3://") {
+		name := fswalker.WalkFilenameWithFormat(hn, now, nameLayout)
+		if dir = strings.Trim(dir, "/"); dir != "" {
+			name = dir + "/" + name
+		}
+		return strings.TrimSuffix(pfx, "/") + "/" + name, nil
+	}
+	if dir != "" {
+		dir = filepath.Join(pfx, dir)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", fmt.Errorf("unable to create output directory: %v", err)
+		}
+	} else {
+		dir = pfx
+	}
+	name, err := fswalker.WalkFilenameUniqueWithFormat(hn, now, dir, nameLayout)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// setupPolicyClient configures the HTTP client used to fetch policies from URLs.
+func setupPolicyClient() {
+	fswalker.PolicyHTTPClient.Timeout = *policyTimeout
+	if *insecureTLS {
+		fswalker.PolicyHTTPClient.Transport = &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+}
+
+// walkerOptions returns the options for creating the Walker from the policy as set by flags.
+func walkerOptions() []fswalker.WalkerOption {
+	var opts []fswalker.WalkerOption
+	if *requireIncludes {
+		opts = append(opts, fswalker.RequireRootPaths())
+	}
+	return opts
+}
+
+// runCheckPolicy validates the policy and prints all problems found along with include paths
+// missing on this host. It returns whether the policy is valid.
+func runCheckPolicy(ctx context.Context) bool {
+	setupPolicyClient()
+	w, err := fswalker.WalkerFromPolicyFile(ctx, *policyFile, "", *verbose, walkerOptions()...)
+	var ipe *fswalker.InvalidPolicyError
+	if errors.As(err, &ipe) {
+		errs := []error{ipe.Err}
+		if u, ok := ipe.Err.(interface{ Unwrap() []error }); ok {
+			errs = u.Unwrap()
+		}
+		fmt.Printf("Policy %q is invalid:\n", *policyFile)
+		for _, e := range errs {
+			fmt.Printf("  - %v\n", e)
+		}
+		return false
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, p := range w.MissingRootPaths() {
+		fmt.Printf("warning: include %q doesn't exist on this host\n", p)
+	}
+	fmt.Printf("Policy %q is valid.\n", *policyFile)
+	return true
+}
+
+func runProbe(ctx context.Context) {
+	setupPolicyClient()
+	w, err := fswalker.WalkerFromPolicyFile(ctx, *policyFile, "", *verbose)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w.ProbeDirs = *probeDirs
+	if flagSet("maxHashFileSize") {
+		w.MaxHashFileSize = *maxHashFileSize
+	}
+	est, err := w.Probe(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if est.Exact {
+		fmt.Printf("Walk of policy %q (all %d directories read):\n", *policyFile, est.SampledDirs)
+	} else {
+		fmt.Printf("Estimated walk of policy %q (%d directories sampled):\n", *policyFile, est.SampledDirs)
+	}
+	fmt.Printf("  - Files: %d (%d bytes, %d hashed), Directories: %d\n", est.Files, est.Bytes, est.HashBytes, est.Dirs)
+	fmt.Printf("  - Duration: %s\n", est.Duration.Round(time.Millisecond))
 }
 
 func main() {
 	ctx := context.Background()
+	flag.Parse()
 
+	switch flag.Arg(0) {
+	case "trim":
+		runTrim(ctx, flag.Args()[1:])
+		return
+	case "csv":
+		runCSV(ctx, flag.Args()[1:])
+		return
+	case "cleanup":
+		runCleanup(ctx, flag.Args()[1:])
+		return
+	}
 	if *policyFile == "" {
 		log.Fatal("policyFile needs to be specified")
 	}
+	if *checkPolicy {
+		if !runCheckPolicy(ctx) {
+			os.Exit(1)
+		}
+		return
+	}
+	if *probe {
+		runProbe(ctx)
+		return
+	}
+	format := fswalker.OutputFormat(*outputFormat)
+	if !format.Valid() {
+		log.Fatalf("unknown outputFormat %q", *outputFormat)
+	}
 
-	outpath, err := outputPath(*outputFilePfx)
+	if *hostnameOvr != "" {
+		if strings.ContainsAny(*hostnameOvr, "/*?[") {
+			log.Fatalf("invalid hostnameOverride %q", *hostnameOvr)
+		}
+		if hn, err := os.Hostname(); err == nil && hn != *hostnameOvr {
+			log.Printf("warning: recording hostname %q instead of %q as set by -hostnameOverride", *hostnameOvr, hn)
+		}
+	}
+	if *outputDirLayout != "" && (*outputFilePfx == "" || *outputFilePfx == fswalker.StdioPath) {
+		log.Fatal("outputDirLayout requires outputFilePfx to be a directory or object prefix")
+	}
+	if err := fswalker.ValidateWalkFilenameFormat(*filenameFormat); err != nil {
+		log.Fatal(err)
+	}
+	outpath, err := outputPath(*outputFilePfx, *outputDirLayout, *filenameFormat, format, *compress)
 	if err != nil {
 		log.Fatal(err)
 	}
-	w, err := fswalker.WalkerFromPolicyFile(ctx, *policyFile, outpath, *verbose)
+	// Keep stdout clean for the Walk when writing it there.
+	stdout := os.Stdout
+	if outpath == fswalker.StdioPath {
+		if *verbose {
+			log.Fatal("verbose can't be combined with writing the Walk to stdout")
+		}
+		stdout = os.Stderr
+	}
+	setupPolicyClient()
+	w, err := fswalker.WalkerFromPolicyFile(ctx, *policyFile, outpath, *verbose, walkerOptions()...)
 	if err != nil {
 		log.Fatal(err)
 	}
+	w.OutputFormat = format
+	w.OutputRoot = *outputFilePfx
+	w.OutputDirLayout = *outputDirLayout
+	w.FilenameFormat = *filenameFormat
+	w.Hostname = *hostnameOvr
+	w.Compress = *compress
+	w.MaxErrors = uint32(*maxErrors)
+	w.DryRun = *dryRun
+	w.DryRunCleanup = *dryRunCleanup
+	if flagSet("maxHashFileSize") {
+		if pm := w.PolicyMaxHashFileSize(); pm != 0 && pm != *maxHashFileSize {
+			log.Printf("warning: -maxHashFileSize=%d overrides max_hash_file_size=%d of the policy", *maxHashFileSize, pm)
+		}
+		w.MaxHashFileSize = *maxHashFileSize
+	}
+	if *metricsAddr != "" {
+		if err := w.Counter.ServePrometheus(*metricsAddr, "fswalker_walker"); err != nil {
+			log.Fatalf("unable to serve metrics: %v", err)
+		}
+	}
 
 	// Walk the file system and wait for completion of processing.
-	if err := w.Run(ctx); err != nil {
+	var progress chan fswalker.WalkProgress
+	if *verbose && *progressEvery > 0 {
+		progress = make(chan fswalker.WalkProgress, 1)
+		go printProgress(progress, *progressEvery)
+	}
+	walkCtx := ctx
+	if *walkTimeout > 0 {
+		var cancel context.CancelFunc
+		walkCtx, cancel = context.WithTimeout(ctx, *walkTimeout)
+		defer cancel()
+	}
+	stopCPUProfile, err := startCPUProfile(*cpuProfile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = w.RunWithProgress(walkCtx, progress)
+	if progress != nil {
+		close(progress)
+	}
+	// Profiles are written even if the walk failed, as they may explain why.
+	stopCPUProfile()
+	if perr := writeMemProfile(*memProfile); perr != nil {
+		log.Print(perr)
+	}
+	if err != nil {
 		log.Fatal(err)
 	}
 
-	fmt.Println("Metrics:")
-	for _, k := range w.Counter.Metrics() {
-		v, _ := w.Counter.Get(k)
-		fmt.Printf("[%-30s] = %6d\n", k, v)
+	fmt.Fprintln(stdout, "Metrics:")
+	for k, v := range w.Counter.Export() {
+		fmt.Fprintf(stdout, "[%-30s] = %6d\n", k, v)
 	}
 }
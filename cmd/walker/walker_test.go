@@ -0,0 +1,90 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/fswalker"
+)
+
+func TestOutputPathAtSameSecond(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "walks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+
+	now := time.Date(2018, 12, 6, 10, 1, 2, 0, time.Local)
+	testCases := []struct {
+		desc      string
+		dirLayout string
+		layout    string
+		format    fswalker.OutputFormat
+		compress  bool
+		want      []string
+	}{
+		{
+			desc:   "default format",
+			layout: fswalker.DefaultWalkFilenameFormat,
+			format: fswalker.OutputFormatProto,
+			want: []string{
+				"host-20181206-100102-fswalker-state.pb",
+				"host-20181206-100102.1-fswalker-state.pb",
+			},
+		}, {
+			desc:      "custom format compressed JSON",
+			dirLayout: "{host}",
+			layout:    "20060102-150405-%h",
+			format:    fswalker.OutputFormatJSON,
+			compress:  true,
+			want: []string{
+				"host/20181206-100102-host.json.gz",
+				"host/20181206-100102.1-host.json.gz",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		for _, want := range tc.want {
+			p, err := outputPathAt(tmpdir, tc.dirLayout, tc.layout, tc.format, tc.compress, "host", now)
+			if err != nil {
+				t.Fatalf("%s: outputPathAt() error: %v", tc.desc, err)
+			}
+			if want = filepath.Join(tmpdir, want); p != want {
+				t.Errorf("%s: outputPathAt() = %q; want %q", tc.desc, p, want)
+			}
+			if err := ioutil.WriteFile(p, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if _, err := outputPathAt(tmpdir, "", "%h-20060102", fswalker.OutputFormatProto, false, "host", now); err != nil {
+		t.Fatalf("outputPathAt() without seconds error: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmpdir, "host-20181206.pb"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := outputPathAt(tmpdir, "", "%h-20060102", fswalker.OutputFormatProto, false, "host", now); err == nil {
+		t.Error("outputPathAt() of an existing Walk without seconds succeeded; want error")
+	}
+	if p, err := outputPathAt("gcs://bucket/walks", "", fswalker.DefaultWalkFilenameFormat, fswalker.OutputFormatProto, false, "host", now); err != nil || p != "gcs://bucket/walks/host-20181206-100102-fswalker-state.pb" {
+		t.Errorf("outputPathAt() of a GCS prefix = %q, %v", p, err)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+// DefaultCSVColumns are the columns written by WalkToCSV if none are given.
+var DefaultCSVColumns = []string{"path", "size", "mode", "uid", "gid", "mtime", "sha256"}
+
+// csvColumns maps the supported column names to functions formatting a file for that column.
+var csvColumns = map[string]func(f *fspb.File) (string, error){
+	"path": func(f *fspb.File) (string, error) { return f.Path, nil },
+	"size": func(f *fspb.File) (string, error) { return strconv.FormatInt(f.GetInfo().GetSize(), 10), nil },
+	"mode": func(f *fspb.File) (string, error) { return fmt.Sprintf("%#o", f.GetStat().GetMode()), nil },
+	"uid":  func(f *fspb.File) (string, error) { return strconv.FormatUint(uint64(f.GetStat().GetUid()), 10), nil },
+	"gid":  func(f *fspb.File) (string, error) { return strconv.FormatUint(uint64(f.GetStat().GetGid()), 10), nil },
+	"mtime": func(f *fspb.File) (string, error) {
+		if f.GetStat().GetMtime() == nil {
+			return "", nil
+		}
+		t, err := ptypes.Timestamp(f.Stat.Mtime)
+		if err != nil {
+			return "", err
+		}
+		return t.UTC().Format(time.RFC3339Nano), nil
+	},
+	"sha256": func(f *fspb.File) (string, error) {
+		for _, fp := range f.Fingerprint {
+			if fp.Method == fspb.Fingerprint_SHA256 {
+				return fp.Value, nil
+			}
+		}
+		return "", nil
+	},
+}
+
+// WalkToCSV writes the files of the Walk as CSV, e.g. to import them into a spreadsheet or
+// database. The first row names the columns, followed by one row per file. columns selects
+// which of path, size, mode, uid, gid, mtime and sha256 to write and in which order; nil
+// means DefaultCSVColumns. Modes are octal st_mode values including the file type, mtimes
+// are RFC 3339 in UTC and files without a SHA256 fingerprint have an empty sha256.
+func WalkToCSV(w *fspb.Walk, out io.Writer, columns []string) error {
+	if columns == nil {
+		columns = DefaultCSVColumns
+	}
+	formats := make([]func(*fspb.File) (string, error), len(columns))
+	for i, c := range columns {
+		fn, ok := csvColumns[c]
+		if !ok {
+			return fmt.Errorf("unknown CSV column %q", c)
+		}
+		formats[i] = fn
+	}
+	cw := csv.NewWriter(out)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	row := make([]string, len(columns))
+	for _, f := range w.File {
+		for i, fn := range formats {
+			v, err := fn(f)
+			if err != nil {
+				return fmt.Errorf("%s of %q: %v", columns[i], f.Path, err)
+			}
+			row[i] = v
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WalkFromCSV reads files written by WalkToCSV into a Walk. The columns are taken from the
+// first row, which needs to contain path. Only the information of the CSV columns is set, so
+// the returned Walk has no ID, hostname or timestamps and files miss e.g. their inode.
+func WalkFromCSV(r io.Reader) (*fspb.Walk, error) {
+	cr := csv.NewReader(r)
+	columns, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("empty CSV: missing header row")
+	}
+	if err != nil {
+		return nil, err
+	}
+	hasPath := false
+	for _, c := range columns {
+		if _, ok := csvColumns[c]; !ok {
+			return nil, fmt.Errorf("unknown CSV column %q", c)
+		}
+		hasPath = hasPath || c == "path"
+	}
+	if !hasPath {
+		return nil, fmt.Errorf("CSV has no path column")
+	}
+
+	wlk := &fspb.Walk{Version: walkVersion}
+	for n := 1; ; n++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return wlk, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		f := &fspb.File{Version: fileVersion, Info: &fspb.FileInfo{}, Stat: &fspb.FileStat{}}
+		for i, v := range row {
+			if err := parseCSVField(f, columns[i], v); err != nil {
+				return nil, fmt.Errorf("row %d: invalid %s %q: %v", n, columns[i], v, err)
+			}
+		}
+		f.Info.Name = path.Base(f.Path)
+		f.Info.IsDir = f.Stat.Mode&syscall.S_IFMT == syscall.S_IFDIR
+		wlk.File = append(wlk.File, f)
+	}
+}
+
+// parseCSVField sets the information of a CSV column of a file.
+func parseCSVField(f *fspb.File, column, v string) error {
+	switch column {
+	case "path":
+		f.Path = v
+	case "size":
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return err
+		}
+		f.Info.Size, f.Stat.Size = n, n
+	case "mode":
+		m, err := strconv.ParseUint(v, 0, 32)
+		if err != nil {
+			return err
+		}
+		f.Stat.Mode = uint32(m)
+	case "uid", "gid":
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return err
+		}
+		if column == "uid" {
+			f.Stat.Uid = uint32(id)
+		} else {
+			f.Stat.Gid = uint32(id)
+		}
+	case "mtime":
+		if v == "" {
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return err
+		}
+		ts, err := ptypes.TimestampProto(t)
+		if err != nil {
+			return err
+		}
+		f.Stat.Mtime, f.Info.Modified = ts, ts
+	case "sha256":
+		if v != "" {
+			f.Fingerprint = []*fspb.Fingerprint{{Method: fspb.Fingerprint_SHA256, Value: v}}
+		}
+	}
+	return nil
+}
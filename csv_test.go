@@ -0,0 +1,130 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"bytes"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/go-cmp/cmp"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+func TestWalkToCSV(t *testing.T) {
+	ts, _ := ptypes.TimestampProto(time.Unix(1546300800, 5).UTC())
+	wlk := &fspb.Walk{
+		File: []*fspb.File{
+			{
+				Path:        "/etc/passwd",
+				Info:        &fspb.FileInfo{Size: 42},
+				Stat:        &fspb.FileStat{Mode: syscall.S_IFREG | 0644, Uid: 0, Gid: 0, Mtime: ts},
+				Fingerprint: []*fspb.Fingerprint{{Method: fspb.Fingerprint_SHA256, Value: "abc"}},
+			},
+			{
+				Path: "/home/user, with comma",
+				Info: &fspb.FileInfo{IsDir: true},
+				Stat: &fspb.FileStat{Mode: syscall.S_IFDIR | 0755, Uid: 1000, Gid: 100},
+			},
+		},
+	}
+	testCases := []struct {
+		desc    string
+		columns []string
+		want    string
+		wantErr bool
+	}{
+		{
+			desc: "default columns",
+			want: "path,size,mode,uid,gid,mtime,sha256\n" +
+				"/etc/passwd,42,0100644,0,0,2019-01-01T00:00:00.000000005Z,abc\n" +
+				"\"/home/user, with comma\",0,040755,1000,100,,\n",
+		}, {
+			desc:    "selected columns",
+			columns: []string{"sha256", "path"},
+			want:    "sha256,path\nabc,/etc/passwd\n,\"/home/user, with comma\"\n",
+		}, {
+			desc:    "unknown column",
+			columns: []string{"path", "inode"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := WalkToCSV(wlk, &buf, tc.columns)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("WalkToCSV() error = %v; want error: %t", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want, buf.String()); diff != "" {
+				t.Errorf("WalkToCSV(): diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWalkFromCSV(t *testing.T) {
+	ts, _ := ptypes.TimestampProto(time.Unix(1546300800, 5).UTC())
+	wlk := &fspb.Walk{
+		Version: walkVersion,
+		File: []*fspb.File{
+			{
+				Version:     fileVersion,
+				Path:        "/etc/passwd",
+				Info:        &fspb.FileInfo{Name: "passwd", Size: 42, Modified: ts},
+				Stat:        &fspb.FileStat{Mode: syscall.S_IFREG | 0644, Size: 42, Mtime: ts},
+				Fingerprint: []*fspb.Fingerprint{{Method: fspb.Fingerprint_SHA256, Value: "abc"}},
+			},
+			{
+				Version: fileVersion,
+				Path:    "/home/user",
+				Info:    &fspb.FileInfo{Name: "user", IsDir: true},
+				Stat:    &fspb.FileStat{Mode: syscall.S_IFDIR | 0755, Uid: 1000, Gid: 100},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	if err := WalkToCSV(wlk, &buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err := WalkFromCSV(&buf)
+	if err != nil {
+		t.Fatalf("WalkFromCSV() error: %v", err)
+	}
+	if diff := cmp.Diff(wlk, got, cmp.Comparer(proto.Equal)); diff != "" {
+		t.Errorf("WalkFromCSV(): diff (-want +got):\n%s", diff)
+	}
+
+	for _, in := range []string{
+		"",
+		"size,mode\n1,0644\n",
+		"path,inode\n/etc/passwd,1\n",
+		"path,size\n/etc/passwd,large\n",
+		"path,mtime\n/etc/passwd,yesterday\n",
+		"path,size\n/etc/passwd\n",
+	} {
+		if _, err := WalkFromCSV(strings.NewReader(in)); err == nil {
+			t.Errorf("WalkFromCSV(%q) succeeded; want error", in)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+// MergeWalks applies a delta Walk to its base Walk and returns the resulting full Walk.
+// Files are kept in the order of base, with files added by the delta appended at the end.
+// All other fields (ID, policy, times, notifications, summary) are taken from the delta.
+// Neither of the given Walks is modified.
+func MergeWalks(base, delta *fspb.Walk) *fspb.Walk {
+	merged := &fspb.Walk{
+		Id:           delta.Id,
+		Version:      delta.Version,
+		Policy:       delta.Policy,
+		Notification: delta.Notification,
+		Hostname:     delta.Hostname,
+		StartWalk:    delta.StartWalk,
+		StopWalk:     delta.StopWalk,
+		Summary:      delta.Summary,
+	}
+	deleted := make(map[string]bool, len(delta.Deleted))
+	for _, p := range delta.Deleted {
+		deleted[p] = true
+	}
+	changed := make(map[string]*fspb.File, len(delta.File))
+	for _, f := range delta.File {
+		changed[f.Path] = f
+	}
+	for _, f := range base.File {
+		if deleted[f.Path] {
+			continue
+		}
+		if c, ok := changed[f.Path]; ok {
+			merged.File = append(merged.File, c)
+			delete(changed, f.Path)
+			continue
+		}
+		merged.File = append(merged.File, f)
+	}
+	for _, f := range delta.File {
+		if _, ok := changed[f.Path]; ok {
+			merged.File = append(merged.File, f)
+		}
+	}
+	return merged
+}
+
+// fileChanged returns whether a file differs from its version in the base Walk.
+// The access time is ignored as hashing the file alone may update it.
+func fileChanged(before, after *fspb.File) bool {
+	b, a := *before, *after
+	if b.Stat != nil && a.Stat != nil {
+		bs, as := *b.Stat, *a.Stat
+		bs.Atime, as.Atime = nil, nil
+		b.Stat, a.Stat = &bs, &as
+	}
+	return !proto.Equal(&b, &a)
+}
+
+// makeDelta reduces wlk to the files which were added or changed since base and records
+// the paths of the files which no longer exist. baseName is the path of the base Walk relative
+// to the directory of wlk, usually just its file name (see relWalkPath).
+func makeDelta(wlk, base *fspb.Walk, baseName string) {
+	baseFiles := make(map[string]*fspb.File, len(base.File))
+	for _, f := range base.File {
+		baseFiles[f.Path] = f
+	}
+	var files []*fspb.File
+	for _, f := range wlk.File {
+		if b, ok := baseFiles[f.Path]; !ok || fileChanged(b, f) {
+			files = append(files, f)
+		}
+		delete(baseFiles, f.Path)
+	}
+	var deleted []string
+	for p := range baseFiles {
+		deleted = append(deleted, p)
+	}
+	sort.Strings(deleted)
+
+	wlk.File = files
+	wlk.Deleted = deleted
+	wlk.BaseWalk = baseName
+}
+
+// gzipMagic are the first bytes of gzip compressed content.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// loadWalk reads a Walk file from the store and verifies its checksum, if any. The encoding (binary or JSON)
+// and compression are determined by the file extension, or by the content when reading from stdin. It returns
+// the Walk along with the file content as stored.
+func loadWalk(ctx context.Context, store WalkStore, path string) (*fspb.Walk, []byte, error) {
+	b, err := store.Read(ctx, path)
+	if err != nil {
+		return nil, nil, &WalkIOError{Path: path, Err: err}
+	}
+	wb := b
+	format, compressed := formatFromPath(path), isCompressed(path)
+	if path == StdioPath {
+		// There is no file extension to go by.
+		compressed = bytes.HasPrefix(b, gzipMagic)
+	}
+	if compressed {
+		if wb, err = gunzip(b); err != nil {
+			return nil, nil, &WalkProtoError{Path: path, Err: err}
+		}
+	}
+	if path == StdioPath && bytes.HasPrefix(bytes.TrimSpace(wb), []byte("{")) {
+		format = OutputFormatJSON
+	}
+	wlk, err := unmarshalWalk(wb, format)
+	if err != nil {
+		return nil, nil, &WalkProtoError{Path: path, Err: err}
+	}
+	if err := verifyWalkChecksum(wlk); err != nil {
+		return nil, nil, &WalkProtoError{Path: path, Err: err}
+	}
+	return wlk, b, nil
+}
+
+// resolveWalk follows the chain of base Walks of the delta Walk read from path and returns
+// the merged full Walk. Base Walks are read from the same store.
+// Walks which aren't deltas are returned as is.
+func resolveWalk(ctx context.Context, store WalkStore, path string, wlk *fspb.Walk) (*fspb.Walk, error) {
+	seen := map[string]bool{path: true}
+	var deltas []*fspb.Walk
+	for p := path; wlk.BaseWalk != ""; {
+		p = joinWalkPath(walkPathDir(p), wlk.BaseWalk)
+		if seen[p] {
+			return nil, fmt.Errorf("base Walks of %q form a cycle at %q", path, p)
+		}
+		seen[p] = true
+		base, _, err := loadWalk(ctx, store, p)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load base Walk of %q: %w", path, err)
+		}
+		deltas = append(deltas, wlk)
+		wlk = base
+	}
+	for i := len(deltas) - 1; i >= 0; i-- {
+		wlk = MergeWalks(wlk, deltas[i])
+	}
+	return wlk, nil
+}
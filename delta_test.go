@@ -0,0 +1,157 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+
+	tspb "github.com/golang/protobuf/ptypes/timestamp"
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+func TestMergeWalks(t *testing.T) {
+	base := &fspb.Walk{
+		Id: "base",
+		File: []*fspb.File{
+			{Path: "/a", Info: &fspb.FileInfo{Size: 1}},
+			{Path: "/b", Info: &fspb.FileInfo{Size: 1}},
+			{Path: "/c", Info: &fspb.FileInfo{Size: 1}},
+		},
+	}
+	delta := &fspb.Walk{
+		Id:       "delta",
+		Hostname: "host",
+		BaseWalk: "base.pb",
+		File: []*fspb.File{
+			{Path: "/d", Info: &fspb.FileInfo{Size: 4}},
+			{Path: "/b", Info: &fspb.FileInfo{Size: 2}},
+		},
+		Deleted: []string{"/c"},
+		Summary: &fspb.WalkSummary{FileCount: 3, TotalSizeBytes: 7},
+	}
+	want := &fspb.Walk{
+		Id:       "delta",
+		Hostname: "host",
+		Summary:  &fspb.WalkSummary{FileCount: 3, TotalSizeBytes: 7},
+		File: []*fspb.File{
+			{Path: "/a", Info: &fspb.FileInfo{Size: 1}},
+			{Path: "/b", Info: &fspb.FileInfo{Size: 2}},
+			{Path: "/d", Info: &fspb.FileInfo{Size: 4}},
+		},
+	}
+
+	got := MergeWalks(base, delta)
+	if diff := cmp.Diff(want, got, cmp.Comparer(proto.Equal)); diff != "" {
+		t.Errorf("MergeWalks(): diff (-want +got):\n%s", diff)
+	}
+	if len(base.File) != 3 || len(delta.File) != 2 {
+		t.Error("MergeWalks() modified its input")
+	}
+}
+
+func TestMakeDelta(t *testing.T) {
+	base := &fspb.Walk{
+		File: []*fspb.File{
+			{Path: "/same", Stat: &fspb.FileStat{Size: 1, Atime: &tspb.Timestamp{Seconds: 1}}},
+			{Path: "/changed", Stat: &fspb.FileStat{Size: 1}},
+			{Path: "/gone", Stat: &fspb.FileStat{Size: 1}},
+		},
+	}
+	wlk := &fspb.Walk{
+		File: []*fspb.File{
+			{Path: "/same", Stat: &fspb.FileStat{Size: 1, Atime: &tspb.Timestamp{Seconds: 2}}},
+			{Path: "/changed", Stat: &fspb.FileStat{Size: 2}},
+			{Path: "/new", Stat: &fspb.FileStat{Size: 1}},
+		},
+	}
+	want := &fspb.Walk{
+		BaseWalk: "base.pb",
+		File: []*fspb.File{
+			{Path: "/changed", Stat: &fspb.FileStat{Size: 2}},
+			{Path: "/new", Stat: &fspb.FileStat{Size: 1}},
+		},
+		Deleted: []string{"/gone"},
+	}
+
+	makeDelta(wlk, base, "base.pb")
+	if diff := cmp.Diff(want, wlk, cmp.Comparer(proto.Equal)); diff != "" {
+		t.Errorf("makeDelta(): diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestResolveWalk(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "walks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+
+	walks := map[string]*fspb.Walk{
+		"full.pb": {
+			Id:   "full",
+			File: []*fspb.File{{Path: "/a"}, {Path: "/b"}},
+		},
+		"delta1.pb": {
+			Id:       "delta1",
+			BaseWalk: "full.pb",
+			File:     []*fspb.File{{Path: "/c"}},
+			Deleted:  []string{"/a"},
+		},
+		"delta2.pb": {
+			Id:       "delta2",
+			BaseWalk: "delta1.pb",
+			Deleted:  []string{"/b"},
+		},
+		"loop.pb": {
+			Id:       "loop",
+			BaseWalk: "loop.pb",
+		},
+	}
+	for name, wlk := range walks {
+		b, err := proto.Marshal(wlk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(tmpdir, name), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := filepath.Join(tmpdir, "delta2.pb")
+	got, err := resolveWalk(ctx, LocalWalkStore{}, p, walks["delta2.pb"])
+	if err != nil {
+		t.Fatalf("resolveWalk() error: %v", err)
+	}
+	want := &fspb.Walk{
+		Id:   "delta2",
+		File: []*fspb.File{{Path: "/c"}},
+	}
+	if diff := cmp.Diff(want, got, cmp.Comparer(proto.Equal)); diff != "" {
+		t.Errorf("resolveWalk(): diff (-want +got):\n%s", diff)
+	}
+
+	p = filepath.Join(tmpdir, "loop.pb")
+	if _, err := resolveWalk(ctx, LocalWalkStore{}, p, walks["loop.pb"]); err == nil {
+		t.Error("resolveWalk() no error for cyclic base Walks")
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import "fmt"
+
+// The error types below are returned (possibly wrapped) by the Walker and Reporter so callers can
+// tell failure modes apart with errors.As, e.g.:
+//
+//	var pe *fswalker.PolicyLoadError
+//	if errors.As(err, &pe) { ... }
+//
+// Each of them wraps the underlying error, so errors.Is(err, os.ErrNotExist) works as well.
+
+// PolicyLoadError is returned when a Walker policy can't be read, e.g. because the file
+// doesn't exist or the policy server can't be reached.
+type PolicyLoadError struct {
+	Path string
+	Err  error
+}
+
+func (e *PolicyLoadError) Error() string {
+	return fmt.Sprintf("unable to load policy %q: %v", e.Path, e.Err)
+}
+
+func (e *PolicyLoadError) Unwrap() error { return e.Err }
+
+// InvalidPolicyError is returned when a Walker policy can't be parsed or contains invalid settings.
+type InvalidPolicyError struct {
+	Err error
+}
+
+func (e *InvalidPolicyError) Error() string {
+	return fmt.Sprintf("invalid policy: %v", e.Err)
+}
+
+func (e *InvalidPolicyError) Unwrap() error { return e.Err }
+
+// ConfigLoadError is returned when a Reporter config can't be read.
+type ConfigLoadError struct {
+	Path string
+	Err  error
+}
+
+func (e *ConfigLoadError) Error() string {
+	return fmt.Sprintf("unable to load report config %q: %v", e.Path, e.Err)
+}
+
+func (e *ConfigLoadError) Unwrap() error { return e.Err }
+
+// InvalidConfigError is returned when a Reporter config can't be parsed or contains invalid settings.
+type InvalidConfigError struct {
+	Err error
+}
+
+func (e *InvalidConfigError) Error() string {
+	return fmt.Sprintf("invalid report config: %v", e.Err)
+}
+
+func (e *InvalidConfigError) Unwrap() error { return e.Err }
+
+// WalkIOError is returned when walking the file system fails or a Walk file can't be read or
+// written. Path is the Walk file, if the error relates to one.
+type WalkIOError struct {
+	Path string
+	Err  error
+}
+
+func (e *WalkIOError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%q: %v", e.Path, e.Err)
+}
+
+func (e *WalkIOError) Unwrap() error { return e.Err }
+
+// WalkProtoError is returned when a Walk file can't be decoded (or a Walk can't be encoded),
+// e.g. because the file is corrupt or isn't a Walk at all.
+type WalkProtoError struct {
+	Path string
+	Err  error
+}
+
+func (e *WalkProtoError) Error() string {
+	return fmt.Sprintf("invalid Walk %q: %v", e.Path, e.Err)
+}
+
+func (e *WalkProtoError) Unwrap() error { return e.Err }
+
+// ReviewLoadError is returned when the reviews (the last known good Walk of each host) can't
+// be loaded. Store describes where the reviews are kept, e.g. the path of the review file.
+type ReviewLoadError struct {
+	Store string
+	Err   error
+}
+
+func (e *ReviewLoadError) Error() string {
+	return fmt.Sprintf("unable to load reviews from %s: %v", e.Store, e.Err)
+}
+
+func (e *ReviewLoadError) Unwrap() error { return e.Err }
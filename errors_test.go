@@ -0,0 +1,128 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+func TestErrorTypes(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "errors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+	missing := filepath.Join(tmpdir, "missing")
+	garbage := filepath.Join(tmpdir, "garbage.pb")
+	if err := ioutil.WriteFile(garbage, []byte("not a walk"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		desc     string
+		fn       func() error
+		as       func(error) bool
+		notExist bool // Whether errors.Is(err, os.ErrNotExist) holds.
+	}{
+		{
+			desc: "missing policy",
+			fn: func() error {
+				_, err := WalkerFromPolicyFile(ctx, missing, "", false)
+				return err
+			},
+			as:       func(err error) bool { var e *PolicyLoadError; return errors.As(err, &e) },
+			notExist: true,
+		}, {
+			desc: "invalid policy",
+			fn: func() error {
+				_, err := WalkerFromPolicyBytes(ctx, []byte("no_such_field: 1"), "", false)
+				return err
+			},
+			as: func(err error) bool { var e *InvalidPolicyError; return errors.As(err, &e) },
+		}, {
+			desc: "invalid policy setting",
+			fn: func() error {
+				_, err := WalkerFromPolicyBytes(ctx, []byte(`exclude_regex: "("`), "", false)
+				return err
+			},
+			as: func(err error) bool { var e *InvalidPolicyError; return errors.As(err, &e) },
+		}, {
+			desc: "missing report config",
+			fn: func() error {
+				_, err := ReporterFromConfigFile(ctx, missing, false)
+				return err
+			},
+			as:       func(err error) bool { var e *ConfigLoadError; return errors.As(err, &e) },
+			notExist: true,
+		}, {
+			desc: "invalid report config",
+			fn: func() error {
+				_, err := ReporterFromConfigBytes(ctx, []byte("no_such_field: 1"), false)
+				return err
+			},
+			as: func(err error) bool { var e *InvalidConfigError; return errors.As(err, &e) },
+		}, {
+			desc: "missing Walk",
+			fn: func() error {
+				return (&Reporter{config: &fspb.ReportConfig{}}).LoadWalks(ctx, "", "", "", missing, "")
+			},
+			as:       func(err error) bool { var e *WalkIOError; return errors.As(err, &e) },
+			notExist: true,
+		}, {
+			desc: "corrupt Walk",
+			fn: func() error {
+				_, err := ReadWalk(ctx, garbage)
+				return err
+			},
+			as: func(err error) bool { var e *WalkProtoError; return errors.As(err, &e) },
+		}, {
+			desc: "unwritable Walk",
+			fn: func() error {
+				return WriteWalk(ctx, filepath.Join(missing, "walk.pb"), &fspb.Walk{})
+			},
+			as:       func(err error) bool { var e *WalkIOError; return errors.As(err, &e) },
+			notExist: true,
+		}, {
+			desc: "missing reviews",
+			fn: func() error {
+				return ReviewManagerFromFile(missing).Load(ctx)
+			},
+			as:       func(err error) bool { var e *ReviewLoadError; return errors.As(err, &e) },
+			notExist: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.fn()
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if !tc.as(err) {
+				t.Errorf("error %v (%T) is not of the expected type", err, err)
+			}
+			if got := errors.Is(err, os.ErrNotExist); got != tc.notExist {
+				t.Errorf("errors.Is(%v, os.ErrNotExist) = %t; want %t", err, got, tc.notExist)
+			}
+		})
+	}
+}
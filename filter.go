@@ -0,0 +1,164 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+// WalkFilter normalizes a Walk before it is compared, e.g. by removing volatile paths.
+// Filters can be passed to Reporter.LoadWalks and combined with ChainFilter.
+type WalkFilter interface {
+	// Apply returns the filtered Walk. The given Walk is not modified.
+	Apply(w *fspb.Walk) (*fspb.Walk, error)
+}
+
+// filterWalk returns a copy of the Walk with only the files for which keep returns true.
+// The deleted files of delta Walks are filtered by keepDeleted, or kept if it is nil.
+func filterWalk(w *fspb.Walk, keep func(f *fspb.File) bool, keepDeleted func(p string) bool) *fspb.Walk {
+	filtered := *w
+	filtered.Checksum = nil // no longer matches, WriteWalk sets a new one.
+	filtered.Summary = nil  // counts the filtered files.
+	filtered.File = nil
+	for _, f := range w.File {
+		if keep(f) {
+			filtered.File = append(filtered.File, f)
+		}
+	}
+	if keepDeleted != nil {
+		filtered.Deleted = nil
+		for _, p := range w.Deleted {
+			if keepDeleted(p) {
+				filtered.Deleted = append(filtered.Deleted, p)
+			}
+		}
+	}
+	return &filtered
+}
+
+// PathPrefixFilter removes the files whose path starts with one of Prefixes, e.g. "/tmp/".
+type PathPrefixFilter struct {
+	Prefixes []string
+}
+
+// Apply removes the files with one of the prefixes.
+func (pf PathPrefixFilter) Apply(w *fspb.Walk) (*fspb.Walk, error) {
+	keep := func(p string) bool {
+		for _, pfx := range pf.Prefixes {
+			if strings.HasPrefix(p, pfx) {
+				return false
+			}
+		}
+		return true
+	}
+	return filterWalk(w, func(f *fspb.File) bool { return keep(f.Path) }, keep), nil
+}
+
+// RegexFilter removes the files whose path matches Regexp, e.g. `\.log(\.\d+)?$`.
+type RegexFilter struct {
+	Regexp *regexp.Regexp
+}
+
+// Apply removes the files matching the regular expression.
+func (rf RegexFilter) Apply(w *fspb.Walk) (*fspb.Walk, error) {
+	if rf.Regexp == nil {
+		return nil, fmt.Errorf("RegexFilter without regular expression")
+	}
+	keep := func(p string) bool { return !rf.Regexp.MatchString(p) }
+	return filterWalk(w, func(f *fspb.File) bool { return keep(f.Path) }, keep), nil
+}
+
+// AgeFilter only keeps the files modified within an age window relative to the start of the
+// Walk, like min_mtime_age and max_mtime_age of the policy. A zero MinAge or MaxAge leaves that
+// side of the window open. Directories are always kept.
+type AgeFilter struct {
+	MinAge time.Duration
+	MaxAge time.Duration
+}
+
+// Apply removes the files outside of the age window.
+func (af AgeFilter) Apply(w *fspb.Walk) (*fspb.Walk, error) {
+	start, err := ptypes.Timestamp(w.StartWalk)
+	if err != nil {
+		return nil, fmt.Errorf("walk %s has no valid start time: %v", w.Id, err)
+	}
+	return filterWalk(w, func(f *fspb.File) bool {
+		if f.GetInfo().GetIsDir() {
+			return true
+		}
+		mtime, err := ptypes.Timestamp(f.GetInfo().GetModified())
+		if err != nil {
+			return true // keep files which can't be judged.
+		}
+		age := start.Sub(mtime)
+		return age >= af.MinAge && (af.MaxAge == 0 || age <= af.MaxAge)
+	}, nil), nil
+}
+
+// SizeFilter only keeps the files with a size in bytes between MinSize and MaxSize, e.g. to skip
+// large media files. A zero MaxSize means no upper limit. Directories are always kept.
+type SizeFilter struct {
+	MinSize int64
+	MaxSize int64
+}
+
+// Apply removes the files outside of the size range.
+func (sf SizeFilter) Apply(w *fspb.Walk) (*fspb.Walk, error) {
+	return filterWalk(w, func(f *fspb.File) bool {
+		if f.GetInfo().GetIsDir() {
+			return true
+		}
+		size := f.GetInfo().GetSize()
+		return size >= sf.MinSize && (sf.MaxSize == 0 || size <= sf.MaxSize)
+	}, nil), nil
+}
+
+// ChainFilter applies its filters one after another.
+type ChainFilter []WalkFilter
+
+// Apply applies all filters in order, failing on the first error.
+func (cf ChainFilter) Apply(w *fspb.Walk) (*fspb.Walk, error) {
+	for _, f := range cf {
+		var err error
+		if w, err = f.Apply(w); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// filterWalks applies the filters to the loaded Walks.
+func (r *Reporter) filterWalks(filters []WalkFilter) error {
+	if len(filters) == 0 {
+		return nil
+	}
+	var err error
+	if r.before != nil {
+		if r.before, err = ChainFilter(filters).Apply(r.before); err != nil {
+			return fmt.Errorf("unable to filter before walk: %w", err)
+		}
+	}
+	if r.after, err = ChainFilter(filters).Apply(r.after); err != nil {
+		return fmt.Errorf("unable to filter after walk: %w", err)
+	}
+	return nil
+}
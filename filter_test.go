@@ -0,0 +1,163 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+
+	tspb "github.com/golang/protobuf/ptypes/timestamp"
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+func TestWalkFilters(t *testing.T) {
+	start := int64(1543831000)
+	file := func(p string, size int64, age time.Duration, dir bool) *fspb.File {
+		return &fspb.File{Path: p, Info: &fspb.FileInfo{
+			Size:     size,
+			IsDir:    dir,
+			Modified: &tspb.Timestamp{Seconds: start - int64(age.Seconds())},
+		}}
+	}
+	wlk := &fspb.Walk{
+		Id:        "walk",
+		StartWalk: &tspb.Timestamp{Seconds: start},
+		File: []*fspb.File{
+			file("/var/log", 0, 72*time.Hour, true),
+			file("/var/log/syslog", 100, time.Hour, false),
+			file("/var/log/syslog.1", 5000, 25*time.Hour, false),
+			file("/etc/passwd", 2000, 48*time.Hour, false),
+		},
+		Deleted:  []string{"/var/log/syslog.2", "/etc/shadow"},
+		Checksum: &fspb.Fingerprint{Value: "abc"},
+	}
+	testCases := []struct {
+		desc        string
+		filter      WalkFilter
+		wantFiles   []string
+		wantDeleted []string
+		wantErr     bool
+	}{
+		{
+			desc:        "path prefix",
+			filter:      PathPrefixFilter{Prefixes: []string{"/var/log/syslog", "/tmp/"}},
+			wantFiles:   []string{"/var/log", "/etc/passwd"},
+			wantDeleted: []string{"/etc/shadow"},
+		}, {
+			desc:        "regex",
+			filter:      RegexFilter{Regexp: regexp.MustCompile(`\.\d+$`)},
+			wantFiles:   []string{"/var/log", "/var/log/syslog", "/etc/passwd"},
+			wantDeleted: []string{"/etc/shadow"},
+		}, {
+			desc:    "regex missing",
+			filter:  RegexFilter{},
+			wantErr: true,
+		}, {
+			desc:        "age",
+			filter:      AgeFilter{MinAge: 2 * time.Hour, MaxAge: 30 * time.Hour},
+			wantFiles:   []string{"/var/log", "/var/log/syslog.1"},
+			wantDeleted: []string{"/var/log/syslog.2", "/etc/shadow"},
+		}, {
+			desc:        "size",
+			filter:      SizeFilter{MaxSize: 2000},
+			wantFiles:   []string{"/var/log", "/var/log/syslog", "/etc/passwd"},
+			wantDeleted: []string{"/var/log/syslog.2", "/etc/shadow"},
+		}, {
+			desc: "chain",
+			filter: ChainFilter{
+				SizeFilter{MinSize: 1000},
+				PathPrefixFilter{Prefixes: []string{"/etc/"}},
+			},
+			wantFiles:   []string{"/var/log", "/var/log/syslog.1"},
+			wantDeleted: []string{"/var/log/syslog.2"},
+		}, {
+			desc:    "chain error",
+			filter:  ChainFilter{SizeFilter{}, RegexFilter{}},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := tc.filter.Apply(wlk)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Apply() error = %v; want error: %t", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var gotFiles []string
+			for _, f := range got.File {
+				gotFiles = append(gotFiles, f.Path)
+			}
+			if diff := cmp.Diff(tc.wantFiles, gotFiles); diff != "" {
+				t.Errorf("Apply() files: diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantDeleted, got.Deleted); diff != "" {
+				t.Errorf("Apply() deleted: diff (-want +got):\n%s", diff)
+			}
+			if got.Checksum != nil {
+				t.Errorf("Apply() kept checksum %v; want none", got.Checksum)
+			}
+			if len(wlk.File) != 4 || len(wlk.Deleted) != 2 || wlk.Checksum == nil {
+				t.Errorf("Apply() modified the given Walk: %v", wlk)
+			}
+		})
+	}
+}
+
+func TestLoadWalksFilters(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "walks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+
+	files := map[string]*fspb.Walk{
+		"before.pb": {Id: "before", Version: 1, Hostname: "host", File: []*fspb.File{{Path: "/etc/passwd"}, {Path: "/tmp/a"}}},
+		"after.pb":  {Id: "after", Version: 1, Hostname: "host", File: []*fspb.File{{Path: "/etc/passwd"}, {Path: "/tmp/b"}}},
+	}
+	for name, wlk := range files {
+		b, err := proto.Marshal(wlk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(tmpdir, name), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := &Reporter{config: &fspb.ReportConfig{}}
+	filter := PathPrefixFilter{Prefixes: []string{"/tmp/"}}
+	if err := r.LoadWalks(ctx, "", "", "", filepath.Join(tmpdir, "after.pb"), filepath.Join(tmpdir, "before.pb"), filter); err != nil {
+		t.Fatalf("LoadWalks() error: %v", err)
+	}
+	for _, wlk := range []*fspb.Walk{r.before, r.after} {
+		want := []*fspb.File{{Path: "/etc/passwd"}}
+		if diff := cmp.Diff(want, wlk.File, cmp.Comparer(proto.Equal)); diff != "" {
+			t.Errorf("LoadWalks() files of %s: diff (-want +got):\n%s", wlk.Id, diff)
+		}
+	}
+	if r.afterFp == nil {
+		t.Error("LoadWalks() with filters set no fingerprint of the after Walk")
+	}
+}
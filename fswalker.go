@@ -16,70 +16,658 @@
 package fswalker
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
 	"io/ioutil"
+	"math"
+	"net/http"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
+	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
+	"github.com/zeebo/blake3"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
 )
 
 // Generating Go representations for the proto buf libraries.
 
 //go:generate protoc -I=. -I=$GOPATH/src --go_out=paths=source_relative:. proto/fswalker/fswalker.proto
 
+// PolicyHTTPClient is the client used to fetch policies given as HTTP(S) URLs.
+// Its timeout and TLS configuration can be adjusted before creating a Walker.
+var PolicyHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
 const (
 	// tsFileFormat is the time format used in file names.
 	tsFileFormat = "20060102-150405"
+
+	// compressedExt is appended to the file name of gzip compressed Walk files.
+	compressedExt = ".gz"
+
+	// hostnamePlaceholder is replaced by the hostname in Walk file name formats.
+	hostnamePlaceholder = "%h"
 )
 
+// DefaultWalkFilenameFormat is the layout of Walk file names, excluding the file extension.
+// See WalkFilenameWithFormat for details.
+const DefaultWalkFilenameFormat = hostnamePlaceholder + "-" + tsFileFormat + "-fswalker-state"
+
+// filenameTimeElements matches the numeric time elements of a formatted file name
+// along with the separators between them.
+var filenameTimeElements = regexp.MustCompile(`[0-9]([-_.:0-9]*[0-9])?`)
+
+// OutputFormat is the serialization format of a Walk file.
+type OutputFormat string
+
+const (
+	// OutputFormatProto writes Walks as binary proto. This is the default.
+	OutputFormatProto = OutputFormat("proto")
+	// OutputFormatJSON writes Walks using the proto JSON encoding.
+	OutputFormatJSON = OutputFormat("json")
+	// OutputFormatTextProto writes Walks as text format proto, which is easy to read and diff,
+	// e.g. to keep small snapshots in version control.
+	OutputFormatTextProto = OutputFormat("textproto")
+)
+
+// outputFormats lists all supported output formats.
+var outputFormats = []OutputFormat{OutputFormatProto, OutputFormatJSON, OutputFormatTextProto}
+
+// ext returns the file extension used for Walk files in the given format.
+func (f OutputFormat) ext() string {
+	switch f {
+	case OutputFormatJSON:
+		return "json"
+	case OutputFormatTextProto:
+		return "textproto"
+	}
+	return "pb"
+}
+
+// Valid returns whether f is a known output format.
+func (f OutputFormat) Valid() bool {
+	for _, of := range outputFormats {
+		if f == of {
+			return true
+		}
+	}
+	return false
+}
+
+// formatFromPath determines the output format of a Walk file based on its file extension.
+// A compression suffix is ignored.
+func formatFromPath(path string) OutputFormat {
+	path = strings.TrimSuffix(path, compressedExt)
+	for _, f := range []OutputFormat{OutputFormatJSON, OutputFormatTextProto} {
+		if strings.HasSuffix(path, "."+f.ext()) {
+			return f
+		}
+	}
+	return OutputFormatProto
+}
+
+// isCompressed determines whether a Walk file is gzip compressed based on its file extension.
+func isCompressed(path string) bool {
+	return strings.HasSuffix(path, compressedExt)
+}
+
 // WalkFilename returns the appropriate filename for a Walk for the given host and time.
 // If time is not provided, it returns a file pattern to glob by.
 func WalkFilename(hostname string, t time.Time) string {
+	return WalkFilenameForFormat(hostname, t, OutputFormatProto, false)
+}
+
+// WalkFilenameUnique is like WalkFilename but makes sure no file of that name exists in dir
+// yet. Two Walks taken within the same second would otherwise get the same name, so on a
+// collision a counter is appended to the timestamp, e.g. "host-20181206-100102.1-fswalker-state.pb".
+// Such names sort after the one without counter, keeping the Walks in chronological order.
+func WalkFilenameUnique(hostname string, t time.Time, dir string) (string, error) {
+	return WalkFilenameUniqueWithFormat(hostname, t, dir, WalkFilenameLayout(DefaultWalkFilenameFormat, OutputFormatProto, false))
+}
+
+// WalkFilenameUniqueWithFormat is like WalkFilenameUnique but names the file with a custom
+// layout as WalkFilenameWithFormat does, including its extension, e.g. as returned by
+// WalkFilenameLayout. The counter follows the seconds of the layout, so layouts without
+// seconds can't be made unique on a collision.
+func WalkFilenameUniqueWithFormat(hostname string, t time.Time, dir, layout string) (string, error) {
+	if t.IsZero() {
+		return "", fmt.Errorf("unable to make a unique Walk file name without a time")
+	}
+	name := WalkFilenameWithFormat(hostname, t, layout)
+	for i := 1; ; i++ {
+		_, err := os.Lstat(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			return name, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if name, err = walkFilenameWithCounter(hostname, t, layout, i); err != nil {
+			return "", err
+		}
+	}
+}
+
+// walkFilenameWithCounter returns the name of WalkFilenameWithFormat with the counter i inserted
+// right after the seconds of the time, i.e. the first "05" element of layout.
+func walkFilenameWithCounter(hostname string, t time.Time, layout string, i int) (string, error) {
+	parts := strings.Split(layout, hostnamePlaceholder)
+	for k, p := range parts {
+		j := strings.Index(p, "05")
+		if j < 0 {
+			continue
+		}
+		// Formatting the layout up to the seconds gives the position to insert the counter at.
+		pfx := WalkFilenameWithFormat(hostname, t, strings.Join(append(parts[:k:k], p[:j+2]), hostnamePlaceholder))
+		name := WalkFilenameWithFormat(hostname, t, layout)
+		return fmt.Sprintf("%s.%d%s", pfx, i, name[len(pfx):]), nil
+	}
+	return "", fmt.Errorf("Walk file name format %q has no seconds to add a counter to", layout)
+}
+
+// WalkFilenameForFormat is like WalkFilename but uses the file extension matching the given output format.
+// If compress is true, the gzip file extension is appended.
+func WalkFilenameForFormat(hostname string, t time.Time, format OutputFormat, compress bool) string {
+	return WalkFilenameWithFormat(hostname, t, WalkFilenameLayout(DefaultWalkFilenameFormat, format, compress))
+}
+
+// WalkFilenameLayout appends the file extension for the given output format to a Walk file
+// name format. If compress is true, the gzip file extension is appended as well.
+func WalkFilenameLayout(format string, of OutputFormat, compress bool) string {
+	format += "." + of.ext()
+	if compress {
+		format += compressedExt
+	}
+	return format
+}
+
+// WalkFilenameWithFormat returns the filename for a Walk for the given host and time
+// using a custom layout. The format is a time.Format layout in which %h is replaced by
+// the hostname, e.g. "20060102-150405-%h.pb" to sort Walk files by date rather than host.
+// If hostname is empty, "*" is used. If time is not provided, the numeric time elements
+// of the layout are replaced by "*" so the result can be used as a file pattern to glob by.
+func WalkFilenameWithFormat(hostname string, t time.Time, format string) string {
 	hn := "*"
 	if hostname != "" {
 		hn = hostname
 	}
-	ts := "*"
-	if !t.IsZero() {
-		ts = t.Format(tsFileFormat)
+	parts := strings.Split(format, hostnamePlaceholder)
+	for i, p := range parts {
+		parts[i] = t.Format(p)
+		if t.IsZero() {
+			parts[i] = filenameTimeElements.ReplaceAllString(parts[i], "*")
+		}
+	}
+	return strings.Join(parts, hn)
+}
+
+// outputDirToken matches a token of an output directory layout, e.g. "{year}".
+var outputDirToken = regexp.MustCompile(`\{[^{}]*\}`)
+
+// OutputDir returns the directory in which to write the Walk of the given host taken at the
+// given time. In layout, {year}, {month}, {day} and {host} are replaced by the respective
+// values, e.g. "{year}/{month}/{day}/{host}" => "2018/12/06/host". Other tokens are an error.
+func OutputDir(layout, hostname string, t time.Time) (string, error) {
+	values := map[string]string{
+		"{year}":  t.Format("2006"),
+		"{month}": t.Format("01"),
+		"{day}":   t.Format("02"),
+		"{host}":  hostname,
+	}
+	var err error
+	dir := outputDirToken.ReplaceAllStringFunc(layout, func(tok string) string {
+		v, ok := values[tok]
+		if !ok && err == nil {
+			err = fmt.Errorf("unknown token %s in output directory layout %q", tok, layout)
+		}
+		return v
+	})
+	if err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// walkTimeFromFilename extracts the time embedded in the name of a Walk file of the given host.
+func walkTimeFromFilename(hostname, name string) (time.Time, error) {
+	_, t, err := parseWalkFilename("", hostname, name)
+	return t, err
+}
+
+// ParseWalkFilename is the inverse of WalkFilename. It returns the hostname and time embedded
+// in the name of a Walk file in the default layout, in any output format and compressed or not.
+func ParseWalkFilename(name string) (string, time.Time, error) {
+	return parseWalkFilename("", "", name)
+}
+
+// walkFilenameExt returns the extension of a Walk file name for any output format, including
+// the compression suffix, or "" if the name has none of them.
+func walkFilenameExt(name string) string {
+	var gz string
+	if isCompressed(name) {
+		name, gz = strings.TrimSuffix(name, compressedExt), compressedExt
+	}
+	for _, f := range outputFormats {
+		if strings.HasSuffix(name, "."+f.ext()) {
+			return "." + f.ext() + gz
+		}
+	}
+	return ""
+}
+
+// parseWalkFilename returns the hostname and time embedded in the name of a Walk file whose
+// name follows format (see WalkFilenameWithFormat), which defaults to DefaultWalkFilenameFormat.
+// If hostname is non-empty, only Walk files of that host are accepted. A counter added by
+// WalkFilenameUnique right after the seconds of the time is dropped.
+func parseWalkFilename(format, hostname, name string) (string, time.Time, error) {
+	if format == "" {
+		format = DefaultWalkFilenameFormat
+	}
+	base := path.Base(name)
+	ext := walkFilenameExt(base)
+	parts := strings.Split(format, hostnamePlaceholder)
+	if ext == "" || len(parts) != 2 {
+		return "", time.Time{}, fmt.Errorf("%q is not a Walk file name", name)
+	}
+	base = strings.TrimSuffix(base, ext)
+	// The hostname is between the times formatted with the layouts before and after it, so try
+	// all splits of base for which both pieces parse, e.g. for hostnames containing digits.
+	var ends []int
+	for j := len(base); j > 0; j-- {
+		if _, err := time.Parse(parts[1], base[j:]); err == nil {
+			ends = append(ends, j)
+		}
+	}
+	for i := 0; i < len(base); i++ {
+		if hostname != "" && !strings.HasPrefix(base[i:], hostname) {
+			continue
+		}
+		if _, err := time.Parse(parts[0], base[:i]); err != nil {
+			continue
+		}
+		for _, j := range ends {
+			host := base[i:j]
+			if j <= i || (hostname != "" && host != hostname) {
+				continue
+			}
+			t, err := time.ParseInLocation(parts[0]+parts[1], base[:i]+base[j:], time.Local)
+			if err != nil {
+				continue
+			}
+			return host, t.Truncate(time.Second), nil
+		}
+	}
+	if hostname != "" {
+		return "", time.Time{}, fmt.Errorf("%q is not a Walk file name for host %q", name, hostname)
+	}
+	return "", time.Time{}, fmt.Errorf("%q is not a Walk file name", name)
+}
+
+// ValidateWalkFilenameFormat checks that Walk files named with format (see
+// WalkFilenameWithFormat) can be found again, e.g. by the reporter, delta walks and
+// max_walk_retention. format needs to contain the hostname placeholder %h exactly once, no
+// directories (see OutputDir instead) and only numeric time elements. An empty format stands
+// for DefaultWalkFilenameFormat.
+func ValidateWalkFilenameFormat(format string) error {
+	if format == "" {
+		return nil
+	}
+	if strings.Count(format, hostnamePlaceholder) != 1 {
+		return fmt.Errorf("file name format %q needs to contain %s exactly once", format, hostnamePlaceholder)
+	}
+	if strings.Contains(format, "/") {
+		return fmt.Errorf("file name format %q can't contain directories, use an output directory layout instead", format)
+	}
+	const host = "host-1.example.com"
+	for _, t := range []time.Time{
+		time.Date(2018, 12, 6, 7, 8, 9, 0, time.Local),
+		time.Date(2019, 11, 30, 23, 59, 58, 0, time.Local),
+	} {
+		layout := WalkFilenameLayout(format, OutputFormatProto, false)
+		name := WalkFilenameWithFormat(host, t, layout)
+		if ok, err := path.Match(WalkFilenameWithFormat(host, time.Time{}, layout), name); err != nil || !ok {
+			return fmt.Errorf("file name format %q can't be searched for, only numeric time elements are supported", format)
+		}
+		h, pt, err := parseWalkFilename(format, "", name)
+		if err != nil || h != host || WalkFilenameWithFormat(host, pt, layout) != name {
+			return fmt.Errorf("file name format %q can't be parsed back from %q", format, name)
+		}
+	}
+	return nil
+}
+
+// walkFilePatterns returns file patterns to glob by for all Walk files of the given host whose
+// names follow format (DefaultWalkFilenameFormat if empty), regardless of their output format
+// and compression.
+func walkFilePatterns(hostname, format string) []string {
+	if format == "" {
+		format = DefaultWalkFilenameFormat
+	}
+	var patterns []string
+	for _, f := range outputFormats {
+		for _, c := range []bool{false, true} {
+			patterns = append(patterns, WalkFilenameWithFormat(hostname, time.Time{}, WalkFilenameLayout(format, f, c)))
+		}
+	}
+	return patterns
+}
+
+// marshalWalk serializes a Walk in the given output format.
+func marshalWalk(walk *fspb.Walk, format OutputFormat) ([]byte, error) {
+	return marshalProto(walk, format)
+}
+
+// marshalProto serializes a proto message, e.g. a Walk or WalkDiff, in the given output format.
+func marshalProto(m proto.Message, format OutputFormat) ([]byte, error) {
+	switch format {
+	case "", OutputFormatProto:
+		return proto.Marshal(m)
+	case OutputFormatJSON:
+		var buf bytes.Buffer
+		if err := (&jsonpb.Marshaler{}).Marshal(&buf, m); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case OutputFormatTextProto:
+		return []byte(proto.MarshalTextString(m)), nil
+	}
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
+
+// gzipBytes gzip compresses content.
+func gzipBytes(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(b); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gunzip decompresses gzip compressed content.
+func gunzip(b []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return ioutil.ReadAll(zr)
+}
+
+// unmarshalWalk deserializes a Walk from the given output format.
+func unmarshalWalk(b []byte, format OutputFormat) (*fspb.Walk, error) {
+	walk := &fspb.Walk{}
+	if err := unmarshalProto(b, format, walk); err != nil {
+		return nil, err
+	}
+	return walk, nil
+}
+
+// unmarshalProto deserializes a proto message from the given output format into m.
+func unmarshalProto(b []byte, format OutputFormat, m proto.Message) error {
+	switch format {
+	case "", OutputFormatProto:
+		return proto.Unmarshal(b, m)
+	case OutputFormatJSON:
+		return jsonpb.Unmarshal(bytes.NewReader(b), m)
+	case OutputFormatTextProto:
+		return proto.UnmarshalText(string(b), m)
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// walkChecksum returns a SHA256 checksum over the files and deleted paths of a Walk.
+// Files are serialized deterministically so the checksum doesn't depend on map ordering.
+func walkChecksum(wlk *fspb.Walk) (*fspb.Fingerprint, error) {
+	h := sha256.New()
+	buf := proto.NewBuffer(nil)
+	buf.SetDeterministic(true)
+	for _, f := range wlk.File {
+		buf.Reset()
+		if err := buf.EncodeMessage(f); err != nil {
+			return nil, err
+		}
+		h.Write(buf.Bytes())
+	}
+	for _, p := range wlk.Deleted {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return &fspb.Fingerprint{
+		Method: fspb.Fingerprint_SHA256,
+		Value:  hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+// verifyWalkChecksum checks the checksum of a Walk, if it has one.
+func verifyWalkChecksum(wlk *fspb.Walk) error {
+	if wlk.Checksum == nil {
+		return nil
+	}
+	if wlk.Checksum.Method != fspb.Fingerprint_SHA256 {
+		return fmt.Errorf("unsupported checksum method %s", wlk.Checksum.Method)
+	}
+	sum, err := walkChecksum(wlk)
+	if err != nil {
+		return err
+	}
+	if sum.Value != wlk.Checksum.Value {
+		return fmt.Errorf("checksum %s doesn't match the content (%s), the Walk file is corrupt", wlk.Checksum.Value, sum.Value)
+	}
+	return nil
+}
+
+// ReadWalk reads a single Walk file from a local path or a gcs:// or s3:// URI. The encoding
+// and compression are determined by the file extension. The checksum of the Walk is verified,
+// if it has one. Delta Walks are returned as is, i.e. without merging them with their base.
+func ReadWalk(ctx context.Context, path string) (*fspb.Walk, error) {
+	wlk, _, err := loadWalk(ctx, storeForPath(path), path)
+	return wlk, err
+}
+
+// WriteWalk writes a Walk to a local path or a gcs:// or s3:// URI. The encoding and compression
+// are determined by the file extension, as for Walks written by the Walker. The checksum is
+// updated in the written file; the given Walk is not modified.
+func WriteWalk(ctx context.Context, path string, wlk *fspb.Walk) error {
+	sum, err := walkChecksum(wlk)
+	if err != nil {
+		return &WalkProtoError{Path: path, Err: err}
+	}
+	c := *wlk
+	c.Checksum = sum
+	b, err := marshalWalk(&c, formatFromPath(path))
+	if err != nil {
+		return &WalkProtoError{Path: path, Err: err}
+	}
+	if isCompressed(path) {
+		if b, err = gzipBytes(b); err != nil {
+			return &WalkIOError{Path: path, Err: err}
+		}
+	}
+	if err := storeForPath(path).Write(ctx, path, b); err != nil {
+		return &WalkIOError{Path: path, Err: err}
+	}
+	return nil
+}
+
+// hashAlgorithm returns the fingerprint method configured in the policy, defaulting to SHA256.
+func hashAlgorithm(pol *fspb.Policy) fspb.Fingerprint_Method {
+	if pol == nil || pol.HashAlgorithm == fspb.Fingerprint_UNKNOWN {
+		return fspb.Fingerprint_SHA256
+	}
+	return pol.HashAlgorithm
+}
+
+// newHash returns a new hash.Hash implementing the given fingerprint method.
+func newHash(method fspb.Fingerprint_Method) (hash.Hash, error) {
+	switch method {
+	case fspb.Fingerprint_SHA256:
+		return sha256.New(), nil
+	case fspb.Fingerprint_SHA512:
+		return sha512.New(), nil
+	case fspb.Fingerprint_BLAKE3:
+		return blake3.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported hash algorithm %s", method)
+}
+
+// hashSum reads the given file path and builds a hash sum over its content using the given method.
+func hashSum(path string, method fspb.Fingerprint_Method) (string, error) {
+	h, err := newHash(method)
+	if err != nil {
+		return "", err
 	}
-	return fmt.Sprintf("%s-%s-fswalker-state.pb", hn, ts)
+	return hashFile(path, h)
 }
 
-// sha256sum reads the given file path and builds a SHA-256 sum over its content.
-func sha256sum(path string) (string, error) {
+// hashFile reads the given file path and returns the hex encoded sum of h over its content.
+func hashFile(path string, h hash.Hash) (string, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return "", err
 	}
 	defer f.Close()
 
-	h := sha256.New()
 	if _, err := io.Copy(h, f); err != nil {
 		return "", err
 	}
 	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
+// fileEntropy returns the Shannon entropy of the file content in bits per byte.
+// Values close to 8 indicate encrypted or compressed content. Empty files have an entropy of 0.
+func fileEntropy(path string) (float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var counts [256]int64
+	var total int64
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := f.Read(buf)
+		for _, b := range buf[:n] {
+			counts[b]++
+		}
+		total += int64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var entropy float64
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy, nil
+}
+
+// detectMimeType returns the MIME type of a file based on the first 512 bytes of its content.
+func detectMimeType(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// isURL returns whether the path is an HTTP(S) URL rather than a local file path.
+func isURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// fetchURL retrieves the content behind an HTTP(S) URL.
+func fetchURL(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch %q: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch %q: %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// readFile reads a local file or an object from GCS or S3.
+func readFile(ctx context.Context, path string) ([]byte, error) {
+	switch {
+	case isGCSPath(path):
+		return readGCS(ctx, path)
+	case isS3Path(path):
+		return readS3(ctx, path)
+	}
+	return ioutil.ReadFile(path)
+}
+
+// writeFile writes a local file or an object to GCS or S3.
+func writeFile(ctx context.Context, path string, data []byte, perm os.FileMode) error {
+	switch {
+	case isGCSPath(path):
+		return writeGCS(ctx, path, data)
+	case isS3Path(path):
+		return writeS3(ctx, path, data)
+	}
+	return atomicWrite(path, data, perm)
+}
+
 // readTextProto reads a text format proto buf and unmarshals it into the provided proto message.
 func readTextProto(ctx context.Context, path string, pb proto.Message) error {
-	b, err := ioutil.ReadFile(path)
+	b, err := readFile(ctx, path)
 	if err != nil {
 		return err
 	}
 	return proto.UnmarshalText(string(b), pb)
 }
 
+// unmarshalConfig unmarshals a text format or JSON encoded proto buf into the provided proto message.
+// JSON is detected by the content starting with a curly brace.
+func unmarshalConfig(b []byte, pb proto.Message) error {
+	if t := bytes.TrimSpace(b); len(t) > 0 && t[0] == '{' {
+		return jsonpb.Unmarshal(bytes.NewReader(t), pb)
+	}
+	return proto.UnmarshalText(string(b), pb)
+}
+
 // writeTextProto writes a text format proto buf for the provided proto message.
 func writeTextProto(ctx context.Context, path string, pb proto.Message) error {
 	blob := proto.MarshalTextString(pb)
 	// replace message boundary characters as curly braces look nicer (both is fine to parse)
 	blob = strings.Replace(strings.Replace(blob, "<", "{", -1), ">", "}", -1)
-	return ioutil.WriteFile(path, []byte(blob), 0644)
+	return writeFile(ctx, path, []byte(blob), 0644)
 }
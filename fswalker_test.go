@@ -16,12 +16,15 @@ package fswalker
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/google/go-cmp/cmp"
 
 	fspb "github.com/google/fswalker/proto/fswalker"
@@ -60,15 +63,414 @@ func TestWalkFilename(t *testing.T) {
 	}
 }
 
-func TestSha256sum(t *testing.T) {
-	gotHash, err := sha256sum(filepath.Join(testdataDir, "hashSumTest"))
+func TestWalkFilenameUnique(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "walks")
 	if err != nil {
-		t.Errorf("sha256sum() error: %v", err)
-		return
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+
+	ts := time.Date(2018, 12, 06, 10, 01, 02, 0, time.Local)
+	want := []string{
+		"host-20181206-100102-fswalker-state.pb",
+		"host-20181206-100102.1-fswalker-state.pb",
+		"host-20181206-100102.2-fswalker-state.pb",
+	}
+	var got []string
+	for range want {
+		name, err := WalkFilenameUnique("host", ts, tmpdir)
+		if err != nil {
+			t.Fatalf("WalkFilenameUnique() error: %v", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(tmpdir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, name)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("WalkFilenameUnique(): diff (-want +got):\n%s", diff)
+	}
+	for _, n := range got {
+		if wt, err := walkTimeFromFilename("host", n); err != nil || !wt.Equal(ts) {
+			t.Errorf("walkTimeFromFilename(%q) = %v, %v; want %v", n, wt, err, ts)
+		}
+	}
+
+	if _, err := WalkFilenameUnique("host", time.Time{}, tmpdir); err == nil {
+		t.Error("WalkFilenameUnique() without time succeeded; want error")
+	}
+}
+
+func TestWalkFilenameUniqueWithFormat(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "walks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+
+	const format = "20060102-150405-%h"
+	ts := time.Date(2018, 12, 06, 10, 01, 02, 0, time.Local)
+	layout := WalkFilenameLayout(format, OutputFormatJSON, true)
+	want := []string{
+		"20181206-100102-host.json.gz",
+		"20181206-100102.1-host.json.gz",
+		"20181206-100102.2-host.json.gz",
+	}
+	var got []string
+	for range want {
+		name, err := WalkFilenameUniqueWithFormat("host", ts, tmpdir, layout)
+		if err != nil {
+			t.Fatalf("WalkFilenameUniqueWithFormat() error: %v", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(tmpdir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, name)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("WalkFilenameUniqueWithFormat(): diff (-want +got):\n%s", diff)
+	}
+	for _, n := range got {
+		if _, wt, err := parseWalkFilename(format, "host", n); err != nil || !wt.Equal(ts) {
+			t.Errorf("parseWalkFilename(%q) = %v, %v; want %v", n, wt, err, ts)
+		}
+	}
+
+	noSeconds := WalkFilenameLayout("%h-20060102", OutputFormatProto, false)
+	name, err := WalkFilenameUniqueWithFormat("host", ts, tmpdir, noSeconds)
+	if err != nil {
+		t.Fatalf("WalkFilenameUniqueWithFormat() without seconds error: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmpdir, name), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WalkFilenameUniqueWithFormat("host", ts, tmpdir, noSeconds); err == nil {
+		t.Error("WalkFilenameUniqueWithFormat() of a collision without seconds succeeded; want error")
+	}
+}
+
+func TestWalkFilenameWithFormat(t *testing.T) {
+	ts := time.Date(2018, 12, 06, 10, 01, 02, 0, time.UTC)
+	testCases := []struct {
+		h        string
+		t        time.Time
+		format   string
+		wantFile string
+	}{
+		{
+			h:        "test-host.google.com",
+			t:        ts,
+			format:   "20060102-150405-%h.pb",
+			wantFile: "20181206-100102-test-host.google.com.pb",
+		}, {
+			h:        "host1",
+			t:        ts,
+			format:   "2006/01/02/%h/150405.json.gz",
+			wantFile: "2018/12/06/host1/100102.json.gz",
+		}, {
+			h:        "host1",
+			format:   "2006/01/02/%h/150405.json.gz",
+			wantFile: "*/*/*/host1/*.json.gz",
+		}, {
+			t:        ts,
+			format:   "%h-%h-20060102",
+			wantFile: "*-*-20181206",
+		}, {
+			h:        "test-host.google.com",
+			t:        ts,
+			format:   WalkFilenameLayout(DefaultWalkFilenameFormat, OutputFormatProto, false),
+			wantFile: "test-host.google.com-20181206-100102-fswalker-state.pb",
+		},
+	}
+
+	for _, tc := range testCases {
+		gotFile := WalkFilenameWithFormat(tc.h, tc.t, tc.format)
+		if gotFile != tc.wantFile {
+			t.Errorf("WalkFilenameWithFormat(%s, %s, %q) = %q; want: %q", tc.h, tc.t, tc.format, gotFile, tc.wantFile)
+		}
+	}
+}
+
+func TestValidateWalkFilenameFormat(t *testing.T) {
+	testCases := []struct {
+		format  string
+		wantErr bool
+	}{
+		{format: ""},
+		{format: DefaultWalkFilenameFormat},
+		{format: "20060102-150405-%h"},
+		{format: "%h_2006-01-02T15-04-05"},
+		{format: "fswalker-%h"},
+		{format: "20060102-150405", wantErr: true},
+		{format: "%h-%h-20060102", wantErr: true},
+		{format: "2006/01/02/%h-150405", wantErr: true},
+		{format: "%h-Jan-02-2006", wantErr: true},
+	}
+	for _, tc := range testCases {
+		err := ValidateWalkFilenameFormat(tc.format)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ValidateWalkFilenameFormat(%q) error: %v; want error: %t", tc.format, err, tc.wantErr)
+		}
+	}
+}
+
+func TestParseWalkFilenameFormat(t *testing.T) {
+	ts := time.Date(2018, 12, 6, 7, 0, 0, 0, time.Local)
+	const format = "20060102-150405-%h"
+	testCases := []struct {
+		name     string
+		hostname string
+		wantHost string
+		wantErr  bool
+	}{
+		{name: "/tmp/" + WalkFilenameWithFormat("web-01", ts, WalkFilenameLayout(format, OutputFormatProto, false)), wantHost: "web-01"},
+		{name: "/tmp/" + WalkFilenameWithFormat("web-01", ts, WalkFilenameLayout(format, OutputFormatJSON, true)), hostname: "web-01", wantHost: "web-01"},
+		{name: "/tmp/20181206-070000.1-host.pb", wantHost: "host"},
+		{name: "/tmp/" + WalkFilenameWithFormat("web-01", ts, WalkFilenameLayout(format, OutputFormatProto, false)), hostname: "web-02", wantErr: true},
+		{name: "/tmp/" + WalkFilename("web-01", ts), wantErr: true},
+	}
+	for _, tc := range testCases {
+		host, got, err := parseWalkFilename(format, tc.hostname, tc.name)
+		switch {
+		case tc.wantErr && err == nil:
+			t.Errorf("parseWalkFilename(%q, %q) no error", tc.hostname, tc.name)
+		case !tc.wantErr && err != nil:
+			t.Errorf("parseWalkFilename(%q, %q) error: %v", tc.hostname, tc.name, err)
+		case !tc.wantErr && (host != tc.wantHost || !got.Equal(ts)):
+			t.Errorf("parseWalkFilename(%q, %q) = %q, %v; want: %q, %v", tc.hostname, tc.name, host, got, tc.wantHost, ts)
+		}
+	}
+}
+
+func TestOutputDir(t *testing.T) {
+	ts := time.Date(2018, 12, 06, 10, 01, 02, 0, time.UTC)
+	testCases := []struct {
+		layout  string
+		want    string
+		wantErr bool
+	}{
+		{layout: "{year}/{month}/{day}/{host}", want: "2018/12/06/test-host"},
+		{layout: "{host}/{year}-{month}", want: "test-host/2018-12"},
+		{layout: "archive", want: "archive"},
+		{layout: "", want: ""},
+		{layout: "{year}/{hour}", wantErr: true},
+	}
+	for _, tc := range testCases {
+		got, err := OutputDir(tc.layout, "test-host", ts)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("OutputDir(%q) error: %v; want error: %t", tc.layout, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("OutputDir(%q) = %q; want: %q", tc.layout, got, tc.want)
+		}
+	}
+}
+
+func TestWalkFilenameForFormat(t *testing.T) {
+	ts := time.Date(2018, 12, 06, 10, 01, 02, 0, time.UTC)
+	testCases := []struct {
+		format   OutputFormat
+		compress bool
+		wantFile string
+	}{
+		{
+			format:   OutputFormatProto,
+			wantFile: "test-host.google.com-20181206-100102-fswalker-state.pb",
+		}, {
+			format:   OutputFormatJSON,
+			wantFile: "test-host.google.com-20181206-100102-fswalker-state.json",
+		}, {
+			format:   OutputFormatTextProto,
+			wantFile: "test-host.google.com-20181206-100102-fswalker-state.textproto",
+		}, {
+			format:   OutputFormatProto,
+			compress: true,
+			wantFile: "test-host.google.com-20181206-100102-fswalker-state.pb.gz",
+		}, {
+			format:   OutputFormatJSON,
+			compress: true,
+			wantFile: "test-host.google.com-20181206-100102-fswalker-state.json.gz",
+		},
+	}
+
+	for _, tc := range testCases {
+		gotFile := WalkFilenameForFormat("test-host.google.com", ts, tc.format, tc.compress)
+		if gotFile != tc.wantFile {
+			t.Errorf("WalkFilenameForFormat(%s, %t) = %q; want: %q", tc.format, tc.compress, gotFile, tc.wantFile)
+		}
+		if gotFormat := formatFromPath(gotFile); gotFormat != tc.format {
+			t.Errorf("formatFromPath(%q) = %s; want: %s", gotFile, gotFormat, tc.format)
+		}
+		if gotCompress := isCompressed(gotFile); gotCompress != tc.compress {
+			t.Errorf("isCompressed(%q) = %t; want: %t", gotFile, gotCompress, tc.compress)
+		}
+	}
+}
+
+func TestWalkTimeFromFilename(t *testing.T) {
+	want := time.Date(2018, 12, 6, 7, 0, 0, 0, time.Local)
+	testCases := []struct {
+		name    string
+		wantErr bool
+	}{
+		{name: "/tmp/host.google.com-20181206-070000-fswalker-state.pb"},
+		{name: "gcs://bucket/host.google.com-20181206-070000-fswalker-state.json.gz"},
+		{name: "/tmp/other.google.com-20181206-070000-fswalker-state.pb", wantErr: true},
+		{name: "/tmp/host.google.com-2018", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		got, err := walkTimeFromFilename("host.google.com", tc.name)
+		switch {
+		case tc.wantErr && err == nil:
+			t.Errorf("walkTimeFromFilename(%q) no error", tc.name)
+		case !tc.wantErr && err != nil:
+			t.Errorf("walkTimeFromFilename(%q) error: %v", tc.name, err)
+		case !tc.wantErr && !got.Equal(want):
+			t.Errorf("walkTimeFromFilename(%q) = %v; want: %v", tc.name, got, want)
+		}
+	}
+}
+
+func TestHashSum(t *testing.T) {
+	testCases := []struct {
+		method   fspb.Fingerprint_Method
+		wantHash string
+		wantErr  bool
+	}{
+		{
+			method:   fspb.Fingerprint_SHA256,
+			wantHash: "aeb02544df0ef515b21cab81ad5c0609b774f86879bf7e2e42c88efdaab2c75f",
+		}, {
+			method:   fspb.Fingerprint_SHA512,
+			wantHash: "af2487e0e356f0b208472dda50ca56642184a23797295634c253d67763903943d9888b3b1e30141c6e23d9b390b991f57f0f4613cc072fc1bb06e8df475fb904",
+		}, {
+			method:   fspb.Fingerprint_BLAKE3,
+			wantHash: "edf0c8c82838174ea13e63da14d0646cf83b1a3b8aa640f532247dd72f10fed6",
+		}, {
+			method:  fspb.Fingerprint_UNKNOWN,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.method.String(), func(t *testing.T) {
+			gotHash, err := hashSum(filepath.Join(testdataDir, "hashSumTest"), tc.method)
+			switch {
+			case tc.wantErr && err == nil:
+				t.Error("hashSum() no error")
+			case !tc.wantErr && err != nil:
+				t.Errorf("hashSum() error: %v", err)
+			case gotHash != tc.wantHash:
+				t.Errorf("hashSum() = %q; want: %q", gotHash, tc.wantHash)
+			}
+		})
+	}
+}
+
+func TestFileEntropy(t *testing.T) {
+	allBytes := make([]byte, 256)
+	for i := range allBytes {
+		allBytes[i] = byte(i)
+	}
+	testCases := []struct {
+		desc    string
+		content []byte
+		want    float64
+	}{
+		{desc: "empty"},
+		{desc: "single byte value", content: []byte("aaaa")},
+		{desc: "two byte values", content: []byte("abab"), want: 1},
+		{desc: "all byte values", content: allBytes, want: 8},
+	}
+
+	tmpdir, err := ioutil.TempDir("", "entropy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+	for i, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			p := filepath.Join(tmpdir, fmt.Sprintf("file%d", i))
+			if err := ioutil.WriteFile(p, tc.content, 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := fileEntropy(p)
+			if err != nil {
+				t.Fatalf("fileEntropy() error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("fileEntropy() = %f; want %f", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDetectMimeType(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		content []byte
+		want    string
+	}{
+		{desc: "text", content: []byte("some text"), want: "text/plain; charset=utf-8"},
+		{desc: "ELF binary", content: []byte("\x7fELF\x02\x01\x01\x00\x00\x00"), want: "application/octet-stream"},
+		{desc: "gzip", content: []byte("\x1f\x8b\x08"), want: "application/x-gzip"},
+		{desc: "empty", want: "text/plain; charset=utf-8"},
+	}
+
+	tmpdir, err := ioutil.TempDir("", "mime")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+	for i, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			p := filepath.Join(tmpdir, fmt.Sprintf("file%d", i))
+			if err := ioutil.WriteFile(p, tc.content, 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := detectMimeType(p)
+			if err != nil {
+				t.Fatalf("detectMimeType() error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("detectMimeType() = %q; want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// BenchmarkHashSum compares the throughput of the supported hash algorithms on a 1 GB file.
+func BenchmarkHashSum(b *testing.B) {
+	const size = 1 << 30
+	tmpfile, err := ioutil.TempFile("", "hashsum")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name()) // clean up
+	buf := make([]byte, 1<<20)
+	for i := range buf {
+		buf[i] = byte(i)
+	}
+	for n := 0; n < size; n += len(buf) {
+		if _, err := tmpfile.Write(buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := tmpfile.Close(); err != nil {
+		b.Fatal(err)
 	}
-	const wantHash = "aeb02544df0ef515b21cab81ad5c0609b774f86879bf7e2e42c88efdaab2c75f"
-	if gotHash != wantHash {
-		t.Errorf("sha256sum() = %q; want: %q", gotHash, wantHash)
+
+	for _, method := range []fspb.Fingerprint_Method{fspb.Fingerprint_SHA256, fspb.Fingerprint_BLAKE3} {
+		b.Run(method.String(), func(b *testing.B) {
+			b.SetBytes(size)
+			for i := 0; i < b.N; i++ {
+				if _, err := hashSum(tmpfile.Name(), method); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
 	}
 }
 
@@ -198,3 +600,126 @@ func TestWriteTextProtoReviews(t *testing.T) {
 		t.Errorf("writeTextProto() reviews: diff (-want +got): \n%s", diff)
 	}
 }
+
+func TestReadWriteWalk(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "walks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+
+	want := &fspb.Walk{
+		Id:       "walk",
+		Hostname: "host",
+		File:     []*fspb.File{{Path: "/etc/passwd"}},
+	}
+	for _, name := range []string{"walk.pb", "walk.json", "walk.pb.gz"} {
+		p := filepath.Join(tmpdir, name)
+		if err := WriteWalk(ctx, p, want); err != nil {
+			t.Fatalf("WriteWalk(%q) error: %v", name, err)
+		}
+		got, err := ReadWalk(ctx, p)
+		if err != nil {
+			t.Fatalf("ReadWalk(%q) error: %v", name, err)
+		}
+		if got.Checksum == nil {
+			t.Errorf("ReadWalk(%q) has no checksum", name)
+		}
+		got.Checksum = nil
+		if !proto.Equal(want, got) {
+			t.Errorf("ReadWalk(%q) = %v; want %v", name, got, want)
+		}
+	}
+}
+
+func TestWalkChecksum(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "walks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+
+	wlk := &fspb.Walk{
+		Id: "walk",
+		File: []*fspb.File{
+			{Path: "/etc/passwd", Info: &fspb.FileInfo{Size: 1}, Xattrs: map[string][]byte{"user.a": []byte("1"), "user.b": []byte("2"), "user.c": []byte("3")}},
+			{Path: "/etc/shadow", Info: &fspb.FileInfo{Size: 2}},
+		},
+		Deleted: []string{"/etc/group"},
+	}
+	want, err := walkChecksum(wlk)
+	if err != nil {
+		t.Fatalf("walkChecksum() error: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		got, err := walkChecksum(proto.Clone(wlk).(*fspb.Walk))
+		if err != nil || !proto.Equal(got, want) {
+			t.Fatalf("walkChecksum() = %v, %v; want %v", got, err, want)
+		}
+	}
+
+	p := filepath.Join(tmpdir, "walk.json")
+	if err := WriteWalk(ctx, p, wlk); err != nil {
+		t.Fatalf("WriteWalk() error: %v", err)
+	}
+	if wlk.Checksum != nil {
+		t.Error("WriteWalk() modified the given Walk")
+	}
+	got, err := ReadWalk(ctx, p)
+	if err != nil {
+		t.Fatalf("ReadWalk() error: %v", err)
+	}
+	if !proto.Equal(got.Checksum, want) {
+		t.Errorf("ReadWalk() checksum = %v; want %v", got.Checksum, want)
+	}
+
+	// A Walk whose content doesn't match its checksum is rejected.
+	corrupt := proto.Clone(got).(*fspb.Walk)
+	corrupt.File[1].Info.Size = 3
+	b, err := marshalWalk(corrupt, OutputFormatProto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p = filepath.Join(tmpdir, "corrupt.pb")
+	if err := ioutil.WriteFile(p, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var e *WalkProtoError
+	if _, err := ReadWalk(ctx, p); !errors.As(err, &e) {
+		t.Errorf("ReadWalk() of a corrupt Walk error = %v; want WalkProtoError", err)
+	}
+	r := &Reporter{config: &fspb.ReportConfig{}}
+	if err := r.LoadWalks(ctx, "", "", "", p, ""); err == nil {
+		t.Error("LoadWalks() of a corrupt Walk succeeded; want error")
+	}
+}
+
+func TestParseWalkFilename(t *testing.T) {
+	ts := time.Date(2018, 12, 6, 7, 0, 0, 0, time.Local)
+	testCases := []struct {
+		name     string
+		wantHost string
+		wantErr  bool
+	}{
+		{name: "/tmp/" + WalkFilename("host.google.com", ts), wantHost: "host.google.com"},
+		{name: "gcs://bucket/" + WalkFilenameForFormat("web-01", ts, OutputFormatJSON, true), wantHost: "web-01"},
+		{name: "/tmp/host-20181206-070000.2-fswalker-state.pb", wantHost: "host"},
+		{name: "/tmp/host-20181206-070000-fswalker-state.txt", wantErr: true},
+		{name: "/tmp/20181206-070000-fswalker-state.pb", wantErr: true},
+		{name: "/tmp/host-2018", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		host, got, err := ParseWalkFilename(tc.name)
+		switch {
+		case tc.wantErr && err == nil:
+			t.Errorf("ParseWalkFilename(%q) no error", tc.name)
+		case !tc.wantErr && err != nil:
+			t.Errorf("ParseWalkFilename(%q) error: %v", tc.name, err)
+		case !tc.wantErr && (host != tc.wantHost || !got.Equal(ts)):
+			t.Errorf("ParseWalkFilename(%q) = %q, %v; want: %q, %v", tc.name, host, got, tc.wantHost, ts)
+		}
+	}
+}
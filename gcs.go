@@ -0,0 +1,260 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// gcsScheme is the URI prefix of paths referring to objects in Google Cloud Storage.
+const gcsScheme = "gcs://"
+
+// isGCSPath returns whether the path refers to Google Cloud Storage.
+func isGCSPath(p string) bool {
+	return strings.HasPrefix(p, gcsScheme)
+}
+
+// splitGCSPath splits a gcs://bucket/object path into its bucket and object name.
+func splitGCSPath(p string) (string, string, error) {
+	return splitBucketPath(gcsScheme, p)
+}
+
+// splitBucketPath splits a path of an object store with the given URI scheme into its
+// bucket and object name.
+func splitBucketPath(scheme, p string) (string, string, error) {
+	if !strings.HasPrefix(p, scheme) {
+		return "", "", fmt.Errorf("%q is not a %s path", p, scheme)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(p, scheme), "/", 2)
+	if parts[0] == "" {
+		return "", "", fmt.Errorf("missing bucket in path %q", p)
+	}
+	if len(parts) == 1 {
+		return parts[0], "", nil
+	}
+	return parts[0], parts[1], nil
+}
+
+// isObjectPath returns whether the path refers to an object store rather than the local file system.
+func isObjectPath(p string) bool {
+	return isGCSPath(p) || isS3Path(p)
+}
+
+// joinWalkPath joins a directory and a file name, keeping the double slash of object store paths.
+// name may also be a path relative to dir, see relWalkPath.
+func joinWalkPath(dir, name string) string {
+	if isObjectPath(dir) {
+		if strings.Contains(name, "/") {
+			i := strings.Index(dir, "://") + len("://")
+			return dir[:i] + path.Join(dir[i:], name)
+		}
+		return strings.TrimSuffix(dir, "/") + "/" + name
+	}
+	return path.Join(dir, name)
+}
+
+// relWalkPath returns the path of the Walk file p relative to the directory dir of the same
+// store, e.g. "../05/host-20181205-070000-fswalker-state.pb". It is the inverse of joinWalkPath.
+func relWalkPath(dir, p string) (string, error) {
+	if walkPathDir(p) == strings.TrimSuffix(dir, "/") {
+		return path.Base(p), nil
+	}
+	if isObjectPath(dir) != isObjectPath(p) {
+		return "", fmt.Errorf("%q is not in the same store as %q", p, dir)
+	}
+	trim := func(s string) string {
+		if i := strings.Index(s, "://"); i >= 0 && isObjectPath(s) {
+			return "/" + s[i+len("://"):]
+		}
+		return s
+	}
+	rel, err := filepath.Rel(trim(dir), trim(p))
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// walkPathDir returns the directory (or object prefix) a Walk file resides in.
+func walkPathDir(p string) string {
+	if isObjectPath(p) {
+		return p[:strings.LastIndex(p, "/")]
+	}
+	return path.Dir(p)
+}
+
+// readGCS reads the content of a GCS object using Application Default Credentials.
+func readGCS(ctx context.Context, p string) ([]byte, error) {
+	bucket, object, err := splitGCSPath(p)
+	if err != nil {
+		return nil, err
+	}
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create GCS client: %v", err)
+	}
+	defer client.Close()
+	rd, err := client.Bucket(bucket).Object(object).NewReader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %q: %v", p, err)
+	}
+	defer rd.Close()
+	return ioutil.ReadAll(rd)
+}
+
+// writeGCS writes data to a GCS object using Application Default Credentials.
+func writeGCS(ctx context.Context, p string, data []byte) error {
+	bucket, object, err := splitGCSPath(p)
+	if err != nil {
+		return err
+	}
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to create GCS client: %v", err)
+	}
+	defer client.Close()
+	wr := client.Bucket(bucket).Object(object).NewWriter(ctx)
+	if _, err := wr.Write(data); err != nil {
+		wr.Close()
+		return fmt.Errorf("unable to write %q: %v", p, err)
+	}
+	if err := wr.Close(); err != nil {
+		return fmt.Errorf("unable to write %q: %v", p, err)
+	}
+	return nil
+}
+
+// deleteGCS deletes a GCS object using Application Default Credentials.
+func deleteGCS(ctx context.Context, p string) error {
+	bucket, object, err := splitGCSPath(p)
+	if err != nil {
+		return err
+	}
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to create GCS client: %v", err)
+	}
+	defer client.Close()
+	if err := client.Bucket(bucket).Object(object).Delete(ctx); err != nil {
+		return fmt.Errorf("unable to delete %q: %v", p, err)
+	}
+	return nil
+}
+
+// GCSWalkStore is a WalkStore keeping Walk files in Google Cloud Storage.
+// File names are gcs://bucket/object URIs. Application Default Credentials are used.
+type GCSWalkStore struct{}
+
+// Write writes a GCS object.
+func (GCSWalkStore) Write(ctx context.Context, filename string, data []byte) error {
+	return writeGCS(ctx, filename, data)
+}
+
+// Read reads a GCS object.
+func (GCSWalkStore) Read(ctx context.Context, filename string) ([]byte, error) {
+	return readGCS(ctx, filename)
+}
+
+// Delete deletes a GCS object.
+func (GCSWalkStore) Delete(ctx context.Context, filename string) error {
+	return deleteGCS(ctx, filename)
+}
+
+// List returns the matching objects of a GCS directory.
+func (GCSWalkStore) List(ctx context.Context, prefix string) ([]string, error) {
+	return listGCS(ctx, prefix, false)
+}
+
+// ListRecursive returns the matching objects of a GCS directory and all its subdirectories.
+func (GCSWalkStore) ListRecursive(ctx context.Context, prefix string) ([]string, error) {
+	return listGCS(ctx, prefix, true)
+}
+
+// listGCS lists the objects starting with prefix, only those directly below its directory
+// unless recursive is set.
+func listGCS(ctx context.Context, prefix string, recursive bool) ([]string, error) {
+	bucket, object, err := splitGCSPath(prefix)
+	if err != nil {
+		return nil, err
+	}
+	dir, _ := splitListPrefix(object)
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create GCS client: %v", err)
+	}
+	defer client.Close()
+
+	q := &storage.Query{Prefix: object, Delimiter: "/"}
+	if recursive {
+		q.Delimiter = ""
+	}
+	var objects []string
+	it := client.Bucket(bucket).Objects(ctx, q)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to list %q: %v", prefix, err)
+		}
+		if attrs.Name != "" {
+			objects = append(objects, attrs.Name)
+		}
+	}
+	if recursive {
+		return objectPaths(gcsScheme, bucket, objects), nil
+	}
+	return matchObjects(gcsScheme, bucket, dir, "*", objects)
+}
+
+// matchObjects returns paths with the given URI scheme for all objects directly below prefix
+// whose base name matches pattern.
+func matchObjects(scheme, bucket, prefix, pattern string, objects []string) ([]string, error) {
+	var names []string
+	for _, o := range objects {
+		base := strings.TrimPrefix(o, prefix)
+		if strings.Contains(base, "/") {
+			continue
+		}
+		ok, err := path.Match(pattern, base)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			names = append(names, scheme+bucket+"/"+o)
+		}
+	}
+	return names, nil
+}
+
+// objectPaths returns the sorted paths with the given URI scheme for the given objects.
+func objectPaths(scheme, bucket string, objects []string) []string {
+	names := make([]string, 0, len(objects))
+	for _, o := range objects {
+		names = append(names, scheme+bucket+"/"+o)
+	}
+	sort.Strings(names)
+	return names
+}
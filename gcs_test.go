@@ -0,0 +1,158 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSplitGCSPath(t *testing.T) {
+	testCases := []struct {
+		path       string
+		wantBucket string
+		wantObject string
+		wantErr    bool
+	}{
+		{
+			path:       "gcs://bucket/walks/host-fswalker-state.pb",
+			wantBucket: "bucket",
+			wantObject: "walks/host-fswalker-state.pb",
+		}, {
+			path:       "gcs://bucket",
+			wantBucket: "bucket",
+		}, {
+			path:    "gcs:///walks",
+			wantErr: true,
+		}, {
+			path:    "/tmp/walks",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			bucket, object, err := splitGCSPath(tc.path)
+			switch {
+			case tc.wantErr && err == nil:
+				t.Error("splitGCSPath() no error")
+			case !tc.wantErr && err != nil:
+				t.Errorf("splitGCSPath() error: %v", err)
+			case bucket != tc.wantBucket || object != tc.wantObject:
+				t.Errorf("splitGCSPath() = %q, %q; want %q, %q", bucket, object, tc.wantBucket, tc.wantObject)
+			}
+		})
+	}
+}
+
+func TestJoinWalkPath(t *testing.T) {
+	testCases := []struct {
+		dir  string
+		want string
+	}{
+		{dir: "/tmp/walks/", want: "/tmp/walks/walk.pb"},
+		{dir: "gcs://bucket/walks", want: "gcs://bucket/walks/walk.pb"},
+		{dir: "gcs://bucket/walks/", want: "gcs://bucket/walks/walk.pb"},
+		{dir: "s3://bucket/walks", want: "s3://bucket/walks/walk.pb"},
+	}
+
+	for _, tc := range testCases {
+		if got := joinWalkPath(tc.dir, "walk.pb"); got != tc.want {
+			t.Errorf("joinWalkPath(%q, \"walk.pb\") = %q; want %q", tc.dir, got, tc.want)
+		}
+	}
+}
+
+func TestRelWalkPath(t *testing.T) {
+	testCases := []struct {
+		dir     string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{dir: "/tmp/walks", path: "/tmp/walks/walk.pb", want: "walk.pb"},
+		{dir: "/tmp/walks/2018/12/06", path: "/tmp/walks/2018/11/30/walk.pb", want: "../../11/30/walk.pb"},
+		{dir: "gcs://bucket/walks", path: "gcs://bucket/walks/walk.pb", want: "walk.pb"},
+		{dir: "s3://bucket/walks/06", path: "s3://bucket/walks/05/walk.pb", want: "../05/walk.pb"},
+		{dir: "walks", path: "/tmp/walks/walk.pb", wantErr: true},
+		{dir: "gcs://bucket/walks", path: "/tmp/walks/walk.pb", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		got, err := relWalkPath(tc.dir, tc.path)
+		switch {
+		case tc.wantErr && err == nil:
+			t.Errorf("relWalkPath(%q, %q) no error", tc.dir, tc.path)
+		case !tc.wantErr && err != nil:
+			t.Errorf("relWalkPath(%q, %q) error: %v", tc.dir, tc.path, err)
+		case !tc.wantErr && got != tc.want:
+			t.Errorf("relWalkPath(%q, %q) = %q; want %q", tc.dir, tc.path, got, tc.want)
+		case !tc.wantErr && joinWalkPath(tc.dir, got) != tc.path:
+			t.Errorf("joinWalkPath(%q, %q) = %q; want %q", tc.dir, got, joinWalkPath(tc.dir, got), tc.path)
+		}
+	}
+}
+
+func TestWalkPathDir(t *testing.T) {
+	testCases := []struct {
+		path string
+		want string
+	}{
+		{path: "/tmp/walks/walk.pb", want: "/tmp/walks"},
+		{path: "walk.pb", want: "."},
+		{path: "gcs://bucket/walks/walk.pb", want: "gcs://bucket/walks"},
+		{path: "s3://bucket/walk.pb", want: "s3://bucket"},
+	}
+
+	for _, tc := range testCases {
+		if got := walkPathDir(tc.path); got != tc.want {
+			t.Errorf("walkPathDir(%q) = %q; want %q", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestMatchObjects(t *testing.T) {
+	objects := []string{
+		"walks/host-20181205-070000-fswalker-state.pb",
+		"walks/host-20181206-070000-fswalker-state.json.gz",
+		"walks/other-20181206-070000-fswalker-state.pb",
+		"walks/archive/host-20171206-070000-fswalker-state.pb",
+	}
+	want := []string{
+		"gcs://bucket/walks/host-20181205-070000-fswalker-state.pb",
+	}
+	got, err := matchObjects(gcsScheme, "bucket", "walks/", "host-*-fswalker-state.pb", objects)
+	if err != nil {
+		t.Fatalf("matchObjects() error: %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("matchObjects(): diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestObjectPaths(t *testing.T) {
+	objects := []string{
+		"walks/2018/12/07/host/host-20181207-070000-fswalker-state.pb",
+		"walks/2018/12/06/host/host-20181206-070000-fswalker-state.pb",
+	}
+	want := []string{
+		"s3://bucket/walks/2018/12/06/host/host-20181206-070000-fswalker-state.pb",
+		"s3://bucket/walks/2018/12/07/host/host-20181207-070000-fswalker-state.pb",
+	}
+	if diff := cmp.Diff(want, objectPaths(s3Scheme, "bucket", objects)); diff != "" {
+		t.Errorf("objectPaths(): diff (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CompareAgainstGolden compares the latest Walk of each of hosts in walkPath against the latest
+// Walk of the golden host, e.g. a gold master all other hosts should match. goldenHostname
+// defaults to golden_hostname of the report config. If hosts is empty, all hosts with Walks in
+// walkPath other than the golden host are compared.
+// For each host, the files only it has, the files only the golden host has and the files which
+// differ are written to the output set with SetOutput. Reviews are neither used nor updated.
+// Afterwards, ChangeCount returns the number of differences over all hosts.
+func (r *Reporter) CompareAgainstGolden(ctx context.Context, goldenHostname, walkPath string, hosts []string) error {
+	if goldenHostname == "" {
+		goldenHostname = r.config.GetGoldenHostname()
+	}
+	if goldenHostname == "" || walkPath == "" {
+		return fmt.Errorf("golden hostname and walkPath need to be specified")
+	}
+	if len(hosts) == 0 {
+		all, err := walkHostsInStore(ctx, r.walkStore(walkPath), r.FilenameFormat, walkPath, r.Recursive)
+		if err != nil {
+			return err
+		}
+		for _, h := range all {
+			if h != goldenHostname {
+				hosts = append(hosts, h)
+			}
+		}
+		if len(hosts) == 0 {
+			return fmt.Errorf("no hosts besides %s found in %q", goldenHostname, walkPath)
+		}
+	}
+	goldenFile, golden, _, err := r.loadLatestWalk(ctx, goldenHostname, walkPath)
+	if err != nil {
+		return fmt.Errorf("unable to load latest walk for golden host %s: %w", goldenHostname, err)
+	}
+
+	total := 0
+	for _, host := range hosts {
+		afterFile, after, _, err := r.loadLatestWalk(ctx, host, walkPath)
+		if err != nil {
+			return fmt.Errorf("unable to load latest walk for %s: %w", host, err)
+		}
+		// The Walks are of different hosts, so they can't pass the checks of LoadWalks.
+		c := r.Clone()
+		c.before, c.beforeFile = golden, goldenFile
+		c.after, c.afterFile = after, afterFile
+		res := c.diffWalks()
+		total += res.ChangeCount()
+		printGoldenDiff(r.output(), goldenHostname, host, res)
+	}
+	r.changeCount = total
+	return nil
+}
+
+// printGoldenDiff writes the differences of a host to the golden host.
+func printGoldenDiff(out io.Writer, golden, host string, res *CompareResult) {
+	fmt.Fprintf(out, "=== %s compared to golden host %s ===\n", host, golden)
+	var differ []FileChange
+	differ = append(differ, res.Modified...)
+	differ = append(differ, res.Retargeted...)
+	differ = append(differ, res.Retyped...)
+	for _, s := range []struct {
+		title string
+		files []FileChange
+	}{
+		{fmt.Sprintf("Only on %s", host), res.Added},
+		{fmt.Sprintf("Only on %s", golden), res.Deleted},
+		{"Different", differ},
+		{"Reporting Errors", res.Errors},
+	} {
+		fmt.Fprintf(out, "%s (%d):\n", s.title, len(s.files))
+		for _, fc := range s.files {
+			switch {
+			case fc.After != nil:
+				fmt.Fprintf(out, "  %s\n", fc.After.Path)
+			case fc.Before != nil:
+				fmt.Fprintf(out, "  %s\n", fc.Before.Path)
+			}
+			if fc.Err != nil {
+				fmt.Fprintf(out, "    %v\n", fc.Err)
+			}
+			if fc.Diff != "" {
+				fmt.Fprintf(out, "    %s\n", strings.ReplaceAll(fc.Diff, "\n", "\n    "))
+			}
+		}
+	}
+	fmt.Fprintln(out)
+}
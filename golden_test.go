@@ -0,0 +1,114 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+func TestCompareAgainstGolden(t *testing.T) {
+	ctx := context.Background()
+	store := memWalkStore{}
+	now := time.Now()
+	for host, files := range map[string][]*fspb.File{
+		"gold": {
+			{Version: 1, Path: "/etc/passwd", Info: &fspb.FileInfo{Size: 1}},
+			{Version: 1, Path: "/etc/shadow", Info: &fspb.FileInfo{Size: 1}},
+		},
+		"same": {
+			{Version: 1, Path: "/etc/passwd", Info: &fspb.FileInfo{Size: 1}},
+			{Version: 1, Path: "/etc/shadow", Info: &fspb.FileInfo{Size: 1}},
+		},
+		"drift": {
+			{Version: 1, Path: "/etc/passwd", Info: &fspb.FileInfo{Size: 2}},
+			{Version: 1, Path: "/etc/extra", Info: &fspb.FileInfo{Size: 1}},
+		},
+	} {
+		b, err := marshalWalk(&fspb.Walk{Id: host, Hostname: host, File: files}, OutputFormatProto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		store["/walks/"+WalkFilename(host, now)] = b
+	}
+
+	testCases := []struct {
+		desc      string
+		golden    string
+		hosts     []string
+		want      string
+		wantCount int
+	}{
+		{
+			desc:   "single host",
+			golden: "gold",
+			hosts:  []string{"drift"},
+			want: "=== drift compared to golden host gold ===\n" +
+				"Only on drift (1):\n  /etc/extra\n" +
+				"Only on gold (1):\n  /etc/shadow\n" +
+				"Different (1):\n  /etc/passwd\n    size: 1 => 2\n" +
+				"Reporting Errors (0):\n\n",
+			wantCount: 3,
+		}, {
+			desc: "all hosts with golden host of the config",
+			want: "=== drift compared to golden host gold ===\n" +
+				"Only on drift (1):\n  /etc/extra\n" +
+				"Only on gold (1):\n  /etc/shadow\n" +
+				"Different (1):\n  /etc/passwd\n    size: 1 => 2\n" +
+				"Reporting Errors (0):\n\n" +
+				"=== same compared to golden host gold ===\n" +
+				"Only on same (0):\n" +
+				"Only on gold (0):\n" +
+				"Different (0):\n" +
+				"Reporting Errors (0):\n\n",
+			wantCount: 3,
+		},
+	}
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		r := &Reporter{config: &fspb.ReportConfig{GoldenHostname: "gold"}, Store: store}
+		r.SetOutput(&buf)
+		if err := r.CompareAgainstGolden(ctx, tc.golden, "/walks/", tc.hosts); err != nil {
+			t.Fatalf("%s: CompareAgainstGolden() error: %v", tc.desc, err)
+		}
+		var got []string
+		for _, l := range strings.SplitAfter(buf.String(), "\n") {
+			if !strings.HasPrefix(l, "Loaded file") {
+				got = append(got, l)
+			}
+		}
+		if diff := cmp.Diff(tc.want, strings.Join(got, "")); diff != "" {
+			t.Errorf("%s: CompareAgainstGolden(): diff (-want +got):\n%s", tc.desc, diff)
+		}
+		if n := r.ChangeCount(); n != tc.wantCount {
+			t.Errorf("%s: ChangeCount() = %d; want %d", tc.desc, n, tc.wantCount)
+		}
+	}
+
+	r := &Reporter{config: &fspb.ReportConfig{}, Store: store}
+	if err := r.CompareAgainstGolden(ctx, "", "/walks/", nil); err == nil {
+		t.Error("CompareAgainstGolden() without golden host succeeded; want error")
+	}
+	if err := r.CompareAgainstGolden(ctx, "missing", "/walks/", []string{"same"}); err == nil {
+		t.Error("CompareAgainstGolden() with missing golden Walk succeeded; want error")
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes"
+
+	tspb "github.com/golang/protobuf/ptypes/timestamp"
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+// htmlReport is the data rendered by htmlTemplate.
+type htmlReport struct {
+	Hostname      string
+	Walks         []htmlWalk
+	Sections      []htmlSection
+	Privileges    []string
+	Notifications []*fspb.Notification
+}
+
+// htmlWalk describes one of the compared Walks.
+type htmlWalk struct {
+	Label string
+	ID    string
+	File  string
+	Start string
+	Stop  string
+}
+
+// htmlSection lists the files of one kind of change.
+type htmlSection struct {
+	Title string
+	Class string
+	Files []htmlFile
+}
+
+// htmlFile is a single changed file. Anchor links the summary to the file.
+type htmlFile struct {
+	Anchor string
+	Path   string
+	Note   string
+	Diff   []htmlDiffLine
+}
+
+// htmlDiffLine is a line of a file diff along with the class used to color it.
+type htmlDiffLine struct {
+	Class string
+	Text  string
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>fswalker report for {{.Hostname}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.8em; text-align: left; }
+th { background: #f0f0f0; }
+code, pre { font-family: monospace; }
+pre { background: #f8f8f8; border: 1px solid #ddd; padding: 0.5em; overflow-x: auto; }
+h2.added { color: #1a7f37; }
+h2.deleted { color: #cf222e; }
+h2.modified, h2.retargeted, h2.retyped { color: #9a6700; }
+h2.error, h2.entropy { color: #8250df; }
+.add { color: #1a7f37; }
+.del { color: #cf222e; }
+.note { color: #666; }
+</style>
+</head>
+<body>
+<h1>fswalker report for {{.Hostname}}</h1>
+<table>
+<tr><th>Walk</th><th>ID</th><th>File</th><th>Start</th><th>Stop</th></tr>
+{{- range .Walks}}
+<tr><td>{{.Label}}</td><td><code>{{.ID}}</code></td><td><code>{{.File}}</code></td><td>{{.Start}}</td><td>{{.Stop}}</td></tr>
+{{- end}}
+</table>
+<h2>Summary</h2>
+<table>
+<tr><th>Change</th><th>Files</th></tr>
+{{- range .Sections}}
+<tr><td>{{.Title}}</td><td>{{len .Files}}</td></tr>
+{{- end}}
+</table>
+{{- range .Sections}}
+{{- if .Files}}
+<h2 class="{{.Class}}">{{.Title}} ({{len .Files}})</h2>
+<ul>
+{{- range .Files}}
+<li><a href="#{{.Anchor}}"><code>{{.Path}}</code></a></li>
+{{- end}}
+</ul>
+{{- end}}
+{{- end}}
+{{- if .Privileges}}
+<h2 class="error">Privilege bit changes ({{len .Privileges}})</h2>
+<ul>
+{{- range .Privileges}}
+<li><code>{{.}}</code></li>
+{{- end}}
+</ul>
+{{- end}}
+<h2>Changed Files</h2>
+{{- range .Sections}}
+{{- range .Files}}
+<h3 id="{{.Anchor}}"><code>{{.Path}}</code></h3>
+{{- if .Note}}
+<p class="note">{{.Note}}</p>
+{{- end}}
+{{- if .Diff}}
+<pre>
+{{- range .Diff}}
+<span class="{{.Class}}">{{.Text}}</span>
+{{- end}}
+</pre>
+{{- end}}
+{{- end}}
+{{- end}}
+{{- if .Notifications}}
+<h2>Walking Errors</h2>
+<ul>
+{{- range .Notifications}}
+<li>{{.Severity}} (<code>{{.Path}}</code>): {{.Message}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</body>
+</html>
+`))
+
+// htmlTimestamp formats a Walk timestamp for the HTML report.
+func htmlTimestamp(ts *tspb.Timestamp) (string, error) {
+	t, err := ptypes.Timestamp(ts)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(timeReportFormat), nil
+}
+
+// htmlWalkInfo describes a Walk for the HTML report.
+func htmlWalkInfo(label, file string, wlk *fspb.Walk) (htmlWalk, error) {
+	start, err := htmlTimestamp(wlk.StartWalk)
+	if err != nil {
+		return htmlWalk{}, fmt.Errorf("unable to convert %s walk start timestamp: %v", label, err)
+	}
+	stop, err := htmlTimestamp(wlk.StopWalk)
+	if err != nil {
+		return htmlWalk{}, fmt.Errorf("unable to convert %s walk stop timestamp: %v", label, err)
+	}
+	return htmlWalk{Label: label, ID: wlk.Id, File: file, Start: start, Stop: stop}, nil
+}
+
+// htmlDiff splits a file diff into lines, coloring removed and added values.
+func htmlDiff(diff string) []htmlDiffLine {
+	if diff == "" {
+		return nil
+	}
+	var lines []htmlDiffLine
+	for _, l := range strings.Split(diff, "\n") {
+		var class string
+		switch t := strings.TrimSpace(l); {
+		case strings.HasPrefix(t, "-"):
+			class = "del"
+		case strings.HasPrefix(t, "+"):
+			class = "add"
+		}
+		lines = append(lines, htmlDiffLine{Class: class, Text: l})
+	}
+	return lines
+}
+
+// CompareHTML is like Compare but writes a self-contained HTML document with a summary table
+// linking to the details of each changed file. Styles are embedded so the file can be shared as is.
+func (r *Reporter) CompareHTML(out io.Writer) error {
+	output := r.diffWalks()
+
+	rpt := htmlReport{
+		Hostname:   r.after.Hostname,
+		Privileges: r.PrivilegeChanges(),
+	}
+	if r.before != nil {
+		bw, err := htmlWalkInfo("Before", r.beforeFile, r.before)
+		if err != nil {
+			return err
+		}
+		rpt.Walks = append(rpt.Walks, bw)
+	}
+	aw, err := htmlWalkInfo("After", r.afterFile, r.after)
+	if err != nil {
+		return err
+	}
+	rpt.Walks = append(rpt.Walks, aw)
+	for _, wlk := range []*fspb.Walk{r.before, r.after} {
+		for _, n := range wlk.GetNotification() {
+			if r.Verbose || (n.Severity != fspb.Notification_UNKNOWN && n.Severity != fspb.Notification_INFO) {
+				rpt.Notifications = append(rpt.Notifications, n)
+			}
+		}
+	}
+
+	sections := []struct {
+		title string
+		class string
+		files []FileChange
+	}{
+		{"Added", "added", output.Added},
+		{"High-Entropy Additions", "entropy", r.highEntropyAdditions(output)},
+		{"Removed", "deleted", output.Deleted},
+		{"Modified", "modified", output.Modified},
+		{"Symlink Target Changed", "retargeted", output.Retargeted},
+		{"MIME Type Changed", "retyped", output.Retyped},
+		{"Reporting Errors", "error", output.Errors},
+	}
+	for _, s := range sections {
+		sec := htmlSection{Title: s.title, Class: s.class}
+		for i, file := range s.files {
+			f := htmlFile{
+				Anchor: fmt.Sprintf("%s-%d", s.class, i),
+				Diff:   htmlDiff(file.Diff),
+			}
+			switch {
+			case file.After != nil:
+				f.Path = file.After.Path
+			case file.Before != nil:
+				f.Path = file.Before.Path
+			}
+			switch {
+			case file.Err != nil:
+				f.Note = file.Err.Error()
+			case s.class == "entropy":
+				f.Note = fmt.Sprintf("%.2f bits/byte", file.After.Entropy)
+			case s.class == "retargeted":
+				f.Note = fmt.Sprintf("%q => %q", file.Before.SymlinkTarget, file.After.SymlinkTarget)
+			case s.class == "retyped":
+				f.Note = fmt.Sprintf("%q => %q", file.Before.MimeType, file.After.MimeType)
+			}
+			sec.Files = append(sec.Files, f)
+		}
+		rpt.Sections = append(rpt.Sections, sec)
+	}
+
+	return htmlTemplate.Execute(out, rpt)
+}
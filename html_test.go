@@ -0,0 +1,84 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+func TestCompareHTML(t *testing.T) {
+	ts, _ := ptypes.TimestampProto(time.Date(2018, 12, 6, 10, 1, 2, 0, time.UTC))
+	r := &Reporter{
+		config:     &fspb.ReportConfig{},
+		beforeFile: "/walks/before.pb",
+		afterFile:  "/walks/after.pb",
+		before: &fspb.Walk{
+			Id:        "before",
+			Hostname:  "host",
+			StartWalk: ts,
+			StopWalk:  ts,
+			File: []*fspb.File{
+				{Version: 1, Path: "/etc/deleted", Info: &fspb.FileInfo{Size: 1}},
+				{Version: 1, Path: "/etc/modified", Info: &fspb.FileInfo{Size: 1, Mode: 644}},
+			},
+		},
+		after: &fspb.Walk{
+			Id:        "after",
+			Hostname:  "host",
+			StartWalk: ts,
+			StopWalk:  ts,
+			File: []*fspb.File{
+				{Version: 1, Path: "/etc/<added>", Info: &fspb.FileInfo{Size: 1}, Entropy: 7.9},
+				{Version: 1, Path: "/etc/modified", Info: &fspb.FileInfo{Size: 1, Mode: 744}},
+			},
+			Notification: []*fspb.Notification{
+				{Severity: fspb.Notification_WARNING, Path: "/root", Message: "permission denied"},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := r.CompareHTML(&buf); err != nil {
+		t.Fatalf("CompareHTML() error: %v", err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		"<style>",
+		`<td><code>/walks/before.pb</code></td>`,
+		`<tr><td>Added</td><td>1</td></tr>`,
+		`<tr><td>Modified</td><td>1</td></tr>`,
+		`<a href="#added-0"><code>/etc/&lt;added&gt;</code></a>`,
+		`<h3 id="modified-0"><code>/etc/modified</code></h3>`,
+		`<a href="#entropy-0">`,
+		"7.90 bits/byte",
+		"mode: 644 =&gt; 744",
+		"WARNING (<code>/root</code>): permission denied",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("CompareHTML() output doesn't contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "<script") || strings.Contains(got, "http") {
+		t.Errorf("CompareHTML() output isn't self-contained:\n%s", got)
+	}
+}
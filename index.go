@@ -0,0 +1,48 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+// WalkIndex looks up the files of a Walk by path in constant time rather than scanning all
+// files. It reflects the files of the Walk at the time it was created.
+type WalkIndex struct {
+	files map[string]*fspb.File
+}
+
+// NewWalkIndex indexes the files of the Walk, which may be nil. If a path occurs more than
+// once, the first file with that path is returned by Get.
+func NewWalkIndex(w *fspb.Walk) *WalkIndex {
+	idx := &WalkIndex{files: make(map[string]*fspb.File, len(w.GetFile()))}
+	for _, f := range w.GetFile() {
+		if _, ok := idx.files[f.Path]; !ok {
+			idx.files[f.Path] = f
+		}
+	}
+	return idx
+}
+
+// Get returns the file with the given path and whether there is one.
+func (idx *WalkIndex) Get(path string) (*fspb.File, bool) {
+	f, ok := idx.files[path]
+	return f, ok
+}
+
+// Len returns the number of distinct paths in the index.
+func (idx *WalkIndex) Len() int {
+	return len(idx.files)
+}
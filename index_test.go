@@ -0,0 +1,63 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"fmt"
+	"testing"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+func TestWalkIndex(t *testing.T) {
+	first := &fspb.File{Path: "/etc/passwd", Version: 1}
+	wlk := &fspb.Walk{File: []*fspb.File{
+		{Path: "/"},
+		first,
+		{Path: "/etc/passwd", Version: 2},
+	}}
+	idx := NewWalkIndex(wlk)
+	if got, ok := idx.Get("/etc/passwd"); !ok || got != first {
+		t.Errorf("Get(%q) = %v, %t; want the first file with that path", "/etc/passwd", got, ok)
+	}
+	if got, ok := idx.Get("/etc/shadow"); ok || got != nil {
+		t.Errorf("Get(%q) = %v, %t; want nil, false", "/etc/shadow", got, ok)
+	}
+	if got, want := idx.Len(), 2; got != want {
+		t.Errorf("Len() = %d; want %d", got, want)
+	}
+
+	idx = NewWalkIndex(nil)
+	if _, ok := idx.Get("/"); ok || idx.Len() != 0 {
+		t.Errorf("NewWalkIndex(nil) isn't empty")
+	}
+}
+
+func BenchmarkCompareLargeWalks(b *testing.B) {
+	const n = 50000
+	before, after := &fspb.Walk{}, &fspb.Walk{}
+	for i := 0; i < n; i++ {
+		p := fmt.Sprintf("/data/file%d", i)
+		before.File = append(before.File, &fspb.File{Path: p, Info: &fspb.FileInfo{Size: 1}})
+		after.File = append(after.File, &fspb.File{Path: p, Info: &fspb.FileInfo{Size: int64(1 + i%2)}})
+	}
+	r := &Reporter{config: &fspb.ReportConfig{}, before: before, after: after}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if got := len(r.diffWalks().Modified); got != n/2 {
+			b.Fatalf("diffWalks() found %d modified files; want %d", got, n/2)
+		}
+	}
+}
@@ -56,3 +56,42 @@ func (c *Counter) Get(name string) (int64, bool) {
 	val, ok := c.counts[name]
 	return val, ok
 }
+
+// Reset discards all metrics, e.g. to start counting afresh for another run.
+func (c *Counter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts = nil
+}
+
+// Export returns a copy of all metrics and their values at this point in time, e.g. to
+// serialize them or hand them to another metrics system.
+func (c *Counter) Export() map[string]int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	snap := make(map[string]int64, len(c.counts))
+	for m, v := range c.counts {
+		snap[m] = v
+	}
+	return snap
+}
+
+// Snapshot is the same as Export.
+func (c *Counter) Snapshot() map[string]int64 {
+	return c.Export()
+}
+
+// ImportFrom adds the values of the given metrics to the Counter, e.g. to merge the metrics
+// of several runs or to set up a Counter in tests. It is the counterpart of Export.
+func (c *Counter) ImportFrom(metrics map[string]int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counts == nil {
+		c.counts = make(map[string]int64, len(metrics))
+	}
+	for m, v := range metrics {
+		c.counts[m] += v
+	}
+}
@@ -15,6 +15,7 @@
 package metrics
 
 import (
+	"reflect"
 	"sync"
 	"testing"
 )
@@ -46,3 +47,53 @@ func TestCounter(t *testing.T) {
 		t.Errorf("c.Metrics()[0] = %q; want %q", m[0], wantMetric)
 	}
 }
+
+func TestCounterSnapshotReset(t *testing.T) {
+	c := &Counter{}
+	c.Add(2, "a")
+	c.Add(3, "b")
+
+	snap := c.Snapshot()
+	want := map[string]int64{"a": 2, "b": 3}
+	if !reflect.DeepEqual(snap, want) {
+		t.Errorf("c.Snapshot() = %v; want %v", snap, want)
+	}
+	c.Add(1, "a")
+	if snap["a"] != 2 {
+		t.Errorf("c.Snapshot() changed after Add: a = %d; want 2", snap["a"])
+	}
+
+	c.Reset()
+	if m := c.Metrics(); len(m) != 0 {
+		t.Errorf("c.Metrics() after Reset() = %q; want none", m)
+	}
+	if n, ok := c.Get("a"); n != 0 || ok {
+		t.Errorf("c.Get(\"a\") after Reset() = %d, %v; want 0, false", n, ok)
+	}
+	c.Add(1, "a")
+	if n, _ := c.Get("a"); n != 1 {
+		t.Errorf("c.Get(\"a\") after Reset() and Add() = %d; want 1", n)
+	}
+}
+
+func TestCounterImportFrom(t *testing.T) {
+	c := &Counter{}
+	c.ImportFrom(map[string]int64{"a": 2, "b": 3})
+	c.Add(1, "a")
+	c.ImportFrom(map[string]int64{"a": 4, "c": 5})
+
+	want := map[string]int64{"a": 7, "b": 3, "c": 5}
+	if got := c.Export(); !reflect.DeepEqual(got, want) {
+		t.Errorf("c.Export() after ImportFrom() = %v; want %v", got, want)
+	}
+
+	// Exported metrics can be imported into another Counter unchanged.
+	other := &Counter{}
+	other.ImportFrom(c.Export())
+	if got := other.Export(); !reflect.DeepEqual(got, want) {
+		t.Errorf("other.Export() after ImportFrom(c.Export()) = %v; want %v", got, want)
+	}
+	if got := c.Snapshot(); !reflect.DeepEqual(got, want) {
+		t.Errorf("c.Snapshot() = %v; want %v", got, want)
+	}
+}
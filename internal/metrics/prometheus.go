@@ -0,0 +1,83 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// invalidNameChars matches characters which are not allowed in Prometheus metric names.
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// prometheusName turns a metric name like "file-count" into a valid Prometheus metric name
+// like "namespace_file_count".
+func prometheusName(namespace, metric string) string {
+	return prometheus.BuildFQName(invalidNameChars.ReplaceAllString(namespace, "_"), "", invalidNameChars.ReplaceAllString(metric, "_"))
+}
+
+// counterCollector exports all metrics of a Counter as Prometheus gauges.
+type counterCollector struct {
+	c         *Counter
+	namespace string
+}
+
+// Describe sends no descriptions as the metrics are only known once they are counted.
+// This makes the collector unchecked.
+func (cc *counterCollector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect sends the current value of each metric.
+func (cc *counterCollector) Collect(ch chan<- prometheus.Metric) {
+	snap := cc.c.Snapshot()
+	names := make([]string, 0, len(snap))
+	for m := range snap {
+		names = append(names, m)
+	}
+	sort.Strings(names)
+	for _, m := range names {
+		desc := prometheus.NewDesc(prometheusName(cc.namespace, m), "fswalker metric "+m, nil, nil)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(snap[m]))
+	}
+}
+
+// RegisterPrometheus registers all metrics of the Counter as gauges with the given namespace.
+// The gauges always report the current values, including metrics counted after registration.
+// Dashes in metric names are replaced by underscores, e.g. "file-count" becomes
+// "<namespace>_file_count".
+func (c *Counter) RegisterPrometheus(reg prometheus.Registerer, namespace string) error {
+	return reg.Register(&counterCollector{c: c, namespace: namespace})
+}
+
+// ServePrometheus starts an HTTP server on addr exposing the metrics of the Counter at /metrics.
+// It returns once the server is listening; requests are served in the background.
+func (c *Counter) ServePrometheus(addr, namespace string) error {
+	reg := prometheus.NewRegistry()
+	if err := c.RegisterPrometheus(reg, namespace); err != nil {
+		return err
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	go http.Serve(l, mux)
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// gather returns the values of all gauges of the registry by name.
+func gather(t *testing.T, reg *prometheus.Registry) map[string]float64 {
+	t.Helper()
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error: %v", err)
+	}
+	got := map[string]float64{}
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			got[mf.GetName()] = m.GetGauge().GetValue()
+		}
+	}
+	return got
+}
+
+func TestRegisterPrometheus(t *testing.T) {
+	c := &Counter{}
+	c.Add(3, "file-count")
+	reg := prometheus.NewRegistry()
+	if err := c.RegisterPrometheus(reg, "fswalker"); err != nil {
+		t.Fatalf("RegisterPrometheus() error: %v", err)
+	}
+
+	want := map[string]float64{"fswalker_file_count": 3}
+	if got := gather(t, reg); !reflect.DeepEqual(got, want) {
+		t.Errorf("Gather() = %v; want %v", got, want)
+	}
+
+	// Metrics counted after registration are exported too.
+	c.Add(2, "file-count")
+	c.Add(1, "dir-count")
+	want = map[string]float64{"fswalker_file_count": 5, "fswalker_dir_count": 1}
+	if got := gather(t, reg); !reflect.DeepEqual(got, want) {
+		t.Errorf("Gather() after Add() = %v; want %v", got, want)
+	}
+}
+
+func TestPrometheusName(t *testing.T) {
+	testCases := []struct {
+		namespace string
+		metric    string
+		want      string
+	}{
+		{namespace: "fswalker", metric: "file-hash-count", want: "fswalker_file_hash_count"},
+		{namespace: "", metric: "errors", want: "errors"},
+		{namespace: "fs-walker", metric: "before-files.x", want: "fs_walker_before_files_x"},
+	}
+	for _, tc := range testCases {
+		if got := prometheusName(tc.namespace, tc.metric); got != tc.want {
+			t.Errorf("prometheusName(%q, %q) = %q; want %q", tc.namespace, tc.metric, got, tc.want)
+		}
+	}
+}
+
+func TestServePrometheus(t *testing.T) {
+	// Find a free port to listen on.
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	c := &Counter{}
+	c.Add(7, "file-count")
+	if err := c.ServePrometheus(addr, "fswalker"); err != nil {
+		t.Fatalf("ServePrometheus() error: %v", err)
+	}
+	resp, err := http.Get("http://" + addr + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics error: %v", err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "fswalker_file_count 7"; !strings.Contains(string(b), want) {
+		t.Errorf("GET /metrics = %q; want it to contain %q", b, want)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+// parseKnownGoodHashes parses the content of a known_good_hashes_file into a map of paths to
+// lower case SHA256 hashes. The hash is separated from the path by the last whitespace of
+// each line, so paths may contain spaces.
+func parseKnownGoodHashes(data []byte) (map[string]string, error) {
+	hashes := map[string]string{}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndexAny(line, " \t")
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: want \"<path> <sha256>\", got %q", n, line)
+		}
+		p, h := strings.TrimSpace(line[:i]), strings.ToLower(line[i+1:])
+		if b, err := hex.DecodeString(h); err != nil || len(b) != 32 {
+			return nil, fmt.Errorf("line %d: invalid SHA256 hash %q", n, h)
+		}
+		hashes[p] = h
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// knownGood returns whether the content of the file matches its entry in the
+// known_good_hashes_file of the config.
+func (r *Reporter) knownGood(f *fspb.File) bool {
+	want, ok := r.knownGoodHashes[f.GetPath()]
+	if !ok {
+		return false
+	}
+	for _, fp := range f.GetFingerprint() {
+		if fp.Method == fspb.Fingerprint_SHA256 && strings.ToLower(fp.Value) == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+const (
+	knownGoodHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	otherHash     = "7a7820f45f405bb98af87ca8ad2434eec99eef8cc8cb646d88c68b6c03fc9b63"
+)
+
+func TestParseKnownGoodHashes(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		data    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			desc: "paths with comments and spaces",
+			data: "# upgrade of foo 1.2\n\n/usr/bin/foo " + knownGoodHash + "\n/usr/share/foo/read me\t" + strings.ToUpper(otherHash) + "\n",
+			want: map[string]string{
+				"/usr/bin/foo":           knownGoodHash,
+				"/usr/share/foo/read me": otherHash,
+			},
+		}, {
+			desc:    "missing hash",
+			data:    "/usr/bin/foo\n",
+			wantErr: true,
+		}, {
+			desc:    "invalid hash",
+			data:    "/usr/bin/foo deadbeef\n",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		got, err := parseKnownGoodHashes([]byte(tc.data))
+		if (err != nil) != tc.wantErr {
+			t.Errorf("parseKnownGoodHashes() %s error: %v; want error: %t", tc.desc, err, tc.wantErr)
+			continue
+		}
+		if diff := cmp.Diff(tc.want, got); !tc.wantErr && diff != "" {
+			t.Errorf("parseKnownGoodHashes() %s: diff (-want +got):\n%s", tc.desc, diff)
+		}
+	}
+}
+
+func TestCompareKnownGoodHashes(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "knowngood")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+	hashes := filepath.Join(tmpdir, "hashes.txt")
+	data := fmt.Sprintf("/usr/bin/foo %s\n/usr/bin/bar %s\n/usr/lib/libfoo.so %s\n", knownGoodHash, knownGoodHash, knownGoodHash)
+	if err := ioutil.WriteFile(hashes, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := ReporterFromConfigBytes(context.Background(), []byte(fmt.Sprintf("known_good_hashes_file: %q", hashes)), false)
+	if err != nil {
+		t.Fatalf("ReporterFromConfigBytes() error: %v", err)
+	}
+
+	fp := func(v string) []*fspb.Fingerprint {
+		return []*fspb.Fingerprint{{Method: fspb.Fingerprint_SHA256, Value: v}}
+	}
+	r.before = &fspb.Walk{File: []*fspb.File{
+		{Version: 1, Path: "/usr/bin/foo", Fingerprint: fp(otherHash)},
+		{Version: 1, Path: "/usr/bin/bar", Fingerprint: fp(otherHash)},
+	}}
+	r.after = &fspb.Walk{File: []*fspb.File{
+		{Version: 1, Path: "/usr/bin/foo", Fingerprint: fp(knownGoodHash)},
+		{Version: 1, Path: "/usr/bin/bar", Fingerprint: fp("0000")},
+		{Version: 1, Path: "/usr/lib/libfoo.so", Fingerprint: fp(knownGoodHash)},
+		{Version: 1, Path: "/usr/lib/libbar.so", Fingerprint: fp(knownGoodHash)},
+	}}
+	var buf bytes.Buffer
+	r.Compare(&buf)
+	for _, want := range []string{"Added (1):\n/usr/lib/libbar.so\n", "Modified (1):\n/usr/bin/bar\n"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Compare() output doesn't contain %q:\n%s", want, buf.String())
+		}
+	}
+	for _, m := range []string{"before-files-known-good", "after-files-known-good"} {
+		if n, _ := r.Counter.Get(m); n != 1 {
+			t.Errorf("Compare() counted %d %s; want 1", n, m)
+		}
+	}
+
+	missing := filepath.Join(tmpdir, "missing.txt")
+	if _, err := ReporterFromConfigBytes(context.Background(), []byte(fmt.Sprintf("known_good_hashes_file: %q", missing)), false); err == nil {
+		t.Error("ReporterFromConfigBytes() with a missing known_good_hashes_file succeeded; want error")
+	}
+}
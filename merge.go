@@ -0,0 +1,137 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/uuid"
+
+	tspb "github.com/golang/protobuf/ptypes/timestamp"
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+// mergedHostSeparator separates the host names of the Walks making up a merged Walk.
+const mergedHostSeparator = ","
+
+// MergeHostWalks combines the Walks of several hosts into a single Walk for aggregate analysis,
+// e.g. of a cluster. Each file path is prefixed with the hostname of its Walk and a colon,
+// e.g. "host-1:/etc/passwd". The hostname of the merged Walk lists all hosts in sorted order,
+// so merged Walks of the same hosts can be compared with each other like the Walks of a single
+// host. The merged Walk spans from the earliest start to the latest stop of the given Walks
+// and keeps the policy of the first host. It is partial if any of the Walks is.
+// Each host may only be given once. Delta Walks need to be merged with their base first (see
+// MergeWalks).
+func MergeHostWalks(walks ...*fspb.Walk) (*fspb.Walk, error) {
+	if len(walks) == 0 {
+		return nil, fmt.Errorf("no Walks to merge")
+	}
+	seen := map[string]bool{}
+	var sorted []*fspb.Walk
+	for _, wlk := range walks {
+		switch {
+		case wlk == nil:
+			return nil, fmt.Errorf("nil Walk given")
+		case wlk.Hostname == "":
+			return nil, fmt.Errorf("Walk %s has no hostname", wlk.Id)
+		case seen[wlk.Hostname]:
+			return nil, fmt.Errorf("more than one Walk for host %q", wlk.Hostname)
+		case wlk.BaseWalk != "":
+			return nil, fmt.Errorf("Walk %s of host %q is a delta Walk", wlk.Id, wlk.Hostname)
+		}
+		seen[wlk.Hostname] = true
+		migrated, err := MigrateWalk(wlk, walkVersion)
+		if err != nil {
+			return nil, err
+		}
+		sorted = append(sorted, migrated)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Hostname < sorted[j].Hostname })
+
+	merged := &fspb.Walk{
+		Id:      uuid.New().String(),
+		Version: walkVersion,
+		Policy:  sorted[0].Policy,
+	}
+	var hosts []string
+	for _, wlk := range sorted {
+		hosts = append(hosts, wlk.Hostname)
+		pfx := wlk.Hostname + ":"
+		for _, f := range wlk.File {
+			f.Path = pfx + f.Path
+			merged.File = append(merged.File, f)
+		}
+		for _, n := range wlk.Notification {
+			n.Path = pfx + n.Path
+			merged.Notification = append(merged.Notification, n)
+		}
+		merged.Partial = merged.Partial || wlk.Partial
+		var err error
+		if merged.StartWalk, err = earliest(merged.StartWalk, wlk.StartWalk); err != nil {
+			return nil, fmt.Errorf("invalid start of Walk %s: %v", wlk.Id, err)
+		}
+		if merged.StopWalk, err = latest(merged.StopWalk, wlk.StopWalk); err != nil {
+			return nil, fmt.Errorf("invalid stop of Walk %s: %v", wlk.Id, err)
+		}
+	}
+	merged.Hostname = strings.Join(hosts, mergedHostSeparator)
+
+	sum, err := walkChecksum(merged)
+	if err != nil {
+		return nil, err
+	}
+	merged.Checksum = sum
+	return merged, nil
+}
+
+// earliest returns the earlier of two timestamps, ignoring a nil current value.
+func earliest(cur, ts *tspb.Timestamp) (*tspb.Timestamp, error) {
+	if cur == nil {
+		return ts, nil
+	}
+	before, err := tsBefore(ts, cur)
+	if err != nil || !before {
+		return cur, err
+	}
+	return ts, nil
+}
+
+// latest returns the later of two timestamps, ignoring a nil current value.
+func latest(cur, ts *tspb.Timestamp) (*tspb.Timestamp, error) {
+	if cur == nil {
+		return ts, nil
+	}
+	before, err := tsBefore(cur, ts)
+	if err != nil || !before {
+		return cur, err
+	}
+	return ts, nil
+}
+
+// tsBefore returns whether timestamp a is before b. A nil timestamp is an error.
+func tsBefore(a, b *tspb.Timestamp) (bool, error) {
+	at, err := ptypes.Timestamp(a)
+	if err != nil {
+		return false, err
+	}
+	bt, err := ptypes.Timestamp(b)
+	if err != nil {
+		return false, err
+	}
+	return at.Before(bt), nil
+}
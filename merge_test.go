@@ -0,0 +1,136 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+
+	tspb "github.com/golang/protobuf/ptypes/timestamp"
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+func TestMergeHostWalks(t *testing.T) {
+	hostB := &fspb.Walk{
+		Id:           "b",
+		Version:      1,
+		Hostname:     "host-b",
+		StartWalk:    &tspb.Timestamp{Seconds: 100},
+		StopWalk:     &tspb.Timestamp{Seconds: 300},
+		File:         []*fspb.File{{Version: 1, Path: "/etc/passwd"}},
+		Notification: []*fspb.Notification{{Severity: fspb.Notification_WARNING, Path: "/root"}},
+		Partial:      true,
+	}
+	hostA := &fspb.Walk{
+		Id:        "a",
+		Version:   1,
+		Hostname:  "host-a",
+		Policy:    &fspb.Policy{Include: []string{"/etc"}},
+		StartWalk: &tspb.Timestamp{Seconds: 50},
+		StopWalk:  &tspb.Timestamp{Seconds: 200},
+		File:      []*fspb.File{{Version: 1, Path: "/etc/passwd"}, {Version: 1, Path: "/etc/shadow"}},
+	}
+	got, err := MergeHostWalks(hostB, hostA)
+	if err != nil {
+		t.Fatalf("MergeHostWalks() error: %v", err)
+	}
+	want := &fspb.Walk{
+		Version:      walkVersion,
+		Hostname:     "host-a,host-b",
+		Policy:       &fspb.Policy{Include: []string{"/etc"}},
+		StartWalk:    &tspb.Timestamp{Seconds: 50},
+		StopWalk:     &tspb.Timestamp{Seconds: 300},
+		File:         []*fspb.File{{Version: 1, Path: "host-a:/etc/passwd"}, {Version: 1, Path: "host-a:/etc/shadow"}, {Version: 1, Path: "host-b:/etc/passwd"}},
+		Notification: []*fspb.Notification{{Severity: fspb.Notification_WARNING, Path: "host-b:/root"}},
+		Partial:      true,
+	}
+	if got.Id == "" || got.Checksum == nil {
+		t.Errorf("MergeHostWalks() = Walk %q with checksum %v; want an ID and a checksum", got.Id, got.Checksum)
+	}
+	got.Id, got.Checksum = "", nil
+	if diff := cmp.Diff(want, got, cmp.Comparer(proto.Equal)); diff != "" {
+		t.Errorf("MergeHostWalks(): diff (-want +got):\n%s", diff)
+	}
+	if hostB.File[0].Path != "/etc/passwd" {
+		t.Errorf("MergeHostWalks() modified the given Walk: path %q", hostB.File[0].Path)
+	}
+
+	for _, tc := range []struct {
+		desc  string
+		walks []*fspb.Walk
+	}{
+		{desc: "no Walks"},
+		{desc: "nil Walk", walks: []*fspb.Walk{hostA, nil}},
+		{desc: "duplicate host", walks: []*fspb.Walk{hostA, hostB, {Id: "c", Hostname: "host-a"}}},
+		{desc: "no hostname", walks: []*fspb.Walk{{Id: "c"}}},
+		{desc: "delta Walk", walks: []*fspb.Walk{{Id: "c", Hostname: "host-c", BaseWalk: "/walks/host-c.pb"}}},
+	} {
+		if _, err := MergeHostWalks(tc.walks...); err == nil {
+			t.Errorf("MergeHostWalks() of %s: no error", tc.desc)
+		}
+	}
+}
+
+func TestCompareMergedWalks(t *testing.T) {
+	walk := func(id, host string, start int64, paths ...string) *fspb.Walk {
+		wlk := &fspb.Walk{
+			Id:        id,
+			Version:   walkVersion,
+			Hostname:  host,
+			StartWalk: &tspb.Timestamp{Seconds: start},
+			StopWalk:  &tspb.Timestamp{Seconds: start + 10},
+		}
+		for _, p := range paths {
+			wlk.File = append(wlk.File, &fspb.File{Version: 1, Path: p})
+		}
+		return wlk
+	}
+	before, err := MergeHostWalks(walk("a1", "host-a", 0, "/etc/passwd"), walk("b1", "host-b", 0, "/etc/passwd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := MergeHostWalks(walk("a2", "host-a", 100, "/etc/passwd"), walk("b2", "host-b", 100, "/etc/passwd", "/etc/shadow"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Reporter{config: &fspb.ReportConfig{}}
+	if err := r.LoadWalksFromProtos(before, after); err != nil {
+		t.Fatalf("LoadWalksFromProtos() error: %v", err)
+	}
+	wd, err := r.CompareToDiff(context.Background())
+	if err != nil {
+		t.Fatalf("CompareToDiff() error: %v", err)
+	}
+	var got []string
+	for _, fd := range wd.FileDiff {
+		got = append(got, fd.DiffType.String()+" "+fd.Path)
+	}
+	want := []string{"ADDED host-b:/etc/shadow"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("CompareToDiff(): diff (-want +got):\n%s", diff)
+	}
+
+	other, err := MergeHostWalks(walk("c2", "host-c", 100, "/etc/passwd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.LoadWalksFromProtos(before, other); err == nil {
+		t.Error("LoadWalksFromProtos() of merged Walks of different hosts: no error")
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+// walkMigrations upgrade a Walk from the version of their key to the next version. Each returns
+// the names of the fields which could not be populated and are left at their zero values.
+var walkMigrations = map[uint32]func(*fspb.Walk) []string{
+	0: migrateWalkV0,
+}
+
+// migrateWalkV0 upgrades a Walk without version, e.g. one built by hand, to version 1.
+// Version 1 is the first version, so there is nothing to populate but the file versions.
+func migrateWalkV0(w *fspb.Walk) []string {
+	for _, f := range w.File {
+		if f.Version == 0 {
+			f.Version = 1
+		}
+	}
+	return nil
+}
+
+// MigrateWalk returns a copy of the Walk upgraded to the given version of the Walk structure
+// (see Walk.version), so it can be compared to Walks of that version. Fields which can't be
+// populated are left at their zero values and logged. Downgrades are not supported.
+func MigrateWalk(old *fspb.Walk, targetVersion uint32) (*fspb.Walk, error) {
+	if old == nil {
+		return nil, fmt.Errorf("no Walk to migrate")
+	}
+	if targetVersion > walkVersion {
+		return nil, fmt.Errorf("unknown Walk version %d, the latest version is %d", targetVersion, walkVersion)
+	}
+	if old.Version > targetVersion {
+		return nil, fmt.Errorf("unable to downgrade Walk %s from version %d to %d", old.Id, old.Version, targetVersion)
+	}
+	w := proto.Clone(old).(*fspb.Walk)
+	for w.Version < targetVersion {
+		migrate, ok := walkMigrations[w.Version]
+		if !ok {
+			return nil, fmt.Errorf("unable to migrate Walk %s from version %d", w.Id, w.Version)
+		}
+		if missing := migrate(w); len(missing) > 0 {
+			log.Printf("migrating Walk %s from version %d to %d: %s left unset", w.Id, w.Version, w.Version+1, strings.Join(missing, ", "))
+		}
+		w.Version++
+	}
+	return w, nil
+}
+
+// migrateWalks upgrades the older of two Walks of different versions to the version of the
+// other one. The before Walk may be nil.
+func migrateWalks(before, after *fspb.Walk) (*fspb.Walk, *fspb.Walk, error) {
+	if before == nil || after == nil || before.Version == after.Version {
+		return before, after, nil
+	}
+	var err error
+	if before.Version < after.Version {
+		before, err = MigrateWalk(before, after.Version)
+	} else {
+		after, err = MigrateWalk(after, before.Version)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return before, after, nil
+}
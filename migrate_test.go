@@ -0,0 +1,93 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+func TestMigrateWalk(t *testing.T) {
+	old := &fspb.Walk{Id: "old", File: []*fspb.File{{Path: "/etc/passwd"}, {Version: 1, Path: "/etc/group"}}}
+	testCases := []struct {
+		desc    string
+		walk    *fspb.Walk
+		target  uint32
+		want    *fspb.Walk
+		wantErr bool
+	}{
+		{
+			desc:   "unversioned to current",
+			walk:   old,
+			target: walkVersion,
+			want:   &fspb.Walk{Id: "old", Version: 1, File: []*fspb.File{{Version: 1, Path: "/etc/passwd"}, {Version: 1, Path: "/etc/group"}}},
+		}, {
+			desc:   "same version",
+			walk:   &fspb.Walk{Id: "new", Version: 1},
+			target: 1,
+			want:   &fspb.Walk{Id: "new", Version: 1},
+		}, {
+			desc:    "unknown version",
+			walk:    &fspb.Walk{Id: "new", Version: 1},
+			target:  walkVersion + 1,
+			wantErr: true,
+		}, {
+			desc:    "downgrade",
+			walk:    &fspb.Walk{Id: "new", Version: 1},
+			target:  0,
+			wantErr: true,
+		}, {
+			desc:    "nil",
+			target:  1,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		got, err := MigrateWalk(tc.walk, tc.target)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("MigrateWalk() %s error: %v; want error: %t", tc.desc, err, tc.wantErr)
+			continue
+		}
+		if !tc.wantErr && !proto.Equal(got, tc.want) {
+			t.Errorf("MigrateWalk() %s = %v; want %v", tc.desc, got, tc.want)
+		}
+	}
+	if old.Version != 0 || old.File[0].Version != 0 {
+		t.Errorf("MigrateWalk() modified the given Walk: %v", old)
+	}
+}
+
+func TestLoadWalksFromProtosMigrates(t *testing.T) {
+	r := &Reporter{config: &fspb.ReportConfig{}}
+	before := &fspb.Walk{Id: "before", Hostname: "host", File: []*fspb.File{{Path: "/etc/passwd"}}}
+	after := &fspb.Walk{Id: "after", Hostname: "host", Version: 1, File: []*fspb.File{{Version: 1, Path: "/etc/passwd"}}}
+	if err := r.LoadWalksFromProtos(before, after); err != nil {
+		t.Fatalf("LoadWalksFromProtos() error: %v", err)
+	}
+	if r.before.Version != 1 {
+		t.Errorf("LoadWalksFromProtos() before Walk version = %d; want 1", r.before.Version)
+	}
+	if n := r.diffWalks().ChangeCount(); n != 0 {
+		t.Errorf("diffWalks() of migrated Walks found %d changes; want 0", n)
+	}
+
+	after.Version = walkVersion + 1
+	if err := r.LoadWalksFromProtos(&fspb.Walk{Id: "before", Hostname: "host", Version: 1}, after); err == nil {
+		t.Error("LoadWalksFromProtos() of Walks with an unknown version succeeded; want error")
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	// Registers the "pgx" database/sql driver.
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+// defaultReviewTable is the table PostgresReviewStore keeps reviews in by default.
+const defaultReviewTable = "fswalker_reviews"
+
+// validTableName matches the table names PostgresReviewStore accepts, optionally schema qualified.
+var validTableName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z_][a-zA-Z0-9_]*)?$`)
+
+// isPostgresURI returns whether the review backend refers to a PostgreSQL database.
+func isPostgresURI(uri string) bool {
+	return strings.HasPrefix(uri, "postgres://") || strings.HasPrefix(uri, "postgresql://")
+}
+
+// reviewStoreForBackend returns the ReviewStore selected by review_backend of the config,
+// nil if the review file is to be used.
+func reviewStoreForBackend(backend string) (ReviewStore, error) {
+	switch {
+	case backend == "":
+		return nil, nil
+	case isPostgresURI(backend):
+		return NewPostgresReviewStore(backend)
+	}
+	return nil, fmt.Errorf("unknown review_backend %q, supported are postgres:// URIs", backend)
+}
+
+// undefinedTable is the SQLSTATE code PostgreSQL reports for queries of a missing table.
+const undefinedTable = "42P01"
+
+// PostgresReviewStore is a HostReviewStore keeping reviews in a PostgreSQL table with one row
+// per host, e.g. for teams managing hundreds of hosts. The table is created when a review is
+// first saved and it doesn't exist yet, so database users which only read and write reviews
+// don't need the privilege to create tables.
+type PostgresReviewStore struct {
+	// DB is the database, e.g. opened with the "pgx" driver.
+	DB *sql.DB
+	// Table is the name of the table of the reviews, "fswalker_reviews" if empty.
+	Table string
+}
+
+// NewPostgresReviewStore creates a PostgresReviewStore for the database at the given URI, e.g.
+// "postgres://fswalker@db.example.com/fswalker". The connection is only established on use.
+func NewPostgresReviewStore(uri string) (*PostgresReviewStore, error) {
+	db, err := sql.Open("pgx", uri)
+	if err != nil {
+		return nil, err
+	}
+	return &PostgresReviewStore{DB: db}, nil
+}
+
+// table returns the validated name of the table.
+func (s *PostgresReviewStore) table() (string, error) {
+	t := s.Table
+	if t == "" {
+		t = defaultReviewTable
+	}
+	if !validTableName.MatchString(t) {
+		return "", fmt.Errorf("invalid table name %q", t)
+	}
+	return t, nil
+}
+
+// createTable creates the table of the reviews unless it exists.
+func (s *PostgresReviewStore) createTable(ctx context.Context, t string) error {
+	_, err := s.DB.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+t+` (
+		hostname TEXT PRIMARY KEY,
+		walk_id TEXT NOT NULL,
+		walk_reference TEXT NOT NULL,
+		fingerprint_method TEXT NOT NULL,
+		fingerprint_value TEXT NOT NULL
+	)`)
+	return err
+}
+
+// isUndefinedTable returns whether err reports that the queried table doesn't exist.
+func isUndefinedTable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == undefinedTable
+}
+
+// write runs fn to modify the table of the reviews. If the table doesn't exist yet, it is
+// created and fn is run again.
+func (s *PostgresReviewStore) write(ctx context.Context, fn func(t string) error) error {
+	t, err := s.table()
+	if err != nil {
+		return err
+	}
+	if err := fn(t); !isUndefinedTable(err) {
+		return err
+	}
+	if err := s.createTable(ctx, t); err != nil {
+		return err
+	}
+	return fn(t)
+}
+
+// LoadReviews reads the reviews of all hosts. There are none if the table doesn't exist yet.
+func (s *PostgresReviewStore) LoadReviews(ctx context.Context) (*fspb.Reviews, error) {
+	t, err := s.table()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.QueryContext(ctx, `SELECT hostname, walk_id, walk_reference, fingerprint_method, fingerprint_value FROM `+t)
+	if isUndefinedTable(err) {
+		return &fspb.Reviews{Review: map[string]*fspb.Review{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	reviews := &fspb.Reviews{Review: map[string]*fspb.Review{}}
+	for rows.Next() {
+		var host, method, value string
+		rvw := &fspb.Review{}
+		if err := rows.Scan(&host, &rvw.WalkId, &rvw.WalkReference, &method, &value); err != nil {
+			return nil, err
+		}
+		if rvw.Fingerprint, err = reviewFingerprint(method, value); err != nil {
+			return nil, fmt.Errorf("review of %q: %v", host, err)
+		}
+		reviews.Review[host] = rvw
+	}
+	return reviews, rows.Err()
+}
+
+// SaveReviews replaces the reviews of all hosts in a single transaction, e.g. to import a
+// review file. ReviewManager uses SaveReview and DeleteReview instead.
+func (s *PostgresReviewStore) SaveReviews(ctx context.Context, reviews *fspb.Reviews) error {
+	return s.write(ctx, func(t string) error {
+		tx, err := s.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback() // no-op after Commit.
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+t); err != nil {
+			return err
+		}
+		for host, rvw := range reviews.GetReview() {
+			method, value := reviewColumns(rvw)
+			if _, err := tx.ExecContext(ctx, `INSERT INTO `+t+` (hostname, walk_id, walk_reference, fingerprint_method, fingerprint_value) VALUES ($1, $2, $3, $4, $5)`,
+				host, rvw.GetWalkId(), rvw.GetWalkReference(), method, value); err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	})
+}
+
+// SaveReview inserts or updates the row of the host, leaving the reviews of other hosts alone.
+func (s *PostgresReviewStore) SaveReview(ctx context.Context, hostname string, review *fspb.Review) error {
+	method, value := reviewColumns(review)
+	return s.write(ctx, func(t string) error {
+		_, err := s.DB.ExecContext(ctx, `INSERT INTO `+t+` (hostname, walk_id, walk_reference, fingerprint_method, fingerprint_value) VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (hostname) DO UPDATE SET walk_id = EXCLUDED.walk_id, walk_reference = EXCLUDED.walk_reference,
+			fingerprint_method = EXCLUDED.fingerprint_method, fingerprint_value = EXCLUDED.fingerprint_value`,
+			hostname, review.GetWalkId(), review.GetWalkReference(), method, value)
+		return err
+	})
+}
+
+// DeleteReview deletes the row of the host.
+func (s *PostgresReviewStore) DeleteReview(ctx context.Context, hostname string) error {
+	t, err := s.table()
+	if err != nil {
+		return err
+	}
+	_, err = s.DB.ExecContext(ctx, `DELETE FROM `+t+` WHERE hostname = $1`, hostname)
+	if isUndefinedTable(err) {
+		return nil // nothing to delete.
+	}
+	return err
+}
+
+// reviewColumns returns the fingerprint columns of a review, empty if it has no fingerprint.
+func reviewColumns(rvw *fspb.Review) (method, value string) {
+	if fp := rvw.GetFingerprint(); fp != nil {
+		return fp.Method.String(), fp.Value
+	}
+	return "", ""
+}
+
+// String describes the table of the reviews.
+func (s *PostgresReviewStore) String() string {
+	t, _ := s.table()
+	return "postgres table " + t
+}
+
+// reviewFingerprint converts a fingerprint stored by PostgresReviewStore, nil if none is stored.
+func reviewFingerprint(method, value string) (*fspb.Fingerprint, error) {
+	if method == "" && value == "" {
+		return nil, nil
+	}
+	m, ok := fspb.Fingerprint_Method_value[method]
+	if !ok {
+		return nil, fmt.Errorf("unknown fingerprint method %q", method)
+	}
+	return &fspb.Fingerprint{Method: fspb.Fingerprint_Method(m), Value: value}, nil
+}
@@ -0,0 +1,187 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+func TestReviewStoreForBackend(t *testing.T) {
+	testCases := []struct {
+		backend      string
+		wantPostgres bool
+		wantErr      bool
+	}{
+		{backend: ""},
+		{backend: "postgres://fswalker@db.example.com/fswalker", wantPostgres: true},
+		{backend: "postgresql:///fswalker", wantPostgres: true},
+		{backend: "mysql://db.example.com/fswalker", wantErr: true},
+		{backend: "/reviews.textpb", wantErr: true},
+	}
+	for _, tc := range testCases {
+		store, err := reviewStoreForBackend(tc.backend)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("reviewStoreForBackend(%q) error = %v; want error: %t", tc.backend, err, tc.wantErr)
+			continue
+		}
+		if _, ok := store.(*PostgresReviewStore); ok != tc.wantPostgres {
+			t.Errorf("reviewStoreForBackend(%q) = %T; want PostgresReviewStore: %t", tc.backend, store, tc.wantPostgres)
+		}
+	}
+}
+
+func TestNewReporterReviewBackend(t *testing.T) {
+	ctx := context.Background()
+	r, err := NewReporter(ctx, &fspb.ReportConfig{ReviewBackend: "postgres:///fswalker"}, false)
+	if err != nil {
+		t.Fatalf("NewReporter() error: %v", err)
+	}
+	if r.Reviews == nil || r.Reviews.String() != "postgres table fswalker_reviews" {
+		t.Errorf("NewReporter() reviews = %v; want postgres table fswalker_reviews", r.Reviews)
+	}
+	_, err = NewReporter(ctx, &fspb.ReportConfig{ReviewBackend: "sqlite://reviews.db"}, false)
+	var ice *InvalidConfigError
+	if !errors.As(err, &ice) {
+		t.Errorf("NewReporter() with unknown review_backend error = %v; want InvalidConfigError", err)
+	}
+}
+
+func TestReviewFingerprint(t *testing.T) {
+	testCases := []struct {
+		method  string
+		value   string
+		want    *fspb.Fingerprint
+		wantErr bool
+	}{
+		{},
+		{method: "SHA256", value: "abc", want: &fspb.Fingerprint{Method: fspb.Fingerprint_SHA256, Value: "abc"}},
+		{method: "MD4", value: "abc", wantErr: true},
+	}
+	for _, tc := range testCases {
+		got, err := reviewFingerprint(tc.method, tc.value)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("reviewFingerprint(%q, %q) error = %v; want error: %t", tc.method, tc.value, err, tc.wantErr)
+			continue
+		}
+		if diff := cmp.Diff(tc.want, got, cmp.Comparer(proto.Equal)); diff != "" {
+			t.Errorf("reviewFingerprint(): diff (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestIsUndefinedTable(t *testing.T) {
+	testCases := []struct {
+		err  error
+		want bool
+	}{
+		{err: nil},
+		{err: errors.New("relation does not exist")},
+		{err: &pgconn.PgError{Code: "42501"}},
+		{err: &pgconn.PgError{Code: undefinedTable}, want: true},
+		{err: fmt.Errorf("query: %w", &pgconn.PgError{Code: undefinedTable}), want: true},
+	}
+	for _, tc := range testCases {
+		if got := isUndefinedTable(tc.err); got != tc.want {
+			t.Errorf("isUndefinedTable(%v) = %t; want %t", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestPostgresReviewStoreInvalidTable(t *testing.T) {
+	s := &PostgresReviewStore{Table: "reviews; DROP TABLE users"}
+	if _, err := s.LoadReviews(context.Background()); err == nil {
+		t.Error("LoadReviews() with invalid table name: no error")
+	}
+}
+
+// TestPostgresReviewStore needs a database to run against, given by FSWALKER_TEST_POSTGRES,
+// e.g. "postgres://postgres@localhost/fswalker_test". The table used is dropped afterwards.
+func TestPostgresReviewStore(t *testing.T) {
+	uri := os.Getenv("FSWALKER_TEST_POSTGRES")
+	if uri == "" {
+		t.Skip("FSWALKER_TEST_POSTGRES not set")
+	}
+	ctx := context.Background()
+	s, err := NewPostgresReviewStore(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Table = "fswalker_reviews_test"
+	defer s.DB.ExecContext(ctx, "DROP TABLE IF EXISTS "+s.Table)
+	if _, err := s.DB.ExecContext(ctx, "DROP TABLE IF EXISTS "+s.Table); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.LoadReviews(ctx); err != nil || len(got.Review) != 0 {
+		t.Fatalf("LoadReviews() without table = %v, %v; want no reviews", got, err)
+	}
+
+	want := &fspb.Reviews{Review: map[string]*fspb.Review{
+		"a.example.com": {
+			WalkId:        "a",
+			WalkReference: "/walks/a.pb",
+			Fingerprint:   &fspb.Fingerprint{Method: fspb.Fingerprint_SHA256, Value: "abc"},
+		},
+		"b.example.com": {WalkId: "b", WalkReference: "/walks/b.pb"},
+	}}
+	for i := 0; i < 2; i++ { // the second save replaces the reviews of the first one.
+		if err := s.SaveReviews(ctx, want); err != nil {
+			t.Fatalf("SaveReviews() error: %v", err)
+		}
+	}
+	got, err := s.LoadReviews(ctx)
+	if err != nil {
+		t.Fatalf("LoadReviews() error: %v", err)
+	}
+	if diff := cmp.Diff(want, got, cmp.Comparer(proto.Equal)); diff != "" {
+		t.Errorf("LoadReviews(): diff (-want +got):\n%s", diff)
+	}
+
+	// Managers of reporters for different hosts only write the rows they changed.
+	ma, mb := NewReviewManager(s), NewReviewManager(s)
+	for _, m := range []*ReviewManager{ma, mb} {
+		if err := m.Load(ctx); err != nil {
+			t.Fatalf("Load() error: %v", err)
+		}
+	}
+	if err := ma.saveBaseline(ctx, "a.example.com", &fspb.Walk{Id: "a2"}, "/walks/a2.pb", nil); err != nil {
+		t.Fatalf("saveBaseline() error: %v", err)
+	}
+	if err := mb.DeleteBaseline("b.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mb.Save(ctx); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	got, err = s.LoadReviews(ctx)
+	if err != nil {
+		t.Fatalf("LoadReviews() error: %v", err)
+	}
+	want = &fspb.Reviews{Review: map[string]*fspb.Review{
+		"a.example.com": {WalkId: "a2", WalkReference: "/walks/a2.pb"},
+	}}
+	if diff := cmp.Diff(want, got, cmp.Comparer(proto.Equal)); diff != "" {
+		t.Errorf("LoadReviews() after per host saves: diff (-want +got):\n%s", diff)
+	}
+}
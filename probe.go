@@ -0,0 +1,280 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+const (
+	// defaultProbeDirs is the number of directories Probe reads from each root path by default.
+	defaultProbeDirs = 100
+	// probeHashBytes bounds the number of bytes Probe hashes to measure the hash throughput.
+	probeHashBytes = 16 * 1024 * 1024
+)
+
+// WalkEstimate is the extent of a walk as estimated by Walker.Probe.
+type WalkEstimate struct {
+	// Files and Dirs are the estimated number of files and directories to record.
+	Files int64
+	Dirs  int64
+	// Bytes is the estimated total size of the files, HashBytes the part of it to be hashed.
+	Bytes     int64
+	HashBytes int64
+	// Duration is the estimated duration of the walk.
+	Duration time.Duration
+	// SampledDirs is the number of directories read for the estimate.
+	SampledDirs int
+	// Exact is set if all directories were read, i.e. the counts are exact.
+	Exact bool
+}
+
+// probeDir is the content of a directory read by Probe.
+type probeDir struct {
+	files     int64
+	bytes     int64
+	hashBytes int64
+	subdirs   []string
+}
+
+// prober reads directories for Probe, caching each of them.
+type prober struct {
+	w    *Walker
+	rnd  *rand.Rand
+	dirs map[string]*probeDir
+	// entries is the number of directory entries read, taking readTime.
+	entries  int64
+	readTime time.Duration
+	// hashFiles are files to hash for measuring the hash throughput.
+	hashFiles []string
+	hashBytes int64
+}
+
+// Probe estimates the extent and duration of a walk without running it, e.g. before walking a
+// large NFS mount. For each root path, up to ProbeDirs directories along random paths from the
+// root are read and the size of the whole tree is extrapolated from them (Knuth's estimator).
+// The duration is extrapolated from the time it took to read the directories and to hash some
+// of the files to be hashed, assuming the walk scales with parallelism of the policy.
+// Nothing is recorded, so Probe can be called before Run.
+func (w *Walker) Probe(ctx context.Context) (WalkEstimate, error) {
+	if err := w.compileExcludeRegex(); err != nil {
+		return WalkEstimate{}, err
+	}
+	n := w.ProbeDirs
+	if n <= 0 {
+		n = defaultProbeDirs
+	}
+	pr := &prober{
+		w:    w,
+		rnd:  rand.New(rand.NewSource(time.Now().UnixNano())),
+		dirs: map[string]*probeDir{},
+	}
+	est := WalkEstimate{Exact: true}
+	includes := map[string]bool{}
+	for _, pc := range w.pol.IncludePaths() {
+		if err := ctx.Err(); err != nil {
+			return WalkEstimate{}, err
+		}
+		root := filepath.Clean(pc.Path)
+		if includes[root] {
+			continue
+		}
+		includes[root] = true
+		maxDepth := pc.MaxDepth
+		if maxDepth == 0 {
+			maxDepth = w.pol.MaxDirectoryDepth
+		}
+		re, err := pr.probeRoot(root, maxDepth, n)
+		if err != nil {
+			return WalkEstimate{}, err
+		}
+		est.Files += re.Files
+		est.Dirs += re.Dirs
+		est.Bytes += re.Bytes
+		est.HashBytes += re.HashBytes
+		est.Exact = est.Exact && re.Exact
+	}
+	est.SampledDirs = len(pr.dirs)
+	est.Duration = pr.duration(est)
+	return est, nil
+}
+
+// probeRoot estimates the tree below root by sampling up to n directories.
+func (pr *prober) probeRoot(root string, maxDepth uint32, n int) (WalkEstimate, error) {
+	info, err := os.Lstat(root)
+	if os.IsNotExist(err) {
+		return WalkEstimate{Exact: true}, nil // not walked, see MissingRootPaths.
+	}
+	if err != nil {
+		return WalkEstimate{}, err
+	}
+	if !info.IsDir() {
+		return WalkEstimate{Files: 1, Bytes: info.Size(), Exact: true}, nil
+	}
+	var dev uint64
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		dev = uint64(st.Dev)
+	}
+	read := func(p string) *probeDir { return pr.readDir(root, p, dev, maxDepth) }
+
+	// Each probe walks a random path down from root. Weighting each directory on the path with
+	// the product of the numbers of subdirectories above it gives an unbiased estimate of the tree.
+	budget := len(pr.dirs) + n
+	var dirs, files, bytes, hashBytes float64
+	probes := 0
+	for probes < n && (probes == 0 || len(pr.dirs) < budget) {
+		var pd, pf, pb, ph float64
+		weight := 1.0
+		complete := true
+		for p := root; ; {
+			if _, ok := pr.dirs[p]; !ok && probes > 0 && len(pr.dirs) >= budget {
+				complete = false
+				break
+			}
+			d := read(p)
+			pd += weight
+			pf += weight * float64(d.files)
+			pb += weight * float64(d.bytes)
+			ph += weight * float64(d.hashBytes)
+			if len(d.subdirs) == 0 {
+				break
+			}
+			weight *= float64(len(d.subdirs))
+			p = d.subdirs[pr.rnd.Intn(len(d.subdirs))]
+		}
+		if !complete {
+			break
+		}
+		probes++
+		dirs, files, bytes, hashBytes = dirs+pd, files+pf, bytes+pb, hashBytes+ph
+	}
+	if est, ok := pr.exact(root); ok {
+		return est, nil
+	}
+	return WalkEstimate{
+		Dirs:      int64(dirs / float64(probes)),
+		Files:     int64(files / float64(probes)),
+		Bytes:     int64(bytes / float64(probes)),
+		HashBytes: int64(hashBytes / float64(probes)),
+	}, nil
+}
+
+// exact sums up the tree below root if all of its directories were read.
+func (pr *prober) exact(root string) (WalkEstimate, bool) {
+	est := WalkEstimate{Exact: true}
+	for todo := []string{root}; len(todo) > 0; todo = todo[1:] {
+		d, ok := pr.dirs[todo[0]]
+		if !ok {
+			return WalkEstimate{}, false
+		}
+		est.Dirs++
+		est.Files += d.files
+		est.Bytes += d.bytes
+		est.HashBytes += d.hashBytes
+		todo = append(todo, d.subdirs...)
+	}
+	return est, true
+}
+
+// readDir reads the directory p below root like the walker would, returning the cached result
+// if it was read before. Unreadable directories are treated as empty.
+func (pr *prober) readDir(root, p string, dev uint64, maxDepth uint32) *probeDir {
+	if d, ok := pr.dirs[p]; ok {
+		return d
+	}
+	w := pr.w
+	d := &probeDir{}
+	pr.dirs[p] = d
+	start := time.Now()
+	infos, err := ioutil.ReadDir(p)
+	pr.readTime += time.Since(start)
+	if err != nil {
+		return d
+	}
+	pr.entries += int64(len(infos))
+	for _, info := range infos {
+		cp := filepath.Join(p, info.Name())
+		if w.isExcluded(cp) || w.excludeRule(cp) != "" {
+			continue
+		}
+		if info.IsDir() {
+			if maxDepth > 0 && w.relDirDepth(root, cp) > maxDepth {
+				continue
+			}
+			if st, ok := info.Sys().(*syscall.Stat_t); ok && !w.pol.WalkCrossDevice && uint64(st.Dev) != dev {
+				continue
+			}
+			d.subdirs = append(d.subdirs, cp)
+			continue
+		}
+		d.files++
+		d.bytes += info.Size()
+		if info.Mode().IsRegular() && w.wantHashing(cp) && info.Size() <= w.maxHashFileSize() {
+			d.hashBytes += info.Size()
+			if pr.hashBytes < probeHashBytes {
+				pr.hashFiles = append(pr.hashFiles, cp)
+				pr.hashBytes += info.Size()
+			}
+		}
+	}
+	return d
+}
+
+// duration estimates the duration of the walk from the time it took to read the sampled
+// directories and to hash the sampled files.
+func (pr *prober) duration(est WalkEstimate) time.Duration {
+	var secs float64
+	if pr.entries > 0 {
+		secs += pr.readTime.Seconds() / float64(pr.entries) * float64(est.Files+est.Dirs)
+	}
+	if rate := pr.hashRate(); rate > 0 {
+		secs += float64(est.HashBytes) / rate
+	}
+	if p := pr.w.pol.Parallelism; p > 1 {
+		secs /= float64(p)
+	}
+	return time.Duration(secs * float64(time.Second))
+}
+
+// hashRate hashes the sampled files and returns the throughput in bytes per second, 0 if
+// nothing was hashed.
+func (pr *prober) hashRate() float64 {
+	var n int64
+	start := time.Now()
+	for _, p := range pr.hashFiles {
+		h, err := pr.w.newHash()
+		if err != nil {
+			return 0
+		}
+		info, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		if _, err := hashFile(p, h); err == nil {
+			n += info.Size()
+		}
+	}
+	secs := time.Since(start).Seconds()
+	if n == 0 || secs == 0 {
+		return 0
+	}
+	return float64(n) / secs
+}
@@ -0,0 +1,128 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+// writeTree creates the given files (with the given content) and directories (ending in "/")
+// below dir.
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if strings.HasSuffix(name, "/") {
+			if err := os.MkdirAll(p, 0700); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestProbe(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "probe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	full := filepath.Join(tmpdir, "full")
+	writeTree(t, full, map[string]string{
+		"f1":     "0123456789",
+		"a/f2":   "01234567890123456789",
+		"b/f3":   "0",
+		"b/c/f4": "01",
+		"b/d/":   "",
+	})
+	// Each directory below wide has the same content, so each probe estimates it exactly.
+	wide := filepath.Join(tmpdir, "wide")
+	files := map[string]string{}
+	for i := 0; i < 10; i++ {
+		files[fmt.Sprintf("d%d/f", i)] = "01234"
+	}
+	writeTree(t, wide, files)
+
+	testCases := []struct {
+		desc string
+		pol  *fspb.Policy
+		dirs int
+		want WalkEstimate
+	}{
+		{
+			desc: "all directories",
+			pol:  &fspb.Policy{Include: []string{full}, HashPfx: []string{filepath.Join(full, "a")}},
+			want: WalkEstimate{Files: 4, Dirs: 5, Bytes: 33, HashBytes: 20, SampledDirs: 5, Exact: true},
+		}, {
+			desc: "excludes",
+			pol:  &fspb.Policy{Include: []string{full}, ExcludePfx: []string{filepath.Join(full, "b")}},
+			want: WalkEstimate{Files: 2, Dirs: 2, Bytes: 30, SampledDirs: 2, Exact: true},
+		}, {
+			desc: "max depth",
+			pol:  &fspb.Policy{IncludePath: []*fspb.PathConfig{{Path: full, MaxDepth: 1}}},
+			want: WalkEstimate{Files: 3, Dirs: 3, Bytes: 31, SampledDirs: 3, Exact: true},
+		}, {
+			desc: "missing root",
+			pol:  &fspb.Policy{Include: []string{filepath.Join(tmpdir, "missing")}},
+			want: WalkEstimate{Exact: true},
+		}, {
+			desc: "extrapolated",
+			pol:  &fspb.Policy{Include: []string{wide}},
+			dirs: 3,
+			want: WalkEstimate{Files: 10, Dirs: 11, Bytes: 50},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			wlkr := &Walker{pol: tc.pol, ProbeDirs: tc.dirs}
+			got, err := wlkr.Probe(ctx)
+			if err != nil {
+				t.Fatalf("Probe() error: %v", err)
+			}
+			if got.Duration < 0 {
+				t.Errorf("Probe() duration = %s; want >= 0", got.Duration)
+			}
+			got.Duration = 0
+			if tc.dirs > 0 {
+				// Which directories are sampled is random.
+				if got.SampledDirs > tc.dirs {
+					t.Errorf("Probe() sampled %d directories; want at most %d", got.SampledDirs, tc.dirs)
+				}
+				got.SampledDirs = 0
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Probe(): diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ package fswalker
 import (
 	fmt "fmt"
 	proto "github.com/golang/protobuf/proto"
+	duration "github.com/golang/protobuf/ptypes/duration"
 	timestamp "github.com/golang/protobuf/ptypes/timestamp"
 	math "math"
 )
@@ -21,6 +22,80 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
+// ChangeType is a kind of change to report, used to filter the report.
+type ReportConfig_ChangeType int32
+
+const (
+	ReportConfig_UNSPECIFIED        ReportConfig_ChangeType = 0
+	ReportConfig_FILE_ADDED         ReportConfig_ChangeType = 1
+	ReportConfig_FILE_DELETED       ReportConfig_ChangeType = 2
+	ReportConfig_CONTENT_CHANGED    ReportConfig_ChangeType = 3
+	ReportConfig_PERMISSION_CHANGED ReportConfig_ChangeType = 4
+	ReportConfig_OWNER_CHANGED      ReportConfig_ChangeType = 5
+	ReportConfig_METADATA_CHANGED   ReportConfig_ChangeType = 6
+)
+
+var ReportConfig_ChangeType_name = map[int32]string{
+	0: "UNSPECIFIED",
+	1: "FILE_ADDED",
+	2: "FILE_DELETED",
+	3: "CONTENT_CHANGED",
+	4: "PERMISSION_CHANGED",
+	5: "OWNER_CHANGED",
+	6: "METADATA_CHANGED",
+}
+
+var ReportConfig_ChangeType_value = map[string]int32{
+	"UNSPECIFIED":        0,
+	"FILE_ADDED":         1,
+	"FILE_DELETED":       2,
+	"CONTENT_CHANGED":    3,
+	"PERMISSION_CHANGED": 4,
+	"OWNER_CHANGED":      5,
+	"METADATA_CHANGED":   6,
+}
+
+func (x ReportConfig_ChangeType) String() string {
+	return proto.EnumName(ReportConfig_ChangeType_name, int32(x))
+}
+
+func (ReportConfig_ChangeType) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_251aa48241d53260, []int{2, 0}
+}
+
+// SortOrder is the order of the files in the written Walk.
+type Policy_SortOrder int32
+
+const (
+	// NONE keeps the order in which files were walked, which may differ
+	// between runs when walking in parallel.
+	Policy_NONE Policy_SortOrder = 0
+	// PATH_LEXICAL sorts files by path, making Walk files diff-friendly.
+	Policy_PATH_LEXICAL Policy_SortOrder = 1
+	// INODE_ORDER sorts files by device and inode number.
+	Policy_INODE_ORDER Policy_SortOrder = 2
+)
+
+var Policy_SortOrder_name = map[int32]string{
+	0: "NONE",
+	1: "PATH_LEXICAL",
+	2: "INODE_ORDER",
+}
+
+var Policy_SortOrder_value = map[string]int32{
+	"NONE":         0,
+	"PATH_LEXICAL": 1,
+	"INODE_ORDER":  2,
+}
+
+func (x Policy_SortOrder) String() string {
+	return proto.EnumName(Policy_SortOrder_name, int32(x))
+}
+
+func (Policy_SortOrder) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_251aa48241d53260, []int{3, 0}
+}
+
 // Indicator for the severity of the notification.
 type Notification_Severity int32
 
@@ -50,7 +125,47 @@ func (x Notification_Severity) String() string {
 }
 
 func (Notification_Severity) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_251aa48241d53260, []int{5, 0}
+	return fileDescriptor_251aa48241d53260, []int{7, 0}
+}
+
+type FileDiff_DiffType int32
+
+const (
+	FileDiff_UNKNOWN    FileDiff_DiffType = 0
+	FileDiff_ADDED      FileDiff_DiffType = 1
+	FileDiff_DELETED    FileDiff_DiffType = 2
+	FileDiff_MODIFIED   FileDiff_DiffType = 3
+	FileDiff_RETARGETED FileDiff_DiffType = 4
+	FileDiff_ERROR      FileDiff_DiffType = 5
+	FileDiff_RETYPED    FileDiff_DiffType = 6
+)
+
+var FileDiff_DiffType_name = map[int32]string{
+	0: "UNKNOWN",
+	1: "ADDED",
+	2: "DELETED",
+	3: "MODIFIED",
+	4: "RETARGETED",
+	5: "ERROR",
+	6: "RETYPED",
+}
+
+var FileDiff_DiffType_value = map[string]int32{
+	"UNKNOWN":    0,
+	"ADDED":      1,
+	"DELETED":    2,
+	"MODIFIED":   3,
+	"RETARGETED": 4,
+	"ERROR":      5,
+	"RETYPED":    6,
+}
+
+func (x FileDiff_DiffType) String() string {
+	return proto.EnumName(FileDiff_DiffType_name, int32(x))
+}
+
+func (FileDiff_DiffType) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_251aa48241d53260, []int{9, 0}
 }
 
 type Fingerprint_Method int32
@@ -58,16 +173,22 @@ type Fingerprint_Method int32
 const (
 	Fingerprint_UNKNOWN Fingerprint_Method = 0
 	Fingerprint_SHA256  Fingerprint_Method = 1
+	Fingerprint_SHA512  Fingerprint_Method = 2
+	Fingerprint_BLAKE3  Fingerprint_Method = 3
 )
 
 var Fingerprint_Method_name = map[int32]string{
 	0: "UNKNOWN",
 	1: "SHA256",
+	2: "SHA512",
+	3: "BLAKE3",
 }
 
 var Fingerprint_Method_value = map[string]int32{
 	"UNKNOWN": 0,
 	"SHA256":  1,
+	"SHA512":  2,
+	"BLAKE3":  3,
 }
 
 func (x Fingerprint_Method) String() string {
@@ -75,7 +196,7 @@ func (x Fingerprint_Method) String() string {
 }
 
 func (Fingerprint_Method) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_251aa48241d53260, []int{8, 0}
+	return fileDescriptor_251aa48241d53260, []int{12, 0}
 }
 
 // Reviews is a collection of "known good" states, one per host.
@@ -186,7 +307,47 @@ type ReportConfig struct {
 	// prefixes will be ignored. These are in addition to the exclusions in the
 	// client policy so more things can be recorded (but ignored in the default
 	// report).
-	ExcludePfx           []string `protobuf:"bytes,2,rep,name=exclude_pfx,json=excludePfx,proto3" json:"exclude_pfx,omitempty"`
+	ExcludePfx []string `protobuf:"bytes,2,rep,name=exclude_pfx,json=excludePfx,proto3" json:"exclude_pfx,omitempty"`
+	// fail_on_privilege_change makes the reporter exit with a non-zero exit code
+	// whenever a setuid or setgid bit appeared on or disappeared from a file,
+	// regardless of other suppression rules.
+	FailOnPrivilegeChange bool `protobuf:"varint,3,opt,name=fail_on_privilege_change,json=failOnPrivilegeChange,proto3" json:"fail_on_privilege_change,omitempty"`
+	// min_file_ratio is the minimum ratio of the file count of the "after" Walk
+	// compared to the "before" Walk for Walks to be considered valid, e.g. 0.1
+	// rejects a Walk with less than 10% of the files of the previous one.
+	// Defaults to 0.1 if unset.
+	MinFileRatio float64 `protobuf:"fixed64,4,opt,name=min_file_ratio,json=minFileRatio,proto3" json:"min_file_ratio,omitempty"`
+	// entropy_threshold is the entropy (in bits per byte, up to 8) above which
+	// added files are reported as high-entropy additions, e.g. encrypted or
+	// compressed payloads. Requires compute_entropy in the policy.
+	// Defaults to 7.5 if unset.
+	EntropyThreshold float64 `protobuf:"fixed64,5,opt,name=entropy_threshold,json=entropyThreshold,proto3" json:"entropy_threshold,omitempty"`
+	// summary_template, if set, is a Go text/template used to print the report
+	// summary instead of the default format. The template is executed with a
+	// ReportSummary as its dot value.
+	SummaryTemplate string `protobuf:"bytes,6,opt,name=summary_template,json=summaryTemplate,proto3" json:"summary_template,omitempty"`
+	// change_type_filter, if set, restricts the report to files with at least
+	// one of the given kinds of changes, e.g. only PERMISSION_CHANGED and
+	// OWNER_CHANGED to ignore mtime updates of log files. Files which could not be
+	// compared are always reported.
+	ChangeTypeFilter []ReportConfig_ChangeType `protobuf:"varint,7,rep,packed,name=change_type_filter,json=changeTypeFilter,proto3,enum=fswalker.ReportConfig_ChangeType" json:"change_type_filter,omitempty"`
+	// known_good_hashes_file is the path of a text file listing files whose
+	// content is expected to change, e.g. after a package upgrade. Each line
+	// holds a path and the SHA256 hash of its expected content, separated by
+	// whitespace. Added or modified files whose SHA256 fingerprint in the
+	// "after" Walk matches their entry are not reported. Empty lines and lines
+	// starting with "#" are ignored.
+	KnownGoodHashesFile string `protobuf:"bytes,8,opt,name=known_good_hashes_file,json=knownGoodHashesFile,proto3" json:"known_good_hashes_file,omitempty"`
+	// golden_hostname is the reference host, e.g. a gold master, whose latest
+	// Walk the Walks of other hosts are compared against by
+	// Reporter.CompareAgainstGolden (the reporter's -compareGolden).
+	GoldenHostname string `protobuf:"bytes,9,opt,name=golden_hostname,json=goldenHostname,proto3" json:"golden_hostname,omitempty"`
+	// review_backend selects where reviews are kept instead of the review file,
+	// e.g. "postgres://fswalker@db.example.com/fswalker" for a PostgreSQL
+	// database (see PostgresReviewStore). Credentials may be given through the
+	// usual PG* environment variables, e.g. PGPASSWORD. If empty, the review file
+	// passed to the reporter is used.
+	ReviewBackend        string   `protobuf:"bytes,10,opt,name=review_backend,json=reviewBackend,proto3" json:"review_backend,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -231,6 +392,62 @@ func (m *ReportConfig) GetExcludePfx() []string {
 	return nil
 }
 
+func (m *ReportConfig) GetFailOnPrivilegeChange() bool {
+	if m != nil {
+		return m.FailOnPrivilegeChange
+	}
+	return false
+}
+
+func (m *ReportConfig) GetMinFileRatio() float64 {
+	if m != nil {
+		return m.MinFileRatio
+	}
+	return 0
+}
+
+func (m *ReportConfig) GetEntropyThreshold() float64 {
+	if m != nil {
+		return m.EntropyThreshold
+	}
+	return 0
+}
+
+func (m *ReportConfig) GetSummaryTemplate() string {
+	if m != nil {
+		return m.SummaryTemplate
+	}
+	return ""
+}
+
+func (m *ReportConfig) GetChangeTypeFilter() []ReportConfig_ChangeType {
+	if m != nil {
+		return m.ChangeTypeFilter
+	}
+	return nil
+}
+
+func (m *ReportConfig) GetKnownGoodHashesFile() string {
+	if m != nil {
+		return m.KnownGoodHashesFile
+	}
+	return ""
+}
+
+func (m *ReportConfig) GetGoldenHostname() string {
+	if m != nil {
+		return m.GoldenHostname
+	}
+	return ""
+}
+
+func (m *ReportConfig) GetReviewBackend() string {
+	if m != nil {
+		return m.ReviewBackend
+	}
+	return ""
+}
+
 type Policy struct {
 	// version is the version of the proto structure.
 	Version uint32 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
@@ -239,28 +456,119 @@ type Policy struct {
 	// available). Important to note that the include paths SHOULD NOT contain
 	// each other because that will lead to paths being visited more than once.
 	Include []string `protobuf:"bytes,2,rep,name=include,proto3" json:"include,omitempty"`
+	// include_path is like include but allows limiting the depth of the walk
+	// per path, e.g. to walk "/etc" fully but only two levels of "/var". The
+	// paths of include and include_path are all walked; an include is the same
+	// as an include_path without max_depth.
+	IncludePath []*PathConfig `protobuf:"bytes,46,rep,name=include_path,json=includePath,proto3" json:"include_path,omitempty"`
 	// exclude_pfx is a list of path prefixes which will be excluded from being
 	// walked. Note that these are prefixes. Any path matching one of these
 	// prefixes will be ignored.
 	ExcludePfx []string `protobuf:"bytes,3,rep,name=exclude_pfx,json=excludePfx,proto3" json:"exclude_pfx,omitempty"`
+	// exclude_paths is a list of glob patterns (see Go's path.Match) matched
+	// against the full path of each file and directory, e.g. "/var/log/*.gz".
+	// Matching directories are skipped including everything below them.
+	ExcludePaths []string `protobuf:"bytes,38,rep,name=exclude_paths,json=excludePaths,proto3" json:"exclude_paths,omitempty"`
+	// exclude_regex is a list of regular expressions (see Go's regexp package)
+	// matched against the full path of each file and directory, e.g.
+	// "/__pycache__(/|$)". Matching directories are skipped including everything
+	// below them. Policies with invalid expressions are rejected.
+	ExcludeRegex []string `protobuf:"bytes,40,rep,name=exclude_regex,json=excludeRegex,proto3" json:"exclude_regex,omitempty"`
 	// hash_pfx is a list of path prefixes. If the discovered File path is not a
 	// directory, matches one of the prefixes and is not larger than
 	// max_hash_file_size, the file will be opened and a file hash built over its
 	// content.
-	HashPfx         []string `protobuf:"bytes,4,rep,name=hash_pfx,json=hashPfx,proto3" json:"hash_pfx,omitempty"`
-	MaxHashFileSize int64    `protobuf:"varint,5,opt,name=max_hash_file_size,json=maxHashFileSize,proto3" json:"max_hash_file_size,omitempty"`
+	HashPfx []string `protobuf:"bytes,4,rep,name=hash_pfx,json=hashPfx,proto3" json:"hash_pfx,omitempty"`
+	// max_hash_file_size is the size in bytes up to which files are hashed.
+	// Larger files are still recorded, just without fingerprint.
+	// Defaults to 1 MiB if unset.
+	MaxHashFileSize int64 `protobuf:"varint,5,opt,name=max_hash_file_size,json=maxHashFileSize,proto3" json:"max_hash_file_size,omitempty"`
+	// hash_algorithm is the method used to build file hashes.
+	// Defaults to SHA256.
+	HashAlgorithm Fingerprint_Method `protobuf:"varint,6,opt,name=hash_algorithm,json=hashAlgorithm,proto3,enum=fswalker.Fingerprint_Method" json:"hash_algorithm,omitempty"`
 	// walk_cross_device controls whether files on different devices from the
 	// include directories should be walked. I.e. if "/" is included, "/tmp" will
 	// only be walked if it is not a separate mount point.
 	WalkCrossDevice bool `protobuf:"varint,30,opt,name=walk_cross_device,json=walkCrossDevice,proto3" json:"walk_cross_device,omitempty"`
 	// ignore_irregular_files controls whether irregular files (i.e. symlinks,
 	// sockets, devices, etc) should be ignored.
-	// Note that symlinks are NOT followed either way.
+	// Note that symlinks are NOT followed either way unless follow_symlinks is
+	// set.
 	IgnoreIrregularFiles bool `protobuf:"varint,31,opt,name=ignore_irregular_files,json=ignoreIrregularFiles,proto3" json:"ignore_irregular_files,omitempty"`
 	// max_directory_depth controls how many levels of directories Walker should
 	// walk into an included directory.
 	// Defaults to no restriction on depth (i.e. go all the way).
-	MaxDirectoryDepth    uint32   `protobuf:"varint,32,opt,name=max_directory_depth,json=maxDirectoryDepth,proto3" json:"max_directory_depth,omitempty"`
+	MaxDirectoryDepth uint32 `protobuf:"varint,32,opt,name=max_directory_depth,json=maxDirectoryDepth,proto3" json:"max_directory_depth,omitempty"`
+	// parallelism controls how many directories Walker reads concurrently.
+	// Defaults to 1 (i.e. no concurrency).
+	Parallelism uint32 `protobuf:"varint,33,opt,name=parallelism,proto3" json:"parallelism,omitempty"`
+	// capture_xattrs controls whether extended attributes of regular files and
+	// directories are recorded.
+	CaptureXattrs bool `protobuf:"varint,34,opt,name=capture_xattrs,json=captureXattrs,proto3" json:"capture_xattrs,omitempty"`
+	// max_errors aborts the walk once this many files or directories could not
+	// be walked. Defaults to 0 (i.e. errors are counted but the walk continues).
+	MaxErrors uint32 `protobuf:"varint,35,opt,name=max_errors,json=maxErrors,proto3" json:"max_errors,omitempty"`
+	// delta_walk makes the walker only record files which changed since the
+	// latest Walk in the output location. The resulting Walk refers to that
+	// Walk via base_walk.
+	DeltaWalk bool `protobuf:"varint,36,opt,name=delta_walk,json=deltaWalk,proto3" json:"delta_walk,omitempty"`
+	// compute_entropy records the Shannon entropy of the content of regular
+	// files which are not larger than max_hash_file_size.
+	ComputeEntropy bool `protobuf:"varint,37,opt,name=compute_entropy,json=computeEntropy,proto3" json:"compute_entropy,omitempty"`
+	// detect_mime_type records the MIME type of regular files as detected from
+	// the first 512 bytes of their content.
+	DetectMimeType bool `protobuf:"varint,39,opt,name=detect_mime_type,json=detectMimeType,proto3" json:"detect_mime_type,omitempty"`
+	// min_mtime_age and max_mtime_age, if set, restrict the recorded files (but
+	// not directories) to those whose modification time lies within this age
+	// window relative to the start of the walk. E.g. a max_mtime_age of 24h only
+	// records files modified within the last day. Directories are still walked.
+	MinMtimeAge *duration.Duration `protobuf:"bytes,41,opt,name=min_mtime_age,json=minMtimeAge,proto3" json:"min_mtime_age,omitempty"`
+	MaxMtimeAge *duration.Duration `protobuf:"bytes,42,opt,name=max_mtime_age,json=maxMtimeAge,proto3" json:"max_mtime_age,omitempty"`
+	// directories_only only records directories, skipping all other files. This
+	// is sufficient for checks of the directory tree structure and much faster.
+	DirectoriesOnly bool `protobuf:"varint,43,opt,name=directories_only,json=directoriesOnly,proto3" json:"directories_only,omitempty"`
+	// sort_order sorts the files of the Walk before it is written.
+	SortOrder Policy_SortOrder `protobuf:"varint,44,opt,name=sort_order,json=sortOrder,proto3,enum=fswalker.Policy_SortOrder" json:"sort_order,omitempty"`
+	// sample_rate, if between 0 and 1, only hashes this fraction of the files
+	// matching hash_pfx; metadata is still recorded for all files. Files are
+	// picked by a hash of their path so the same files are hashed in each run.
+	// 0 (unset) hashes all files.
+	SampleRate float64 `protobuf:"fixed64,45,opt,name=sample_rate,json=sampleRate,proto3" json:"sample_rate,omitempty"`
+	// no_hash_extensions is a list of file extensions, e.g. "mp4" or ".iso",
+	// of files which are recorded without hash even if they match hash_pfx and
+	// are not larger than max_hash_file_size. Matching ignores case.
+	NoHashExtensions []string `protobuf:"bytes,47,rep,name=no_hash_extensions,json=noHashExtensions,proto3" json:"no_hash_extensions,omitempty"`
+	// hash_only_extensions, if set, restricts hashing to files with one of the
+	// given extensions, e.g. "conf" or ".so". Extensions may span several
+	// dots, e.g. "tar.gz". no_hash_extensions takes precedence.
+	HashOnlyExtensions []string `protobuf:"bytes,48,rep,name=hash_only_extensions,json=hashOnlyExtensions,proto3" json:"hash_only_extensions,omitempty"`
+	// capture_birth_time controls whether the birth (creation) time of files is
+	// recorded. It is only available on Linux 4.11 or later and file systems
+	// supporting it; elsewhere no birth time is recorded.
+	CaptureBirthTime bool `protobuf:"varint,49,opt,name=capture_birth_time,json=captureBirthTime,proto3" json:"capture_birth_time,omitempty"`
+	// max_walk_retention is the number of Walk files of the host to keep in the
+	// output location. After each successful walk, the oldest Walks beyond this
+	// number are deleted. Can't be combined with delta_walk as deltas depend on
+	// earlier Walks. Defaults to 0 (i.e. all Walks are kept).
+	MaxWalkRetention int32 `protobuf:"varint,50,opt,name=max_walk_retention,json=maxWalkRetention,proto3" json:"max_walk_retention,omitempty"`
+	// capture_capabilities controls whether the Linux file capabilities of
+	// regular files (e.g. cap_net_raw of ping) are recorded.
+	CaptureCapabilities bool `protobuf:"varint,51,opt,name=capture_capabilities,json=captureCapabilities,proto3" json:"capture_capabilities,omitempty"`
+	// follow_symlinks controls whether symlinks to directories are followed,
+	// walking the files of the target directory below the symlink's path. The
+	// symlink itself is recorded either way. A symlink to a directory which was
+	// walked already from the same include path, e.g. a symlink loop, is not
+	// followed again.
+	FollowSymlinks bool `protobuf:"varint,52,opt,name=follow_symlinks,json=followSymlinks,proto3" json:"follow_symlinks,omitempty"`
+	// inspect_archives controls whether the contents of archives are recorded
+	// as well, with virtual paths like "/opt/app.jar!/META-INF/MANIFEST.MF".
+	// Only archives up to max_hash_file_size are opened and at most that many
+	// uncompressed bytes are read from each. Entries are hashed if the archive
+	// is below a hash_pfx. Archives within archives are not opened.
+	InspectArchives bool `protobuf:"varint,53,opt,name=inspect_archives,json=inspectArchives,proto3" json:"inspect_archives,omitempty"`
+	// archive_formats lists the file extensions of the archives to inspect:
+	// zip, jar, war, ear, tar, tar.gz or tgz. Defaults to all of them.
+	ArchiveFormats       []string `protobuf:"bytes,54,rep,name=archive_formats,json=archiveFormats,proto3" json:"archive_formats,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -305,6 +613,13 @@ func (m *Policy) GetInclude() []string {
 	return nil
 }
 
+func (m *Policy) GetIncludePath() []*PathConfig {
+	if m != nil {
+		return m.IncludePath
+	}
+	return nil
+}
+
 func (m *Policy) GetExcludePfx() []string {
 	if m != nil {
 		return m.ExcludePfx
@@ -312,6 +627,20 @@ func (m *Policy) GetExcludePfx() []string {
 	return nil
 }
 
+func (m *Policy) GetExcludePaths() []string {
+	if m != nil {
+		return m.ExcludePaths
+	}
+	return nil
+}
+
+func (m *Policy) GetExcludeRegex() []string {
+	if m != nil {
+		return m.ExcludeRegex
+	}
+	return nil
+}
+
 func (m *Policy) GetHashPfx() []string {
 	if m != nil {
 		return m.HashPfx
@@ -326,6 +655,13 @@ func (m *Policy) GetMaxHashFileSize() int64 {
 	return 0
 }
 
+func (m *Policy) GetHashAlgorithm() Fingerprint_Method {
+	if m != nil {
+		return m.HashAlgorithm
+	}
+	return Fingerprint_UNKNOWN
+}
+
 func (m *Policy) GetWalkCrossDevice() bool {
 	if m != nil {
 		return m.WalkCrossDevice
@@ -347,6 +683,189 @@ func (m *Policy) GetMaxDirectoryDepth() uint32 {
 	return 0
 }
 
+func (m *Policy) GetParallelism() uint32 {
+	if m != nil {
+		return m.Parallelism
+	}
+	return 0
+}
+
+func (m *Policy) GetCaptureXattrs() bool {
+	if m != nil {
+		return m.CaptureXattrs
+	}
+	return false
+}
+
+func (m *Policy) GetMaxErrors() uint32 {
+	if m != nil {
+		return m.MaxErrors
+	}
+	return 0
+}
+
+func (m *Policy) GetDeltaWalk() bool {
+	if m != nil {
+		return m.DeltaWalk
+	}
+	return false
+}
+
+func (m *Policy) GetComputeEntropy() bool {
+	if m != nil {
+		return m.ComputeEntropy
+	}
+	return false
+}
+
+func (m *Policy) GetDetectMimeType() bool {
+	if m != nil {
+		return m.DetectMimeType
+	}
+	return false
+}
+
+func (m *Policy) GetMinMtimeAge() *duration.Duration {
+	if m != nil {
+		return m.MinMtimeAge
+	}
+	return nil
+}
+
+func (m *Policy) GetMaxMtimeAge() *duration.Duration {
+	if m != nil {
+		return m.MaxMtimeAge
+	}
+	return nil
+}
+
+func (m *Policy) GetDirectoriesOnly() bool {
+	if m != nil {
+		return m.DirectoriesOnly
+	}
+	return false
+}
+
+func (m *Policy) GetSortOrder() Policy_SortOrder {
+	if m != nil {
+		return m.SortOrder
+	}
+	return Policy_NONE
+}
+
+func (m *Policy) GetSampleRate() float64 {
+	if m != nil {
+		return m.SampleRate
+	}
+	return 0
+}
+
+func (m *Policy) GetNoHashExtensions() []string {
+	if m != nil {
+		return m.NoHashExtensions
+	}
+	return nil
+}
+
+func (m *Policy) GetHashOnlyExtensions() []string {
+	if m != nil {
+		return m.HashOnlyExtensions
+	}
+	return nil
+}
+
+func (m *Policy) GetCaptureBirthTime() bool {
+	if m != nil {
+		return m.CaptureBirthTime
+	}
+	return false
+}
+
+func (m *Policy) GetMaxWalkRetention() int32 {
+	if m != nil {
+		return m.MaxWalkRetention
+	}
+	return 0
+}
+
+func (m *Policy) GetCaptureCapabilities() bool {
+	if m != nil {
+		return m.CaptureCapabilities
+	}
+	return false
+}
+
+func (m *Policy) GetFollowSymlinks() bool {
+	if m != nil {
+		return m.FollowSymlinks
+	}
+	return false
+}
+
+func (m *Policy) GetInspectArchives() bool {
+	if m != nil {
+		return m.InspectArchives
+	}
+	return false
+}
+
+func (m *Policy) GetArchiveFormats() []string {
+	if m != nil {
+		return m.ArchiveFormats
+	}
+	return nil
+}
+
+// PathConfig is a path to walk along with settings specific to it.
+type PathConfig struct {
+	Path string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	// max_depth controls how many levels of directories Walker should walk
+	// into the path. Defaults to max_directory_depth of the policy.
+	MaxDepth             uint32   `protobuf:"varint,2,opt,name=max_depth,json=maxDepth,proto3" json:"max_depth,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PathConfig) Reset()         { *m = PathConfig{} }
+func (m *PathConfig) String() string { return proto.CompactTextString(m) }
+func (*PathConfig) ProtoMessage()    {}
+func (*PathConfig) Descriptor() ([]byte, []int) {
+	return fileDescriptor_251aa48241d53260, []int{4}
+}
+
+func (m *PathConfig) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PathConfig.Unmarshal(m, b)
+}
+func (m *PathConfig) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PathConfig.Marshal(b, m, deterministic)
+}
+func (m *PathConfig) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PathConfig.Merge(m, src)
+}
+func (m *PathConfig) XXX_Size() int {
+	return xxx_messageInfo_PathConfig.Size(m)
+}
+func (m *PathConfig) XXX_DiscardUnknown() {
+	xxx_messageInfo_PathConfig.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PathConfig proto.InternalMessageInfo
+
+func (m *PathConfig) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *PathConfig) GetMaxDepth() uint32 {
+	if m != nil {
+		return m.MaxDepth
+	}
+	return 0
+}
+
 type Walk struct {
 	// A unique string identifying this specific Walk.
 	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -361,18 +880,37 @@ type Walk struct {
 	// hostname of the machine the walk originates from.
 	Hostname string `protobuf:"bytes,10,opt,name=hostname,proto3" json:"hostname,omitempty"`
 	// start and stop time of the walk.
-	StartWalk            *timestamp.Timestamp `protobuf:"bytes,11,opt,name=start_walk,json=startWalk,proto3" json:"start_walk,omitempty"`
-	StopWalk             *timestamp.Timestamp `protobuf:"bytes,12,opt,name=stop_walk,json=stopWalk,proto3" json:"stop_walk,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
-	XXX_unrecognized     []byte               `json:"-"`
-	XXX_sizecache        int32                `json:"-"`
+	StartWalk *timestamp.Timestamp `protobuf:"bytes,11,opt,name=start_walk,json=startWalk,proto3" json:"start_walk,omitempty"`
+	StopWalk  *timestamp.Timestamp `protobuf:"bytes,12,opt,name=stop_walk,json=stopWalk,proto3" json:"stop_walk,omitempty"`
+	// base_walk is the file name of the Walk this one is a delta to. If set,
+	// file only holds files which were added or changed since the base Walk
+	// and deleted lists the paths of the files which are gone.
+	// The base Walk is expected next to this one, unless base_walk is a path
+	// relative to the directory of this one, e.g. in another day's directory.
+	BaseWalk string   `protobuf:"bytes,13,opt,name=base_walk,json=baseWalk,proto3" json:"base_walk,omitempty"`
+	Deleted  []string `protobuf:"bytes,14,rep,name=deleted,proto3" json:"deleted,omitempty"`
+	// checksum is computed over file and deleted when the Walk is written. It
+	// allows detecting corrupted Walk files. Walks written by older versions of
+	// the walker have none.
+	Checksum *Fingerprint `protobuf:"bytes,15,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	// partial is set if the walk was stopped before all included paths were
+	// walked, e.g. because it was cancelled or ran past its deadline. file
+	// then only holds the files discovered up to that point.
+	Partial bool `protobuf:"varint,16,opt,name=partial,proto3" json:"partial,omitempty"`
+	// summary holds aggregate statistics of the walk, so they can be shown
+	// without going through all files. Walks written by older versions of the
+	// walker have none.
+	Summary              *WalkSummary `protobuf:"bytes,17,opt,name=summary,proto3" json:"summary,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
 }
 
 func (m *Walk) Reset()         { *m = Walk{} }
 func (m *Walk) String() string { return proto.CompactTextString(m) }
 func (*Walk) ProtoMessage()    {}
 func (*Walk) Descriptor() ([]byte, []int) {
-	return fileDescriptor_251aa48241d53260, []int{4}
+	return fileDescriptor_251aa48241d53260, []int{5}
 }
 
 func (m *Walk) XXX_Unmarshal(b []byte) error {
@@ -449,6 +987,145 @@ func (m *Walk) GetStopWalk() *timestamp.Timestamp {
 	return nil
 }
 
+func (m *Walk) GetBaseWalk() string {
+	if m != nil {
+		return m.BaseWalk
+	}
+	return ""
+}
+
+func (m *Walk) GetDeleted() []string {
+	if m != nil {
+		return m.Deleted
+	}
+	return nil
+}
+
+func (m *Walk) GetChecksum() *Fingerprint {
+	if m != nil {
+		return m.Checksum
+	}
+	return nil
+}
+
+func (m *Walk) GetPartial() bool {
+	if m != nil {
+		return m.Partial
+	}
+	return false
+}
+
+func (m *Walk) GetSummary() *WalkSummary {
+	if m != nil {
+		return m.Summary
+	}
+	return nil
+}
+
+// WalkSummary holds aggregate statistics of a Walk. For delta Walks, they
+// describe the full walk rather than the files recorded in the delta.
+type WalkSummary struct {
+	// file_count is the number of files other than directories, dir_count the
+	// number of directories.
+	FileCount int64 `protobuf:"varint,1,opt,name=file_count,json=fileCount,proto3" json:"file_count,omitempty"`
+	DirCount  int64 `protobuf:"varint,2,opt,name=dir_count,json=dirCount,proto3" json:"dir_count,omitempty"`
+	// total_size_bytes is the sum of the sizes of all files other than
+	// directories.
+	TotalSizeBytes int64 `protobuf:"varint,3,opt,name=total_size_bytes,json=totalSizeBytes,proto3" json:"total_size_bytes,omitempty"`
+	// hash_count is the number of files which were fingerprinted.
+	HashCount     int64                `protobuf:"varint,4,opt,name=hash_count,json=hashCount,proto3" json:"hash_count,omitempty"`
+	WalkStartTime *timestamp.Timestamp `protobuf:"bytes,5,opt,name=walk_start_time,json=walkStartTime,proto3" json:"walk_start_time,omitempty"`
+	WalkEndTime   *timestamp.Timestamp `protobuf:"bytes,6,opt,name=walk_end_time,json=walkEndTime,proto3" json:"walk_end_time,omitempty"`
+	Hostname      string               `protobuf:"bytes,7,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	// policy_name identifies the policy used, e.g. the path or URL it was read
+	// from. It is empty if the policy wasn't read from a file.
+	PolicyName           string   `protobuf:"bytes,8,opt,name=policy_name,json=policyName,proto3" json:"policy_name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WalkSummary) Reset()         { *m = WalkSummary{} }
+func (m *WalkSummary) String() string { return proto.CompactTextString(m) }
+func (*WalkSummary) ProtoMessage()    {}
+func (*WalkSummary) Descriptor() ([]byte, []int) {
+	return fileDescriptor_251aa48241d53260, []int{6}
+}
+
+func (m *WalkSummary) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WalkSummary.Unmarshal(m, b)
+}
+func (m *WalkSummary) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WalkSummary.Marshal(b, m, deterministic)
+}
+func (m *WalkSummary) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WalkSummary.Merge(m, src)
+}
+func (m *WalkSummary) XXX_Size() int {
+	return xxx_messageInfo_WalkSummary.Size(m)
+}
+func (m *WalkSummary) XXX_DiscardUnknown() {
+	xxx_messageInfo_WalkSummary.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WalkSummary proto.InternalMessageInfo
+
+func (m *WalkSummary) GetFileCount() int64 {
+	if m != nil {
+		return m.FileCount
+	}
+	return 0
+}
+
+func (m *WalkSummary) GetDirCount() int64 {
+	if m != nil {
+		return m.DirCount
+	}
+	return 0
+}
+
+func (m *WalkSummary) GetTotalSizeBytes() int64 {
+	if m != nil {
+		return m.TotalSizeBytes
+	}
+	return 0
+}
+
+func (m *WalkSummary) GetHashCount() int64 {
+	if m != nil {
+		return m.HashCount
+	}
+	return 0
+}
+
+func (m *WalkSummary) GetWalkStartTime() *timestamp.Timestamp {
+	if m != nil {
+		return m.WalkStartTime
+	}
+	return nil
+}
+
+func (m *WalkSummary) GetWalkEndTime() *timestamp.Timestamp {
+	if m != nil {
+		return m.WalkEndTime
+	}
+	return nil
+}
+
+func (m *WalkSummary) GetHostname() string {
+	if m != nil {
+		return m.Hostname
+	}
+	return ""
+}
+
+func (m *WalkSummary) GetPolicyName() string {
+	if m != nil {
+		return m.PolicyName
+	}
+	return ""
+}
+
 type Notification struct {
 	Severity Notification_Severity `protobuf:"varint,1,opt,name=severity,proto3,enum=fswalker.Notification_Severity" json:"severity,omitempty"`
 	// path where the notification occurred.
@@ -464,7 +1141,7 @@ func (m *Notification) Reset()         { *m = Notification{} }
 func (m *Notification) String() string { return proto.CompactTextString(m) }
 func (*Notification) ProtoMessage()    {}
 func (*Notification) Descriptor() ([]byte, []int) {
-	return fileDescriptor_251aa48241d53260, []int{5}
+	return fileDescriptor_251aa48241d53260, []int{7}
 }
 
 func (m *Notification) XXX_Unmarshal(b []byte) error {
@@ -506,6 +1183,262 @@ func (m *Notification) GetMessage() string {
 	return ""
 }
 
+// WalkDiff is the structured result of comparing two Walks.
+type WalkDiff struct {
+	// hostname of the machine the compared Walks originate from.
+	Hostname string `protobuf:"bytes,1,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	// IDs and references (e.g. file paths) of the compared Walks.
+	// The before fields are empty if there was no before Walk.
+	BeforeWalkId        string `protobuf:"bytes,2,opt,name=before_walk_id,json=beforeWalkId,proto3" json:"before_walk_id,omitempty"`
+	BeforeWalkReference string `protobuf:"bytes,3,opt,name=before_walk_reference,json=beforeWalkReference,proto3" json:"before_walk_reference,omitempty"`
+	AfterWalkId         string `protobuf:"bytes,4,opt,name=after_walk_id,json=afterWalkId,proto3" json:"after_walk_id,omitempty"`
+	AfterWalkReference  string `protobuf:"bytes,5,opt,name=after_walk_reference,json=afterWalkReference,proto3" json:"after_walk_reference,omitempty"`
+	// file_diff lists the differences grouped by diff type.
+	FileDiff []*FileDiff `protobuf:"bytes,6,rep,name=file_diff,json=fileDiff,proto3" json:"file_diff,omitempty"`
+	// warning lists issues limiting the comparison, e.g. Walks using different
+	// hash algorithms.
+	Warning []string `protobuf:"bytes,7,rep,name=warning,proto3" json:"warning,omitempty"`
+	// Notifications which occurred during the compared Walks.
+	BeforeNotification   []*Notification `protobuf:"bytes,8,rep,name=before_notification,json=beforeNotification,proto3" json:"before_notification,omitempty"`
+	AfterNotification    []*Notification `protobuf:"bytes,9,rep,name=after_notification,json=afterNotification,proto3" json:"after_notification,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *WalkDiff) Reset()         { *m = WalkDiff{} }
+func (m *WalkDiff) String() string { return proto.CompactTextString(m) }
+func (*WalkDiff) ProtoMessage()    {}
+func (*WalkDiff) Descriptor() ([]byte, []int) {
+	return fileDescriptor_251aa48241d53260, []int{8}
+}
+
+func (m *WalkDiff) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WalkDiff.Unmarshal(m, b)
+}
+func (m *WalkDiff) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WalkDiff.Marshal(b, m, deterministic)
+}
+func (m *WalkDiff) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WalkDiff.Merge(m, src)
+}
+func (m *WalkDiff) XXX_Size() int {
+	return xxx_messageInfo_WalkDiff.Size(m)
+}
+func (m *WalkDiff) XXX_DiscardUnknown() {
+	xxx_messageInfo_WalkDiff.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WalkDiff proto.InternalMessageInfo
+
+func (m *WalkDiff) GetHostname() string {
+	if m != nil {
+		return m.Hostname
+	}
+	return ""
+}
+
+func (m *WalkDiff) GetBeforeWalkId() string {
+	if m != nil {
+		return m.BeforeWalkId
+	}
+	return ""
+}
+
+func (m *WalkDiff) GetBeforeWalkReference() string {
+	if m != nil {
+		return m.BeforeWalkReference
+	}
+	return ""
+}
+
+func (m *WalkDiff) GetAfterWalkId() string {
+	if m != nil {
+		return m.AfterWalkId
+	}
+	return ""
+}
+
+func (m *WalkDiff) GetAfterWalkReference() string {
+	if m != nil {
+		return m.AfterWalkReference
+	}
+	return ""
+}
+
+func (m *WalkDiff) GetFileDiff() []*FileDiff {
+	if m != nil {
+		return m.FileDiff
+	}
+	return nil
+}
+
+func (m *WalkDiff) GetWarning() []string {
+	if m != nil {
+		return m.Warning
+	}
+	return nil
+}
+
+func (m *WalkDiff) GetBeforeNotification() []*Notification {
+	if m != nil {
+		return m.BeforeNotification
+	}
+	return nil
+}
+
+func (m *WalkDiff) GetAfterNotification() []*Notification {
+	if m != nil {
+		return m.AfterNotification
+	}
+	return nil
+}
+
+// FileDiff is a single file which differs between two Walks.
+type FileDiff struct {
+	DiffType FileDiff_DiffType `protobuf:"varint,1,opt,name=diff_type,json=diffType,proto3,enum=fswalker.FileDiff_DiffType" json:"diff_type,omitempty"`
+	// path is the full file path including the file name.
+	Path string `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	// before and after are unset if the file was added or deleted respectively.
+	Before *FileInfo `protobuf:"bytes,3,opt,name=before,proto3" json:"before,omitempty"`
+	After  *FileInfo `protobuf:"bytes,4,opt,name=after,proto3" json:"after,omitempty"`
+	// changed_field lists the names of the changed metadata fields, e.g. "size"
+	// or "fingerprint".
+	ChangedField []string `protobuf:"bytes,5,rep,name=changed_field,json=changedField,proto3" json:"changed_field,omitempty"`
+	// diff describes the changes in human readable form, one per line.
+	Diff []string `protobuf:"bytes,6,rep,name=diff,proto3" json:"diff,omitempty"`
+	// error is set for diffs of type ERROR.
+	Error               string `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`
+	BeforeSymlinkTarget string `protobuf:"bytes,8,opt,name=before_symlink_target,json=beforeSymlinkTarget,proto3" json:"before_symlink_target,omitempty"`
+	AfterSymlinkTarget  string `protobuf:"bytes,9,opt,name=after_symlink_target,json=afterSymlinkTarget,proto3" json:"after_symlink_target,omitempty"`
+	// entropy of the file content as recorded in the after Walk.
+	Entropy float64 `protobuf:"fixed64,10,opt,name=entropy,proto3" json:"entropy,omitempty"`
+	// high_entropy is set for added files with an entropy above the threshold
+	// of the report config.
+	HighEntropy          bool     `protobuf:"varint,11,opt,name=high_entropy,json=highEntropy,proto3" json:"high_entropy,omitempty"`
+	BeforeMimeType       string   `protobuf:"bytes,12,opt,name=before_mime_type,json=beforeMimeType,proto3" json:"before_mime_type,omitempty"`
+	AfterMimeType        string   `protobuf:"bytes,13,opt,name=after_mime_type,json=afterMimeType,proto3" json:"after_mime_type,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *FileDiff) Reset()         { *m = FileDiff{} }
+func (m *FileDiff) String() string { return proto.CompactTextString(m) }
+func (*FileDiff) ProtoMessage()    {}
+func (*FileDiff) Descriptor() ([]byte, []int) {
+	return fileDescriptor_251aa48241d53260, []int{9}
+}
+
+func (m *FileDiff) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FileDiff.Unmarshal(m, b)
+}
+func (m *FileDiff) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FileDiff.Marshal(b, m, deterministic)
+}
+func (m *FileDiff) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FileDiff.Merge(m, src)
+}
+func (m *FileDiff) XXX_Size() int {
+	return xxx_messageInfo_FileDiff.Size(m)
+}
+func (m *FileDiff) XXX_DiscardUnknown() {
+	xxx_messageInfo_FileDiff.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FileDiff proto.InternalMessageInfo
+
+func (m *FileDiff) GetDiffType() FileDiff_DiffType {
+	if m != nil {
+		return m.DiffType
+	}
+	return FileDiff_UNKNOWN
+}
+
+func (m *FileDiff) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *FileDiff) GetBefore() *FileInfo {
+	if m != nil {
+		return m.Before
+	}
+	return nil
+}
+
+func (m *FileDiff) GetAfter() *FileInfo {
+	if m != nil {
+		return m.After
+	}
+	return nil
+}
+
+func (m *FileDiff) GetChangedField() []string {
+	if m != nil {
+		return m.ChangedField
+	}
+	return nil
+}
+
+func (m *FileDiff) GetDiff() []string {
+	if m != nil {
+		return m.Diff
+	}
+	return nil
+}
+
+func (m *FileDiff) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *FileDiff) GetBeforeSymlinkTarget() string {
+	if m != nil {
+		return m.BeforeSymlinkTarget
+	}
+	return ""
+}
+
+func (m *FileDiff) GetAfterSymlinkTarget() string {
+	if m != nil {
+		return m.AfterSymlinkTarget
+	}
+	return ""
+}
+
+func (m *FileDiff) GetEntropy() float64 {
+	if m != nil {
+		return m.Entropy
+	}
+	return 0
+}
+
+func (m *FileDiff) GetHighEntropy() bool {
+	if m != nil {
+		return m.HighEntropy
+	}
+	return false
+}
+
+func (m *FileDiff) GetBeforeMimeType() string {
+	if m != nil {
+		return m.BeforeMimeType
+	}
+	return ""
+}
+
+func (m *FileDiff) GetAfterMimeType() string {
+	if m != nil {
+		return m.AfterMimeType
+	}
+	return ""
+}
+
 type FileInfo struct {
 	// base name of the file
 	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
@@ -526,7 +1459,7 @@ func (m *FileInfo) Reset()         { *m = FileInfo{} }
 func (m *FileInfo) String() string { return proto.CompactTextString(m) }
 func (*FileInfo) ProtoMessage()    {}
 func (*FileInfo) Descriptor() ([]byte, []int) {
-	return fileDescriptor_251aa48241d53260, []int{6}
+	return fileDescriptor_251aa48241d53260, []int{10}
 }
 
 func (m *FileInfo) XXX_Unmarshal(b []byte) error {
@@ -583,19 +1516,22 @@ func (m *FileInfo) GetIsDir() bool {
 }
 
 type FileStat struct {
-	Dev                  uint64               `protobuf:"varint,1,opt,name=dev,proto3" json:"dev,omitempty"`
-	Inode                uint64               `protobuf:"varint,2,opt,name=inode,proto3" json:"inode,omitempty"`
-	Nlink                uint64               `protobuf:"varint,3,opt,name=nlink,proto3" json:"nlink,omitempty"`
-	Mode                 uint32               `protobuf:"varint,4,opt,name=mode,proto3" json:"mode,omitempty"`
-	Uid                  uint32               `protobuf:"varint,5,opt,name=uid,proto3" json:"uid,omitempty"`
-	Gid                  uint32               `protobuf:"varint,6,opt,name=gid,proto3" json:"gid,omitempty"`
-	Rdev                 uint64               `protobuf:"varint,7,opt,name=rdev,proto3" json:"rdev,omitempty"`
-	Size                 int64                `protobuf:"varint,8,opt,name=size,proto3" json:"size,omitempty"`
-	Blksize              int64                `protobuf:"varint,9,opt,name=blksize,proto3" json:"blksize,omitempty"`
-	Blocks               int64                `protobuf:"varint,10,opt,name=blocks,proto3" json:"blocks,omitempty"`
-	Atime                *timestamp.Timestamp `protobuf:"bytes,11,opt,name=atime,proto3" json:"atime,omitempty"`
-	Mtime                *timestamp.Timestamp `protobuf:"bytes,12,opt,name=mtime,proto3" json:"mtime,omitempty"`
-	Ctime                *timestamp.Timestamp `protobuf:"bytes,13,opt,name=ctime,proto3" json:"ctime,omitempty"`
+	Dev     uint64               `protobuf:"varint,1,opt,name=dev,proto3" json:"dev,omitempty"`
+	Inode   uint64               `protobuf:"varint,2,opt,name=inode,proto3" json:"inode,omitempty"`
+	Nlink   uint64               `protobuf:"varint,3,opt,name=nlink,proto3" json:"nlink,omitempty"`
+	Mode    uint32               `protobuf:"varint,4,opt,name=mode,proto3" json:"mode,omitempty"`
+	Uid     uint32               `protobuf:"varint,5,opt,name=uid,proto3" json:"uid,omitempty"`
+	Gid     uint32               `protobuf:"varint,6,opt,name=gid,proto3" json:"gid,omitempty"`
+	Rdev    uint64               `protobuf:"varint,7,opt,name=rdev,proto3" json:"rdev,omitempty"`
+	Size    int64                `protobuf:"varint,8,opt,name=size,proto3" json:"size,omitempty"`
+	Blksize int64                `protobuf:"varint,9,opt,name=blksize,proto3" json:"blksize,omitempty"`
+	Blocks  int64                `protobuf:"varint,10,opt,name=blocks,proto3" json:"blocks,omitempty"`
+	Atime   *timestamp.Timestamp `protobuf:"bytes,11,opt,name=atime,proto3" json:"atime,omitempty"`
+	Mtime   *timestamp.Timestamp `protobuf:"bytes,12,opt,name=mtime,proto3" json:"mtime,omitempty"`
+	Ctime   *timestamp.Timestamp `protobuf:"bytes,13,opt,name=ctime,proto3" json:"ctime,omitempty"`
+	// btime is the birth (creation) time of the file, if capture_birth_time is
+	// set in the policy and the platform supports it.
+	Btime                *timestamp.Timestamp `protobuf:"bytes,14,opt,name=btime,proto3" json:"btime,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
 	XXX_unrecognized     []byte               `json:"-"`
 	XXX_sizecache        int32                `json:"-"`
@@ -605,7 +1541,7 @@ func (m *FileStat) Reset()         { *m = FileStat{} }
 func (m *FileStat) String() string { return proto.CompactTextString(m) }
 func (*FileStat) ProtoMessage()    {}
 func (*FileStat) Descriptor() ([]byte, []int) {
-	return fileDescriptor_251aa48241d53260, []int{7}
+	return fileDescriptor_251aa48241d53260, []int{11}
 }
 
 func (m *FileStat) XXX_Unmarshal(b []byte) error {
@@ -717,6 +1653,13 @@ func (m *FileStat) GetCtime() *timestamp.Timestamp {
 	return nil
 }
 
+func (m *FileStat) GetBtime() *timestamp.Timestamp {
+	if m != nil {
+		return m.Btime
+	}
+	return nil
+}
+
 // Fingerprint is a unique identifier for a given File.
 // It consists of a Method (e.g. SHA256) and a value.
 type Fingerprint struct {
@@ -731,7 +1674,7 @@ func (m *Fingerprint) Reset()         { *m = Fingerprint{} }
 func (m *Fingerprint) String() string { return proto.CompactTextString(m) }
 func (*Fingerprint) ProtoMessage()    {}
 func (*Fingerprint) Descriptor() ([]byte, []int) {
-	return fileDescriptor_251aa48241d53260, []int{8}
+	return fileDescriptor_251aa48241d53260, []int{12}
 }
 
 func (m *Fingerprint) XXX_Unmarshal(b []byte) error {
@@ -774,17 +1717,38 @@ type File struct {
 	Info *FileInfo `protobuf:"bytes,3,opt,name=info,proto3" json:"info,omitempty"`
 	Stat *FileStat `protobuf:"bytes,4,opt,name=stat,proto3" json:"stat,omitempty"`
 	// fingerprint is optionally set when requested for the specific file.
-	Fingerprint          []*Fingerprint `protobuf:"bytes,5,rep,name=fingerprint,proto3" json:"fingerprint,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
-	XXX_unrecognized     []byte         `json:"-"`
-	XXX_sizecache        int32          `json:"-"`
+	Fingerprint []*Fingerprint `protobuf:"bytes,5,rep,name=fingerprint,proto3" json:"fingerprint,omitempty"`
+	// xattrs holds the extended attributes of the file keyed by name.
+	// It is only set when requested by the policy.
+	Xattrs map[string][]byte `protobuf:"bytes,6,rep,name=xattrs,proto3" json:"xattrs,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// symlink_target is the path a symbolic link points to. It is only set for
+	// symbolic links.
+	SymlinkTarget string `protobuf:"bytes,7,opt,name=symlink_target,json=symlinkTarget,proto3" json:"symlink_target,omitempty"`
+	// entropy is the Shannon entropy of the file content in bits per byte.
+	// It is only set when requested by the policy.
+	Entropy float64 `protobuf:"fixed64,8,opt,name=entropy,proto3" json:"entropy,omitempty"`
+	// mime_type is the MIME type detected from the file content, e.g.
+	// "text/plain; charset=utf-8". It is only set when requested by the policy.
+	MimeType string `protobuf:"bytes,9,opt,name=mime_type,json=mimeType,proto3" json:"mime_type,omitempty"`
+	// owner_name and group_name are the names of the user and group owning the
+	// file (see stat.uid and stat.gid) as resolved on the walked host. They are
+	// empty if the ID is unknown on the host.
+	OwnerName string `protobuf:"bytes,10,opt,name=owner_name,json=ownerName,proto3" json:"owner_name,omitempty"`
+	GroupName string `protobuf:"bytes,11,opt,name=group_name,json=groupName,proto3" json:"group_name,omitempty"`
+	// capabilities are the Linux file capabilities in the text form of
+	// setcap(8), e.g. "cap_net_raw=ep". It is only set when requested by the
+	// policy and the file has capabilities.
+	Capabilities         string   `protobuf:"bytes,12,opt,name=capabilities,proto3" json:"capabilities,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *File) Reset()         { *m = File{} }
 func (m *File) String() string { return proto.CompactTextString(m) }
 func (*File) ProtoMessage()    {}
 func (*File) Descriptor() ([]byte, []int) {
-	return fileDescriptor_251aa48241d53260, []int{9}
+	return fileDescriptor_251aa48241d53260, []int{13}
 }
 
 func (m *File) XXX_Unmarshal(b []byte) error {
@@ -840,87 +1804,240 @@ func (m *File) GetFingerprint() []*Fingerprint {
 	return nil
 }
 
+func (m *File) GetXattrs() map[string][]byte {
+	if m != nil {
+		return m.Xattrs
+	}
+	return nil
+}
+
+func (m *File) GetSymlinkTarget() string {
+	if m != nil {
+		return m.SymlinkTarget
+	}
+	return ""
+}
+
+func (m *File) GetEntropy() float64 {
+	if m != nil {
+		return m.Entropy
+	}
+	return 0
+}
+
+func (m *File) GetMimeType() string {
+	if m != nil {
+		return m.MimeType
+	}
+	return ""
+}
+
+func (m *File) GetOwnerName() string {
+	if m != nil {
+		return m.OwnerName
+	}
+	return ""
+}
+
+func (m *File) GetGroupName() string {
+	if m != nil {
+		return m.GroupName
+	}
+	return ""
+}
+
+func (m *File) GetCapabilities() string {
+	if m != nil {
+		return m.Capabilities
+	}
+	return ""
+}
+
 func init() {
+	proto.RegisterEnum("fswalker.ReportConfig_ChangeType", ReportConfig_ChangeType_name, ReportConfig_ChangeType_value)
+	proto.RegisterEnum("fswalker.Policy_SortOrder", Policy_SortOrder_name, Policy_SortOrder_value)
 	proto.RegisterEnum("fswalker.Notification_Severity", Notification_Severity_name, Notification_Severity_value)
+	proto.RegisterEnum("fswalker.FileDiff_DiffType", FileDiff_DiffType_name, FileDiff_DiffType_value)
 	proto.RegisterEnum("fswalker.Fingerprint_Method", Fingerprint_Method_name, Fingerprint_Method_value)
 	proto.RegisterType((*Reviews)(nil), "fswalker.Reviews")
 	proto.RegisterMapType((map[string]*Review)(nil), "fswalker.Reviews.ReviewEntry")
 	proto.RegisterType((*Review)(nil), "fswalker.Review")
 	proto.RegisterType((*ReportConfig)(nil), "fswalker.ReportConfig")
 	proto.RegisterType((*Policy)(nil), "fswalker.Policy")
+	proto.RegisterType((*PathConfig)(nil), "fswalker.PathConfig")
 	proto.RegisterType((*Walk)(nil), "fswalker.Walk")
+	proto.RegisterType((*WalkSummary)(nil), "fswalker.WalkSummary")
 	proto.RegisterType((*Notification)(nil), "fswalker.Notification")
+	proto.RegisterType((*WalkDiff)(nil), "fswalker.WalkDiff")
+	proto.RegisterType((*FileDiff)(nil), "fswalker.FileDiff")
 	proto.RegisterType((*FileInfo)(nil), "fswalker.FileInfo")
 	proto.RegisterType((*FileStat)(nil), "fswalker.FileStat")
 	proto.RegisterType((*Fingerprint)(nil), "fswalker.Fingerprint")
 	proto.RegisterType((*File)(nil), "fswalker.File")
+	proto.RegisterMapType((map[string][]byte)(nil), "fswalker.File.XattrsEntry")
 }
 
 func init() { proto.RegisterFile("proto/fswalker/fswalker.proto", fileDescriptor_251aa48241d53260) }
 
 var fileDescriptor_251aa48241d53260 = []byte{
-	// 1003 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x56, 0xdd, 0x6e, 0xdb, 0x36,
-	0x14, 0x9e, 0x64, 0x59, 0x96, 0x8f, 0x93, 0xd4, 0xe5, 0xda, 0x4c, 0x0b, 0xda, 0xc5, 0x13, 0xb0,
-	0xc2, 0xd8, 0x00, 0x67, 0xc8, 0xfa, 0xb3, 0x76, 0x57, 0x5d, 0xd3, 0xac, 0x46, 0x31, 0xa7, 0x60,
-	0x36, 0x04, 0xd8, 0x8d, 0xa1, 0x58, 0x94, 0x4d, 0x58, 0x12, 0x0d, 0x92, 0x76, 0x9d, 0x62, 0x37,
-	0x7b, 0x80, 0x5d, 0xee, 0x4d, 0x06, 0xec, 0x6a, 0x2f, 0xb0, 0xa7, 0xd9, 0x23, 0x14, 0x3c, 0x94,
-	0x1c, 0xc7, 0x08, 0x9a, 0x5e, 0xe5, 0x9c, 0xef, 0xfb, 0xce, 0x4f, 0x0e, 0x0f, 0x29, 0xc3, 0xfd,
-	0x99, 0x14, 0x5a, 0x1c, 0xa4, 0xea, 0x6d, 0x9c, 0x4d, 0x99, 0x5c, 0x19, 0x3d, 0xc4, 0x49, 0x50,
-	0xf9, 0x7b, 0xfb, 0x63, 0x21, 0xc6, 0x19, 0x3b, 0x40, 0xfc, 0x7c, 0x9e, 0x1e, 0x68, 0x9e, 0x33,
-	0xa5, 0xe3, 0x7c, 0x66, 0xa5, 0xd1, 0x9f, 0x0e, 0x34, 0x28, 0x5b, 0x70, 0xf6, 0x56, 0x91, 0x47,
-	0xe0, 0x4b, 0x34, 0x43, 0xa7, 0x53, 0xeb, 0xb6, 0x0e, 0xef, 0xf7, 0x56, 0x79, 0x4b, 0x49, 0xf9,
-	0xf7, 0x65, 0xa1, 0xe5, 0x05, 0x2d, 0xc5, 0x7b, 0xaf, 0xa1, 0xb5, 0x06, 0x93, 0x36, 0xd4, 0xa6,
-	0xec, 0x22, 0x74, 0x3a, 0x4e, 0xb7, 0x49, 0x8d, 0x49, 0x1e, 0x40, 0x7d, 0x11, 0x67, 0x73, 0x16,
-	0xba, 0x1d, 0xa7, 0xdb, 0x3a, 0x6c, 0x6f, 0xa6, 0xa5, 0x96, 0x7e, 0xe6, 0x7e, 0xef, 0x44, 0x7f,
-	0x38, 0xe0, 0x5b, 0x94, 0x7c, 0x06, 0x0d, 0x23, 0x1b, 0xf2, 0xa4, 0x4c, 0xe6, 0x1b, 0xb7, 0x9f,
-	0x90, 0xaf, 0x60, 0x07, 0x09, 0xc9, 0x52, 0x26, 0x59, 0x31, 0xb2, 0x89, 0x9b, 0x74, 0xdb, 0xa0,
-	0xb4, 0x02, 0xc9, 0x13, 0x68, 0xa5, 0xbc, 0x18, 0x33, 0x39, 0x93, 0xbc, 0xd0, 0x61, 0x0d, 0x8b,
-	0xdf, 0xbd, 0x2c, 0x7e, 0x7c, 0x49, 0xd2, 0x75, 0x65, 0xd4, 0x87, 0x2d, 0xca, 0x66, 0x42, 0xea,
-	0x17, 0xa2, 0x48, 0xf9, 0x98, 0x84, 0xd0, 0x58, 0x30, 0xa9, 0xb8, 0x28, 0xb0, 0x91, 0x6d, 0x5a,
-	0xb9, 0x64, 0x1f, 0x5a, 0x6c, 0x39, 0xca, 0xe6, 0x09, 0x1b, 0xce, 0xd2, 0x65, 0xe8, 0x76, 0x6a,
-	0xdd, 0x26, 0x85, 0x12, 0x7a, 0x93, 0x2e, 0xa3, 0x7f, 0x5c, 0xf0, 0xdf, 0x88, 0x8c, 0x8f, 0x2e,
-	0x3e, 0x90, 0x25, 0x84, 0x06, 0x2f, 0x30, 0xa4, 0xcc, 0x50, 0xb9, 0x9b, 0xf9, 0x6b, 0x9b, 0xf9,
-	0xc9, 0xe7, 0x10, 0x4c, 0x62, 0x35, 0x41, 0xd6, 0xb3, 0xb1, 0xc6, 0x37, 0xd4, 0x37, 0x40, 0xf2,
-	0x78, 0x39, 0x44, 0x3a, 0xe5, 0x19, 0x1b, 0x2a, 0xfe, 0x8e, 0x85, 0xf5, 0x8e, 0xd3, 0xad, 0xd1,
-	0x5b, 0x79, 0xbc, 0x7c, 0x15, 0xab, 0xc9, 0x31, 0xcf, 0xd8, 0x29, 0x7f, 0xc7, 0xc8, 0xd7, 0x70,
-	0x1b, 0x47, 0x3a, 0x92, 0x42, 0xa9, 0x61, 0xc2, 0x16, 0x7c, 0xc4, 0xc2, 0x2f, 0x3a, 0x4e, 0x37,
-	0xa0, 0xb7, 0x0c, 0xf1, 0xc2, 0xe0, 0x47, 0x08, 0x93, 0x87, 0xb0, 0xcb, 0xc7, 0x85, 0x90, 0x6c,
-	0xc8, 0xa5, 0x64, 0xe3, 0x79, 0x16, 0x4b, 0x2c, 0xa0, 0xc2, 0x7d, 0x0c, 0xb8, 0x63, 0xd9, 0x7e,
-	0x45, 0x9a, 0x22, 0x8a, 0xf4, 0xe0, 0x53, 0xd3, 0x4e, 0xc2, 0x25, 0x1b, 0x69, 0x21, 0x2f, 0x86,
-	0x09, 0x9b, 0xe9, 0x49, 0xd8, 0xc1, 0x51, 0xdc, 0xce, 0xe3, 0xe5, 0x51, 0xc5, 0x1c, 0x19, 0x22,
-	0xfa, 0xcf, 0x05, 0xef, 0x2c, 0xce, 0xa6, 0x64, 0x07, 0xdc, 0xd5, 0x06, 0xb8, 0x3c, 0x59, 0x9f,
-	0xa3, 0x7b, 0x75, 0x8e, 0x5d, 0xf0, 0x67, 0x38, 0xeb, 0xf2, 0xac, 0xd7, 0x16, 0xcd, 0x9e, 0x01,
-	0x2d, 0x79, 0x12, 0x81, 0x67, 0x3a, 0xc6, 0x91, 0xb5, 0x0e, 0x77, 0xd6, 0x77, 0x22, 0x63, 0x14,
-	0x39, 0xf2, 0x0c, 0xb6, 0x0a, 0xa1, 0x79, 0xca, 0x47, 0xb1, 0x36, 0xc5, 0xea, 0xa8, 0xdd, 0xbd,
-	0xd4, 0x0e, 0xd6, 0x58, 0x7a, 0x45, 0x4b, 0xf6, 0x20, 0x98, 0x08, 0xa5, 0x8b, 0x38, 0x67, 0x21,
-	0x60, 0xe7, 0x2b, 0x9f, 0x3c, 0x05, 0x50, 0x3a, 0x96, 0x7a, 0x68, 0xd2, 0x84, 0x2d, 0xec, 0x74,
-	0xaf, 0x67, 0xef, 0x69, 0xaf, 0xba, 0xa7, 0xbd, 0x5f, 0xaa, 0x7b, 0x4a, 0x9b, 0xa8, 0xc6, 0x51,
-	0x3c, 0x81, 0xa6, 0xd2, 0x62, 0x66, 0x23, 0xb7, 0x6e, 0x8c, 0x0c, 0x8c, 0xd8, 0x04, 0x46, 0x7f,
-	0x3b, 0xb0, 0xb5, 0xde, 0x2e, 0xf9, 0x01, 0x02, 0xc5, 0x16, 0x4c, 0x72, 0x6d, 0x6f, 0xea, 0xce,
-	0xe1, 0xfe, 0xf5, 0xff, 0x58, 0xef, 0xb4, 0x94, 0xd1, 0x55, 0x00, 0x21, 0xe0, 0xcd, 0x62, 0x3d,
-	0x29, 0x6f, 0x1d, 0xda, 0xe6, 0x54, 0x72, 0xa6, 0x54, 0x3c, 0x66, 0x38, 0xfc, 0x26, 0xad, 0xdc,
-	0xe8, 0x29, 0x04, 0x55, 0x0e, 0xd2, 0x82, 0xc6, 0xaf, 0x83, 0xd7, 0x83, 0x93, 0xb3, 0x41, 0xfb,
-	0x13, 0x12, 0x80, 0xd7, 0x1f, 0x1c, 0x9f, 0xb4, 0x1d, 0x03, 0x9f, 0x3d, 0xa7, 0x83, 0xfe, 0xe0,
-	0xa7, 0xb6, 0x4b, 0x9a, 0x50, 0x7f, 0x49, 0xe9, 0x09, 0x6d, 0xd7, 0xa2, 0xbf, 0x1c, 0x08, 0xcc,
-	0x89, 0xf4, 0x8b, 0x54, 0x98, 0xaa, 0x38, 0x4f, 0xbb, 0x09, 0x68, 0x1b, 0x0c, 0xb7, 0xda, 0xc5,
-	0xad, 0x46, 0xdb, 0x60, 0xb9, 0x48, 0x6c, 0x1b, 0xdb, 0x14, 0x6d, 0xf2, 0x18, 0x82, 0x5c, 0x24,
-	0x3c, 0xe5, 0x2c, 0x09, 0xbd, 0x9b, 0xe7, 0x56, 0x69, 0xc9, 0x5d, 0xf0, 0xb9, 0x32, 0x3b, 0x8b,
-	0xf7, 0x26, 0xa0, 0x75, 0xae, 0x8e, 0xb8, 0x8c, 0xfe, 0x77, 0x6d, 0x5f, 0xa7, 0x3a, 0xd6, 0xe6,
-	0xbd, 0x4b, 0xd8, 0x02, 0xdb, 0xf2, 0xa8, 0x31, 0xc9, 0x1d, 0xa8, 0xf3, 0x42, 0x24, 0xb6, 0x2d,
-	0x8f, 0x5a, 0xc7, 0xa0, 0x45, 0xc6, 0x8b, 0x29, 0x36, 0xe6, 0x51, 0xeb, 0xac, 0xba, 0xf5, 0xd6,
-	0xba, 0x6d, 0x43, 0x6d, 0xce, 0x13, 0x2c, 0xb9, 0x4d, 0x8d, 0x69, 0x90, 0x31, 0x4f, 0x42, 0xdf,
-	0x22, 0x63, 0x9e, 0x98, 0x38, 0x69, 0xca, 0x36, 0x30, 0x19, 0xda, 0xab, 0x69, 0x04, 0x6b, 0xd3,
-	0x08, 0xa1, 0x71, 0x9e, 0x4d, 0x11, 0x6e, 0x22, 0x5c, 0xb9, 0x64, 0x17, 0xfc, 0xf3, 0x4c, 0x8c,
-	0xa6, 0x0a, 0x37, 0xb4, 0x46, 0x4b, 0x8f, 0x7c, 0x0b, 0xf5, 0xd8, 0x7c, 0x25, 0x3e, 0x62, 0x35,
-	0xad, 0xd0, 0x44, 0xe4, 0x18, 0x71, 0xf3, 0x4a, 0x5a, 0xa1, 0x89, 0x18, 0x61, 0xc4, 0xf6, 0xcd,
-	0x11, 0x28, 0x8c, 0x7e, 0x87, 0xd6, 0xda, 0x7b, 0x4d, 0x1e, 0x82, 0x9f, 0x33, 0x3d, 0x11, 0x49,
-	0xb9, 0xbd, 0xf7, 0xae, 0x7d, 0xd6, 0x7b, 0x3f, 0xa3, 0x86, 0x96, 0x5a, 0x73, 0x04, 0x97, 0x1f,
-	0xa2, 0x66, 0xf9, 0xd9, 0x89, 0xbe, 0x04, 0xdf, 0xea, 0xae, 0xae, 0x27, 0x80, 0x7f, 0xfa, 0xea,
-	0xf9, 0xe1, 0xa3, 0xc7, 0x6d, 0x27, 0xfa, 0xd7, 0x01, 0xcf, 0x1c, 0xf8, 0x07, 0x1e, 0xf1, 0xeb,
-	0x2e, 0xc5, 0x03, 0xf0, 0x78, 0x91, 0x8a, 0xf2, 0x39, 0x22, 0x57, 0x9f, 0x19, 0xb3, 0xd4, 0x14,
-	0x79, 0xa3, 0x53, 0x3a, 0xd6, 0xe5, 0x6a, 0x6e, 0xe8, 0xcc, 0x92, 0x51, 0xe4, 0x37, 0xbf, 0x68,
-	0xf6, 0x45, 0xfa, 0x88, 0x2f, 0xda, 0x8f, 0xf7, 0x7e, 0xdb, 0x1b, 0x73, 0x3d, 0x99, 0x9f, 0xf7,
-	0x46, 0x22, 0x3f, 0x28, 0x7f, 0x13, 0x54, 0x61, 0xe7, 0x3e, 0x8e, 0xfd, 0xbb, 0xf7, 0x01, 0x00,
-	0x00, 0xff, 0xff, 0xe2, 0x39, 0xd8, 0xb5, 0x56, 0x08, 0x00, 0x00,
+	// 2534 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x58, 0xdb, 0x72, 0x1b, 0xc7,
+	0xd1, 0x36, 0xce, 0x40, 0xe3, 0xc0, 0xd5, 0x88, 0xd2, 0x3f, 0xa6, 0x2d, 0x8b, 0x86, 0x6d, 0x99,
+	0x3e, 0xfc, 0xa0, 0x44, 0x59, 0x96, 0xe5, 0x94, 0x53, 0x05, 0x11, 0x4b, 0x89, 0x65, 0x09, 0x60,
+	0x0d, 0xe1, 0x92, 0x93, 0x9b, 0xad, 0x25, 0x76, 0x00, 0x4c, 0x71, 0x0f, 0xa8, 0xdd, 0x21, 0x45,
+	0xfa, 0x2e, 0x55, 0xb9, 0xcd, 0x5d, 0xfc, 0x02, 0x79, 0x83, 0x54, 0xe5, 0x4d, 0x72, 0x95, 0x77,
+	0x48, 0xe5, 0x15, 0x92, 0xea, 0x9e, 0x5d, 0x60, 0x41, 0xcb, 0xa2, 0x6f, 0x80, 0x99, 0xaf, 0xbf,
+	0x9e, 0xed, 0x9d, 0xe9, 0xee, 0xe9, 0x5e, 0xb8, 0xb3, 0x88, 0x23, 0x1d, 0xed, 0x4e, 0x93, 0xd7,
+	0xae, 0x7f, 0x2a, 0xe3, 0xe5, 0xa0, 0x47, 0x38, 0xab, 0x67, 0xf3, 0xad, 0x0f, 0x66, 0x51, 0x34,
+	0xf3, 0xe5, 0x2e, 0xe1, 0x27, 0x67, 0xd3, 0x5d, 0xef, 0x2c, 0x76, 0xb5, 0x8a, 0x42, 0xc3, 0xdc,
+	0xba, 0x7b, 0x55, 0xae, 0x55, 0x20, 0x13, 0xed, 0x06, 0x0b, 0x43, 0xe8, 0xfe, 0xa5, 0x00, 0x35,
+	0x21, 0xcf, 0x95, 0x7c, 0x9d, 0xb0, 0x47, 0x50, 0x8d, 0x69, 0xc8, 0x0b, 0xdb, 0xa5, 0x9d, 0xe6,
+	0xde, 0x9d, 0xde, 0xf2, 0xb9, 0x29, 0x25, 0xfd, 0xb7, 0x43, 0x1d, 0x5f, 0x8a, 0x94, 0xbc, 0xf5,
+	0x3d, 0x34, 0x73, 0x30, 0xb3, 0xa0, 0x74, 0x2a, 0x2f, 0x79, 0x61, 0xbb, 0xb0, 0xd3, 0x10, 0x38,
+	0x64, 0xf7, 0xa0, 0x72, 0xee, 0xfa, 0x67, 0x92, 0x17, 0xb7, 0x0b, 0x3b, 0xcd, 0x3d, 0xeb, 0xea,
+	0xb2, 0xc2, 0x88, 0xbf, 0x2d, 0x7e, 0x53, 0xe8, 0xfe, 0xa9, 0x00, 0x55, 0x83, 0xb2, 0xff, 0x83,
+	0x1a, 0xd2, 0x1c, 0xe5, 0xa5, 0x8b, 0x55, 0x71, 0x7a, 0xe8, 0xb1, 0x4f, 0xa0, 0x43, 0x82, 0x58,
+	0x4e, 0x65, 0x2c, 0xc3, 0x89, 0x59, 0xb8, 0x21, 0xda, 0x88, 0x8a, 0x0c, 0x64, 0x8f, 0xa1, 0x39,
+	0x55, 0xe1, 0x4c, 0xc6, 0x8b, 0x58, 0x85, 0x9a, 0x97, 0xe8, 0xe1, 0xb7, 0x56, 0x0f, 0x3f, 0x58,
+	0x09, 0x45, 0x9e, 0xd9, 0xfd, 0x4f, 0x19, 0x5a, 0x42, 0x2e, 0xa2, 0x58, 0xef, 0x47, 0xe1, 0x54,
+	0xcd, 0x18, 0x87, 0xda, 0xb9, 0x8c, 0x13, 0x15, 0x85, 0x64, 0x49, 0x5b, 0x64, 0x53, 0x76, 0x17,
+	0x9a, 0xf2, 0x62, 0xe2, 0x9f, 0x79, 0xd2, 0x59, 0x4c, 0x2f, 0x78, 0x71, 0xbb, 0xb4, 0xd3, 0x10,
+	0x90, 0x42, 0x47, 0xd3, 0x0b, 0xf6, 0x18, 0xf8, 0xd4, 0x55, 0xbe, 0x13, 0x85, 0xce, 0x22, 0x56,
+	0xe7, 0xca, 0x97, 0x33, 0xe9, 0x4c, 0xe6, 0x6e, 0x38, 0x93, 0x64, 0x51, 0x5d, 0xdc, 0x42, 0xf9,
+	0x28, 0x3c, 0xca, 0xa4, 0xfb, 0x24, 0x64, 0x1f, 0x43, 0x27, 0x50, 0xa1, 0x33, 0x55, 0xbe, 0x74,
+	0xe8, 0x48, 0x79, 0x79, 0xbb, 0xb0, 0x53, 0x10, 0xad, 0x40, 0x85, 0x07, 0xca, 0x97, 0x02, 0x31,
+	0xf6, 0x05, 0xdc, 0x90, 0xa1, 0x8e, 0xa3, 0xc5, 0xa5, 0xa3, 0xe7, 0xb1, 0x4c, 0xe6, 0x91, 0xef,
+	0xf1, 0x0a, 0x11, 0xad, 0x54, 0x30, 0xce, 0x70, 0xf6, 0x19, 0x58, 0xc9, 0x59, 0x10, 0xb8, 0xf1,
+	0xa5, 0xa3, 0x65, 0xb0, 0xf0, 0x5d, 0x2d, 0x79, 0x95, 0x76, 0x6e, 0x23, 0xc5, 0xc7, 0x29, 0xcc,
+	0x46, 0xc0, 0x8c, 0x91, 0x8e, 0xbe, 0x5c, 0x48, 0xb4, 0x42, 0xcb, 0x98, 0xd7, 0xb6, 0x4b, 0x3b,
+	0x9d, 0xbd, 0x0f, 0xf3, 0xe7, 0xb7, 0xda, 0xa5, 0x9e, 0x31, 0x7c, 0x7c, 0xb9, 0x90, 0xc2, 0x9a,
+	0x2c, 0xc7, 0x07, 0xa4, 0xca, 0x1e, 0xc2, 0xed, 0xd3, 0x30, 0x7a, 0x1d, 0x3a, 0xb3, 0x28, 0xf2,
+	0x9c, 0xb9, 0x9b, 0xcc, 0x65, 0x42, 0x2f, 0xc7, 0xeb, 0x64, 0xc1, 0x4d, 0x92, 0x3e, 0x8b, 0x22,
+	0xef, 0x39, 0xc9, 0xf0, 0x15, 0xd9, 0xa7, 0xb0, 0x31, 0x8b, 0x7c, 0x4f, 0x86, 0xce, 0x3c, 0x4a,
+	0x74, 0xe8, 0x06, 0x92, 0x37, 0x88, 0xdd, 0x31, 0xf0, 0xf3, 0x14, 0x45, 0x8f, 0x30, 0xce, 0xe8,
+	0x9c, 0xb8, 0x93, 0x53, 0x19, 0x7a, 0x1c, 0x8c, 0x47, 0x18, 0xf4, 0xa9, 0x01, 0xbb, 0x3f, 0x17,
+	0x00, 0x56, 0x56, 0xb2, 0x0d, 0x68, 0xfe, 0x30, 0x3c, 0x3e, 0xb2, 0xf7, 0x0f, 0x0f, 0x0e, 0xed,
+	0x81, 0xf5, 0x0e, 0xeb, 0x00, 0x1c, 0x1c, 0xbe, 0xb0, 0x9d, 0xfe, 0x60, 0x60, 0x0f, 0xac, 0x02,
+	0xb3, 0xa0, 0x45, 0xf3, 0x81, 0xfd, 0xc2, 0x1e, 0xdb, 0x03, 0xab, 0xc8, 0x6e, 0xc2, 0xc6, 0xfe,
+	0x68, 0x38, 0xb6, 0x87, 0x63, 0x67, 0xff, 0x79, 0x7f, 0xf8, 0xcc, 0x1e, 0x58, 0x25, 0x76, 0x1b,
+	0xd8, 0x91, 0x2d, 0x5e, 0x1e, 0x1e, 0x1f, 0x1f, 0x8e, 0x86, 0x4b, 0xbc, 0xcc, 0x6e, 0x40, 0x7b,
+	0xf4, 0x6a, 0x68, 0x8b, 0x25, 0x54, 0x61, 0x9b, 0x60, 0xbd, 0xb4, 0xc7, 0xfd, 0x41, 0x7f, 0xdc,
+	0x5f, 0xa2, 0xd5, 0xee, 0x9f, 0x9b, 0x50, 0x3d, 0x8a, 0x7c, 0x35, 0xb9, 0x7c, 0x8b, 0xab, 0x71,
+	0xa8, 0xa9, 0x90, 0xfc, 0x2a, 0x75, 0xb3, 0x6c, 0xca, 0x1e, 0x43, 0x2b, 0x1d, 0x3a, 0x0b, 0x57,
+	0xcf, 0x79, 0x8f, 0xa2, 0x77, 0x73, 0x75, 0x4c, 0x47, 0xae, 0x9e, 0x9b, 0x43, 0x12, 0xcd, 0x94,
+	0x89, 0xd0, 0x55, 0xef, 0x2d, 0xfd, 0xc2, 0x7b, 0x3f, 0x82, 0xf6, 0x92, 0xe0, 0xea, 0x79, 0xc2,
+	0xef, 0x11, 0xa5, 0x95, 0x51, 0x10, 0xcb, 0x93, 0x62, 0x39, 0x93, 0x17, 0x7c, 0x67, 0x8d, 0x24,
+	0x10, 0x63, 0xef, 0x42, 0x1d, 0x0f, 0x9d, 0x9e, 0x53, 0x36, 0xe6, 0xe3, 0x1c, 0x1f, 0xf2, 0x05,
+	0xb0, 0xc0, 0xbd, 0x20, 0x9f, 0x30, 0xee, 0x9e, 0xa8, 0x9f, 0x24, 0x39, 0x71, 0x49, 0x6c, 0x04,
+	0xee, 0x05, 0x3a, 0x04, 0xba, 0xc3, 0xb1, 0xfa, 0x49, 0xb2, 0x7d, 0xe8, 0x10, 0xd1, 0xf5, 0x67,
+	0x51, 0xac, 0xf4, 0x3c, 0x20, 0x0f, 0xee, 0xec, 0xbd, 0xff, 0xc6, 0xb8, 0xee, 0xbd, 0x94, 0x7a,
+	0x1e, 0x79, 0xa2, 0x8d, 0x3a, 0xfd, 0x4c, 0x85, 0x7d, 0x0e, 0x37, 0x28, 0x81, 0x4c, 0xe2, 0x28,
+	0x49, 0x1c, 0x4f, 0x9e, 0xab, 0x89, 0xe4, 0x1f, 0x50, 0x34, 0x6e, 0xa0, 0x60, 0x1f, 0xf1, 0x01,
+	0xc1, 0xec, 0x2b, 0xb8, 0xad, 0x66, 0x61, 0x14, 0x4b, 0x47, 0xc5, 0xb1, 0x9c, 0x9d, 0xf9, 0x6e,
+	0x4c, 0x56, 0x26, 0xfc, 0x2e, 0x29, 0x6c, 0x1a, 0xe9, 0x61, 0x26, 0x44, 0x4b, 0x13, 0xd6, 0x83,
+	0x9b, 0xf8, 0x4e, 0x9e, 0x8a, 0xe5, 0x44, 0x47, 0xf1, 0xa5, 0xe3, 0xc9, 0x85, 0x9e, 0xf3, 0x6d,
+	0x3a, 0xd2, 0x1b, 0x81, 0x7b, 0x31, 0xc8, 0x24, 0x03, 0x14, 0xb0, 0x6d, 0x68, 0x2e, 0xdc, 0xd8,
+	0xf5, 0x7d, 0xe9, 0xab, 0x24, 0xe0, 0x1f, 0x12, 0x2f, 0x0f, 0xa1, 0x8b, 0x4f, 0xdc, 0x85, 0x3e,
+	0x8b, 0xa5, 0x73, 0xe1, 0x6a, 0x1d, 0x27, 0xbc, 0x4b, 0xcf, 0x6f, 0xa7, 0xe8, 0x8f, 0x04, 0xb2,
+	0x3b, 0x00, 0xf8, 0x60, 0x19, 0xc7, 0x51, 0x9c, 0xf0, 0x8f, 0x68, 0x9d, 0x46, 0xe0, 0x5e, 0xd8,
+	0x04, 0xa0, 0xd8, 0x93, 0xbe, 0x76, 0x1d, 0x7c, 0x4d, 0xfe, 0x31, 0xad, 0xd0, 0x20, 0xe4, 0x95,
+	0xeb, 0x9f, 0x62, 0xc0, 0x4d, 0xa2, 0x60, 0x71, 0xa6, 0xa5, 0x93, 0x66, 0x0f, 0xfe, 0x09, 0x71,
+	0x3a, 0x29, 0x6c, 0x1b, 0x94, 0xed, 0x80, 0xe5, 0x49, 0x2d, 0x27, 0xda, 0x09, 0x54, 0x60, 0x92,
+	0x04, 0xff, 0xd4, 0x30, 0x0d, 0xfe, 0x52, 0x05, 0x26, 0xc8, 0xbe, 0x83, 0x36, 0xe6, 0xb1, 0x00,
+	0x2f, 0x1e, 0xc7, 0x9d, 0x49, 0xfe, 0x19, 0xe5, 0xe1, 0x77, 0x7b, 0xe6, 0x66, 0xea, 0x65, 0x37,
+	0x53, 0x6f, 0x90, 0xde, 0x5c, 0xa2, 0x19, 0xa8, 0xf0, 0x25, 0xd2, 0xfb, 0x33, 0xa3, 0xee, 0x5e,
+	0xe4, 0xd4, 0x3f, 0xbf, 0x5e, 0xdd, 0xbd, 0x58, 0xaa, 0x7f, 0x06, 0x56, 0x76, 0x06, 0x4a, 0x26,
+	0x4e, 0x14, 0xfa, 0x97, 0xfc, 0x0b, 0x73, 0xd0, 0x39, 0x7c, 0x14, 0xfa, 0x97, 0xec, 0x09, 0x40,
+	0x12, 0xc5, 0xda, 0x89, 0x62, 0x4f, 0xc6, 0xfc, 0x4b, 0xf2, 0xaa, 0xad, 0x5c, 0x0c, 0x51, 0x7c,
+	0xf6, 0x8e, 0xa3, 0x58, 0x8f, 0x90, 0x21, 0x1a, 0x49, 0x36, 0xc4, 0x38, 0x4a, 0xdc, 0x60, 0x61,
+	0x32, 0xb5, 0xe4, 0xff, 0x4f, 0xf9, 0x17, 0x0c, 0x24, 0x30, 0x9d, 0x7e, 0x09, 0x2c, 0x8c, 0x8c,
+	0x87, 0xcb, 0x0b, 0x2d, 0x43, 0x0c, 0xe8, 0x84, 0xef, 0x52, 0x1c, 0x58, 0x61, 0x84, 0x1e, 0x6e,
+	0x2f, 0x71, 0x76, 0x1f, 0x36, 0x89, 0x8a, 0xd6, 0xe6, 0xf9, 0xf7, 0x89, 0xcf, 0x50, 0x86, 0x16,
+	0xe7, 0x34, 0xbe, 0x04, 0x96, 0x39, 0xc7, 0x89, 0x8a, 0xf5, 0xdc, 0xc1, 0xf7, 0xe7, 0x0f, 0xe8,
+	0x45, 0xad, 0x54, 0xf2, 0x14, 0x05, 0x63, 0x15, 0x90, 0x35, 0xb8, 0xa7, 0xe9, 0x1d, 0xaa, 0x65,
+	0x88, 0xfb, 0xc6, 0xf7, 0xb6, 0x0b, 0x3b, 0x15, 0x61, 0x05, 0xee, 0xc5, 0x2b, 0xba, 0x46, 0x53,
+	0x9c, 0x3d, 0x80, 0xcd, 0x6c, 0xed, 0x89, 0xbb, 0x70, 0x4f, 0x94, 0xaf, 0xb4, 0x92, 0x09, 0x7f,
+	0x48, 0xab, 0xdf, 0x4c, 0x65, 0xfb, 0x39, 0x11, 0xba, 0xd1, 0x34, 0xf2, 0xfd, 0xe8, 0xb5, 0x93,
+	0x5c, 0x06, 0xbe, 0x0a, 0x4f, 0x13, 0xfe, 0x95, 0x71, 0x0e, 0x03, 0x1f, 0xa7, 0x28, 0x1e, 0x8f,
+	0x0a, 0x93, 0x05, 0xfa, 0x91, 0x1b, 0x4f, 0xe6, 0xea, 0x5c, 0x26, 0xfc, 0x91, 0x39, 0x9e, 0x14,
+	0xef, 0xa7, 0x30, 0xae, 0x99, 0x52, 0x9c, 0x69, 0x14, 0x07, 0xae, 0x4e, 0xf8, 0xd7, 0xb4, 0x1f,
+	0x9d, 0x14, 0x3e, 0x30, 0x68, 0xf7, 0x1b, 0x68, 0x2c, 0x0f, 0x89, 0xd5, 0xa1, 0x3c, 0x1c, 0x0d,
+	0x6d, 0xeb, 0x1d, 0xcc, 0xe5, 0x47, 0xfd, 0xf1, 0x73, 0xe7, 0x85, 0xfd, 0xe3, 0xe1, 0x7e, 0xff,
+	0x85, 0x55, 0xc0, 0xf4, 0x7f, 0x38, 0x1c, 0x0d, 0x6c, 0x67, 0x24, 0x06, 0xb6, 0xb0, 0x8a, 0xdd,
+	0xef, 0x00, 0x56, 0x99, 0x92, 0x31, 0x28, 0x53, 0x36, 0x35, 0xb5, 0x07, 0x8d, 0xd9, 0x7b, 0xd0,
+	0xa0, 0xb0, 0xa6, 0x60, 0x2e, 0x52, 0x70, 0xd5, 0x31, 0x98, 0x71, 0xde, 0xfd, 0x6f, 0x09, 0xca,
+	0x14, 0x45, 0x1d, 0x28, 0x2e, 0x6b, 0x96, 0xa2, 0xf2, 0xf2, 0x39, 0xbd, 0xb8, 0x9e, 0xd3, 0x77,
+	0xa0, 0xba, 0x20, 0xbf, 0xe2, 0xa5, 0xab, 0xa5, 0x91, 0xf1, 0x37, 0x91, 0xca, 0x59, 0x17, 0xca,
+	0x74, 0x5b, 0x96, 0x29, 0xb7, 0x77, 0xf2, 0xd9, 0xce, 0x97, 0x82, 0x64, 0xec, 0x5b, 0x68, 0x85,
+	0x91, 0x56, 0x53, 0x35, 0xa1, 0x48, 0xe0, 0x15, 0xe2, 0xde, 0x5e, 0x71, 0x87, 0x39, 0xa9, 0x58,
+	0xe3, 0xb2, 0x2d, 0xa8, 0x2f, 0xef, 0x58, 0x73, 0x77, 0x2e, 0xe7, 0x14, 0x19, 0xda, 0x8d, 0xb5,
+	0x49, 0x1a, 0x4d, 0xb2, 0x74, 0xeb, 0x17, 0x01, 0x38, 0xce, 0x2a, 0x4b, 0xd1, 0x20, 0x36, 0x6d,
+	0xc5, 0x63, 0x68, 0x24, 0x3a, 0x5a, 0x18, 0xcd, 0xd6, 0xb5, 0x9a, 0x75, 0x24, 0x93, 0xe2, 0x7b,
+	0xd0, 0x38, 0x71, 0x13, 0x69, 0x14, 0xdb, 0xc6, 0x20, 0x04, 0x48, 0xc8, 0xa1, 0xe6, 0x49, 0x5f,
+	0x6a, 0xe9, 0xf1, 0x8e, 0xb9, 0x4b, 0xd2, 0x29, 0x7b, 0x00, 0xf5, 0xc9, 0x5c, 0x4e, 0x4e, 0x93,
+	0xb3, 0x80, 0x6f, 0xbc, 0xad, 0xe0, 0x5b, 0xd2, 0x70, 0xb1, 0x85, 0x1b, 0x6b, 0xe5, 0xfa, 0xdc,
+	0x22, 0xd7, 0xcb, 0xa6, 0x6c, 0x17, 0x6a, 0x69, 0x5d, 0xc4, 0x6f, 0x5c, 0x5d, 0x0b, 0xed, 0x38,
+	0x36, 0x42, 0x91, 0xb1, 0xba, 0xff, 0x2c, 0x42, 0x33, 0x27, 0xc0, 0x6c, 0x4b, 0x17, 0xda, 0x24,
+	0x3a, 0x0b, 0x35, 0x39, 0x44, 0x49, 0x34, 0x10, 0xd9, 0x47, 0x00, 0xdf, 0xd1, 0x53, 0x71, 0x2a,
+	0x2d, 0x92, 0xb4, 0xee, 0xa9, 0xd8, 0x08, 0x77, 0xc0, 0xd2, 0x91, 0x76, 0x7d, 0xba, 0x0d, 0x9d,
+	0x93, 0x4b, 0x2d, 0x13, 0x72, 0x92, 0x92, 0xe8, 0x10, 0x8e, 0xb7, 0xe1, 0x53, 0x44, 0xf1, 0x29,
+	0x94, 0x2e, 0xcc, 0x3a, 0x65, 0xf3, 0x14, 0x44, 0xcc, 0x42, 0x4f, 0x81, 0xee, 0x34, 0xc7, 0x1c,
+	0x21, 0x25, 0x86, 0xca, 0xb5, 0x07, 0x41, 0xa5, 0xf4, 0x31, 0x6a, 0x20, 0xc6, 0x7e, 0x0f, 0x04,
+	0x38, 0x32, 0xf4, 0xcc, 0x0a, 0xd5, 0x6b, 0x57, 0x68, 0xa2, 0x82, 0x1d, 0x7a, 0xa4, 0x9f, 0xf7,
+	0xae, 0xda, 0x15, 0xef, 0xba, 0x0b, 0x4d, 0xe3, 0xe3, 0x0e, 0x89, 0x4d, 0x39, 0x08, 0x06, 0x1a,
+	0xba, 0x81, 0xec, 0xfe, 0xa3, 0x00, 0xad, 0xbc, 0xe7, 0xb2, 0xdf, 0x41, 0x3d, 0x91, 0xe7, 0x32,
+	0x56, 0xda, 0xb4, 0x19, 0x9d, 0xbd, 0xbb, 0x6f, 0xf6, 0xf1, 0xde, 0x71, 0x4a, 0x13, 0x4b, 0x85,
+	0x65, 0x58, 0x17, 0x73, 0x61, 0xcd, 0xa1, 0x16, 0xc8, 0x24, 0x71, 0xd3, 0x9a, 0xbc, 0x21, 0xb2,
+	0x69, 0xf7, 0x09, 0xd4, 0xb3, 0x35, 0x58, 0x13, 0x6a, 0x3f, 0x0c, 0xbf, 0x1f, 0x8e, 0x5e, 0x0d,
+	0xad, 0x77, 0x30, 0xb1, 0x1c, 0x0e, 0x0f, 0x46, 0x56, 0x01, 0xe1, 0x57, 0x7d, 0x31, 0x3c, 0x1c,
+	0x3e, 0xb3, 0x8a, 0xac, 0x01, 0x15, 0x5b, 0x88, 0x91, 0xb0, 0x4a, 0xdd, 0xbf, 0x97, 0xa0, 0x8e,
+	0xce, 0x30, 0x50, 0xd3, 0xe9, 0xda, 0x06, 0x14, 0xae, 0x6c, 0xc0, 0xc7, 0xd0, 0x39, 0x91, 0x53,
+	0xac, 0x30, 0xb2, 0x76, 0xc7, 0xd8, 0xd6, 0x32, 0xe8, 0x2b, 0xd3, 0xf4, 0xec, 0xc1, 0xad, 0x3c,
+	0x6b, 0xd5, 0xfb, 0x18, 0x8b, 0x6f, 0xae, 0xc8, 0xab, 0x0e, 0xa8, 0x0b, 0x6d, 0x77, 0xaa, 0x65,
+	0xbc, 0x5c, 0xb8, 0x4c, 0xdc, 0x26, 0x81, 0xe9, 0xba, 0xf7, 0x61, 0x33, 0xc7, 0x59, 0x2d, 0x5b,
+	0x21, 0x2a, 0x5b, 0x52, 0x57, 0xab, 0xee, 0x02, 0xf9, 0xb0, 0xe3, 0xa9, 0xe9, 0x94, 0x57, 0x29,
+	0xc7, 0xb0, 0xf5, 0x7c, 0x84, 0xaf, 0x2c, 0xea, 0xd3, 0x74, 0x84, 0xdb, 0xfb, 0xda, 0x8d, 0x43,
+	0x15, 0xce, 0xa8, 0x83, 0x68, 0x88, 0x6c, 0xca, 0x9e, 0x41, 0x6a, 0xb7, 0xb3, 0x96, 0xb8, 0xea,
+	0x6f, 0x4d, 0x5c, 0xcc, 0xa8, 0xe4, 0x31, 0x66, 0x83, 0xb1, 0x74, 0x7d, 0x9d, 0xc6, 0x5b, 0xd7,
+	0xb9, 0x41, 0x1a, 0x79, 0xa8, 0xfb, 0xaf, 0x32, 0xd4, 0xb3, 0x17, 0x60, 0xdf, 0x60, 0x78, 0x4e,
+	0xa7, 0xa6, 0xb8, 0x31, 0x7e, 0xf6, 0xde, 0x2f, 0xdf, 0xb3, 0x87, 0x3f, 0xd4, 0xf4, 0xd4, 0xbd,
+	0x74, 0xf4, 0x46, 0x1f, 0xfb, 0x1c, 0xaa, 0xc6, 0xee, 0x34, 0xd5, 0x5f, 0xd9, 0xb2, 0xc3, 0x70,
+	0x1a, 0x89, 0x94, 0xc1, 0x76, 0xa0, 0x42, 0xb6, 0xf1, 0xf2, 0xaf, 0x52, 0x0d, 0x01, 0x6b, 0x6f,
+	0xd3, 0x6a, 0x79, 0xce, 0x54, 0x49, 0xea, 0xfd, 0xa8, 0xf6, 0x4e, 0xc1, 0x03, 0xc4, 0xd0, 0x9c,
+	0xe5, 0x59, 0x35, 0x04, 0x8d, 0xd9, 0x26, 0x54, 0xa8, 0x46, 0x4c, 0xc3, 0xd1, 0x4c, 0x72, 0x4e,
+	0x96, 0x5e, 0xdc, 0x8e, 0x76, 0xe3, 0x99, 0xd4, 0x59, 0x93, 0x66, 0x84, 0xe9, 0xf5, 0x3d, 0x26,
+	0xd1, 0xca, 0x81, 0xae, 0xa8, 0x34, 0x72, 0x0e, 0xb4, 0xae, 0xc1, 0xa1, 0x96, 0x55, 0x97, 0x40,
+	0xa5, 0x52, 0x36, 0x65, 0x1f, 0x42, 0x6b, 0xae, 0x66, 0xf3, 0x65, 0xf1, 0xd9, 0xa4, 0x84, 0xdc,
+	0x44, 0x2c, 0x57, 0x79, 0xa6, 0x26, 0xae, 0x2a, 0xcf, 0x96, 0x69, 0x0a, 0x0d, 0xbe, 0xac, 0x3c,
+	0xef, 0xc1, 0x86, 0x31, 0x6c, 0x45, 0x34, 0x17, 0x89, 0x09, 0x8a, 0x8c, 0xd7, 0x95, 0x50, 0xcf,
+	0xce, 0x70, 0x3d, 0xc6, 0x1b, 0x50, 0xc9, 0x3a, 0xc1, 0x26, 0xd4, 0x56, 0x4d, 0x60, 0x0b, 0xea,
+	0x2f, 0x47, 0x03, 0xd3, 0x34, 0x96, 0xb0, 0x69, 0x14, 0xf6, 0xb8, 0x2f, 0x9e, 0x91, 0xb4, 0xbc,
+	0x4a, 0x01, 0x15, 0xd4, 0x12, 0xf6, 0xf8, 0x0f, 0x47, 0xd4, 0xe4, 0xfd, 0x5c, 0x80, 0x7a, 0x76,
+	0x7c, 0x78, 0x24, 0xb9, 0x5c, 0x40, 0x63, 0xc4, 0xa8, 0xf3, 0x31, 0x37, 0x01, 0x8d, 0x11, 0x0b,
+	0x22, 0xcf, 0xf8, 0x4c, 0x5b, 0xd0, 0x98, 0x7d, 0x0d, 0xf5, 0x20, 0xf2, 0xd4, 0x54, 0x49, 0x8f,
+	0x97, 0xaf, 0xcd, 0xc3, 0x4b, 0x2e, 0xbb, 0x05, 0x55, 0x95, 0x60, 0x4b, 0x42, 0xb1, 0x5d, 0x17,
+	0x15, 0x95, 0x0c, 0x54, 0xdc, 0xfd, 0x5b, 0xc9, 0xd8, 0x75, 0xac, 0x5d, 0x8d, 0x1f, 0x6f, 0x3c,
+	0x79, 0x4e, 0x66, 0x95, 0x05, 0x0e, 0xd1, 0x51, 0x54, 0x18, 0x79, 0xc6, 0xac, 0xb2, 0x30, 0x13,
+	0x44, 0x43, 0x3c, 0x51, 0x32, 0xac, 0x2c, 0xcc, 0x64, 0x69, 0x6d, 0x39, 0x67, 0xad, 0x05, 0xa5,
+	0x33, 0x65, 0xbe, 0x49, 0xb4, 0x05, 0x0e, 0x11, 0x99, 0x29, 0x8f, 0xae, 0x90, 0xb6, 0xc0, 0x21,
+	0xea, 0xc5, 0xf8, 0xd8, 0x1a, 0x2d, 0x46, 0xe3, 0xe5, 0x6e, 0xd4, 0x73, 0xbb, 0xc1, 0xa1, 0x76,
+	0xe2, 0x9f, 0x12, 0xdc, 0x20, 0x38, 0x9b, 0xb2, 0xdb, 0x50, 0x3d, 0xf1, 0xa3, 0xc9, 0x69, 0x42,
+	0x1e, 0x55, 0x12, 0xe9, 0x8c, 0xdd, 0x87, 0x8a, 0x4b, 0x17, 0xd6, 0xf5, 0x55, 0x8b, 0x21, 0xa2,
+	0x06, 0x35, 0x1b, 0xbf, 0xa1, 0x5a, 0xa9, 0x04, 0x99, 0xc6, 0x84, 0x34, 0xda, 0xd7, 0x6b, 0x4c,
+	0x32, 0x8d, 0x13, 0xd2, 0xe8, 0x5c, 0xaf, 0x41, 0xc4, 0xee, 0x5f, 0x0b, 0xd0, 0xcc, 0x95, 0x2f,
+	0xec, 0x2b, 0xa8, 0x06, 0xd4, 0xda, 0xf2, 0xc2, 0x6f, 0x68, 0x7f, 0x53, 0x2e, 0x9e, 0xda, 0xea,
+	0x43, 0x5c, 0x23, 0xfd, 0xec, 0xd6, 0x7d, 0x02, 0x55, 0xc3, 0x5b, 0xf7, 0x7e, 0x80, 0xea, 0xf1,
+	0xf3, 0xfe, 0xde, 0xa3, 0xaf, 0xad, 0x42, 0x3a, 0x7e, 0xf4, 0x60, 0xcf, 0x2a, 0xe2, 0xf8, 0xe9,
+	0x8b, 0xfe, 0xf7, 0xf6, 0x43, 0xab, 0xd4, 0xfd, 0x77, 0x09, 0xca, 0xf4, 0xa5, 0xe6, 0xd7, 0x3f,
+	0x5b, 0xbc, 0x29, 0x17, 0xde, 0x83, 0xb2, 0x0a, 0xa7, 0xd1, 0x5b, 0x32, 0x21, 0xc9, 0x91, 0x97,
+	0x68, 0x57, 0xbf, 0x39, 0x0d, 0xa2, 0xbf, 0x0a, 0x92, 0x5f, 0xfd, 0xd2, 0x67, 0xea, 0xde, 0xdf,
+	0xf0, 0xa5, 0x8f, 0xed, 0x41, 0x35, 0x6d, 0xa6, 0xcd, 0x3d, 0xb6, 0xb5, 0xfe, 0x88, 0x9e, 0x69,
+	0xaa, 0xd3, 0xcf, 0x9d, 0x86, 0x89, 0x8d, 0xf8, 0x95, 0x4c, 0x67, 0x52, 0x68, 0x3b, 0xf9, 0xb5,
+	0x24, 0x57, 0x5f, 0x4f, 0x72, 0xd8, 0x44, 0x2c, 0x33, 0x92, 0xc9, 0x92, 0xf5, 0x20, 0x4b, 0x5a,
+	0x77, 0x00, 0xa2, 0xd7, 0x21, 0x5e, 0x64, 0xab, 0x4a, 0xbc, 0x41, 0x08, 0xd6, 0x42, 0x28, 0x9e,
+	0xc5, 0xd1, 0xd9, 0xc2, 0x88, 0x9b, 0x46, 0x4c, 0x08, 0x89, 0xbb, 0xd0, 0x5a, 0xeb, 0xd1, 0x4c,
+	0x62, 0x5c, 0xc3, 0xb6, 0x9e, 0x40, 0x33, 0xf7, 0x5a, 0x6f, 0xf8, 0x5c, 0xbb, 0xe6, 0x25, 0xad,
+	0xdc, 0xc7, 0xd9, 0xa7, 0xef, 0xff, 0x71, 0x6b, 0xa6, 0xf4, 0xfc, 0xec, 0xa4, 0x37, 0x89, 0x82,
+	0xdd, 0xf4, 0xd3, 0x72, 0xb6, 0x63, 0x27, 0x55, 0x72, 0xdf, 0x87, 0xff, 0x1b, 0x00, 0x39, 0x4d,
+	0x1c, 0xe3, 0xbd, 0x16, 0x00, 0x00,
 }
@@ -0,0 +1,124 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+)
+
+// Validate checks the Policy for settings which are invalid or contradict each other, e.g. no
+// include paths, malformed exclude patterns or include paths which are excluded themselves.
+// It returns all problems found, nil if there are none. Include paths are not required to exist
+// as the same Policy is often used for many hosts, see ValidateRoots.
+func (p *Policy) Validate() []error {
+	var errs []error
+	includes := p.IncludePaths()
+	if len(includes) == 0 {
+		errs = append(errs, fmt.Errorf("no include or include_path given, nothing would be walked"))
+	}
+	for _, pc := range includes {
+		if pc.Path == "" {
+			errs = append(errs, fmt.Errorf("empty include path"))
+			continue
+		}
+		inc := filepath.Clean(pc.Path)
+		for _, pfx := range p.ExcludePfx {
+			if pfx != "" && strings.HasPrefix(inc+"/", pfx) {
+				errs = append(errs, fmt.Errorf("include %q is excluded by exclude_pfx %q", pc.Path, pfx))
+			}
+		}
+		for _, outer := range includes {
+			if outer.Path == "" || outer.MaxDepth != 0 || p.MaxDirectoryDepth != 0 {
+				continue
+			}
+			if o := filepath.Clean(outer.Path); o != inc && strings.HasPrefix(inc, strings.TrimSuffix(o, "/")+"/") {
+				errs = append(errs, fmt.Errorf("include %q is below include %q, its files would be walked twice", pc.Path, outer.Path))
+			}
+		}
+	}
+	for _, pat := range p.ExcludePaths {
+		if _, err := path.Match(pat, ""); err != nil {
+			errs = append(errs, fmt.Errorf("invalid exclude_paths pattern %q: %w", pat, err))
+		}
+	}
+	for _, e := range p.ExcludeRegex {
+		if _, err := regexp.Compile(e); err != nil {
+			errs = append(errs, fmt.Errorf("invalid exclude_regex: %w", err))
+		}
+	}
+	if p.MaxHashFileSize < 0 {
+		errs = append(errs, fmt.Errorf("max_hash_file_size %d is negative", p.MaxHashFileSize))
+	}
+	var minAge, maxAge time.Duration
+	var err error
+	if p.MinMtimeAge != nil {
+		if minAge, err = ptypes.Duration(p.MinMtimeAge); err != nil {
+			errs = append(errs, fmt.Errorf("invalid min_mtime_age: %v", err))
+		}
+	}
+	if p.MaxMtimeAge != nil {
+		if maxAge, err = ptypes.Duration(p.MaxMtimeAge); err != nil {
+			errs = append(errs, fmt.Errorf("invalid max_mtime_age: %v", err))
+		}
+	}
+	if minAge > 0 && maxAge > 0 && minAge > maxAge {
+		errs = append(errs, fmt.Errorf("min_mtime_age %s is larger than max_mtime_age %s, no file would be recorded", minAge, maxAge))
+	}
+	if p.SampleRate < 0 || p.SampleRate > 1 {
+		errs = append(errs, fmt.Errorf("sample_rate %v is not between 0 and 1", p.SampleRate))
+	}
+	if p.MaxWalkRetention < 0 {
+		errs = append(errs, fmt.Errorf("max_walk_retention %d is negative", p.MaxWalkRetention))
+	}
+	if p.MaxWalkRetention > 0 && p.DeltaWalk {
+		errs = append(errs, fmt.Errorf("max_walk_retention can't be combined with delta_walk"))
+	}
+	for _, f := range p.InspectedArchiveFormats() {
+		known := false
+		for _, af := range archiveFormats {
+			known = known || f == af
+		}
+		if !known {
+			errs = append(errs, fmt.Errorf("unknown archive_formats %q, supported are %s", f, strings.Join(archiveFormats, ", ")))
+		}
+	}
+	return errs
+}
+
+// ValidateRoots checks that the include paths of the Policy exist on this host. It is separate
+// from Validate for policies shared by hosts which don't have all of them; missing include paths
+// are skipped by the walker otherwise.
+func (p *Policy) ValidateRoots() []error {
+	var errs []error
+	for _, pc := range p.IncludePaths() {
+		if pc.Path == "" {
+			continue
+		}
+		if _, err := os.Lstat(pc.Path); os.IsNotExist(err) {
+			errs = append(errs, fmt.Errorf("include %q doesn't exist on this host", pc.Path))
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("unable to check include %q: %v", pc.Path, err))
+		}
+	}
+	return errs
+}
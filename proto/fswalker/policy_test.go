@@ -0,0 +1,120 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	dpb "github.com/golang/protobuf/ptypes/duration"
+)
+
+func TestPolicyValidate(t *testing.T) {
+	testCases := []struct {
+		desc string
+		pol  *Policy
+		want []string // Substrings of the expected errors, in order.
+	}{
+		{
+			desc: "valid",
+			pol: &Policy{
+				Include:     []string{"/etc", "/usr"},
+				IncludePath: []*PathConfig{{Path: "/", MaxDepth: 1}, {Path: "/var/lib"}},
+				ExcludePfx:  []string{"/usr/share/"},
+				MinMtimeAge: &dpb.Duration{Seconds: 60},
+				MaxMtimeAge: &dpb.Duration{Seconds: 3600},
+			},
+		}, {
+			desc: "empty policy",
+			pol:  &Policy{},
+			want: []string{"no include"},
+		}, {
+			desc: "empty include",
+			pol:  &Policy{Include: []string{""}},
+			want: []string{"empty include path"},
+		}, {
+			desc: "excluded include",
+			pol:  &Policy{Include: []string{"/tmp", "/etc"}, ExcludePfx: []string{"/tmp/"}},
+			want: []string{`include "/tmp" is excluded by exclude_pfx "/tmp/"`},
+		}, {
+			desc: "nested includes",
+			pol:  &Policy{Include: []string{"/", "/etc"}},
+			want: []string{`include "/etc" is below include "/"`},
+		}, {
+			desc: "nested includes with depth limit",
+			pol:  &Policy{Include: []string{"/", "/etc"}, MaxDirectoryDepth: 2},
+		}, {
+			desc: "several problems",
+			pol: &Policy{
+				Include:          []string{"/"},
+				ExcludePaths:     []string{"/home/["},
+				ExcludeRegex:     []string{"("},
+				MaxHashFileSize:  -1,
+				SampleRate:       2,
+				MaxWalkRetention: -1,
+			},
+			want: []string{"exclude_paths", "exclude_regex", "max_hash_file_size", "sample_rate", "max_walk_retention"},
+		}, {
+			desc: "invalid mtime ages",
+			pol:  &Policy{Include: []string{"/"}, MinMtimeAge: &dpb.Duration{Seconds: 1, Nanos: -1}},
+			want: []string{"min_mtime_age"},
+		}, {
+			desc: "empty mtime window",
+			pol:  &Policy{Include: []string{"/"}, MinMtimeAge: &dpb.Duration{Seconds: 3600}, MaxMtimeAge: &dpb.Duration{Seconds: 60}},
+			want: []string{"no file would be recorded"},
+		}, {
+			desc: "retention of delta Walks",
+			pol:  &Policy{Include: []string{"/"}, MaxWalkRetention: 3, DeltaWalk: true},
+			want: []string{"delta_walk"},
+		}, {
+			desc: "archive formats",
+			pol:  &Policy{Include: []string{"/"}, InspectArchives: true, ArchiveFormats: []string{".jar", "rar"}},
+			want: []string{`unknown archive_formats "rar"`},
+		},
+	}
+	for _, tc := range testCases {
+		errs := tc.pol.Validate()
+		if len(errs) != len(tc.want) {
+			t.Errorf("%s: Validate() = %v; want %d errors", tc.desc, errs, len(tc.want))
+			continue
+		}
+		for i, err := range errs {
+			if !strings.Contains(err.Error(), tc.want[i]) {
+				t.Errorf("%s: Validate()[%d] = %v; want it to contain %q", tc.desc, i, err, tc.want[i])
+			}
+		}
+	}
+}
+
+func TestPolicyValidateRoots(t *testing.T) {
+	pol := &Policy{
+		Include:     []string{os.TempDir(), "/nonexistent"},
+		IncludePath: []*PathConfig{{Path: "/nonexistent2", MaxDepth: 1}},
+	}
+	errs := pol.ValidateRoots()
+	want := []string{`include "/nonexistent" doesn't exist`, `include "/nonexistent2" doesn't exist`}
+	if len(errs) != len(want) {
+		t.Fatalf("ValidateRoots() = %v; want %d errors", errs, len(want))
+	}
+	for i, err := range errs {
+		if !strings.Contains(err.Error(), want[i]) {
+			t.Errorf("ValidateRoots()[%d] = %v; want it to contain %q", i, err, want[i])
+		}
+	}
+	if errs := (&Policy{Include: []string{os.TempDir()}}).ValidateRoots(); errs != nil {
+		t.Errorf("ValidateRoots() of existing include paths = %v; want nil", errs)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import "strings"
+
+// FileCount returns the number of file entries (including directories) of the Walk.
+func (w *Walk) FileCount() int {
+	return len(w.GetFile())
+}
+
+// TotalSizeBytes returns the sum of the sizes of all files of the Walk.
+// Directories count as zero size as their size says nothing about their content.
+func (w *Walk) TotalSizeBytes() int64 {
+	var total int64
+	for _, f := range w.GetFile() {
+		if !f.GetInfo().GetIsDir() {
+			total += f.GetInfo().GetSize()
+		}
+	}
+	return total
+}
+
+// FilterByPath returns a new Walk with only the files whose path starts with prefix, e.g.
+// "/etc/" to drill down into a directory of a large Walk. The files are shared with the given
+// Walk rather than copied, so only the slice of pointers is new. Deleted paths of delta Walks
+// are filtered alike. The checksum and summary, which describe the whole Walk, are dropped.
+// It returns nil for a nil Walk.
+func (w *Walk) FilterByPath(prefix string) *Walk {
+	if w == nil {
+		return nil
+	}
+	filtered := *w
+	filtered.Checksum = nil
+	filtered.Summary = nil
+	filtered.File = nil
+	for _, f := range w.File {
+		if strings.HasPrefix(f.GetPath(), prefix) {
+			filtered.File = append(filtered.File, f)
+		}
+	}
+	filtered.Deleted = nil
+	for _, p := range w.Deleted {
+		if strings.HasPrefix(p, prefix) {
+			filtered.Deleted = append(filtered.Deleted, p)
+		}
+	}
+	return &filtered
+}
+
+// archiveFormats are the archive file extensions understood by the walker.
+var archiveFormats = []string{"zip", "jar", "war", "ear", "tar", "tar.gz", "tgz"}
+
+// InspectedArchiveFormats returns the file extensions of the archives to inspect according to
+// archive_formats, without leading dot and in lower case. Defaults to all known formats.
+func (p *Policy) InspectedArchiveFormats() []string {
+	if len(p.GetArchiveFormats()) == 0 {
+		return archiveFormats
+	}
+	var formats []string
+	for _, f := range p.GetArchiveFormats() {
+		formats = append(formats, strings.ToLower(strings.TrimPrefix(f, ".")))
+	}
+	return formats
+}
+
+// IncludePaths returns the paths to walk of the Policy: all include paths, which have no depth
+// limit of their own, followed by include_path.
+func (p *Policy) IncludePaths() []*PathConfig {
+	var paths []*PathConfig
+	for _, inc := range p.GetInclude() {
+		paths = append(paths, &PathConfig{Path: inc})
+	}
+	return append(paths, p.GetIncludePath()...)
+}
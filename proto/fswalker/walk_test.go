@@ -0,0 +1,127 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWalkStats(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		walk      *Walk
+		wantCount int
+		wantSize  int64
+	}{
+		{
+			desc: "nil",
+		}, {
+			desc: "empty",
+			walk: &Walk{},
+		}, {
+			desc:      "single file",
+			walk:      &Walk{File: []*File{{Path: "/a", Info: &FileInfo{Size: 42}}}},
+			wantCount: 1,
+			wantSize:  42,
+		}, {
+			desc: "with directories",
+			walk: &Walk{File: []*File{
+				{Path: "/", Info: &FileInfo{Size: 4096, IsDir: true}},
+				{Path: "/a", Info: &FileInfo{Size: 42}},
+				{Path: "/b", Info: &FileInfo{Size: 8}},
+				{Path: "/c"},
+			}},
+			wantCount: 4,
+			wantSize:  50,
+		},
+	}
+	for _, tc := range testCases {
+		if got := tc.walk.FileCount(); got != tc.wantCount {
+			t.Errorf("%s: FileCount() = %d; want %d", tc.desc, got, tc.wantCount)
+		}
+		if got := tc.walk.TotalSizeBytes(); got != tc.wantSize {
+			t.Errorf("%s: TotalSizeBytes() = %d; want %d", tc.desc, got, tc.wantSize)
+		}
+	}
+}
+
+func TestIncludePaths(t *testing.T) {
+	pol := &Policy{
+		Include:     []string{"/etc", "/usr"},
+		IncludePath: []*PathConfig{{Path: "/var", MaxDepth: 2}},
+	}
+	want := []*PathConfig{{Path: "/etc"}, {Path: "/usr"}, {Path: "/var", MaxDepth: 2}}
+	got := pol.IncludePaths()
+	if len(got) != len(want) {
+		t.Fatalf("IncludePaths() = %v; want %v", got, want)
+	}
+	for i := range want {
+		if got[i].Path != want[i].Path || got[i].MaxDepth != want[i].MaxDepth {
+			t.Errorf("IncludePaths()[%d] = %v; want %v", i, got[i], want[i])
+		}
+	}
+	if got := (*Policy)(nil).IncludePaths(); got != nil {
+		t.Errorf("IncludePaths() of nil Policy = %v; want nil", got)
+	}
+}
+
+func TestFilterByPath(t *testing.T) {
+	etc := &File{Path: "/etc", Info: &FileInfo{IsDir: true}}
+	passwd := &File{Path: "/etc/passwd"}
+	wlk := &Walk{
+		Id:       "walk",
+		Hostname: "host",
+		File:     []*File{{Path: "/"}, etc, passwd, {Path: "/etcetera"}, {Path: "/home/a"}},
+		Deleted:  []string{"/etc/shadow", "/home/b"},
+		Checksum: &Fingerprint{Value: "abc"},
+		Summary:  &WalkSummary{FileCount: 4},
+	}
+	got := wlk.FilterByPath("/etc/")
+	if want := []*File{passwd}; !reflect.DeepEqual(got.File, want) || got.File[0] != passwd {
+		t.Errorf("FilterByPath() files = %v; want the same %v", got.File, want)
+	}
+	if want := []string{"/etc/shadow"}; !reflect.DeepEqual(got.Deleted, want) {
+		t.Errorf("FilterByPath() deleted = %q; want %q", got.Deleted, want)
+	}
+	if got.Id != "walk" || got.Hostname != "host" || got.Checksum != nil || got.Summary != nil {
+		t.Errorf("FilterByPath() = %v; want ID and hostname without checksum and summary", got)
+	}
+	if len(wlk.File) != 5 || len(wlk.Deleted) != 2 || wlk.Checksum == nil {
+		t.Errorf("FilterByPath() modified the given Walk: %v", wlk)
+	}
+	if got := wlk.FilterByPath("/etc"); len(got.File) != 3 {
+		t.Errorf("FilterByPath(%q) = %d files; want 3", "/etc", len(got.File))
+	}
+	if got := (*Walk)(nil).FilterByPath("/"); got != nil {
+		t.Errorf("FilterByPath() of nil Walk = %v; want nil", got)
+	}
+}
+
+func TestInspectedArchiveFormats(t *testing.T) {
+	testCases := []struct {
+		pol  *Policy
+		want []string
+	}{
+		{pol: &Policy{}, want: archiveFormats},
+		{pol: nil, want: archiveFormats},
+		{pol: &Policy{ArchiveFormats: []string{".JAR", "tar.gz"}}, want: []string{"jar", "tar.gz"}},
+	}
+	for _, tc := range testCases {
+		if got := tc.pol.InspectedArchiveFormats(); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("InspectedArchiveFormats() of %v = %q; want %q", tc.pol, got, tc.want)
+		}
+	}
+}
@@ -15,21 +15,28 @@
 package fswalker
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"os"
 	"path"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
+	"syscall"
+	"text/template"
 	"time"
 
 	"github.com/google/fswalker/internal/metrics"
 
+	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/google/go-cmp/cmp"
@@ -39,35 +46,234 @@ import (
 )
 
 const (
-	actionAdd    = action("Added")
-	actionModify = action("Modified")
-	actionDelete = action("Deleted")
-	actionError  = action("Error")
+	actionAdd      = action("Added")
+	actionModify   = action("Modified")
+	actionDelete   = action("Deleted")
+	actionError    = action("Error")
+	actionRetarget = action("Retargeted") // a symlink pointing to a different target than before.
+	actionRetype   = action("Retyped")    // a file with content of a different MIME type than before.
 
 	timeReportFormat = "2006-01-02 15:04:05 MST"
+	// timeReportNanoFormat is used for timestamps which only differ in their fractional seconds.
+	timeReportNanoFormat = "2006-01-02 15:04:05.000000000 MST"
+
+	// defaultMinFileRatio is used by Validate if the report config has no min_file_ratio.
+	defaultMinFileRatio = 0.1
+
+	// defaultEntropyThreshold is used if the report config has no entropy_threshold.
+	defaultEntropyThreshold = 7.5
 )
 
+// privilegeBits are the file mode bits which grant elevated privileges when executing a file.
+var privilegeBits = []struct {
+	name string
+	mode os.FileMode
+}{
+	{"setuid", os.ModeSetuid},
+	{"setgid", os.ModeSetgid},
+}
+
+// privilegeBitDiffs reports setuid and setgid bits which were added or removed between two file modes.
+func privilegeBitDiffs(bm, am uint32) []string {
+	var diffs []string
+	for _, pb := range privilegeBits {
+		b, a := os.FileMode(bm)&pb.mode != 0, os.FileMode(am)&pb.mode != 0
+		switch {
+		case !b && a:
+			diffs = append(diffs, pb.name+" added")
+		case b && !a:
+			diffs = append(diffs, pb.name+" removed")
+		}
+	}
+	return diffs
+}
+
+// withoutPrivilegeBits returns a file mode with setuid and setgid bits cleared.
+func withoutPrivilegeBits(m uint32) uint32 {
+	return uint32(os.FileMode(m) &^ (os.ModeSetuid | os.ModeSetgid))
+}
+
 type action string
 
-type actionData struct {
-	before *fspb.File
-	after  *fspb.File
-	diff   string
-	err    error
+// FileChange is a file which differs between two Walks.
+type FileChange struct {
+	// Before is the file in the "before" Walk or nil if the file was added.
+	Before *fspb.File
+	// After is the file in the "after" Walk or nil if the file was deleted.
+	After *fspb.File
+	// Diff lists the changed metadata, one change per line.
+	Diff string
+	// Err is set if the file could not be compared.
+	Err error
+}
+
+// CompareResult holds all differences between two Walks grouped by the kind of change.
+type CompareResult struct {
+	Added      []FileChange
+	Deleted    []FileChange
+	Modified   []FileChange
+	Retargeted []FileChange // symlinks pointing to a different target than before.
+	Retyped    []FileChange // files with content of a different MIME type than before.
+	Errors     []FileChange
+}
+
+// ChangeCount returns the number of added, deleted, modified, retargeted and retyped files.
+func (c *CompareResult) ChangeCount() int {
+	return len(c.Added) + len(c.Deleted) + len(c.Modified) + len(c.Retargeted) + len(c.Retyped)
+}
+
+// byAction returns the changes of the given kind.
+func (c *CompareResult) byAction(a action) []FileChange {
+	switch a {
+	case actionAdd:
+		return c.Added
+	case actionDelete:
+		return c.Deleted
+	case actionModify:
+		return c.Modified
+	case actionRetarget:
+		return c.Retargeted
+	case actionRetype:
+		return c.Retyped
+	case actionError:
+		return c.Errors
+	}
+	return nil
 }
 
 // ReporterFromConfigFile creates a new Reporter based on a config path.
 func ReporterFromConfigFile(ctx context.Context, path string, verbose bool) (*Reporter, error) {
-	config := &fspb.ReportConfig{}
-	if err := readTextProto(ctx, path, config); err != nil {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, &ConfigLoadError{Path: path, Err: err}
+	}
+	r, err := ReporterFromConfigBytes(ctx, b, verbose)
+	if err != nil {
+		return nil, err
+	}
+	r.configPath = path
+	return r, nil
+}
+
+// ReporterOptions are the settings of ReporterFromWalkDirectory.
+type ReporterOptions struct {
+	// ConfigFile is the report config file to use. An empty config is used if not set.
+	ConfigFile string
+	// Verbose makes the Reporter print more information for all diffs found.
+	Verbose bool
+	// ReviewFile holds the last known good Walk of each host. If neither it nor review_backend
+	// of the config is set, the latest Walk is compared without a baseline, i.e. all of its
+	// files are reported as added.
+	ReviewFile string
+	// Filters are applied to both Walks before they are compared, see LoadWalks.
+	Filters []WalkFilter
+}
+
+// ReporterFromWalkDirectory creates a Reporter which compares the latest Walk of the host in
+// walkPath against its last known good Walk. It combines ReporterFromConfigFile and LoadWalks
+// for the common case, so the output methods (e.g. Compare) can be called right away.
+func ReporterFromWalkDirectory(ctx context.Context, walkPath, hostname string, opts ReporterOptions) (*Reporter, error) {
+	if walkPath == "" || hostname == "" {
+		return nil, fmt.Errorf("walkPath and hostname need to be specified")
+	}
+	var r *Reporter
+	var err error
+	if opts.ConfigFile != "" {
+		r, err = ReporterFromConfigFile(ctx, opts.ConfigFile, opts.Verbose)
+	} else {
+		r, err = NewReporter(ctx, &fspb.ReportConfig{}, opts.Verbose)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if opts.ReviewFile != "" || r.Reviews != nil {
+		if err := r.LoadWalks(ctx, hostname, opts.ReviewFile, walkPath, "", "", opts.Filters...); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+	afterFile, after, afterFp, err := r.loadLatestWalk(ctx, hostname, walkPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load latest walk for %s: %w", hostname, err)
+	}
+	if err := r.loadWalkFiles(nil, "", nil, after, afterFile, afterFp); err != nil {
+		return nil, err
+	}
+	if err := r.filterWalks(opts.Filters); err != nil {
 		return nil, err
 	}
+	return r, nil
+}
+
+// ReporterFromConfigBytes creates a new Reporter based on a text format or JSON encoded config.
+func ReporterFromConfigBytes(ctx context.Context, data []byte, verbose bool) (*Reporter, error) {
+	config := &fspb.ReportConfig{}
+	if err := unmarshalConfig(data, config); err != nil {
+		return nil, &InvalidConfigError{Err: err}
+	}
+	return NewReporter(ctx, config, verbose)
+}
+
+// NewReporter creates a new Reporter for a config built in code. The config is validated like
+// one read from a file. It must not be modified afterwards.
+func NewReporter(ctx context.Context, config *fspb.ReportConfig, verbose bool) (*Reporter, error) {
+	if config == nil {
+		return nil, &InvalidConfigError{Err: fmt.Errorf("no config given")}
+	}
+	r := &Reporter{
+		config:  config,
+		Verbose: verbose,
+		Counter: &metrics.Counter{},
+	}
+	if config.SummaryTemplate != "" {
+		tmpl, err := template.New("summary").Parse(config.SummaryTemplate)
+		if err != nil {
+			return nil, &InvalidConfigError{Err: fmt.Errorf("unable to parse summary_template: %w", err)}
+		}
+		r.summaryTmpl = tmpl
+	}
+	if p := config.KnownGoodHashesFile; p != "" {
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, &ConfigLoadError{Path: p, Err: err}
+		}
+		if r.knownGoodHashes, err = parseKnownGoodHashes(b); err != nil {
+			return nil, &InvalidConfigError{Err: fmt.Errorf("invalid known_good_hashes_file %q: %w", p, err)}
+		}
+	}
+	store, err := reviewStoreForBackend(config.ReviewBackend)
+	if err != nil {
+		return nil, &InvalidConfigError{Err: err}
+	}
+	if store != nil {
+		r.Reviews = NewReviewManager(store)
+	}
+	return r, nil
+}
+
+// Clone returns a new Reporter with the same config and settings, sharing Counter, Reviews and
+// Store, but without any loaded Walks. Clones can compare the Walks of different hosts in
+// parallel, e.g. each writing to its own output set with SetOutput.
+// Set up Reviews before cloning so all clones record their reviews in the same ReviewManager.
+func (r *Reporter) Clone() *Reporter {
 	return &Reporter{
-		config:     config,
-		configPath: path,
-		Verbose:    verbose,
-		Counter:    &metrics.Counter{},
-	}, nil
+		config:           r.config,
+		configPath:       r.configPath,
+		summaryTmpl:      r.summaryTmpl,
+		knownGoodHashes:  r.knownGoodHashes,
+		Verbose:          r.Verbose,
+		Counter:          r.Counter,
+		PrefixHostname:   r.PrefixHostname,
+		Since:            r.Since,
+		FilenameFormat:   r.FilenameFormat,
+		Recursive:        r.Recursive,
+		Reviews:          r.Reviews,
+		ChangeTypeFilter: r.ChangeTypeFilter,
+		DiffContext:      r.DiffContext,
+		IgnoreMtimeOnly:  r.IgnoreMtimeOnly,
+		Store:            r.Store,
+		out:              r.out,
+	}
 }
 
 // Reporter compares two Walks against each other based on the config provided
@@ -76,6 +282,10 @@ type Reporter struct {
 	// config is the configuration defining paths to exclude from the report as well as other aspects.
 	config     *fspb.ReportConfig
 	configPath string
+	// summaryTmpl is the parsed summary_template of the config, nil if unset.
+	summaryTmpl *template.Template
+	// knownGoodHashes maps paths to the SHA256 hashes of the known_good_hashes_file of the config.
+	knownGoodHashes map[string]string
 
 	// Verbose, when true, makes Reporter print more information for all diffs found.
 	Verbose bool
@@ -83,8 +293,45 @@ type Reporter struct {
 	// Counter records stats over all processed files, if non-nil.
 	Counter *metrics.Counter
 
-	reviewFile string
-	reviews    *fspb.Reviews
+	// PrefixHostname, when true, makes Compare prefix each section with the hostname of the
+	// compared Walks. This keeps the output unambiguous when reporting on several hosts.
+	PrefixHostname bool
+
+	// Since, if non-zero, makes LoadWalks only consider Walk files whose embedded
+	// timestamp is no older than this duration when searching for the latest Walk.
+	Since time.Duration
+
+	// FilenameFormat, if non-empty, is the layout of the names of the Walk files LoadWalks searches
+	// for (see WalkFilenameWithFormat), e.g. as set with the walker's -filenameFormat. Defaults
+	// to DefaultWalkFilenameFormat.
+	FilenameFormat string
+
+	// Recursive, when true, makes LoadWalks also search the subdirectories of walkPath for the
+	// latest Walk, e.g. for Walks written with an output directory layout (see OutputDir).
+	Recursive bool
+
+	// Reviews keeps track of the last known good Walk of each host. LoadWalks sets it up
+	// for the given review file unless it has been set already, e.g. to a custom ReviewStore.
+	Reviews *ReviewManager
+
+	// ChangeTypeFilter, if non-empty, overrides change_type_filter of the config.
+	ChangeTypeFilter []fspb.ReportConfig_ChangeType
+
+	// DiffContext, if positive, makes Compare list up to this many neighbouring paths of the
+	// Walk before and after each changed path, in path order like "diff -C".
+	DiffContext int
+
+	// IgnoreMtimeOnly, when true, suppresses modified files whose only change is their mtime,
+	// e.g. log and cache files which were touched without changing their content.
+	IgnoreMtimeOnly bool
+
+	// Store, if non-nil, is where Walk files are read from. Otherwise it is determined by the
+	// URI scheme of each path (local file system, GCS or S3).
+	Store WalkStore
+
+	// out is where the ...ToOutput methods and the messages of LoadWalks and
+	// UpdateReviewProto write to, stdout if nil.
+	out io.Writer
 
 	beforeFile string
 	before     *fspb.Walk
@@ -92,6 +339,11 @@ type Reporter struct {
 	afterFile  string
 	after      *fspb.Walk
 	afterFp    *fspb.Fingerprint
+
+	// changeCount is the number of changes found by the last comparison.
+	changeCount int
+	// metrics are the statistics of the last comparison.
+	metrics DiffMetrics
 }
 
 func (r *Reporter) verifyFingerprint(goodFp *fspb.Fingerprint, checkFp *fspb.Fingerprint) error {
@@ -119,56 +371,184 @@ func (r *Reporter) fingerprint(b []byte) *fspb.Fingerprint {
 }
 
 // readWalk reads a file as marshaled proto in fspb.Walk format.
+// The encoding (binary or JSON) and compression are determined by the file extension.
+// The fingerprint is built over the file content as stored on disk.
+// Delta Walks are merged with their base Walks; the fingerprint covers the delta only.
 func (r *Reporter) readWalk(ctx context.Context, path string) (*fspb.Walk, *fspb.Fingerprint, error) {
-	b, err := ioutil.ReadFile(path)
+	store := r.walkStore(path)
+	p, b, err := loadWalk(ctx, store, path)
 	if err != nil {
 		return nil, nil, err
 	}
-	p := &fspb.Walk{}
-	if err := proto.Unmarshal(b, p); err != nil {
+	if p, err = resolveWalk(ctx, store, path, p); err != nil {
 		return nil, nil, err
 	}
 	fp := r.fingerprint(b)
-	fmt.Printf("Loaded file %q with fingerprint: %s(%s)\n", path, fp.Method, fp.Value)
+	fmt.Fprintf(r.output(), "Loaded file %q with fingerprint: %s(%s)\n", path, fp.Method, fp.Value)
 	return p, fp, nil
 }
 
-// getFile rummages through a list of files to find the one with the right path.
-func (r *Reporter) getFile(path string, files []*fspb.File) *fspb.File {
-	for _, f := range files {
-		if f.Path == path {
-			return f
+// walkStore returns the store Walk files are read from.
+func (r *Reporter) walkStore(path string) WalkStore {
+	if r.Store != nil {
+		return r.Store
+	}
+	return storeForPath(path)
+}
+
+// findWalkFiles returns all Walk files of the given host in walkPath of the store, regardless of
+// their output format and compression. With an empty hostname, Walk files of all hosts are returned.
+// If recursive is set, the subdirectories of walkPath are searched as well. Only files named
+// in the given format (see WalkFilenameWithFormat, DefaultWalkFilenameFormat if empty) are found.
+func findWalkFiles(ctx context.Context, store WalkStore, hostname, format, walkPath string, recursive bool) ([]string, error) {
+	var prefix string
+	if walkPath != "" {
+		prefix = strings.TrimSuffix(walkPath, "/") + "/"
+	}
+	var files []string
+	var err error
+	if recursive {
+		rs, ok := store.(RecursiveWalkStore)
+		if !ok {
+			return nil, fmt.Errorf("searching subdirectories of %q is not supported by its Walk store", walkPath)
 		}
+		files, err = rs.ListRecursive(ctx, prefix)
+	} else {
+		files, err = store.List(ctx, prefix)
 	}
-	return nil
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, p := range walkFilePatterns(hostname, format) {
+		for _, f := range files {
+			ok, err := path.Match(p, path.Base(f))
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				names = append(names, f)
+			}
+		}
+	}
+	return names, nil
+}
+
+// globWalkFiles is like findWalkFiles but walkPath may also be a glob pattern (see filepath.Glob)
+// like "/walks/*/" matching several directories on the local file system, e.g. one per
+// datacenter. All matching directories are searched.
+func globWalkFiles(ctx context.Context, store WalkStore, hostname, format, walkPath string, recursive bool) ([]string, error) {
+	if !strings.ContainsAny(walkPath, "*?[") {
+		return findWalkFiles(ctx, store, hostname, format, walkPath, recursive)
+	}
+	if _, ok := store.(LocalWalkStore); !ok {
+		return nil, fmt.Errorf("walkPath pattern %q is only supported on the local file system", walkPath)
+	}
+	dirs, err := filepath.Glob(strings.TrimSuffix(walkPath, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid walkPath pattern %q: %v", walkPath, err)
+	}
+	var names []string
+	for _, d := range dirs {
+		if fi, err := os.Stat(d); err != nil || !fi.IsDir() {
+			continue
+		}
+		dn, err := findWalkFiles(ctx, store, hostname, format, d, recursive)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, dn...)
+	}
+	return names, nil
+}
+
+// WalkHosts returns the sorted list of unique hostnames for which Walk files exist in walkPath.
+func WalkHosts(ctx context.Context, walkPath string) ([]string, error) {
+	return walkHosts(ctx, walkPath, false)
+}
+
+// WalkHostsRecursive is like WalkHosts but also searches the subdirectories of walkPath.
+func WalkHostsRecursive(ctx context.Context, walkPath string) ([]string, error) {
+	return walkHosts(ctx, walkPath, true)
+}
+
+func walkHosts(ctx context.Context, walkPath string, recursive bool) ([]string, error) {
+	return walkHostsInStore(ctx, storeForPath(walkPath), "", walkPath, recursive)
+}
+
+// WalkHosts is like the WalkHosts function but finds Walk files named in FilenameFormat, in the
+// Walk store of the Reporter and also in subdirectories if Recursive is set.
+func (r *Reporter) WalkHosts(ctx context.Context, walkPath string) ([]string, error) {
+	return walkHostsInStore(ctx, r.walkStore(walkPath), r.FilenameFormat, walkPath, r.Recursive)
+}
+
+// walkHostsInStore is like walkHosts but searches the given store for Walk files named in format.
+func walkHostsInStore(ctx context.Context, store WalkStore, format, walkPath string, recursive bool) ([]string, error) {
+	names, err := globWalkFiles(ctx, store, "", format, walkPath, recursive)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var hosts []string
+	for _, n := range names {
+		h, _, err := parseWalkFilename(format, "", n)
+		if err != nil || seen[h] {
+			continue
+		}
+		seen[h] = true
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+	return hosts, nil
 }
 
 // loadLatestWalk looks for the latest Walk in a given folder for a given hostname.
 // It returns the file path it ended up reading, the Walk it read and the fingerprint for it.
 func (r *Reporter) loadLatestWalk(ctx context.Context, hostname, walkPath string) (string, *fspb.Walk, *fspb.Fingerprint, error) {
-	matchpath := path.Join(walkPath, WalkFilename(hostname, time.Time{}))
-	names, err := filepath.Glob(matchpath)
+	matchpath := joinWalkPath(walkPath, walkFilePatterns(hostname, r.FilenameFormat)[0])
+	names, err := globWalkFiles(ctx, r.walkStore(walkPath), hostname, r.FilenameFormat, walkPath, r.Recursive)
 	if err != nil {
 		return "", nil, nil, err
 	}
 	if len(names) == 0 {
 		return "", nil, nil, fmt.Errorf("no files found for %q", matchpath)
 	}
-	sort.Strings(names) // the assumption is that the file names are such that the latest is last.
+	if r.Since > 0 {
+		names = walksSince(hostname, r.FilenameFormat, names, time.Now().Add(-r.Since))
+		if len(names) == 0 {
+			log.Printf("WARNING: no Walk files found for %q within the last %s", matchpath, r.Since)
+			return "", nil, nil, fmt.Errorf("no files found for %q within the last %s", matchpath, r.Since)
+		}
+	}
+	sortWalkFiles(hostname, r.FilenameFormat, names)
 	wlk, fp, err := r.readWalk(ctx, names[len(names)-1])
 	return names[len(names)-1], wlk, fp, err
 }
 
-// loadLastGoodWalk reads the designated review file and attempts to find an entry matching
-// the given hostname. Note that if it can't find one but the review file itself was read
+// sortWalkFiles sorts the Walk files of a host named in format by the time in their names,
+// oldest first. Walks may be spread over several directories (subdirectories or a walkPath
+// pattern), so files of the same time, e.g. told apart by WalkFilenameUnique, and files without
+// a time in their name are sorted by file name only.
+func sortWalkFiles(hostname, format string, names []string) {
+	sort.SliceStable(names, func(i, j int) bool {
+		_, ti, erri := parseWalkFilename(format, hostname, names[i])
+		_, tj, errj := parseWalkFilename(format, hostname, names[j])
+		if erri == nil && errj == nil && !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return path.Base(names[i]) < path.Base(names[j])
+	})
+}
+
+// loadLastGoodWalk loads the reviews and attempts to find an entry matching the given
+// hostname. Note that if it can't find one but the reviews themselves were loaded
 // successfully, it will return an empty Walk and no error.
 // It returns the file path it ended up reading, the Walk it read and the fingerprint for it.
-func (r *Reporter) loadLastGoodWalk(ctx context.Context, hostname, reviewFile string) (string, *fspb.Walk, *fspb.Fingerprint, error) {
-	reviews := &fspb.Reviews{}
-	if err := readTextProto(ctx, reviewFile, reviews); err != nil {
+func (r *Reporter) loadLastGoodWalk(ctx context.Context, hostname string) (string, *fspb.Walk, *fspb.Fingerprint, error) {
+	if err := r.Reviews.Load(ctx); err != nil {
 		return "", nil, nil, err
 	}
-	rvws, ok := reviews.Review[hostname]
+	rvws, ok := r.Reviews.GetBaseline(hostname)
 	if !ok {
 		return "", nil, nil, nil
 	}
@@ -180,68 +560,181 @@ func (r *Reporter) loadLastGoodWalk(ctx context.Context, hostname, reviewFile st
 		return "", nil, nil, err
 	}
 	if good.Id != rvws.WalkId {
-		return "", nil, fp, fmt.Errorf("walk ID doesn't match: %s (from %s) != %s (from %s)", good.Id, rvws.WalkReference, rvws.WalkId, reviewFile)
+		return "", nil, fp, fmt.Errorf("walk ID doesn't match: %s (from %s) != %s (from %s)", good.Id, rvws.WalkReference, rvws.WalkId, r.Reviews)
 	}
 	return rvws.WalkReference, good, fp, nil
 }
 
+// walksSince returns the Walk files of the given host named in format whose embedded timestamp
+// is not before cutoff. Files without a parseable timestamp are skipped.
+func walksSince(hostname, format string, names []string, cutoff time.Time) []string {
+	var recent []string
+	for _, n := range names {
+		_, t, err := parseWalkFilename(format, hostname, n)
+		if err != nil {
+			log.Printf("skipping %q: %v", n, err)
+			continue
+		}
+		if !t.Before(cutoff) {
+			recent = append(recent, n)
+		}
+	}
+	return recent
+}
+
+// walksInRange returns the Walk files of the given host whose embedded timestamp is within
+// [start, end]. A zero start or end leaves that side of the range open.
+// Files without a parseable timestamp in format are skipped.
+func walksInRange(hostname, format string, names []string, start, end time.Time) []string {
+	var inRange []string
+	for _, n := range names {
+		_, t, err := parseWalkFilename(format, hostname, n)
+		if err != nil {
+			log.Printf("skipping %q: %v", n, err)
+			continue
+		}
+		if (start.IsZero() || !t.Before(start)) && (end.IsZero() || !t.After(end)) {
+			inRange = append(inRange, n)
+		}
+	}
+	return inRange
+}
+
+// Validate checks whether the loaded Walks are fit for comparison. Besides the checks done by
+// LoadWalks, it verifies that the "after" Walk is complete and that its file count has not dropped
+// below the configured ratio of the "before" Walk's file count, which hints at a truncated Walk.
+func (r *Reporter) Validate(ctx context.Context) error {
+	if err := r.sanityCheck(r.before, r.after); err != nil {
+		return err
+	}
+	if r.after.StartWalk == nil || r.after.StopWalk == nil {
+		return fmt.Errorf("walk %s is incomplete: missing start or stop time", r.after.Id)
+	}
+	afterStart, _ := ptypes.Timestamp(r.after.StartWalk)
+	afterStop, _ := ptypes.Timestamp(r.after.StopWalk)
+	if afterStop.Before(afterStart) {
+		return fmt.Errorf("walk %s indicates it ended (%s) before it started (%s)", r.after.Id, afterStop, afterStart)
+	}
+	if r.before == nil || len(r.before.File) == 0 {
+		return nil
+	}
+	minRatio := r.config.GetMinFileRatio()
+	if minRatio == 0 {
+		minRatio = defaultMinFileRatio
+	}
+	if ratio := float64(len(r.after.File)) / float64(len(r.before.File)); ratio < minRatio {
+		return fmt.Errorf("walk %s has %d files which is less than %.0f%% of the %d files in walk %s", r.after.Id, len(r.after.File), minRatio*100, len(r.before.File), r.before.Id)
+	}
+	return nil
+}
+
 // LoadWalks accepts a number of parameters on which it decides how to load the walks to compare.
 // Note that the "before" walk (i.e. last known good) may be legitimately empty.
-func (r *Reporter) LoadWalks(ctx context.Context, hostname, reviewFile, walkPath, afterFile, beforeFile string) error {
+// When searching walkPath for the latest Walk, Since limits the files considered.
+// walkPath may be a glob pattern like "/walks/*/" to search several local directories.
+// The given filters, if any, are applied to both Walks in order before they are compared.
+func (r *Reporter) LoadWalks(ctx context.Context, hostname, reviewFile, walkPath, afterFile, beforeFile string, filters ...WalkFilter) error {
 	var err error
 	var before, after *fspb.Walk
 	var beforeFp, afterFp *fspb.Fingerprint
-	if hostname != "" && reviewFile != "" && walkPath != "" {
+	if hostname != "" && (reviewFile != "" || r.Reviews != nil) && walkPath != "" {
 		if afterFile != "" || beforeFile != "" {
 			return fmt.Errorf("[hostname reviewFile walkPath] and [beforeFile afterFile] are mutually exclusive")
 		}
+		if r.Reviews == nil {
+			r.Reviews = ReviewManagerFromFile(reviewFile)
+		}
 
-		beforeFile, before, beforeFp, err = r.loadLastGoodWalk(ctx, hostname, reviewFile)
+		beforeFile, before, beforeFp, err = r.loadLastGoodWalk(ctx, hostname)
 		if err != nil {
-			return fmt.Errorf("unable to load last good walk for %s: %v", hostname, err)
+			return fmt.Errorf("unable to load last good walk for %s: %w", hostname, err)
 		}
 		afterFile, after, afterFp, err = r.loadLatestWalk(ctx, hostname, walkPath)
 		if err != nil {
-			return fmt.Errorf("unable to load latest walk for %s: %v", hostname, err)
+			return fmt.Errorf("unable to load latest walk for %s: %w", hostname, err)
 		}
-		if err := r.sanityCheck(before, after); err != nil {
+		if err := r.loadWalkFiles(before, beforeFile, beforeFp, after, afterFile, afterFp); err != nil {
 			return err
 		}
-		r.before = before
-		r.beforeFp = beforeFp
-		r.beforeFile = beforeFile
-		r.after = after
-		r.afterFp = afterFp
-		r.afterFile = afterFile
-		return nil
+		return r.filterWalks(filters)
 	}
 
 	if afterFile != "" {
 		after, afterFp, err = r.readWalk(ctx, afterFile)
 		if err != nil {
-			return fmt.Errorf("File cannot be read: %s", afterFile)
+			return fmt.Errorf("File cannot be read: %s: %w", afterFile, err)
 		}
 		if beforeFile != "" {
 			before, beforeFp, err = r.readWalk(ctx, beforeFile)
 			if err != nil {
-				return fmt.Errorf("File cannot be read: %s", beforeFile)
+				return fmt.Errorf("File cannot be read: %s: %w", beforeFile, err)
 			}
 		}
-		if err := r.sanityCheck(before, after); err != nil {
+		if err := r.loadWalkFiles(before, beforeFile, beforeFp, after, afterFile, afterFp); err != nil {
 			return err
 		}
-		r.before = before
-		r.beforeFp = beforeFp
-		r.beforeFile = beforeFile
-		r.after = after
-		r.afterFp = afterFp
-		r.afterFile = afterFile
-		return nil
+		return r.filterWalks(filters)
 	}
 
 	return fmt.Errorf("either [hostname reviewFile walkPath] OR [[beforeFile] afterFile] need to be specified")
 }
 
+// LoadWalksByTimeRange finds all Walk files of the host in walkPath whose embedded timestamp is
+// within [start, end] and returns them from oldest to latest. A zero start or end leaves that
+// side of the range open. The oldest and the latest of them are loaded for comparison, showing
+// the drift over the whole range. To follow changes step by step (e.g. permissions drifting over
+// a week), pass consecutive files to LoadWalks as beforeFile and afterFile.
+// At least two Walk files need to be in the range. Reviews are neither used nor updated.
+func (r *Reporter) LoadWalksByTimeRange(ctx context.Context, hostname, walkPath string, start, end time.Time) ([]string, error) {
+	if hostname == "" || walkPath == "" {
+		return nil, fmt.Errorf("hostname and walkPath need to be specified")
+	}
+	if !start.IsZero() && !end.IsZero() && end.Before(start) {
+		return nil, fmt.Errorf("end time %s is before start time %s", end, start)
+	}
+	names, err := globWalkFiles(ctx, r.walkStore(walkPath), hostname, r.FilenameFormat, walkPath, r.Recursive)
+	if err != nil {
+		return nil, err
+	}
+	names = walksInRange(hostname, r.FilenameFormat, names, start, end)
+	if len(names) < 2 {
+		return nil, fmt.Errorf("found %d Walk files for %q between %s and %s, need at least 2", len(names), hostname, start, end)
+	}
+	sortWalkFiles(hostname, r.FilenameFormat, names)
+	if err := r.LoadWalks(ctx, "", "", "", names[len(names)-1], names[0]); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// loadWalkFiles makes the Reporter compare the given Walks read from the given files.
+func (r *Reporter) loadWalkFiles(before *fspb.Walk, beforeFile string, beforeFp *fspb.Fingerprint, after *fspb.Walk, afterFile string, afterFp *fspb.Fingerprint) error {
+	if err := r.LoadWalksFromProtos(before, after); err != nil {
+		return err
+	}
+	r.beforeFile, r.beforeFp = beforeFile, beforeFp
+	r.afterFile, r.afterFp = afterFile, afterFp
+	return nil
+}
+
+// LoadWalksFromProtos makes the Reporter compare the given Walks without any file I/O, e.g. when
+// the Walks were received from another service. The "before" Walk may be nil. The same sanity
+// checks apply as for LoadWalks. As there are no Walk files, the Reporter has no file names or
+// fingerprints to report or to record when updating reviews.
+// If the Walks are of different versions, the older one is migrated (see MigrateWalk).
+func (r *Reporter) LoadWalksFromProtos(before, after *fspb.Walk) error {
+	before, after, err := migrateWalks(before, after)
+	if err != nil {
+		return fmt.Errorf("versions don't match: %w", err)
+	}
+	if err := r.sanityCheck(before, after); err != nil {
+		return err
+	}
+	r.before, r.beforeFile, r.beforeFp = before, "", nil
+	r.after, r.afterFile, r.afterFp = after, "", nil
+	return nil
+}
+
 // sanityCheck runs a few checks to ensure the "before" and "after" Walks are sane-ish.
 func (r *Reporter) sanityCheck(before, after *fspb.Walk) error {
 	if after == nil {
@@ -276,6 +769,8 @@ func (r *Reporter) isIgnored(path string) bool {
 	return false
 }
 
+// timestampDiff compares two timestamps with nanosecond precision. Values differing only in
+// their fractional seconds are printed with nanoseconds.
 func (r *Reporter) timestampDiff(bt, at *tspb.Timestamp) (string, error) {
 	if bt == nil && at == nil {
 		return "", nil
@@ -291,7 +786,52 @@ func (r *Reporter) timestampDiff(bt, at *tspb.Timestamp) (string, error) {
 	if bmt.Equal(amt) {
 		return "", nil
 	}
-	return fmt.Sprintf("%s => %s", bmt.Format(timeReportFormat), amt.Format(timeReportFormat)), nil
+	// Timestamps recorded without fractional seconds (e.g. by file systems with a resolution of
+	// one second) are only compared to the second.
+	if (bt.GetNanos() == 0) != (at.GetNanos() == 0) && bt.GetSeconds() == at.GetSeconds() {
+		return "", nil
+	}
+	format := timeReportFormat
+	if bt.GetSeconds() == at.GetSeconds() {
+		format = timeReportNanoFormat
+	}
+	return fmt.Sprintf("%s => %s", bmt.Format(format), amt.Format(format)), nil
+}
+
+// FileInfoDiff returns the names of the fields which differ between two FileInfo protos, in
+// proto field order: "name", "size", "mode", "mtime" and "is_dir". Unlike the Report, any
+// difference counts, including changes of privilege bits only. A nil FileInfo is treated as
+// an empty one.
+func FileInfoDiff(a, b *fspb.FileInfo) []string {
+	if a == nil {
+		a = &fspb.FileInfo{}
+	}
+	if b == nil {
+		b = &fspb.FileInfo{}
+	}
+	var fields []string
+	if a.Name != b.Name {
+		fields = append(fields, "name")
+	}
+	if a.Size != b.Size {
+		fields = append(fields, "size")
+	}
+	if a.Mode != b.Mode {
+		fields = append(fields, "mode")
+	}
+	if !proto.Equal(a.Modified, b.Modified) {
+		fields = append(fields, "mtime")
+	}
+	if a.IsDir != b.IsDir {
+		fields = append(fields, "is_dir")
+	}
+	return fields
+}
+
+// FileInfoEqual returns whether two FileInfo protos describe the same file metadata.
+// See FileInfoDiff for which differences are found.
+func FileInfoEqual(a, b *fspb.FileInfo) bool {
+	return len(FileInfoDiff(a, b)) == 0
 }
 
 // diffFileStat compares the FileInfo proto of two files and reports all relevant diffs as human readable strings.
@@ -308,9 +848,13 @@ func (r *Reporter) diffFileInfo(fib, fia *fspb.FileInfo) ([]string, error) {
 	if fib.Size != fia.Size {
 		diffs = append(diffs, fmt.Sprintf("size: %d => %d", fib.Size, fia.Size))
 	}
-	if fib.Mode != fia.Mode {
+	// Privilege bit changes are reported separately from other permission changes.
+	if withoutPrivilegeBits(fib.Mode) != withoutPrivilegeBits(fia.Mode) {
 		diffs = append(diffs, fmt.Sprintf("mode: %d => %d", fib.Mode, fia.Mode))
 	}
+	for _, d := range privilegeBitDiffs(fib.Mode, fia.Mode) {
+		diffs = append(diffs, "privileges: "+d)
+	}
 	if fib.IsDir != fia.IsDir {
 		diffs = append(diffs, fmt.Sprintf("is_dir: %t => %t", fib.IsDir, fia.IsDir))
 	}
@@ -330,16 +874,31 @@ func (r *Reporter) diffFileInfo(fib, fia *fspb.FileInfo) ([]string, error) {
 	return diffs, nil
 }
 
+// nlinkChanged returns whether the hard link count of a file other than a directory changed.
+func nlinkChanged(fsb, fsa *fspb.FileStat) bool {
+	if fsb == nil || fsa == nil || fsa.Mode&syscall.S_IFMT == syscall.S_IFDIR {
+		return false
+	}
+	return fsb.Nlink != fsa.Nlink
+}
+
 // diffFileStat compares the FileStat proto of two files and reports all relevant diffs as human readable strings.
+// The hard link count (nlink) is compared for everything but directories, whose link count
+// changes with every added or removed subdirectory. An increase may indicate that a file has
+// been hard-linked from an unexpected location.
+// The birth time (btime) is only compared if both Walks recorded it.
 // The following fields are ignored as they are not regarded as relevant in this context:
 //   - atime
-//   - inode, nlink, dev, rdev
+//   - inode, dev, rdev
 //   - blksize, blocks
+//
 // The following fields are ignored as they are already part of diffFileInfo() check
 // which is more guaranteed to be available (to avoid duplicate output):
 //   - mode
 //   - size
 //   - mtime
+//
+// uid and gid are compared by diffOwnership() along with the owner and group names.
 func (r *Reporter) diffFileStat(fsb, fsa *fspb.FileStat) ([]string, error) {
 	var diffs []string
 
@@ -347,11 +906,19 @@ func (r *Reporter) diffFileStat(fsb, fsa *fspb.FileStat) ([]string, error) {
 		return diffs, nil
 	}
 
-	if fsb.Uid != fsa.Uid {
-		diffs = append(diffs, fmt.Sprintf("uid: %d => %d", fsb.Uid, fsa.Uid))
+	if nlinkChanged(fsb, fsa) {
+		diffs = append(diffs, fmt.Sprintf("nlink: %d => %d", fsb.Nlink, fsa.Nlink))
 	}
-	if fsb.Gid != fsa.Gid {
-		diffs = append(diffs, fmt.Sprintf("gid: %d => %d", fsb.Gid, fsa.Gid))
+
+	// A new birth time means the file was replaced rather than modified in place.
+	if fsb.GetBtime() != nil && fsa.GetBtime() != nil {
+		bdiff, err := r.timestampDiff(fsb.Btime, fsa.Btime)
+		if err != nil {
+			return diffs, fmt.Errorf("unable to convert timestamps: %v", err)
+		}
+		if bdiff != "" {
+			diffs = append(diffs, fmt.Sprintf("btime: %s (file replaced)", bdiff))
+		}
 	}
 
 	// Ignore ctime changes if mtime equals to ctime or if both are nil.
@@ -373,6 +940,70 @@ func (r *Reporter) diffFileStat(fsb, fsa *fspb.FileStat) ([]string, error) {
 	return diffs, nil
 }
 
+// idWithName formats a user or group ID along with its name, if known.
+func idWithName(id uint32, name string) string {
+	if name == "" {
+		return fmt.Sprint(id)
+	}
+	return fmt.Sprintf("%d (%s)", id, name)
+}
+
+// diffOwnership compares the user and group owning two files and reports changes as human
+// readable strings, naming both the ID and the name. A changed name of an unchanged ID is
+// reported as well since the same ID may resolve to different users on different hosts.
+// Names are only compared if both are known.
+func diffOwnership(before, after *fspb.File) []string {
+	fsb, fsa := before.Stat, after.Stat
+	if fsb == nil || fsa == nil {
+		return nil
+	}
+	var diffs []string
+	ids := []struct {
+		idLabel, nameLabel string
+		bid, aid           uint32
+		bname, aname       string
+	}{
+		{"uid", "owner", fsb.Uid, fsa.Uid, before.OwnerName, after.OwnerName},
+		{"gid", "group", fsb.Gid, fsa.Gid, before.GroupName, after.GroupName},
+	}
+	for _, id := range ids {
+		switch {
+		case id.bid != id.aid:
+			diffs = append(diffs, fmt.Sprintf("%s: %s => %s", id.idLabel, idWithName(id.bid, id.bname), idWithName(id.aid, id.aname)))
+		case id.bname != "" && id.aname != "" && id.bname != id.aname:
+			diffs = append(diffs, fmt.Sprintf("%s: %s => %s (%s %d)", id.nameLabel, id.bname, id.aname, id.idLabel, id.aid))
+		}
+	}
+	return diffs
+}
+
+// diffXattrs compares the extended attributes of two files and reports added, removed
+// and changed attributes as human readable strings.
+func (r *Reporter) diffXattrs(xb, xa map[string][]byte) []string {
+	var diffs []string
+	for name, vb := range xb {
+		va, ok := xa[name]
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("xattr removed: %s", name))
+		case !bytes.Equal(vb, va):
+			diffs = append(diffs, fmt.Sprintf("xattr changed: %s: %q => %q", name, vb, va))
+		}
+	}
+	for name, va := range xa {
+		if _, ok := xb[name]; !ok {
+			diffs = append(diffs, fmt.Sprintf("xattr added: %s: %q", name, va))
+		}
+	}
+	return diffs
+}
+
+// mimeTypeChanged returns whether the detected MIME type of a file changed. Files for which
+// either Walk has no MIME type, e.g. because detection was only enabled recently, are skipped.
+func mimeTypeChanged(before, after *fspb.File) bool {
+	return before.MimeType != "" && after.MimeType != "" && before.MimeType != after.MimeType
+}
+
 // diffFile compares two File entries of a Walk and shows the diffs between the two.
 func (r *Reporter) diffFile(before, after *fspb.File) (string, error) {
 	if before.Version != after.Version {
@@ -384,16 +1015,35 @@ func (r *Reporter) diffFile(before, after *fspb.File) (string, error) {
 
 	var diffs []string
 	// Ensure fingerprints are the same - if there was one before. Do not show a diff if there's a new fingerprint.
-	if len(before.Fingerprint) > 0 {
-		if diff := cmp.Diff(before.Fingerprint, after.Fingerprint); diff != "" {
+	// Fingerprints built with different methods can't be compared so only the method change is reported.
+	// A missing fingerprint is expected if the Walks sampled different files for hashing.
+	if len(before.Fingerprint) > 0 && (len(after.Fingerprint) > 0 || !r.sampleRateMismatch()) {
+		bm, am := before.Fingerprint[0].Method, fspb.Fingerprint_UNKNOWN
+		if len(after.Fingerprint) > 0 {
+			am = after.Fingerprint[0].Method
+		}
+		if am != fspb.Fingerprint_UNKNOWN && bm != am {
+			diffs = append(diffs, fmt.Sprintf("fingerprint method: %s => %s (content not compared)", bm, am))
+		} else if diff := cmp.Diff(before.Fingerprint, after.Fingerprint); diff != "" {
 			diffs = append(diffs, diff)
 		}
 	}
+	diffs = append(diffs, r.diffXattrs(before.Xattrs, after.Xattrs)...)
+	if before.SymlinkTarget != after.SymlinkTarget {
+		diffs = append(diffs, fmt.Sprintf("symlink target: %q => %q", before.SymlinkTarget, after.SymlinkTarget))
+	}
+	if mimeTypeChanged(before, after) {
+		diffs = append(diffs, fmt.Sprintf("mime type: %q => %q", before.MimeType, after.MimeType))
+	}
+	if before.Capabilities != after.Capabilities {
+		diffs = append(diffs, fmt.Sprintf("capabilities: %q => %q", before.Capabilities, after.Capabilities))
+	}
 	fiDiffs, err := r.diffFileInfo(before.Info, after.Info)
 	if err != nil {
 		return "", fmt.Errorf("unable to diff file info for %q: %v", before.Path, err)
 	}
 	diffs = append(diffs, fiDiffs...)
+	diffs = append(diffs, diffOwnership(before, after)...)
 	fsDiffs, err := r.diffFileStat(before.Stat, after.Stat)
 	if err != nil {
 		return "", fmt.Errorf("unable to diff file stat for %q: %v", before.Path, err)
@@ -410,40 +1060,91 @@ func (r *Reporter) count(metric string) {
 	r.Counter.Add(1, metric)
 }
 
-// Compare runs through two Walks (before and after) with a given ReportConfig and shows the diffs.
-func (r *Reporter) Compare(out io.Writer) {
-	// Processing report.
-	output := map[action][]actionData{}
+// sampleRate returns the fraction of files hashed according to sample_rate of the policy.
+func sampleRate(pol *fspb.Policy) float64 {
+	if r := pol.GetSampleRate(); r > 0 && r < 1 {
+		return r
+	}
+	return 1
+}
+
+// sampleRateMismatch returns whether the Walks hashed different samples of files.
+func (r *Reporter) sampleRateMismatch() bool {
+	return r.before != nil && sampleRate(r.before.GetPolicy()) != sampleRate(r.after.GetPolicy())
+}
+
+// directoriesOnlyMismatch returns whether only one of the Walks recorded directories only.
+// Other files are then left out of the comparison as they would all show up as added or removed.
+func (r *Reporter) directoriesOnlyMismatch() bool {
+	return r.before != nil && r.before.GetPolicy().GetDirectoriesOnly() != r.after.GetPolicy().GetDirectoriesOnly()
+}
+
+// diffWalks runs through two Walks (before and after) with a given ReportConfig and
+// collects the diffs by the kind of action which happened to the files.
+func (r *Reporter) diffWalks() *CompareResult {
+	output := &CompareResult{}
 	walked := map[string]bool{}
+	dirsOnly := r.directoriesOnlyMismatch()
 	if r.before != nil {
+		after := NewWalkIndex(r.after)
 		for _, fb := range r.before.File {
 			r.count("before-files")
-			if r.isIgnored(fb.Path) {
+			if r.isIgnored(fb.Path) || (dirsOnly && !fb.GetInfo().GetIsDir()) {
 				r.count("before-files-ignored")
 				continue
 			}
-			fa := r.getFile(fb.Path, r.after.File)
-			if fa == nil {
+			fa, ok := after.Get(fb.Path)
+			if !ok {
+				if !r.wantChange(fspb.ReportConfig_FILE_DELETED) {
+					r.count("before-files-filtered")
+					continue
+				}
 				r.count("before-files-removed")
-				output[actionDelete] = append(output[actionDelete], actionData{before: fb})
+				output.Deleted = append(output.Deleted, FileChange{Before: fb})
 				continue
 			}
 			diff, err := r.diffFile(fb, fa)
 			if err != nil {
 				r.count("file-diff-error")
-				output[actionError] = append(output[actionError], actionData{
-					before: fb,
-					after:  fa,
-					diff:   diff,
-					err:    err,
+				output.Errors = append(output.Errors, FileChange{
+					Before: fb,
+					After:  fa,
+					Diff:   diff,
+					Err:    err,
 				})
 			}
-			if diff != "" {
+			if diff != "" && !r.wantChange(diffChangeTypes(diff)...) {
+				r.count("before-files-filtered")
+				diff = ""
+			}
+			if diff != "" && r.knownGood(fa) {
+				r.count("before-files-known-good")
+				diff = ""
+			}
+			if diff != "" && r.IgnoreMtimeOnly && mtimeOnly(diff) {
+				r.count("before-files-mtime-only")
+				diff = ""
+			}
+			if diff != "" && fb.SymlinkTarget != fa.SymlinkTarget {
+				r.count("before-files-retargeted")
+				output.Retargeted = append(output.Retargeted, FileChange{
+					Before: fb,
+					After:  fa,
+					Diff:   diff,
+				})
+			} else if diff != "" && mimeTypeChanged(fb, fa) {
+				r.count("before-files-retyped")
+				output.Retyped = append(output.Retyped, FileChange{
+					Before: fb,
+					After:  fa,
+					Diff:   diff,
+				})
+			} else if diff != "" {
 				r.count("before-files-modified")
-				output[actionModify] = append(output[actionModify], actionData{
-					before: fb,
-					after:  fa,
-					diff:   diff,
+				output.Modified = append(output.Modified, FileChange{
+					Before: fb,
+					After:  fa,
+					Diff:   diff,
 				})
 			}
 			walked[fb.Path] = true
@@ -451,7 +1152,7 @@ func (r *Reporter) Compare(out io.Writer) {
 	}
 	for _, fa := range r.after.File {
 		r.count("after-files")
-		if r.isIgnored(fa.Path) {
+		if r.isIgnored(fa.Path) || (dirsOnly && !fa.GetInfo().GetIsDir()) {
 			r.count("after-files-ignored")
 			continue
 		}
@@ -459,111 +1160,980 @@ func (r *Reporter) Compare(out io.Writer) {
 		if ok {
 			continue
 		}
+		if !r.wantChange(fspb.ReportConfig_FILE_ADDED) {
+			r.count("after-files-filtered")
+			continue
+		}
+		if r.knownGood(fa) {
+			r.count("after-files-known-good")
+			continue
+		}
 		r.count("after-files-created")
-		output[actionAdd] = append(output[actionAdd], actionData{after: fa})
+		output.Added = append(output.Added, FileChange{After: fa})
 	}
+	r.changeCount = output.ChangeCount()
+	r.metrics = diffMetrics(output)
+	return output
+}
 
-	// Writing sorted output.
-	fmt.Fprintln(out, "===============================================================================")
-	fmt.Fprintln(out, "Object Summary:")
-	fmt.Fprintln(out, "===============================================================================")
-	if len(output[actionAdd]) > 0 {
-		fmt.Fprintf(out, "Added (%d):\n", len(output[actionAdd]))
-		for _, file := range output[actionAdd] {
-			fmt.Fprintln(out, file.after.Path)
-		}
-		fmt.Fprintln(out)
-	}
-	if len(output[actionDelete]) > 0 {
-		fmt.Fprintf(out, "Removed (%d):\n", len(output[actionDelete]))
-		for _, file := range output[actionDelete] {
-			fmt.Fprintln(out, file.before.Path)
-		}
-		fmt.Fprintln(out)
+// CompareWalks compares two Walks in memory with the given ReportConfig and returns all differences.
+// The "before" Walk may be nil in which case all files count as added.
+func CompareWalks(before, after *fspb.Walk, cfg *fspb.ReportConfig) (*CompareResult, error) {
+	if after == nil {
+		return nil, fmt.Errorf("the after Walk needs to be specified")
 	}
-	if len(output[actionModify]) > 0 {
-		fmt.Fprintf(out, "Modified (%d):\n", len(output[actionModify]))
-		for _, file := range output[actionModify] {
-			fmt.Fprintln(out, file.after.Path)
-			if r.Verbose {
-				fmt.Fprintln(out, file.diff)
-				fmt.Fprintln(out)
-			}
-		}
-		fmt.Fprintln(out)
+	if cfg == nil {
+		cfg = &fspb.ReportConfig{}
 	}
-	if len(output[actionError]) > 0 {
-		fmt.Fprintf(out, "Reporting Errors (%d):\n", len(output[actionError]))
-		for _, file := range output[actionError] {
-			fmt.Fprintf(out, "%s: %v\n", file.before.Path, file.err)
-		}
-		fmt.Fprintln(out)
+	r := &Reporter{
+		config: cfg,
+		before: before,
+		after:  after,
 	}
-	if r.before != nil && len(r.before.Notification) > 0 {
-		fmt.Fprintln(out, "Walking Errors for BEFORE file:")
-		for _, err := range r.before.Notification {
-			if r.Verbose || (err.Severity != fspb.Notification_UNKNOWN && err.Severity != fspb.Notification_INFO) {
-				fmt.Fprintf(out, "%s(%s): %s\n", err.Severity, err.Path, err.Message)
-			}
+	return r.diffWalks(), nil
+}
+
+// ChangeCount returns the number of added, deleted, modified, retargeted and retyped files found
+// by the last comparison run with Compare, CompareJSON or CompareHTML. After
+// CompareAgainstGolden, it is the number of differences over all compared hosts.
+func (r *Reporter) ChangeCount() int {
+	return r.changeCount
+}
+
+// DiffMetrics are the numbers of changed files found by a comparison, by kind of change.
+type DiffMetrics struct {
+	// Numbers of changed files by type, as reported by Compare.
+	Added      int64
+	Deleted    int64
+	Modified   int64
+	Retargeted int64
+	Retyped    int64
+	Errors     int64
+
+	// Numbers of modified, retargeted and retyped files with at least one change of the
+	// respective kind (see ReportConfig.ChangeType). A file can count for several of them.
+	ContentChanges    int64
+	PermissionChanges int64
+	OwnerChanges      int64
+	MetadataChanges   int64
+	// PermissionOnlyChanges is the number of files whose permissions changed and nothing else.
+	PermissionOnlyChanges int64
+}
+
+// diffMetrics counts the changes of a CompareResult by kind.
+func diffMetrics(c *CompareResult) DiffMetrics {
+	m := DiffMetrics{
+		Added:      int64(len(c.Added)),
+		Deleted:    int64(len(c.Deleted)),
+		Modified:   int64(len(c.Modified)),
+		Retargeted: int64(len(c.Retargeted)),
+		Retyped:    int64(len(c.Retyped)),
+		Errors:     int64(len(c.Errors)),
+	}
+	for _, changes := range [][]FileChange{c.Modified, c.Retargeted, c.Retyped} {
+		for _, fc := range changes {
+			types := diffChangeTypes(fc.Diff)
+			for _, t := range types {
+				switch t {
+				case fspb.ReportConfig_CONTENT_CHANGED:
+					m.ContentChanges++
+				case fspb.ReportConfig_PERMISSION_CHANGED:
+					m.PermissionChanges++
+					if len(types) == 1 {
+						m.PermissionOnlyChanges++
+					}
+				case fspb.ReportConfig_OWNER_CHANGED:
+					m.OwnerChanges++
+				case fspb.ReportConfig_METADATA_CHANGED:
+					m.MetadataChanges++
+				}
+			}
+		}
+	}
+	return m
+}
+
+// Metrics returns the statistics of the last comparison run with Compare or CompareJSON.
+// Unlike the Counter, it only covers that comparison and doesn't require parsing metric names.
+func (r *Reporter) Metrics() DiffMetrics {
+	return r.metrics
+}
+
+// entropyThreshold returns the entropy above which added files are considered suspicious.
+func (r *Reporter) entropyThreshold() float64 {
+	if t := r.config.GetEntropyThreshold(); t > 0 {
+		return t
+	}
+	return defaultEntropyThreshold
+}
+
+// highEntropyAdditions returns the added files whose content entropy exceeds the threshold.
+func (r *Reporter) highEntropyAdditions(c *CompareResult) []FileChange {
+	var he []FileChange
+	for _, file := range c.Added {
+		if file.After.Entropy > r.entropyThreshold() {
+			he = append(he, file)
+		}
+	}
+	return he
+}
+
+// diffTypes maps the kinds of changes of a CompareResult to FileDiff types, in the order
+// they are reported in.
+var diffTypes = []struct {
+	action   action
+	diffType fspb.FileDiff_DiffType
+}{
+	{actionAdd, fspb.FileDiff_ADDED},
+	{actionDelete, fspb.FileDiff_DELETED},
+	{actionModify, fspb.FileDiff_MODIFIED},
+	{actionRetarget, fspb.FileDiff_RETARGETED},
+	{actionRetype, fspb.FileDiff_RETYPED},
+	{actionError, fspb.FileDiff_ERROR},
+}
+
+// diffFieldLabel matches the label of a diff line as written by diffFile, e.g. "size: 1 => 2".
+var diffFieldLabel = regexp.MustCompile(`^(name|size|mode|privileges|is_dir|mtime|uid|gid|owner|group|nlink|ctime|btime|xattr \w+|symlink target|mime type|capabilities|fingerprint method):`)
+
+// changedFields returns the names of the metadata fields changed according to the diff lines
+// of a file. Fingerprint content diffs span several unlabeled lines.
+func changedFields(diff []string) []string {
+	var fields []string
+	seen := map[string]bool{}
+	for _, l := range diff {
+		f := "fingerprint"
+		if m := diffFieldLabel.FindStringSubmatch(l); m != nil {
+			switch f = m[1]; {
+			case strings.HasPrefix(f, "xattr"):
+				f = "xattrs"
+			case f == "symlink target":
+				f = "symlink_target"
+			case f == "mime type":
+				f = "mime_type"
+			case f == "fingerprint method":
+				f = "fingerprint"
+			}
+		}
+		if !seen[f] {
+			seen[f] = true
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// changeTypeOfField maps the fields returned by changedFields to the kind of change.
+// Fields not listed are METADATA_CHANGED.
+var changeTypeOfField = map[string]fspb.ReportConfig_ChangeType{
+	"fingerprint":    fspb.ReportConfig_CONTENT_CHANGED,
+	"size":           fspb.ReportConfig_CONTENT_CHANGED,
+	"symlink_target": fspb.ReportConfig_CONTENT_CHANGED,
+	"mime_type":      fspb.ReportConfig_CONTENT_CHANGED,
+	"mode":           fspb.ReportConfig_PERMISSION_CHANGED,
+	"privileges":     fspb.ReportConfig_PERMISSION_CHANGED,
+	"capabilities":   fspb.ReportConfig_PERMISSION_CHANGED,
+	"uid":            fspb.ReportConfig_OWNER_CHANGED,
+	"gid":            fspb.ReportConfig_OWNER_CHANGED,
+	"owner":          fspb.ReportConfig_OWNER_CHANGED,
+	"group":          fspb.ReportConfig_OWNER_CHANGED,
+}
+
+// mtimeOnly returns whether the diff of a modified file only changes its mtime.
+func mtimeOnly(diff string) bool {
+	fields := changedFields(strings.Split(diff, "\n"))
+	return len(fields) == 1 && fields[0] == "mtime"
+}
+
+// diffChangeTypes returns the kinds of changes described by the diff of a modified file.
+func diffChangeTypes(diff string) []fspb.ReportConfig_ChangeType {
+	var types []fspb.ReportConfig_ChangeType
+	seen := map[fspb.ReportConfig_ChangeType]bool{}
+	for _, f := range changedFields(strings.Split(diff, "\n")) {
+		t, ok := changeTypeOfField[f]
+		if !ok {
+			t = fspb.ReportConfig_METADATA_CHANGED
+		}
+		if !seen[t] {
+			seen[t] = true
+			types = append(types, t)
 		}
+	}
+	return types
+}
+
+// changeTypeFilter returns the kinds of changes to report, all if empty.
+func (r *Reporter) changeTypeFilter() []fspb.ReportConfig_ChangeType {
+	if len(r.ChangeTypeFilter) > 0 {
+		return r.ChangeTypeFilter
+	}
+	return r.config.GetChangeTypeFilter()
+}
+
+// wantChange determines whether a file with any of the given kinds of changes is reported.
+func (r *Reporter) wantChange(types ...fspb.ReportConfig_ChangeType) bool {
+	filter := r.changeTypeFilter()
+	if len(filter) == 0 {
+		return true
+	}
+	for _, t := range types {
+		for _, f := range filter {
+			if t == f {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ParseChangeTypes parses a comma separated list of change type names as used in
+// change_type_filter, e.g. "PERMISSION_CHANGED,OWNER_CHANGED". Names are case insensitive.
+func ParseChangeTypes(s string) ([]fspb.ReportConfig_ChangeType, error) {
+	var types []fspb.ReportConfig_ChangeType
+	for _, n := range strings.Split(s, ",") {
+		n = strings.ToUpper(strings.TrimSpace(n))
+		if n == "" {
+			continue
+		}
+		v, ok := fspb.ReportConfig_ChangeType_value[n]
+		if !ok || v == int32(fspb.ReportConfig_UNSPECIFIED) {
+			return nil, fmt.Errorf("unknown change type %q", n)
+		}
+		types = append(types, fspb.ReportConfig_ChangeType(v))
+	}
+	return types, nil
+}
+
+// fileDiff converts a FileChange into its FileDiff proto.
+func (r *Reporter) fileDiff(t fspb.FileDiff_DiffType, c FileChange) *fspb.FileDiff {
+	fd := &fspb.FileDiff{DiffType: t}
+	if c.Before != nil {
+		fd.Path = c.Before.Path
+		fd.Before = c.Before.Info
+		fd.BeforeSymlinkTarget = c.Before.SymlinkTarget
+		fd.BeforeMimeType = c.Before.MimeType
+	}
+	if c.After != nil {
+		fd.Path = c.After.Path
+		fd.After = c.After.Info
+		fd.AfterSymlinkTarget = c.After.SymlinkTarget
+		fd.AfterMimeType = c.After.MimeType
+		fd.Entropy = c.After.Entropy
+		fd.HighEntropy = t == fspb.FileDiff_ADDED && c.After.Entropy > r.entropyThreshold()
+	}
+	if c.Diff != "" {
+		fd.Diff = strings.Split(c.Diff, "\n")
+		fd.ChangedField = changedFields(fd.Diff)
+	}
+	if c.Err != nil {
+		fd.Error = c.Err.Error()
+	}
+	return fd
+}
+
+// CompareToDiff compares the loaded Walks and returns the differences as a WalkDiff proto
+// which can be serialized, stored or fed to other systems. Compare renders it as text.
+func (r *Reporter) CompareToDiff(ctx context.Context) (*fspb.WalkDiff, error) {
+	if r.after == nil {
+		return nil, fmt.Errorf("no Walks loaded")
+	}
+	output := r.diffWalks()
+	wd := &fspb.WalkDiff{
+		Hostname:           r.after.Hostname,
+		AfterWalkId:        r.after.Id,
+		AfterWalkReference: r.afterFile,
+		AfterNotification:  r.after.Notification,
+	}
+	if r.before != nil {
+		wd.BeforeWalkId = r.before.Id
+		wd.BeforeWalkReference = r.beforeFile
+		wd.BeforeNotification = r.before.Notification
+		if bm, am := hashAlgorithm(r.before.Policy), hashAlgorithm(r.after.Policy); bm != am {
+			wd.Warning = append(wd.Warning, fmt.Sprintf("Walks used different hash algorithms (%s => %s), content changes can't be detected.", bm, am))
+		}
+		if r.sampleRateMismatch() {
+			wd.Warning = append(wd.Warning, fmt.Sprintf("Walks hashed different samples of files (sample rate %g => %g), content changes are only detected for files hashed in both.", sampleRate(r.before.Policy), sampleRate(r.after.Policy)))
+		}
+		if r.directoriesOnlyMismatch() {
+			wd.Warning = append(wd.Warning, "Only one of the Walks recorded directories only (directories_only), changes of other files are not reported.")
+		}
+		if r.before.Partial {
+			wd.Warning = append(wd.Warning, "The before Walk is partial (it was stopped before completion), files it is missing are reported as added.")
+		}
+	}
+	if r.after.Partial {
+		wd.Warning = append(wd.Warning, "The after Walk is partial (it was stopped before completion), files it is missing are reported as removed.")
+	}
+	for _, dt := range diffTypes {
+		for _, c := range output.byAction(dt.action) {
+			wd.FileDiff = append(wd.FileDiff, r.fileDiff(dt.diffType, c))
+		}
+	}
+	return wd, nil
+}
+
+// diffsOfType returns the FileDiffs of the given type.
+func diffsOfType(wd *fspb.WalkDiff, t fspb.FileDiff_DiffType) []*fspb.FileDiff {
+	var diffs []*fspb.FileDiff
+	for _, fd := range wd.FileDiff {
+		if fd.DiffType == t {
+			diffs = append(diffs, fd)
+		}
+	}
+	return diffs
+}
+
+// SetOutput sets the writer used by CompareToOutput, PrintReportSummaryToOutput and
+// PrintRuleSummaryToOutput as well as for the messages of LoadWalks and UpdateReviewProto,
+// e.g. a file or a buffer in tests. The default is stdout.
+func (r *Reporter) SetOutput(w io.Writer) {
+	r.out = w
+}
+
+// output returns the writer set with SetOutput, or stdout.
+func (r *Reporter) output() io.Writer {
+	if r.out == nil {
+		return os.Stdout
+	}
+	return r.out
+}
+
+// CompareToOutput is like Compare but writes to the output set with SetOutput.
+func (r *Reporter) CompareToOutput() {
+	r.Compare(r.output())
+}
+
+// PrintReportSummaryToOutput is like PrintReportSummary but writes to the output set with SetOutput.
+func (r *Reporter) PrintReportSummaryToOutput() {
+	r.PrintReportSummary(r.output())
+}
+
+// PrintRuleSummaryToOutput is like PrintRuleSummary but writes to the output set with SetOutput.
+func (r *Reporter) PrintRuleSummaryToOutput() {
+	r.PrintRuleSummary(r.output())
+}
+
+// Compare runs through two Walks (before and after) with a given ReportConfig and shows the diffs.
+// It renders the WalkDiff returned by CompareToDiff as text.
+func (r *Reporter) Compare(out io.Writer) {
+	wd, err := r.CompareToDiff(context.Background())
+	if err != nil {
+		fmt.Fprintf(out, "unable to compare Walks: %v\n", err)
+		return
+	}
+	pfx := ""
+	if r.PrefixHostname {
+		pfx = fmt.Sprintf("[%s] ", wd.Hostname)
+	}
+
+	// Writing sorted output.
+	fmt.Fprintln(out, "===============================================================================")
+	fmt.Fprintf(out, "%sObject Summary:\n", pfx)
+	fmt.Fprintln(out, "===============================================================================")
+	for _, w := range wd.Warning {
+		fmt.Fprintf(out, "WARNING: %s\n\n", w)
+	}
+	dc := r.newDiffContext()
+	if added := diffsOfType(wd, fspb.FileDiff_ADDED); len(added) > 0 {
+		fmt.Fprintf(out, "%sAdded (%d):\n", pfx, len(added))
+		var he []*fspb.FileDiff
+		dc.print(out, dc.after, added, func(fd *fspb.FileDiff) {
+			fmt.Fprintln(out, fd.Path)
+			if fd.HighEntropy {
+				he = append(he, fd)
+			}
+		})
 		fmt.Fprintln(out)
+		if len(he) > 0 {
+			fmt.Fprintf(out, "%sHigh-Entropy Additions (%d):\n", pfx, len(he))
+			for _, fd := range he {
+				fmt.Fprintf(out, "%s: %.2f bits/byte\n", fd.Path, fd.Entropy)
+			}
+			fmt.Fprintln(out)
+		}
 	}
-	if len(r.after.Notification) > 0 {
-		fmt.Fprintln(out, "Walking Errors for AFTER file:")
-		for _, err := range r.after.Notification {
-			if r.Verbose || (err.Severity != fspb.Notification_UNKNOWN && err.Severity != fspb.Notification_INFO) {
-				fmt.Fprintf(out, "%s(%s): %s\n", err.Severity, err.Path, err.Message)
+	if deleted := diffsOfType(wd, fspb.FileDiff_DELETED); len(deleted) > 0 {
+		fmt.Fprintf(out, "%sRemoved (%d):\n", pfx, len(deleted))
+		dc.print(out, dc.before, deleted, func(fd *fspb.FileDiff) {
+			fmt.Fprintln(out, fd.Path)
+		})
+		fmt.Fprintln(out)
+	}
+	if modified := diffsOfType(wd, fspb.FileDiff_MODIFIED); len(modified) > 0 {
+		fmt.Fprintf(out, "%sModified (%d):\n", pfx, len(modified))
+		dc.print(out, dc.after, modified, func(fd *fspb.FileDiff) {
+			fmt.Fprintln(out, fd.Path)
+			if r.Verbose {
+				fmt.Fprintln(out, strings.Join(fd.Diff, "\n"))
+				fmt.Fprintln(out)
 			}
+		})
+		fmt.Fprintln(out)
+	}
+	if retargeted := diffsOfType(wd, fspb.FileDiff_RETARGETED); len(retargeted) > 0 {
+		fmt.Fprintf(out, "%sSymlink Target Changed (%d):\n", pfx, len(retargeted))
+		dc.print(out, dc.after, retargeted, func(fd *fspb.FileDiff) {
+			fmt.Fprintf(out, "%s: %q => %q\n", fd.Path, fd.BeforeSymlinkTarget, fd.AfterSymlinkTarget)
+			if r.Verbose {
+				fmt.Fprintln(out, strings.Join(fd.Diff, "\n"))
+				fmt.Fprintln(out)
+			}
+		})
+		fmt.Fprintln(out)
+	}
+	if retyped := diffsOfType(wd, fspb.FileDiff_RETYPED); len(retyped) > 0 {
+		fmt.Fprintf(out, "%sMIME Type Changed (%d):\n", pfx, len(retyped))
+		dc.print(out, dc.after, retyped, func(fd *fspb.FileDiff) {
+			fmt.Fprintf(out, "%s: %q => %q\n", fd.Path, fd.BeforeMimeType, fd.AfterMimeType)
+			if r.Verbose {
+				fmt.Fprintln(out, strings.Join(fd.Diff, "\n"))
+				fmt.Fprintln(out)
+			}
+		})
+		fmt.Fprintln(out)
+	}
+	if errs := diffsOfType(wd, fspb.FileDiff_ERROR); len(errs) > 0 {
+		fmt.Fprintf(out, "%sReporting Errors (%d):\n", pfx, len(errs))
+		for _, fd := range errs {
+			fmt.Fprintf(out, "%s: %s\n", fd.Path, fd.Error)
 		}
 		fmt.Fprintln(out)
 	}
+	if len(wd.BeforeNotification) > 0 {
+		fmt.Fprintf(out, "%sWalking Errors for BEFORE file:\n", pfx)
+		r.printNotifications(out, wd.BeforeNotification)
+	}
+	if len(wd.AfterNotification) > 0 {
+		fmt.Fprintf(out, "%sWalking Errors for AFTER file:\n", pfx)
+		r.printNotifications(out, wd.AfterNotification)
+	}
+}
+
+// diffContext holds the sorted paths of the compared Walks for DiffContext.
+type diffContext struct {
+	n      int
+	before []string
+	after  []string
+}
+
+// newDiffContext returns the diffContext of the loaded Walks, without paths if DiffContext isn't set.
+func (r *Reporter) newDiffContext() *diffContext {
+	if r.DiffContext <= 0 {
+		return &diffContext{}
+	}
+	paths := func(wlk *fspb.Walk) []string {
+		var ps []string
+		for _, f := range wlk.GetFile() {
+			if !r.isIgnored(f.Path) {
+				ps = append(ps, f.Path)
+			}
+		}
+		sort.Strings(ps)
+		return ps
+	}
+	return &diffContext{n: r.DiffContext, before: paths(r.before), after: paths(r.after)}
+}
+
+// print prints the changed files in fds with fn. With context, they are printed in
+// path order along with up to n paths around each of them in the sorted paths of the Walk they
+// are in, indented by two spaces. Non-adjacent groups are separated by "--" like in grep -C.
+func (dc *diffContext) print(out io.Writer, paths []string, fds []*fspb.FileDiff, fn func(fd *fspb.FileDiff)) {
+	if dc.n <= 0 {
+		for _, fd := range fds {
+			fn(fd)
+		}
+		return
+	}
+	changed := map[string]*fspb.FileDiff{}
+	for _, fd := range fds {
+		changed[fd.Path] = fd
+		if i := sort.SearchStrings(paths, fd.Path); i == len(paths) || paths[i] != fd.Path {
+			// Keep paths complete in case a changed file is missing from the Walk.
+			paths = append(paths[:i:i], append([]string{fd.Path}, paths[i:]...)...)
+		}
+	}
+	next := 0 // index of the first path not printed yet.
+	for i, p := range paths {
+		fd, ok := changed[p]
+		if !ok {
+			continue
+		}
+		start := i - dc.n
+		if start < next {
+			start = next
+		}
+		if start > next && next > 0 {
+			fmt.Fprintln(out, "--")
+		}
+		for _, cp := range paths[start:i] {
+			fmt.Fprintf(out, "  %s\n", cp)
+		}
+		fn(fd)
+		next = i + 1
+		// Print the context after the change unless it contains another change.
+		for next < len(paths) && next <= i+dc.n {
+			if _, ok := changed[paths[next]]; ok {
+				break
+			}
+			fmt.Fprintf(out, "  %s\n", paths[next])
+			next++
+		}
+	}
+}
+
+// printNotifications prints all notifications of a Walk which are worth a warning,
+// or all of them in verbose mode.
+func (r *Reporter) printNotifications(out io.Writer, notifications []*fspb.Notification) {
+	for _, err := range notifications {
+		if r.Verbose || (err.Severity != fspb.Notification_UNKNOWN && err.Severity != fspb.Notification_INFO) {
+			fmt.Fprintf(out, "%s(%s): %s\n", err.Severity, err.Path, err.Message)
+		}
+	}
+	fmt.Fprintln(out)
+}
+
+// jsonChange is a single change between two Walks as written by CompareJSON.
+type jsonChange struct {
+	Path        string          `json:"path"`
+	ChangeType  string          `json:"change_type"`
+	Diff        []string        `json:"diff,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	HighEntropy bool            `json:"high_entropy,omitempty"`
+	Before      json.RawMessage `json:"before,omitempty"`
+	After       json.RawMessage `json:"after,omitempty"`
+}
+
+// marshalFileJSON encodes a File with the proto JSON encoding or returns nil if there is no File.
+func marshalFileJSON(f *fspb.File) (json.RawMessage, error) {
+	if f == nil {
+		return nil, nil
+	}
+	s, err := (&jsonpb.Marshaler{}).MarshalToString(f)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(s), nil
+}
+
+// CompareJSON is like Compare but writes the diffs as a JSON array of changes for machine consumption.
+// Each change contains the path, the change type (added, deleted, modified, retargeted, retyped or error), the list of
+// changed metadata fields as well as the before and after File entries. Added files with an entropy
+// above the configured threshold are marked with high_entropy.
+func (r *Reporter) CompareJSON(out io.Writer) error {
+	output := r.diffWalks()
+
+	changes := []jsonChange{}
+	for _, a := range []action{actionAdd, actionDelete, actionModify, actionRetarget, actionRetype, actionError} {
+		for _, file := range output.byAction(a) {
+			c := jsonChange{
+				ChangeType: strings.ToLower(string(a)),
+			}
+			switch {
+			case file.After != nil:
+				c.Path = file.After.Path
+			case file.Before != nil:
+				c.Path = file.Before.Path
+			}
+			if file.Diff != "" {
+				c.Diff = strings.Split(file.Diff, "\n")
+			}
+			if file.Err != nil {
+				c.Error = file.Err.Error()
+			}
+			if a == actionAdd && file.After.Entropy > r.entropyThreshold() {
+				c.HighEntropy = true
+			}
+			var err error
+			if c.Before, err = marshalFileJSON(file.Before); err != nil {
+				return fmt.Errorf("unable to encode %q: %v", c.Path, err)
+			}
+			if c.After, err = marshalFileJSON(file.After); err != nil {
+				return fmt.Errorf("unable to encode %q: %v", c.Path, err)
+			}
+			changes = append(changes, c)
+		}
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(changes)
+}
+
+// PrivilegeChanges returns all files of the "after" Walk on which a setuid or setgid bit
+// appeared or disappeared compared to the "before" Walk. Files which are new are reported
+// if they carry any of these bits.
+// Note that these are reported regardless of the exclusions in the report config.
+func (r *Reporter) PrivilegeChanges() []string {
+	before := NewWalkIndex(r.before)
+	var changes []string
+	for _, fa := range r.after.File {
+		if fa.Info == nil {
+			continue
+		}
+		var bm uint32
+		if fb, ok := before.Get(fa.Path); ok && fb.Info != nil {
+			bm = fb.Info.Mode
+		}
+		for _, d := range privilegeBitDiffs(bm, fa.Info.Mode) {
+			changes = append(changes, fmt.Sprintf("%s: %s", fa.Path, d))
+		}
+	}
+	return changes
+}
+
+// CapabilityAdditions lists the files of the after Walk which gained Linux capabilities, i.e.
+// added files with capabilities and files whose capabilities changed, along with the new
+// capabilities, e.g. "/usr/bin/ping: cap_net_raw=ep". Like SUID bits, these warrant attention.
+func (r *Reporter) CapabilityAdditions() []string {
+	before := NewWalkIndex(r.before)
+	var additions []string
+	for _, fa := range r.after.File {
+		if fb, _ := before.Get(fa.Path); fa.Capabilities != "" && fa.Capabilities != fb.GetCapabilities() {
+			additions = append(additions, fmt.Sprintf("%s: %s", fa.Path, fa.Capabilities))
+		}
+	}
+	return additions
+}
+
+// FailOnPrivilegeChange returns whether there are privilege changes and the report config asks
+// for them to be treated as failure regardless of other suppression rules.
+func (r *Reporter) FailOnPrivilegeChange() bool {
+	return r.config.FailOnPrivilegeChange && len(r.PrivilegeChanges()) > 0
+}
+
+// nlinkChangeCount returns the number of files not ignored by the report config whose hard
+// link count changed between the Walks.
+func (r *Reporter) nlinkChangeCount() int {
+	if r.before == nil {
+		return 0
+	}
+	after := NewWalkIndex(r.after)
+	n := 0
+	for _, fb := range r.before.File {
+		if r.isIgnored(fb.Path) {
+			continue
+		}
+		if fa, ok := after.Get(fb.Path); ok && nlinkChanged(fb.Stat, fa.Stat) {
+			n++
+		}
+	}
+	return n
+}
+
+// WalkSummary describes one of the compared Walks in a ReportSummary.
+type WalkSummary struct {
+	ID        string
+	File      string
+	Start     time.Time
+	Stop      time.Time
+	FileCount int
+	// Stats are the statistics recorded by the walker, nil for Walks of older walkers.
+	Stats *fspb.WalkSummary
+}
+
+// walkSummary describes wlk, read from file, for the report summary.
+func walkSummary(label, file string, wlk *fspb.Walk) (*WalkSummary, error) {
+	start, err := ptypes.Timestamp(wlk.StartWalk)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert %s walk start timestamp: %v", label, err)
+	}
+	stop, err := ptypes.Timestamp(wlk.StopWalk)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert %s walk stop timestamp: %v", label, err)
+	}
+	return &WalkSummary{
+		ID:        wlk.Id,
+		File:      file,
+		Start:     start,
+		Stop:      stop,
+		FileCount: wlk.FileCount(),
+		Stats:     wlk.Summary,
+	}, nil
+}
+
+// ReportSummary holds the key information pieces around the Report.
+// It is the dot value when executing the summary_template of the report config.
+type ReportSummary struct {
+	Hostname   string
+	ConfigPath string
+	// Before is nil if there is no before Walk.
+	Before *WalkSummary
+	After  *WalkSummary
+
+	// Numbers of changed files by type, as reported by Compare.
+	Added      int
+	Deleted    int
+	Modified   int
+	Retargeted int
+	Retyped    int
+	Errors     int
+
+	NlinkChanges        int
+	PrivilegeChanges    []string
+	CapabilityAdditions []string
+	// Metrics are the values recorded by the Counter so far, if any.
+	Metrics map[string]int64
+}
+
+// ChangeCount returns the number of added, deleted, modified, retargeted and retyped files.
+func (s *ReportSummary) ChangeCount() int {
+	return s.Added + s.Deleted + s.Modified + s.Retargeted + s.Retyped
+}
+
+// Summary collects the key information pieces around the Report.
+func (r *Reporter) Summary() (*ReportSummary, error) {
+	s := &ReportSummary{
+		Hostname:            r.after.Hostname,
+		ConfigPath:          r.configPath,
+		NlinkChanges:        r.nlinkChangeCount(),
+		PrivilegeChanges:    r.PrivilegeChanges(),
+		CapabilityAdditions: r.CapabilityAdditions(),
+	}
+	if r.before != nil {
+		bw, err := walkSummary("before", r.beforeFile, r.before)
+		if err != nil {
+			return nil, err
+		}
+		s.Before = bw
+	}
+	aw, err := walkSummary("after", r.afterFile, r.after)
+	if err != nil {
+		return nil, err
+	}
+	s.After = aw
+
+	// Compare on a clone without Counter so the metrics aren't counted twice. The clone keeps
+	// all filters, e.g. IgnoreMtimeOnly and known good hashes, so the counts match Compare.
+	cr := r.Clone()
+	cr.Counter = nil
+	cr.before, cr.after = r.before, r.after
+	c := cr.diffWalks()
+	s.Added = len(c.Added)
+	s.Deleted = len(c.Deleted)
+	s.Modified = len(c.Modified)
+	s.Retargeted = len(c.Retargeted)
+	s.Retyped = len(c.Retyped)
+	s.Errors = len(c.Errors)
+
+	if r.Counter != nil {
+		s.Metrics = r.Counter.Snapshot()
+	}
+	return s, nil
+}
+
+// printWalkStats prints the statistics recorded in a Walk, if any.
+func printWalkStats(out io.Writer, st *fspb.WalkSummary) {
+	if st == nil {
+		return
+	}
+	if st.PolicyName != "" {
+		fmt.Fprintf(out, "  - Policy: %s\n", st.PolicyName)
+	}
+	fmt.Fprintf(out, "  - Files: %d (%d bytes, %d hashed), Directories: %d\n", st.FileCount, st.TotalSizeBytes, st.HashCount, st.DirCount)
 }
 
 // PrintReportSummary prints a few key information pieces around the Report.
+// If the report config has a summary_template, it is used instead of the default format.
 func (r *Reporter) PrintReportSummary(out io.Writer) {
+	s, err := r.Summary()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if r.summaryTmpl != nil {
+		// Render into a buffer first so a failing template doesn't leave partial output behind.
+		var buf bytes.Buffer
+		err := r.summaryTmpl.Execute(&buf, s)
+		if err == nil {
+			out.Write(buf.Bytes())
+			return
+		}
+		log.Printf("unable to execute summary_template, using the default format: %v", err)
+	}
+
 	fmt.Fprintln(out, "===============================================================================")
 	fmt.Fprintln(out, "Report Summary:")
 	fmt.Fprintln(out, "===============================================================================")
 	fmt.Fprintln(out)
-	fmt.Fprintf(out, "Host name: %s\n", r.after.Hostname)
-	fmt.Fprintf(out, "Report config used: %s\n", r.configPath)
+	fmt.Fprintf(out, "Host name: %s\n", s.Hostname)
+	fmt.Fprintf(out, "Report config used: %s\n", s.ConfigPath)
 
-	if r.before != nil {
-		bwst, err := ptypes.Timestamp(r.before.StartWalk)
-		if err != nil {
-			log.Fatalf("unable to convert before walk start timestamp: %v", err)
+	if s.Before != nil {
+		fmt.Fprintln(out, "Walk (Before)")
+		fmt.Fprintf(out, "  - ID: %s\n", s.Before.ID)
+		fmt.Fprintf(out, "  - Start Time: %s\n", s.Before.Start)
+		fmt.Fprintf(out, "  - Stop Time: %s\n", s.Before.Stop)
+		printWalkStats(out, s.Before.Stats)
+	}
+
+	fmt.Fprintln(out, "Walk (After)")
+	fmt.Fprintf(out, "  - ID: %s\n", s.After.ID)
+	fmt.Fprintf(out, "  - Start Time: %s\n", s.After.Start)
+	fmt.Fprintf(out, "  - Stop Time: %s\n", s.After.Stop)
+	printWalkStats(out, s.After.Stats)
+	fmt.Fprintln(out)
+
+	if s.NlinkChanges > 0 {
+		fmt.Fprintf(out, "Files with changed hard link count: %d\n", s.NlinkChanges)
+		fmt.Fprintln(out)
+	}
+
+	if pc := s.PrivilegeChanges; len(pc) > 0 {
+		fmt.Fprintf(out, "Privilege bit changes (%d):\n", len(pc))
+		for _, c := range pc {
+			fmt.Fprintln(out, c)
 		}
-		bwet, err := ptypes.Timestamp(r.before.StopWalk)
-		if err != nil {
-			log.Fatalf("unable to convert before walk stop timestamp: %v", err)
+		fmt.Fprintln(out)
+	}
+
+	if ca := s.CapabilityAdditions; len(ca) > 0 {
+		fmt.Fprintf(out, "Capability additions (%d):\n", len(ca))
+		for _, c := range ca {
+			fmt.Fprintln(out, c)
 		}
-		fmt.Fprintln(out, "Walk (Before)")
-		fmt.Fprintf(out, "  - ID: %s\n", r.before.Id)
-		fmt.Fprintf(out, "  - Start Time: %s\n", bwst)
-		fmt.Fprintf(out, "  - Stop Time: %s\n", bwet)
+		fmt.Fprintln(out)
 	}
+}
 
-	awst, err := ptypes.Timestamp(r.after.StartWalk)
-	if err != nil {
-		log.Fatalf("unable to convert after walk start timestamp: %v", err)
+// RuleSummary describes the files covered by a single rule of the policy or report config.
+type RuleSummary struct {
+	// RuleName is the rule as in the policy or config, e.g. `exclude_pfx: "/tmp/"`.
+	RuleName string `json:"rule_name"`
+	// MatchCount is the number of files covered by the rule.
+	MatchCount int `json:"match_count"`
+	// FileList lists the paths of the covered files.
+	FileList []string `json:"file_list"`
+}
+
+// RuleSummaryData returns the number of files covered by each include and hash_pfx of the
+// policy of the "after" Walk and by each exclude_pfx of the report config, in this order.
+// Rules with a MatchCount of 0 had no effect. Report config excludes are matched against the
+// files of both Walks. Excludes of the policy can't be evaluated as excluded files aren't recorded.
+func (r *Reporter) RuleSummaryData() []RuleSummary {
+	if r.after == nil {
+		return nil
 	}
-	awet, err := ptypes.Timestamp(r.after.StopWalk)
-	if err != nil {
-		log.Fatalf("unable to convert after walk stop timestamp: %v", err)
+	var summaries []RuleSummary
+	collect := func(name string, files []*fspb.File, match func(string) bool) {
+		s := RuleSummary{RuleName: name, FileList: []string{}}
+		seen := map[string]bool{}
+		for _, f := range files {
+			if !seen[f.Path] && match(f.Path) {
+				seen[f.Path] = true
+				s.FileList = append(s.FileList, f.Path)
+			}
+		}
+		s.MatchCount = len(s.FileList)
+		summaries = append(summaries, s)
 	}
-	fmt.Fprintln(out, "Walk (After)")
-	fmt.Fprintf(out, "  - ID: %s\n", r.after.Id)
-	fmt.Fprintf(out, "  - Start Time: %s\n", awst)
-	fmt.Fprintf(out, "  - Stop Time: %s\n", awet)
-	fmt.Fprintln(out)
+	includes := func(field string, paths []string) {
+		for _, inc := range paths {
+			inc := path.Clean(inc)
+			collect(fmt.Sprintf("%s: %q", field, inc), r.after.File, func(p string) bool {
+				return p == inc || strings.HasPrefix(p, strings.TrimSuffix(inc, "/")+"/")
+			})
+		}
+	}
+	includes("include", r.after.GetPolicy().GetInclude())
+	var includePaths []string
+	for _, pc := range r.after.GetPolicy().GetIncludePath() {
+		includePaths = append(includePaths, pc.Path)
+	}
+	includes("include_path", includePaths)
+	for _, pfx := range r.after.GetPolicy().GetHashPfx() {
+		pfx := pfx
+		collect(fmt.Sprintf("hash_pfx: %q", pfx), r.after.File, func(p string) bool {
+			return strings.HasPrefix(p, pfx)
+		})
+	}
+	both := append(append([]*fspb.File(nil), r.before.GetFile()...), r.after.File...)
+	for _, pfx := range r.config.GetExcludePfx() {
+		pfx := pfx
+		collect(fmt.Sprintf("exclude_pfx: %q", pfx), both, func(p string) bool {
+			return strings.HasPrefix(p, pfx)
+		})
+	}
+	return summaries
 }
 
-// PrintRuleSummary prints the configs and policies involved in creating the Walk and Report.
+// PrintRuleSummaryJSON writes RuleSummaryData as a JSON array for automated rule coverage analysis.
+func (r *Reporter) PrintRuleSummaryJSON(out io.Writer) error {
+	summaries := r.RuleSummaryData()
+	if summaries == nil {
+		summaries = []RuleSummary{}
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(summaries)
+}
+
+// ExplainRule lists the files of the "after" Walk covered by the named rule of RuleSummaryData
+// along with their metadata (the DefaultCSVColumns), e.g. to find out why a rule matches
+// unexpected files. ruleName is the RuleName as printed by PrintRuleSummary, e.g.
+// `include: "/etc"`. An unknown rule is an error listing the known ones.
+func (r *Reporter) ExplainRule(ruleName string, out io.Writer) error {
+	summaries := r.RuleSummaryData()
+	var names []string
+	for _, s := range summaries {
+		if s.RuleName != ruleName {
+			names = append(names, s.RuleName)
+			continue
+		}
+		matched := map[string]bool{}
+		for _, p := range s.FileList {
+			matched[p] = true
+		}
+		var lines []string
+		for _, f := range r.after.File {
+			if !matched[f.Path] {
+				continue
+			}
+			delete(matched, f.Path) // only list each path once.
+			fields := []string{f.Path}
+			for _, c := range DefaultCSVColumns {
+				if c == "path" {
+					continue
+				}
+				v, err := csvColumns[c](f)
+				if err != nil {
+					return fmt.Errorf("unable to format %s of %q: %v", c, f.Path, err)
+				}
+				if v != "" {
+					fields = append(fields, c+"="+v)
+				}
+			}
+			lines = append(lines, strings.Join(fields, " "))
+		}
+		fmt.Fprintf(out, "Files of the after Walk covered by %s (%d):\n", ruleName, len(lines))
+		for _, l := range lines {
+			fmt.Fprintln(out, l)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown rule %q, known rules are: %s", ruleName, strings.Join(names, ", "))
+}
+
+// PrintRuleSummary prints the configs and policies involved in creating the Walk and Report
+// along with the number of files covered by each rule (see RuleSummaryData). In verbose mode,
+// the covered files are listed as well.
 func (r *Reporter) PrintRuleSummary(out io.Writer) {
 	fmt.Fprintln(out, "===============================================================================")
 	fmt.Fprintln(out, "Rule Summary:")
 	fmt.Fprintln(out, "===============================================================================")
 	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Rule Coverage:")
+	for _, s := range r.RuleSummaryData() {
+		if s.MatchCount == 0 {
+			fmt.Fprintf(out, "%s: no matches\n", s.RuleName)
+			continue
+		}
+		fmt.Fprintf(out, "%s: %d files\n", s.RuleName, s.MatchCount)
+		if r.Verbose {
+			for _, p := range s.FileList {
+				fmt.Fprintf(out, "  %s\n", p)
+			}
+		}
+	}
+	fmt.Fprintln(out)
 	fmt.Fprintln(out, "Client Policy:")
 	if r.before == nil {
 		fmt.Fprintln(out, proto.MarshalTextString(r.after.Policy))
@@ -582,6 +2152,9 @@ func (r *Reporter) PrintRuleSummary(out io.Writer) {
 
 // UpdateReviewProto updates the reviews file to the reviewed version to be "last known good".
 func (r *Reporter) UpdateReviewProto(ctx context.Context) error {
+	if r.afterFile == StdioPath {
+		return fmt.Errorf("a Walk read from stdin can't be recorded as reviewed, write it to a file first")
+	}
 	review := &fspb.Review{
 		WalkId:        r.after.Id,
 		WalkReference: r.afterFile,
@@ -592,17 +2165,17 @@ func (r *Reporter) UpdateReviewProto(ctx context.Context) error {
 			r.after.Hostname: review,
 		},
 	})
-	fmt.Println("New review section:")
+	out := r.output()
+	fmt.Fprintln(out, "New review section:")
 	// replace message boundary characters as curly braces look nicer (both is fine to parse)
-	fmt.Println(strings.Replace(strings.Replace(blob, "<", "{", -1), ">", "}", -1))
-	if r.reviewFile != "" && r.reviews != nil {
-		r.reviews.Review[r.after.Hostname] = review
-		if err := writeTextProto(ctx, r.reviewFile, r.reviews); err != nil {
+	fmt.Fprintln(out, strings.Replace(strings.Replace(blob, "<", "{", -1), ">", "}", -1))
+	if r.Reviews != nil {
+		if err := r.Reviews.saveBaseline(ctx, r.after.Hostname, r.after, r.afterFile, r.afterFp); err != nil {
 			return err
 		}
-		fmt.Printf("Changes written to %q\n", r.reviewFile)
+		fmt.Fprintf(out, "Changes written to %q\n", r.Reviews)
 	} else {
-		fmt.Println("No reviews file provided so you will have to update it manually.")
+		fmt.Fprintln(out, "No reviews file provided so you will have to update it manually.")
 	}
 	return nil
 }
@@ -15,15 +15,22 @@
 package fswalker
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/google/go-cmp/cmp"
@@ -32,6 +39,52 @@ import (
 	fspb "github.com/google/fswalker/proto/fswalker"
 )
 
+func TestReporterFromConfigBytes(t *testing.T) {
+	wantConfig := &fspb.ReportConfig{
+		Version: 1,
+		ExcludePfx: []string{
+			"/tmp/",
+			"/var/log/",
+		},
+	}
+	ctx := context.Background()
+	for _, data := range []string{
+		"version: 1\nexclude_pfx: \"/tmp/\"\nexclude_pfx: \"/var/log/\"\n",
+		`{"version": 1, "excludePfx": ["/tmp/", "/var/log/"]}`,
+	} {
+		r, err := ReporterFromConfigBytes(ctx, []byte(data), false)
+		if err != nil {
+			t.Errorf("ReporterFromConfigBytes(%q) error: %v", data, err)
+			continue
+		}
+		if diff := cmp.Diff(r.config, wantConfig); diff != "" {
+			t.Errorf("ReporterFromConfigBytes(%q) config: diff (-want +got):\n%s", data, diff)
+		}
+	}
+}
+
+func TestNewReporter(t *testing.T) {
+	ctx := context.Background()
+	cfg := &fspb.ReportConfig{Version: 1, ExcludePfx: []string{"/tmp/"}}
+	r, err := NewReporter(ctx, cfg, true)
+	if err != nil {
+		t.Fatalf("NewReporter() error: %v", err)
+	}
+	if r.config != cfg || !r.Verbose || r.Counter == nil {
+		t.Errorf("NewReporter() = %+v; want config %v, verbose and a counter", r, cfg)
+	}
+
+	var cfgErr *InvalidConfigError
+	for _, cfg := range []*fspb.ReportConfig{
+		nil,
+		{SummaryTemplate: "{{.Hostname"},
+	} {
+		if _, err := NewReporter(ctx, cfg, false); !errors.As(err, &cfgErr) {
+			t.Errorf("NewReporter(%v) error = %v; want InvalidConfigError", cfg, err)
+		}
+	}
+}
+
 func TestVerifyFingerprint(t *testing.T) {
 	testCases := []struct {
 		desc    string
@@ -207,6 +260,84 @@ func TestReadWalk(t *testing.T) {
 	}
 }
 
+func TestLoadWalksFromProtos(t *testing.T) {
+	ctx := context.Background()
+	ts1, _ := ptypes.TimestampProto(time.Now())
+	ts2, _ := ptypes.TimestampProto(time.Now().Add(time.Hour))
+	before := &fspb.Walk{
+		Id:        "before",
+		Hostname:  "host",
+		StartWalk: ts1,
+		StopWalk:  ts1,
+		File: []*fspb.File{
+			{Path: "/etc/passwd", Info: &fspb.FileInfo{Size: 1}},
+			{Path: "/etc/shadow", Info: &fspb.FileInfo{Size: 1}},
+		},
+	}
+	after := &fspb.Walk{
+		Id:        "after",
+		Hostname:  "host",
+		StartWalk: ts2,
+		StopWalk:  ts2,
+		File: []*fspb.File{
+			{Path: "/etc/passwd", Info: &fspb.FileInfo{Size: 2}},
+			{Path: "/etc/group", Info: &fspb.FileInfo{Size: 1}},
+		},
+	}
+
+	fromProtos := &Reporter{config: &fspb.ReportConfig{}}
+	if err := fromProtos.LoadWalksFromProtos(before, after); err != nil {
+		t.Fatalf("LoadWalksFromProtos() error: %v", err)
+	}
+	if fromProtos.afterFile != "" || fromProtos.afterFp != nil {
+		t.Errorf("LoadWalksFromProtos() set file %q and fingerprint %v; want none", fromProtos.afterFile, fromProtos.afterFp)
+	}
+	if err := fromProtos.LoadWalksFromProtos(after, after); err == nil {
+		t.Error("LoadWalksFromProtos() no error for Walks with the same ID")
+	}
+
+	// Loading the same Walks from files yields the same comparison.
+	tmpdir, err := ioutil.TempDir("", "walks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+	files := map[string]*fspb.Walk{"before.pb": before, "after.pb": after}
+	for name, wlk := range files {
+		b, err := proto.Marshal(wlk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(tmpdir, name), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fromFiles := &Reporter{config: &fspb.ReportConfig{}}
+	afterFile, beforeFile := filepath.Join(tmpdir, "after.pb"), filepath.Join(tmpdir, "before.pb")
+	if err := fromFiles.LoadWalks(ctx, "", "", "", afterFile, beforeFile); err != nil {
+		t.Fatalf("LoadWalks() error: %v", err)
+	}
+	if fromFiles.afterFile != afterFile || fromFiles.afterFp == nil {
+		t.Errorf("LoadWalks() set file %q and fingerprint %v; want %q and a fingerprint", fromFiles.afterFile, fromFiles.afterFp, afterFile)
+	}
+
+	want, err := fromFiles.CompareToDiff(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := fromProtos.CompareToDiff(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want.BeforeWalkReference, want.AfterWalkReference = "", ""
+	if diff := cmp.Diff(want, got, cmp.Comparer(proto.Equal)); diff != "" {
+		t.Errorf("CompareToDiff() after LoadWalksFromProtos(): diff (-want +got):\n%s", diff)
+	}
+	if len(got.FileDiff) != 3 {
+		t.Errorf("CompareToDiff() after LoadWalksFromProtos() found %d diffs; want 3", len(got.FileDiff))
+	}
+}
+
 func TestSanityCheck(t *testing.T) {
 	ts1, _ := ptypes.TimestampProto(time.Now())
 	ts2, _ := ptypes.TimestampProto(time.Now().Add(time.Hour * 10))
@@ -307,6 +438,86 @@ func TestSanityCheck(t *testing.T) {
 	}
 }
 
+func TestValidate(t *testing.T) {
+	ts1, _ := ptypes.TimestampProto(time.Now())
+	ts2, _ := ptypes.TimestampProto(time.Now().Add(time.Hour))
+	ts3, _ := ptypes.TimestampProto(time.Now().Add(time.Hour * 2))
+	files := func(n int) []*fspb.File {
+		var f []*fspb.File
+		for i := 0; i < n; i++ {
+			f = append(f, &fspb.File{Path: fmt.Sprintf("/tmp/f%d", i)})
+		}
+		return f
+	}
+	before := &fspb.Walk{Id: "before", Hostname: "host", StartWalk: ts1, StopWalk: ts1, File: files(100)}
+	testCases := []struct {
+		desc    string
+		config  *fspb.ReportConfig
+		before  *fspb.Walk
+		after   *fspb.Walk
+		wantErr bool
+	}{
+		{
+			desc:   "valid walks",
+			config: &fspb.ReportConfig{},
+			before: before,
+			after:  &fspb.Walk{Id: "after", Hostname: "host", StartWalk: ts2, StopWalk: ts3, File: files(90)},
+		}, {
+			desc:   "no before walk",
+			config: &fspb.ReportConfig{},
+			after:  &fspb.Walk{Id: "after", Hostname: "host", StartWalk: ts2, StopWalk: ts3},
+		}, {
+			desc:    "different hosts",
+			config:  &fspb.ReportConfig{},
+			before:  before,
+			after:   &fspb.Walk{Id: "after", Hostname: "other", StartWalk: ts2, StopWalk: ts3, File: files(90)},
+			wantErr: true,
+		}, {
+			desc:    "missing stop time",
+			config:  &fspb.ReportConfig{},
+			before:  before,
+			after:   &fspb.Walk{Id: "after", Hostname: "host", StartWalk: ts2, File: files(90)},
+			wantErr: true,
+		}, {
+			desc:    "stop before start",
+			config:  &fspb.ReportConfig{},
+			before:  before,
+			after:   &fspb.Walk{Id: "after", Hostname: "host", StartWalk: ts3, StopWalk: ts2, File: files(90)},
+			wantErr: true,
+		}, {
+			desc:    "file count below default ratio",
+			config:  &fspb.ReportConfig{},
+			before:  before,
+			after:   &fspb.Walk{Id: "after", Hostname: "host", StartWalk: ts2, StopWalk: ts3, File: files(9)},
+			wantErr: true,
+		}, {
+			desc:    "file count below configured ratio",
+			config:  &fspb.ReportConfig{MinFileRatio: 0.95},
+			before:  before,
+			after:   &fspb.Walk{Id: "after", Hostname: "host", StartWalk: ts2, StopWalk: ts3, File: files(90)},
+			wantErr: true,
+		},
+	}
+
+	ctx := context.Background()
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			r := &Reporter{
+				config: tc.config,
+				before: tc.before,
+				after:  tc.after,
+			}
+			err := r.Validate(ctx)
+			if tc.wantErr && err == nil {
+				t.Error("Validate() no error")
+			}
+			if !tc.wantErr && err != nil {
+				t.Errorf("Validate() error: %v", err)
+			}
+		})
+	}
+}
+
 func TestIsIgnored(t *testing.T) {
 	conf := &fspb.ReportConfig{
 		Version: 1,
@@ -350,6 +561,83 @@ func TestIsIgnored(t *testing.T) {
 	}
 }
 
+func TestFileInfoDiff(t *testing.T) {
+	base := func() *fspb.FileInfo {
+		return &fspb.FileInfo{
+			Name:     "passwd",
+			Size:     100,
+			Mode:     0644,
+			Modified: &tspb.Timestamp{Seconds: 1543831000},
+			IsDir:    false,
+		}
+	}
+	testCases := []struct {
+		desc   string
+		modify func(fi *fspb.FileInfo)
+		want   []string
+	}{
+		{
+			desc:   "equal",
+			modify: func(fi *fspb.FileInfo) {},
+		}, {
+			desc:   "name",
+			modify: func(fi *fspb.FileInfo) { fi.Name = "shadow" },
+			want:   []string{"name"},
+		}, {
+			desc:   "size",
+			modify: func(fi *fspb.FileInfo) { fi.Size = 101 },
+			want:   []string{"size"},
+		}, {
+			desc:   "mode",
+			modify: func(fi *fspb.FileInfo) { fi.Mode = 0600 },
+			want:   []string{"mode"},
+		}, {
+			desc:   "privilege bits only",
+			modify: func(fi *fspb.FileInfo) { fi.Mode |= uint32(os.ModeSetuid) },
+			want:   []string{"mode"},
+		}, {
+			desc:   "mtime",
+			modify: func(fi *fspb.FileInfo) { fi.Modified = &tspb.Timestamp{Seconds: 1543831001} },
+			want:   []string{"mtime"},
+		}, {
+			desc:   "mtime removed",
+			modify: func(fi *fspb.FileInfo) { fi.Modified = nil },
+			want:   []string{"mtime"},
+		}, {
+			desc:   "is_dir",
+			modify: func(fi *fspb.FileInfo) { fi.IsDir = true },
+			want:   []string{"is_dir"},
+		}, {
+			desc: "several fields",
+			modify: func(fi *fspb.FileInfo) {
+				fi.Size = 0
+				fi.Modified = &tspb.Timestamp{Seconds: 1543831001}
+				fi.Name = "shadow"
+			},
+			want: []string{"name", "size", "mtime"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			a, b := base(), base()
+			tc.modify(b)
+			if diff := cmp.Diff(tc.want, FileInfoDiff(a, b)); diff != "" {
+				t.Errorf("FileInfoDiff(): diff (-want +got):\n%s", diff)
+			}
+			if got, want := FileInfoEqual(a, b), len(tc.want) == 0; got != want {
+				t.Errorf("FileInfoEqual() = %t; want %t", got, want)
+			}
+		})
+	}
+
+	if !FileInfoEqual(nil, &fspb.FileInfo{}) {
+		t.Error("FileInfoEqual(nil, empty) = false; want true")
+	}
+	if diff := cmp.Diff([]string{"name", "size"}, FileInfoDiff(nil, &fspb.FileInfo{Name: "a", Size: 1})); diff != "" {
+		t.Errorf("FileInfoDiff(nil, ...): diff (-want +got):\n%s", diff)
+	}
+}
+
 func TestDiffFile(t *testing.T) {
 	testCases := []struct {
 		desc     string
@@ -433,40 +721,1811 @@ func TestDiffFile(t *testing.T) {
 			},
 			wantDiff: "ctime: 2018-12-03 09:56:40 UTC => 2018-12-04 13:43:20 UTC\nuid: 5000 => 0",
 		}, {
-			desc: "file changes version",
+			desc: "file stat changes ctime by nanoseconds",
+			before: &fspb.File{
+				Version: 1,
+				Path:    "/tmp/testfile",
+				Stat: &fspb.FileStat{
+					Ctime: &tspb.Timestamp{Seconds: 1543831000, Nanos: 100},
+				},
+			},
+			after: &fspb.File{
+				Version: 1,
+				Path:    "/tmp/testfile",
+				Stat: &fspb.FileStat{
+					Ctime: &tspb.Timestamp{Seconds: 1543831000, Nanos: 200},
+				},
+			},
+			wantDiff: "ctime: 2018-12-03 09:56:40.000000100 UTC => 2018-12-03 09:56:40.000000200 UTC",
+		}, {
+			desc: "file replaced with new birth time",
+			before: &fspb.File{
+				Version: 1,
+				Path:    "/tmp/testfile",
+				Stat: &fspb.FileStat{
+					Btime: &tspb.Timestamp{Seconds: 1543831000},
+				},
+			},
+			after: &fspb.File{
+				Version: 1,
+				Path:    "/tmp/testfile",
+				Stat: &fspb.FileStat{
+					Btime: &tspb.Timestamp{Seconds: 1543931000},
+				},
+			},
+			wantDiff: "btime: 2018-12-03 09:56:40 UTC => 2018-12-04 13:43:20 UTC (file replaced)",
+		}, {
+			desc: "birth time only recorded after",
+			before: &fspb.File{
+				Version: 1,
+				Path:    "/tmp/testfile",
+				Stat:    &fspb.FileStat{},
+			},
+			after: &fspb.File{
+				Version: 1,
+				Path:    "/tmp/testfile",
+				Stat: &fspb.FileStat{
+					Btime: &tspb.Timestamp{Seconds: 1543931000},
+				},
+			},
+			wantDiff: "",
+		}, {
+			desc: "capabilities added",
+			before: &fspb.File{
+				Version: 1,
+				Path:    "/usr/bin/ping",
+			},
+			after: &fspb.File{
+				Version:      1,
+				Path:         "/usr/bin/ping",
+				Capabilities: "cap_net_raw=ep",
+			},
+			wantDiff: `capabilities: "" => "cap_net_raw=ep"`,
+		}, {
+			desc: "mtime without nanoseconds compared to the second",
 			before: &fspb.File{
 				Version: 1,
 				Path:    "/tmp/testfile",
 				Info: &fspb.FileInfo{
-					Size: 1000,
-					Mode: 644,
+					Modified: &tspb.Timestamp{Seconds: 1543831000},
 				},
 			},
 			after: &fspb.File{
-				Version: 2,
+				Version: 1,
 				Path:    "/tmp/testfile",
 				Info: &fspb.FileInfo{
-					Size: 1000,
-					Mode: 644,
+					Modified: &tspb.Timestamp{Seconds: 1543831000, Nanos: 123456789},
 				},
 			},
-			wantErr: true,
-		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.desc, func(t *testing.T) {
-			r := &Reporter{}
-			gotDiff, err := r.diffFile(tc.before, tc.after)
-			switch {
-			case tc.wantErr && err == nil:
-				t.Error("diffFile() no error")
-			case !tc.wantErr && err != nil:
-				t.Errorf("diffFile() error: %v", err)
-			default:
-				if gotDiff != tc.wantDiff {
-					t.Errorf("diffFile() diff: got=%s, want=%s", gotDiff, tc.wantDiff)
-				}
+			wantDiff: "",
+		}, {
+			desc: "owner changes with names",
+			before: &fspb.File{
+				Version:   1,
+				Path:      "/tmp/testfile",
+				Stat:      &fspb.FileStat{Uid: 1000, Gid: 1000},
+				OwnerName: "alice",
+				GroupName: "alice",
+			},
+			after: &fspb.File{
+				Version:   1,
+				Path:      "/tmp/testfile",
+				Stat:      &fspb.FileStat{Uid: 0, Gid: 1000},
+				OwnerName: "root",
+				GroupName: "alice",
+			},
+			wantDiff: "uid: 1000 (alice) => 0 (root)",
+		}, {
+			desc: "same IDs resolve to different names",
+			before: &fspb.File{
+				Version:   1,
+				Path:      "/tmp/testfile",
+				Stat:      &fspb.FileStat{Uid: 1001, Gid: 1001},
+				OwnerName: "alice",
+				GroupName: "alice",
+			},
+			after: &fspb.File{
+				Version:   1,
+				Path:      "/tmp/testfile",
+				Stat:      &fspb.FileStat{Uid: 1001, Gid: 1001},
+				OwnerName: "bob",
+				GroupName: "bob",
+			},
+			wantDiff: "group: alice => bob (gid 1001)\nowner: alice => bob (uid 1001)",
+		}, {
+			desc: "unknown name after",
+			before: &fspb.File{
+				Version:   1,
+				Path:      "/tmp/testfile",
+				Stat:      &fspb.FileStat{Uid: 1001},
+				OwnerName: "alice",
+			},
+			after: &fspb.File{
+				Version: 1,
+				Path:    "/tmp/testfile",
+				Stat:    &fspb.FileStat{Uid: 1001},
+			},
+			wantDiff: "",
+		}, {
+			desc: "file info changes privilege bits",
+			before: &fspb.File{
+				Version: 1,
+				Path:    "/tmp/testfile",
+				Info: &fspb.FileInfo{
+					Size:     1000,
+					Mode:     uint32(os.ModeSetgid | 0755),
+					Modified: &tspb.Timestamp{},
+				},
+			},
+			after: &fspb.File{
+				Version: 1,
+				Path:    "/tmp/testfile",
+				Info: &fspb.FileInfo{
+					Size:     1000,
+					Mode:     uint32(os.ModeSetuid | 0755),
+					Modified: &tspb.Timestamp{},
+				},
+			},
+			wantDiff: "privileges: setgid removed\nprivileges: setuid added",
+		}, {
+			desc: "hard link count changes",
+			before: &fspb.File{
+				Version: 1,
+				Path:    "/usr/bin/passwd",
+				Stat:    &fspb.FileStat{Mode: syscall.S_IFREG | 0755, Nlink: 1},
+			},
+			after: &fspb.File{
+				Version: 1,
+				Path:    "/usr/bin/passwd",
+				Stat:    &fspb.FileStat{Mode: syscall.S_IFREG | 0755, Nlink: 2},
+			},
+			wantDiff: "nlink: 1 => 2",
+		}, {
+			desc: "directory link count changes",
+			before: &fspb.File{
+				Version: 1,
+				Path:    "/etc",
+				Stat:    &fspb.FileStat{Mode: syscall.S_IFDIR | 0755, Nlink: 10},
+			},
+			after: &fspb.File{
+				Version: 1,
+				Path:    "/etc",
+				Stat:    &fspb.FileStat{Mode: syscall.S_IFDIR | 0755, Nlink: 11},
+			},
+			wantDiff: "",
+		}, {
+			desc: "symlink target changes",
+			before: &fspb.File{
+				Version:       1,
+				Path:          "/etc/alternatives/java",
+				SymlinkTarget: "/usr/lib/jvm/java-8/bin/java",
+			},
+			after: &fspb.File{
+				Version:       1,
+				Path:          "/etc/alternatives/java",
+				SymlinkTarget: "/usr/lib/jvm/java-11/bin/java",
+			},
+			wantDiff: `symlink target: "/usr/lib/jvm/java-8/bin/java" => "/usr/lib/jvm/java-11/bin/java"`,
+		}, {
+			desc:     "mime type changes",
+			before:   &fspb.File{Version: 1, Path: "/usr/bin/tool", MimeType: "text/plain; charset=utf-8"},
+			after:    &fspb.File{Version: 1, Path: "/usr/bin/tool", MimeType: "application/octet-stream"},
+			wantDiff: `mime type: "text/plain; charset=utf-8" => "application/octet-stream"`,
+		}, {
+			desc:     "mime type newly detected",
+			before:   &fspb.File{Version: 1, Path: "/usr/bin/tool"},
+			after:    &fspb.File{Version: 1, Path: "/usr/bin/tool", MimeType: "application/octet-stream"},
+			wantDiff: "",
+		}, {
+			desc: "fingerprint method changes",
+			before: &fspb.File{
+				Version: 1,
+				Path:    "/tmp/testfile",
+				Fingerprint: []*fspb.Fingerprint{
+					{
+						Method: fspb.Fingerprint_SHA256,
+						Value:  "deadbeef",
+					},
+				},
+			},
+			after: &fspb.File{
+				Version: 1,
+				Path:    "/tmp/testfile",
+				Fingerprint: []*fspb.Fingerprint{
+					{
+						Method: fspb.Fingerprint_SHA512,
+						Value:  "beefdead",
+					},
+				},
+			},
+			wantDiff: "fingerprint method: SHA256 => SHA512 (content not compared)",
+		}, {
+			desc: "extended attributes change",
+			before: &fspb.File{
+				Version: 1,
+				Path:    "/tmp/testfile",
+				Xattrs: map[string][]byte{
+					"security.selinux": []byte("system_u:object_r:etc_t:s0"),
+					"user.removed":     []byte("value"),
+				},
+			},
+			after: &fspb.File{
+				Version: 1,
+				Path:    "/tmp/testfile",
+				Xattrs: map[string][]byte{
+					"security.selinux": []byte("system_u:object_r:shadow_t:s0"),
+					"user.added":       []byte("value"),
+				},
+			},
+			wantDiff: "xattr added: user.added: \"value\"\nxattr changed: security.selinux: \"system_u:object_r:etc_t:s0\" => \"system_u:object_r:shadow_t:s0\"\nxattr removed: user.removed",
+		}, {
+			desc: "file changes version",
+			before: &fspb.File{
+				Version: 1,
+				Path:    "/tmp/testfile",
+				Info: &fspb.FileInfo{
+					Size: 1000,
+					Mode: 644,
+				},
+			},
+			after: &fspb.File{
+				Version: 2,
+				Path:    "/tmp/testfile",
+				Info: &fspb.FileInfo{
+					Size: 1000,
+					Mode: 644,
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			r := &Reporter{}
+			gotDiff, err := r.diffFile(tc.before, tc.after)
+			switch {
+			case tc.wantErr && err == nil:
+				t.Error("diffFile() no error")
+			case !tc.wantErr && err != nil:
+				t.Errorf("diffFile() error: %v", err)
+			default:
+				if gotDiff != tc.wantDiff {
+					t.Errorf("diffFile() diff: got=%s, want=%s", gotDiff, tc.wantDiff)
+				}
+			}
+		})
+	}
+}
+
+func TestCapabilityAdditions(t *testing.T) {
+	ts, _ := ptypes.TimestampProto(time.Now())
+	r := &Reporter{
+		config: &fspb.ReportConfig{},
+		before: &fspb.Walk{
+			StartWalk: ts,
+			StopWalk:  ts,
+			File: []*fspb.File{
+				{Version: 1, Path: "/usr/bin/ping", Capabilities: "cap_net_raw=ep"},
+				{Version: 1, Path: "/usr/bin/mtr", Capabilities: "cap_net_raw=ep"},
+				{Version: 1, Path: "/usr/bin/arping", Capabilities: "cap_net_raw=ep"},
+			},
+		},
+		after: &fspb.Walk{
+			StartWalk: ts,
+			StopWalk:  ts,
+			File: []*fspb.File{
+				{Version: 1, Path: "/usr/bin/ping", Capabilities: "cap_net_raw=ep"},
+				{Version: 1, Path: "/usr/bin/mtr", Capabilities: "cap_net_admin,cap_net_raw=ep"},
+				{Version: 1, Path: "/usr/bin/arping"},
+				{Version: 1, Path: "/tmp/python", Capabilities: "cap_setuid=ep"},
+			},
+		},
+	}
+	want := []string{
+		"/usr/bin/mtr: cap_net_admin,cap_net_raw=ep",
+		"/tmp/python: cap_setuid=ep",
+	}
+	if diff := cmp.Diff(want, r.CapabilityAdditions()); diff != "" {
+		t.Errorf("CapabilityAdditions(): diff (-want +got):\n%s", diff)
+	}
+
+	var buf bytes.Buffer
+	r.PrintReportSummary(&buf)
+	if want := "Capability additions (2):\n/usr/bin/mtr: cap_net_admin,cap_net_raw=ep\n/tmp/python: cap_setuid=ep\n"; !strings.Contains(buf.String(), want) {
+		t.Errorf("PrintReportSummary() output doesn't contain %q:\n%s", want, buf.String())
+	}
+}
+
+func TestPrivilegeChanges(t *testing.T) {
+	r := &Reporter{
+		config: &fspb.ReportConfig{
+			ExcludePfx:            []string{"/usr/bin/"},
+			FailOnPrivilegeChange: true,
+		},
+		before: &fspb.Walk{
+			File: []*fspb.File{
+				{Path: "/usr/bin/passwd", Info: &fspb.FileInfo{Mode: 0755}},
+				{Path: "/usr/bin/wall", Info: &fspb.FileInfo{Mode: uint32(os.ModeSetgid | 0755)}},
+				{Path: "/usr/bin/sudo", Info: &fspb.FileInfo{Mode: uint32(os.ModeSetuid | 0755)}},
+			},
+		},
+		after: &fspb.Walk{
+			File: []*fspb.File{
+				{Path: "/usr/bin/passwd", Info: &fspb.FileInfo{Mode: uint32(os.ModeSetuid | 0755)}},
+				{Path: "/usr/bin/wall", Info: &fspb.FileInfo{Mode: 0755}},
+				{Path: "/usr/bin/sudo", Info: &fspb.FileInfo{Mode: uint32(os.ModeSetuid | 0700)}},
+				{Path: "/tmp/shell", Info: &fspb.FileInfo{Mode: uint32(os.ModeSetuid | os.ModeSetgid | 0755)}},
+				{Path: "/tmp/file", Info: &fspb.FileInfo{Mode: 0644}},
+			},
+		},
+	}
+	want := []string{
+		"/usr/bin/passwd: setuid added",
+		"/usr/bin/wall: setgid removed",
+		"/tmp/shell: setuid added",
+		"/tmp/shell: setgid added",
+	}
+	if diff := cmp.Diff(want, r.PrivilegeChanges()); diff != "" {
+		t.Errorf("PrivilegeChanges(): diff (-want +got):\n%s", diff)
+	}
+	if !r.FailOnPrivilegeChange() {
+		t.Error("FailOnPrivilegeChange() = false, want true")
+	}
+	r.config.FailOnPrivilegeChange = false
+	if r.FailOnPrivilegeChange() {
+		t.Error("FailOnPrivilegeChange() = true with fail_on_privilege_change unset, want false")
+	}
+}
+
+func TestWalksSince(t *testing.T) {
+	names := []string{
+		"/tmp/host-20181204-070000-fswalker-state.pb",
+		"/tmp/host-20181205-070000-fswalker-state.pb",
+		"/tmp/host-20181206-070000-fswalker-state.json.gz",
+		"/tmp/host-invalid-fswalker-state.pb",
+	}
+	cutoff := time.Date(2018, 12, 5, 7, 0, 0, 0, time.Local)
+	want := []string{
+		"/tmp/host-20181205-070000-fswalker-state.pb",
+		"/tmp/host-20181206-070000-fswalker-state.json.gz",
+	}
+	if diff := cmp.Diff(want, walksSince("host", "", names, cutoff)); diff != "" {
+		t.Errorf("walksSince(): diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoadLatestWalkSince(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "walks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+
+	old := filepath.Join(tmpdir, WalkFilename("host", time.Now().Add(-48*time.Hour)))
+	if err := ioutil.WriteFile(old, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := &Reporter{Since: 24 * time.Hour}
+	if _, _, _, err := r.loadLatestWalk(ctx, "host", tmpdir); err == nil {
+		t.Error("loadLatestWalk() no error for Walk older than Since")
+	}
+
+	recent := filepath.Join(tmpdir, WalkFilename("host", time.Now().Add(-time.Hour)))
+	if err := ioutil.WriteFile(recent, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, _, _, err := r.loadLatestWalk(ctx, "host", tmpdir)
+	if err != nil {
+		t.Fatalf("loadLatestWalk() error: %v", err)
+	}
+	if got != recent {
+		t.Errorf("loadLatestWalk() = %q; want: %q", got, recent)
+	}
+}
+
+func TestLoadWalksByTimeRange(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	store := memWalkStore{}
+	var names []string
+	for i := 4; i > 0; i-- {
+		wt := now.Add(-time.Duration(i) * 24 * time.Hour)
+		ts, _ := ptypes.TimestampProto(wt)
+		b, err := marshalWalk(&fspb.Walk{Id: fmt.Sprintf("walk-%d", i), Version: 1, Hostname: "host", StartWalk: ts, StopWalk: ts}, OutputFormatProto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := "/walks/" + WalkFilename("host", wt)
+		store[n] = b
+		names = append(names, n)
+	}
+	store["/walks/"+WalkFilename("other", now)] = nil
+
+	r := &Reporter{Store: store}
+	got, err := r.LoadWalksByTimeRange(ctx, "host", "/walks", now.Add(-84*time.Hour), now.Add(-36*time.Hour))
+	if err != nil {
+		t.Fatalf("LoadWalksByTimeRange() error: %v", err)
+	}
+	if diff := cmp.Diff(names[1:3], got); diff != "" {
+		t.Errorf("LoadWalksByTimeRange(): diff (-want +got):\n%s", diff)
+	}
+	if r.before.Id != "walk-3" || r.after.Id != "walk-2" {
+		t.Errorf("LoadWalksByTimeRange() loaded %q and %q; want %q and %q", r.before.Id, r.after.Id, "walk-3", "walk-2")
+	}
+
+	got, err = r.LoadWalksByTimeRange(ctx, "host", "/walks", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("LoadWalksByTimeRange() of an open range error: %v", err)
+	}
+	if diff := cmp.Diff(names, got); diff != "" {
+		t.Errorf("LoadWalksByTimeRange() of an open range: diff (-want +got):\n%s", diff)
+	}
+
+	for _, tc := range []struct {
+		desc       string
+		host       string
+		start, end time.Time
+	}{
+		{desc: "no hostname", start: now.Add(-100 * time.Hour)},
+		{desc: "end before start", host: "host", start: now, end: now.Add(-100 * time.Hour)},
+		{desc: "single Walk in range", host: "host", start: now.Add(-30 * time.Hour)},
+		{desc: "no Walks in range", host: "host", end: now.Add(-200 * time.Hour)},
+	} {
+		if _, err := r.LoadWalksByTimeRange(ctx, tc.host, "/walks", tc.start, tc.end); err == nil {
+			t.Errorf("LoadWalksByTimeRange() with %s succeeded; want error", tc.desc)
+		}
+	}
+}
+
+func TestLoadLatestWalkGlob(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "walks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+
+	now := time.Now()
+	files := []string{
+		filepath.Join("dc1", WalkFilename("host", now.Add(-time.Hour))),
+		filepath.Join("dc2", WalkFilename("host", now.Add(-2*time.Hour))),
+		filepath.Join("dc2", WalkFilename("other", now)),
+	}
+	for _, f := range files {
+		p := filepath.Join(tmpdir, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Files matching the pattern are not searched.
+	if err := ioutil.WriteFile(filepath.Join(tmpdir, "dc3"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pattern := filepath.Join(tmpdir, "dc*") + "/"
+	r := &Reporter{}
+	got, _, _, err := r.loadLatestWalk(ctx, "host", pattern)
+	if err != nil {
+		t.Fatalf("loadLatestWalk() error: %v", err)
+	}
+	if want := filepath.Join(tmpdir, files[0]); got != want {
+		t.Errorf("loadLatestWalk() = %q; want: %q", got, want)
+	}
+
+	hosts, err := WalkHosts(ctx, pattern)
+	if err != nil {
+		t.Fatalf("WalkHosts() error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"host", "other"}, hosts); diff != "" {
+		t.Errorf("WalkHosts(): diff (-want +got):\n%s", diff)
+	}
+
+	r = &Reporter{Store: memWalkStore{}}
+	if _, _, _, err := r.loadLatestWalk(ctx, "host", "/walks/*/"); err == nil {
+		t.Error("loadLatestWalk() of a pattern with a store other than the local file system succeeded; want error")
+	}
+}
+
+func TestLoadLatestWalkRecursive(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "walks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+
+	var want string
+	for _, d := range []time.Duration{2 * time.Hour, time.Hour} {
+		ts := time.Now().Add(-d)
+		dir, err := OutputDir("{host}/{year}/{month}/{day}", "host", ts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Join(tmpdir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		want = filepath.Join(tmpdir, dir, WalkFilename("host", ts))
+		if err := ioutil.WriteFile(want, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := &Reporter{}
+	if _, _, _, err := r.loadLatestWalk(ctx, "host", tmpdir); err == nil {
+		t.Error("loadLatestWalk() found a Walk in a subdirectory without Recursive")
+	}
+	r.Recursive = true
+	got, _, _, err := r.loadLatestWalk(ctx, "host", tmpdir)
+	if err != nil {
+		t.Fatalf("loadLatestWalk() error: %v", err)
+	}
+	if got != want {
+		t.Errorf("loadLatestWalk() = %q; want: %q", got, want)
+	}
+
+	hosts, err := WalkHostsRecursive(ctx, tmpdir)
+	if err != nil {
+		t.Fatalf("WalkHostsRecursive() error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"host"}, hosts); diff != "" {
+		t.Errorf("WalkHostsRecursive(): diff (-want +got):\n%s", diff)
+	}
+
+	r = &Reporter{Recursive: true, Store: memWalkStore{}}
+	if _, _, _, err := r.loadLatestWalk(ctx, "host", "/walks"); err == nil {
+		t.Error("loadLatestWalk() with a store which can't list subdirectories succeeded; want error")
+	}
+}
+
+func TestReportSummaryChangeCount(t *testing.T) {
+	s := &ReportSummary{Added: 1, Deleted: 2, Modified: 3, Retargeted: 4, Retyped: 5, Errors: 6, NlinkChanges: 7}
+	if got, want := s.ChangeCount(), 15; got != want {
+		t.Errorf("ChangeCount() = %d; want %d", got, want)
+	}
+}
+
+func TestSummaryMatchesCompare(t *testing.T) {
+	ts, _ := ptypes.TimestampProto(time.Now())
+	mtime := func(sec int64) *fspb.FileInfo {
+		return &fspb.FileInfo{Modified: &tspb.Timestamp{Seconds: sec}}
+	}
+	testCases := []struct {
+		desc      string
+		reporter  func(r *Reporter)
+		before    []*fspb.File
+		after     []*fspb.File
+		wantCount int
+	}{
+		{
+			desc:      "no filters",
+			reporter:  func(r *Reporter) {},
+			before:    []*fspb.File{{Version: 1, Path: "/var/log/syslog", Info: mtime(1)}},
+			after:     []*fspb.File{{Version: 1, Path: "/var/log/syslog", Info: mtime(2)}},
+			wantCount: 1,
+		}, {
+			desc:     "ignore mtime only",
+			reporter: func(r *Reporter) { r.IgnoreMtimeOnly = true },
+			before:   []*fspb.File{{Version: 1, Path: "/var/log/syslog", Info: mtime(1)}},
+			after:    []*fspb.File{{Version: 1, Path: "/var/log/syslog", Info: mtime(2)}},
+		}, {
+			desc:     "ignore mtime only keeps content changes",
+			reporter: func(r *Reporter) { r.IgnoreMtimeOnly = true },
+			before: []*fspb.File{
+				{Version: 1, Path: "/var/log/syslog", Info: mtime(1)},
+				{Version: 1, Path: "/etc/hosts", Info: &fspb.FileInfo{Size: 1, Modified: &tspb.Timestamp{Seconds: 1}}},
+			},
+			after: []*fspb.File{
+				{Version: 1, Path: "/var/log/syslog", Info: mtime(2)},
+				{Version: 1, Path: "/etc/hosts", Info: &fspb.FileInfo{Size: 2, Modified: &tspb.Timestamp{Seconds: 2}}},
+			},
+			wantCount: 1,
+		}, {
+			desc: "change type filter",
+			reporter: func(r *Reporter) {
+				r.ChangeTypeFilter = []fspb.ReportConfig_ChangeType{fspb.ReportConfig_PERMISSION_CHANGED}
+			},
+			before: []*fspb.File{{Version: 1, Path: "/etc/passwd", Stat: &fspb.FileStat{Mode: syscall.S_IFREG, Uid: 0}}},
+			after: []*fspb.File{
+				{Version: 1, Path: "/etc/passwd", Stat: &fspb.FileStat{Mode: syscall.S_IFREG, Uid: 1000}},
+				{Version: 1, Path: "/etc/new"},
+			},
+		}, {
+			desc: "change type filter overrides config",
+			reporter: func(r *Reporter) {
+				r.config.ChangeTypeFilter = []fspb.ReportConfig_ChangeType{fspb.ReportConfig_OWNER_CHANGED}
+				r.ChangeTypeFilter = []fspb.ReportConfig_ChangeType{fspb.ReportConfig_PERMISSION_CHANGED}
+			},
+			before: []*fspb.File{
+				{Version: 1, Path: "/etc/passwd", Stat: &fspb.FileStat{Mode: syscall.S_IFREG, Uid: 0}},
+				{Version: 1, Path: "/etc/shadow", Info: &fspb.FileInfo{Mode: 0640}},
+			},
+			after: []*fspb.File{
+				{Version: 1, Path: "/etc/passwd", Stat: &fspb.FileStat{Mode: syscall.S_IFREG, Uid: 1000}},
+				{Version: 1, Path: "/etc/shadow", Info: &fspb.FileInfo{Mode: 0644}},
+			},
+			wantCount: 1,
+		}, {
+			desc: "known good hashes",
+			reporter: func(r *Reporter) {
+				r.knownGoodHashes = map[string]string{"/usr/bin/tool": "aa", "/usr/bin/new": "bb"}
+			},
+			before: []*fspb.File{{Version: 1, Path: "/usr/bin/tool", Fingerprint: []*fspb.Fingerprint{{Method: fspb.Fingerprint_SHA256, Value: "00"}}}},
+			after: []*fspb.File{
+				{Version: 1, Path: "/usr/bin/tool", Fingerprint: []*fspb.Fingerprint{{Method: fspb.Fingerprint_SHA256, Value: "aa"}}},
+				{Version: 1, Path: "/usr/bin/new", Fingerprint: []*fspb.Fingerprint{{Method: fspb.Fingerprint_SHA256, Value: "bb"}}},
+				{Version: 1, Path: "/usr/bin/other", Fingerprint: []*fspb.Fingerprint{{Method: fspb.Fingerprint_SHA256, Value: "cc"}}},
+			},
+			wantCount: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			r := &Reporter{
+				config: &fspb.ReportConfig{},
+				before: &fspb.Walk{StartWalk: ts, StopWalk: ts, File: tc.before},
+				after:  &fspb.Walk{StartWalk: ts, StopWalk: ts, File: tc.after},
+			}
+			tc.reporter(r)
+			s, err := r.Summary()
+			if err != nil {
+				t.Fatalf("Summary() error: %v", err)
+			}
+			r.Compare(ioutil.Discard)
+			if got := s.ChangeCount(); got != tc.wantCount || got != r.ChangeCount() {
+				t.Errorf("Summary().ChangeCount() = %d; want %d, the ChangeCount() of Compare() is %d", got, tc.wantCount, r.ChangeCount())
+			}
+		})
+	}
+}
+
+func TestPrintReportSummaryNlink(t *testing.T) {
+	ts, _ := ptypes.TimestampProto(time.Now())
+	r := &Reporter{
+		config: &fspb.ReportConfig{ExcludePfx: []string{"/tmp/"}},
+		before: &fspb.Walk{
+			StartWalk: ts,
+			StopWalk:  ts,
+			File: []*fspb.File{
+				{Path: "/etc/shadow", Stat: &fspb.FileStat{Mode: syscall.S_IFREG, Nlink: 1}},
+				{Path: "/etc/passwd", Stat: &fspb.FileStat{Mode: syscall.S_IFREG, Nlink: 1}},
+				{Path: "/tmp/file", Stat: &fspb.FileStat{Mode: syscall.S_IFREG, Nlink: 1}},
+			},
+		},
+		after: &fspb.Walk{
+			StartWalk: ts,
+			StopWalk:  ts,
+			File: []*fspb.File{
+				{Path: "/etc/shadow", Stat: &fspb.FileStat{Mode: syscall.S_IFREG, Nlink: 2}},
+				{Path: "/etc/passwd", Stat: &fspb.FileStat{Mode: syscall.S_IFREG, Nlink: 1}},
+				{Path: "/tmp/file", Stat: &fspb.FileStat{Mode: syscall.S_IFREG, Nlink: 2}},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	r.PrintReportSummary(&buf)
+	if want := "Files with changed hard link count: 1\n"; !strings.Contains(buf.String(), want) {
+		t.Errorf("PrintReportSummary() output doesn't contain %q:\n%s", want, buf.String())
+	}
+}
+
+func TestPrintReportSummaryWalkStats(t *testing.T) {
+	ts, _ := ptypes.TimestampProto(time.Now())
+	r := &Reporter{
+		config: &fspb.ReportConfig{},
+		before: &fspb.Walk{
+			StartWalk: ts,
+			StopWalk:  ts,
+			File:      []*fspb.File{{Path: "/etc/passwd"}},
+		},
+		after: &fspb.Walk{
+			StartWalk: ts,
+			StopWalk:  ts,
+			File:      []*fspb.File{{Path: "/etc/passwd"}},
+			Summary:   &fspb.WalkSummary{FileCount: 10, DirCount: 2, TotalSizeBytes: 4096, HashCount: 3, PolicyName: "/etc/fswalker/policy.asciipb"},
+		},
+	}
+	var buf bytes.Buffer
+	r.PrintReportSummary(&buf)
+	for _, want := range []string{
+		"Walk (After)\n  - ID: \n  - Start Time: ",
+		"  - Policy: /etc/fswalker/policy.asciipb\n  - Files: 10 (4096 bytes, 3 hashed), Directories: 2\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("PrintReportSummary() output doesn't contain %q:\n%s", want, buf.String())
+		}
+	}
+	// The before Walk was written by an older walker without summary.
+	if n := strings.Count(buf.String(), "  - Files: "); n != 1 {
+		t.Errorf("PrintReportSummary() printed %d Walk statistics; want 1:\n%s", n, buf.String())
+	}
+}
+
+func TestSetOutput(t *testing.T) {
+	ts, _ := ptypes.TimestampProto(time.Now())
+	r := &Reporter{
+		config: &fspb.ReportConfig{},
+		before: &fspb.Walk{
+			StartWalk: ts,
+			StopWalk:  ts,
+			File:      []*fspb.File{{Path: "/etc/passwd"}},
+		},
+		after: &fspb.Walk{
+			StartWalk: ts,
+			StopWalk:  ts,
+			File:      []*fspb.File{{Path: "/etc/passwd"}, {Path: "/etc/shadow"}},
+		},
+	}
+	var want bytes.Buffer
+	r.Compare(&want)
+	r.PrintReportSummary(&want)
+	r.PrintRuleSummary(&want)
+
+	var got bytes.Buffer
+	r.SetOutput(&got)
+	r.CompareToOutput()
+	r.PrintReportSummaryToOutput()
+	r.PrintRuleSummaryToOutput()
+	if diff := cmp.Diff(want.String(), got.String()); diff != "" {
+		t.Errorf("...ToOutput(): diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestCloneConcurrentHosts(t *testing.T) {
+	ctx := context.Background()
+	const numHosts = 10
+	now := time.Now()
+	store := memWalkStore{}
+	reviews := &memReviewStore{reviews: &fspb.Reviews{Review: map[string]*fspb.Review{}}}
+	var hosts []string
+	for i := 0; i < numHosts; i++ {
+		host := fmt.Sprintf("host%d", i)
+		hosts = append(hosts, host)
+		for j, d := range []time.Duration{2 * time.Hour, time.Hour} {
+			ts, _ := ptypes.TimestampProto(now.Add(-d))
+			wlk := &fspb.Walk{
+				Id:        fmt.Sprintf("%s-%d", host, j),
+				Version:   1,
+				Hostname:  host,
+				StartWalk: ts,
+				StopWalk:  ts,
+				File:      []*fspb.File{{Path: "/etc/passwd"}},
+			}
+			if j > 0 {
+				wlk.File = append(wlk.File, &fspb.File{Path: "/etc/" + host})
+			}
+			b, err := marshalWalk(wlk, OutputFormatProto)
+			if err != nil {
+				t.Fatal(err)
+			}
+			name := "/walks/" + WalkFilename(host, now.Add(-d))
+			store[name] = b
+			if j == 0 {
+				reviews.reviews.Review[host] = &fspb.Review{WalkId: wlk.Id, WalkReference: name, Fingerprint: (&Reporter{}).fingerprint(b)}
+			}
+		}
+	}
+
+	r, err := NewReporter(ctx, &fspb.ReportConfig{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Store = store
+	r.Reviews = NewReviewManager(reviews)
+	r.PrefixHostname = true
+
+	outs := make([]bytes.Buffer, numHosts)
+	errs := make([]error, numHosts)
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			c := r.Clone()
+			c.SetOutput(&outs[i])
+			if errs[i] = c.LoadWalks(ctx, host, "", "/walks", "", ""); errs[i] != nil {
+				return
+			}
+			c.CompareToOutput()
+			errs[i] = c.UpdateReviewProto(ctx)
+		}(i, host)
+	}
+	wg.Wait()
+
+	for i, host := range hosts {
+		if errs[i] != nil {
+			t.Errorf("reporting on %s error: %v", host, errs[i])
+			continue
+		}
+		out := outs[i].String()
+		if !strings.Contains(out, "/etc/"+host+"\n") {
+			t.Errorf("output of %s doesn't contain its added file:\n%s", host, out)
+		}
+		for _, other := range hosts {
+			if other != host && strings.Contains(out, "/etc/"+other+"\n") {
+				t.Errorf("output of %s contains the added file of %s:\n%s", host, other, out)
+			}
+		}
+		if got, want := reviews.reviews.Review[host].GetWalkId(), host+"-1"; got != want {
+			t.Errorf("review of %s = %q; want %q", host, got, want)
+		}
+	}
+	for metric, want := range map[string]int64{"before-files": numHosts, "after-files": 2 * numHosts, "after-files-created": numHosts} {
+		if got, _ := r.Counter.Get(metric); got != want {
+			t.Errorf("Counter.Get(%q) = %d; want %d", metric, got, want)
+		}
+	}
+}
+
+func TestPrintReportSummaryTemplate(t *testing.T) {
+	ctx := context.Background()
+	ts, _ := ptypes.TimestampProto(time.Unix(1546300800, 0).UTC())
+	before := &fspb.Walk{
+		Id:        "before-walk",
+		StartWalk: ts,
+		StopWalk:  ts,
+		File: []*fspb.File{
+			{Path: "/etc/modified", Info: &fspb.FileInfo{Size: 1}},
+			{Path: "/etc/deleted", Info: &fspb.FileInfo{Size: 1}},
+		},
+	}
+	after := &fspb.Walk{
+		Id:        "after-walk",
+		Hostname:  "host",
+		StartWalk: ts,
+		StopWalk:  ts,
+		File: []*fspb.File{
+			{Path: "/etc/modified", Info: &fspb.FileInfo{Size: 2}},
+			{Path: "/etc/added", Info: &fspb.FileInfo{Size: 1}},
+		},
+	}
+
+	testCases := []struct {
+		desc     string
+		template string
+		want     string
+	}{
+		{
+			desc:     "custom template",
+			template: `{{.Hostname}} {{.Before.ID}}..{{.After.ID}} +{{.Added}} -{{.Deleted}} ~{{.Modified}} {{.After.Start.Year}}`,
+			want:     "host before-walk..after-walk +1 -1 ~1 2019",
+		},
+		{
+			desc:     "missing field falls back to default",
+			template: `{{.NoSuchField}}`,
+			want:     "Report Summary:",
+		},
+		{
+			desc: "no template",
+			want: "Host name: host\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			cfg := fmt.Sprintf("summary_template: %q", tc.template)
+			r, err := ReporterFromConfigBytes(ctx, []byte(cfg), false)
+			if err != nil {
+				t.Fatalf("ReporterFromConfigBytes() error: %v", err)
+			}
+			r.before, r.after = before, after
+			var buf bytes.Buffer
+			r.PrintReportSummary(&buf)
+			if !strings.Contains(buf.String(), tc.want) {
+				t.Errorf("PrintReportSummary() output doesn't contain %q:\n%s", tc.want, buf.String())
+			}
+		})
+	}
+
+	if _, err := ReporterFromConfigBytes(ctx, []byte(`summary_template: "{{.Hostname"`), false); err == nil {
+		t.Error("ReporterFromConfigBytes() no error for invalid summary_template")
+	}
+}
+
+func TestRuleSummaryData(t *testing.T) {
+	r := &Reporter{
+		config: &fspb.ReportConfig{ExcludePfx: []string{"/tmp/", "/home/"}},
+		before: &fspb.Walk{
+			File: []*fspb.File{{Path: "/etc/passwd"}, {Path: "/tmp/old"}},
+		},
+		after: &fspb.Walk{
+			Policy: &fspb.Policy{
+				Include: []string{"/etc/", "/opt"},
+				HashPfx: []string{"/etc/pass"},
+			},
+			File: []*fspb.File{{Path: "/etc"}, {Path: "/etc/passwd"}, {Path: "/etc/group"}, {Path: "/tmp/new"}},
+		},
+	}
+	want := []RuleSummary{
+		{RuleName: `include: "/etc"`, MatchCount: 3, FileList: []string{"/etc", "/etc/passwd", "/etc/group"}},
+		{RuleName: `include: "/opt"`, FileList: []string{}},
+		{RuleName: `hash_pfx: "/etc/pass"`, MatchCount: 1, FileList: []string{"/etc/passwd"}},
+		{RuleName: `exclude_pfx: "/tmp/"`, MatchCount: 2, FileList: []string{"/tmp/old", "/tmp/new"}},
+		{RuleName: `exclude_pfx: "/home/"`, FileList: []string{}},
+	}
+	if diff := cmp.Diff(want, r.RuleSummaryData()); diff != "" {
+		t.Errorf("RuleSummaryData(): diff (-want +got):\n%s", diff)
+	}
+
+	var buf bytes.Buffer
+	if err := r.PrintRuleSummaryJSON(&buf); err != nil {
+		t.Fatalf("PrintRuleSummaryJSON() error: %v", err)
+	}
+	var got []RuleSummary
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("PrintRuleSummaryJSON() wrote invalid JSON: %v\n%s", err, buf.String())
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("PrintRuleSummaryJSON(): diff (-want +got):\n%s", diff)
+	}
+
+	buf.Reset()
+	r.PrintRuleSummary(&buf)
+	for _, want := range []string{
+		"include: \"/etc\": 3 files\n",
+		"include: \"/opt\": no matches\n",
+		"exclude_pfx: \"/tmp/\": 2 files\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("PrintRuleSummary() output doesn't contain %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestExplainRule(t *testing.T) {
+	r := &Reporter{
+		config: &fspb.ReportConfig{ExcludePfx: []string{"/tmp/"}},
+		before: &fspb.Walk{
+			File: []*fspb.File{{Path: "/tmp/old"}},
+		},
+		after: &fspb.Walk{
+			Policy: &fspb.Policy{Include: []string{"/etc/"}},
+			File: []*fspb.File{
+				{
+					Path:        "/etc/passwd",
+					Info:        &fspb.FileInfo{Size: 42},
+					Stat:        &fspb.FileStat{Mode: 0100644, Uid: 0, Gid: 0, Mtime: &tspb.Timestamp{Seconds: 1543831000}},
+					Fingerprint: []*fspb.Fingerprint{{Method: fspb.Fingerprint_SHA256, Value: "abcd"}},
+				},
+				{Path: "/tmp/new", Info: &fspb.FileInfo{Size: 1}},
+			},
+		},
+	}
+	testCases := []struct {
+		rule    string
+		want    string
+		wantErr bool
+	}{
+		{
+			rule: `include: "/etc"`,
+			want: "Files of the after Walk covered by include: \"/etc\" (1):\n" +
+				"/etc/passwd size=42 mode=0100644 uid=0 gid=0 mtime=2018-12-03T09:56:40Z sha256=abcd\n",
+		}, {
+			rule: `exclude_pfx: "/tmp/"`,
+			want: "Files of the after Walk covered by exclude_pfx: \"/tmp/\" (1):\n" +
+				"/tmp/new size=1 mode=0 uid=0 gid=0\n",
+		}, {
+			rule:    `include: "/opt"`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		err := r.ExplainRule(tc.rule, &buf)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ExplainRule(%q) error = %v; want error: %t", tc.rule, err, tc.wantErr)
+			continue
+		}
+		if diff := cmp.Diff(tc.want, buf.String()); diff != "" {
+			t.Errorf("ExplainRule(%q): diff (-want +got):\n%s", tc.rule, diff)
+		}
+	}
+}
+
+func TestCompareWalks(t *testing.T) {
+	before := &fspb.Walk{
+		File: []*fspb.File{
+			{Version: 1, Path: "/etc/deleted", Info: &fspb.FileInfo{Size: 1}},
+			{Version: 1, Path: "/etc/modified", Info: &fspb.FileInfo{Size: 1}},
+			{Version: 1, Path: "/tmp/ignored", Info: &fspb.FileInfo{Size: 1}},
+		},
+	}
+	after := &fspb.Walk{
+		File: []*fspb.File{
+			{Version: 1, Path: "/etc/added", Info: &fspb.FileInfo{Size: 1}},
+			{Version: 1, Path: "/etc/modified", Info: &fspb.FileInfo{Size: 2}},
+		},
+	}
+	cfg := &fspb.ReportConfig{ExcludePfx: []string{"/tmp/"}}
+
+	got, err := CompareWalks(before, after, cfg)
+	if err != nil {
+		t.Fatalf("CompareWalks() error: %v", err)
+	}
+	want := &CompareResult{
+		Added:    []FileChange{{After: after.File[0]}},
+		Deleted:  []FileChange{{Before: before.File[0]}},
+		Modified: []FileChange{{Before: before.File[1], After: after.File[1], Diff: "size: 1 => 2"}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("CompareWalks(): diff (-want +got):\n%s", diff)
+	}
+	if n := got.ChangeCount(); n != 3 {
+		t.Errorf("CompareWalks().ChangeCount() = %d; want 3", n)
+	}
+
+	if _, err := CompareWalks(before, nil, cfg); err == nil {
+		t.Error("CompareWalks() no error without after Walk")
+	}
+}
+
+func TestWalkHosts(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "walks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+
+	ts := time.Date(2018, 12, 6, 7, 0, 0, 0, time.Local)
+	for _, n := range []string{
+		WalkFilename("b.google.com", ts),
+		WalkFilename("b.google.com", ts.Add(time.Hour)),
+		WalkFilenameForFormat("a-1.google.com", ts, OutputFormatJSON, true),
+		"c.google.com-20181206-070000.1-fswalker-state.pb",
+		"unrelated.txt",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(tmpdir, n), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := WalkHosts(ctx, tmpdir)
+	if err != nil {
+		t.Fatalf("WalkHosts() error: %v", err)
+	}
+	want := []string{"a-1.google.com", "b.google.com", "c.google.com"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("WalkHosts(): diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoadWalksFilenameFormat(t *testing.T) {
+	ctx := context.Background()
+	treeDir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(treeDir) // clean up
+	walkDir, err := ioutil.TempDir("", "walks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(walkDir) // clean up
+	makeTree(t, treeDir, 2, 3)
+	hn, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const format = "20060102-150405-%h-walk"
+	pol := &fspb.Policy{Include: []string{treeDir}, DeltaWalk: true}
+	now := time.Now()
+	var paths []string
+	var w *Walker
+	for _, ts := range []time.Time{now.Add(-time.Hour), now} {
+		p := filepath.Join(walkDir, WalkFilenameWithFormat(hn, ts, WalkFilenameLayout(format, OutputFormatProto, false)))
+		w = &Walker{pol: pol, Outpath: p, FilenameFormat: format}
+		if err := w.Run(ctx); err != nil {
+			t.Fatalf("Run() error: %v", err)
+		}
+		paths = append(paths, p)
+	}
+	if want := filepath.Base(paths[0]); w.walk.BaseWalk != want {
+		t.Errorf("Run() of a delta walk: base_walk = %q; want %q", w.walk.BaseWalk, want)
+	}
+
+	r := &Reporter{
+		config:         &fspb.ReportConfig{},
+		Reviews:        NewReviewManager(&memReviewStore{reviews: &fspb.Reviews{}}),
+		FilenameFormat: format,
+	}
+	if err := r.LoadWalks(ctx, hn, "", walkDir, "", ""); err != nil {
+		t.Fatalf("LoadWalks() error: %v", err)
+	}
+	if r.afterFile != paths[1] {
+		t.Errorf("LoadWalks() loaded %q; want %q", r.afterFile, paths[1])
+	}
+	hosts, err := r.WalkHosts(ctx, walkDir)
+	if err != nil {
+		t.Fatalf("WalkHosts() error: %v", err)
+	}
+	if diff := cmp.Diff([]string{hn}, hosts); diff != "" {
+		t.Errorf("WalkHosts(): diff (-want +got):\n%s", diff)
+	}
+
+	r = &Reporter{config: &fspb.ReportConfig{}, Reviews: NewReviewManager(&memReviewStore{reviews: &fspb.Reviews{}})}
+	if err := r.LoadWalks(ctx, hn, "", walkDir, "", ""); err == nil {
+		t.Error("LoadWalks() without FilenameFormat found Walks in a custom format; want error")
+	}
+}
+
+func TestComparePrefixHostname(t *testing.T) {
+	r := &Reporter{
+		config:         &fspb.ReportConfig{},
+		PrefixHostname: true,
+		after: &fspb.Walk{
+			Hostname: "some-host.google.com",
+			File: []*fspb.File{
+				{Version: 1, Path: "/etc/added", Info: &fspb.FileInfo{}},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	r.Compare(&buf)
+	for _, want := range []string{"[some-host.google.com] Object Summary:", "[some-host.google.com] Added (1):"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Compare() output doesn't contain %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestCompareHighEntropy(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		threshold float64
+		want      string
+	}{
+		{
+			desc: "default threshold",
+			want: "High-Entropy Additions (1):\n/tmp/payload: 7.90 bits/byte\n",
+		}, {
+			desc:      "custom threshold",
+			threshold: 6,
+			want:      "High-Entropy Additions (2):\n/tmp/payload: 7.90 bits/byte\n/tmp/text: 6.50 bits/byte\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			r := &Reporter{
+				config: &fspb.ReportConfig{EntropyThreshold: tc.threshold},
+				before: &fspb.Walk{
+					File: []*fspb.File{
+						{Version: 1, Path: "/tmp/existing", Info: &fspb.FileInfo{}, Entropy: 7.9},
+					},
+				},
+				after: &fspb.Walk{
+					File: []*fspb.File{
+						{Version: 1, Path: "/tmp/existing", Info: &fspb.FileInfo{}, Entropy: 7.9},
+						{Version: 1, Path: "/tmp/payload", Info: &fspb.FileInfo{}, Entropy: 7.9},
+						{Version: 1, Path: "/tmp/text", Info: &fspb.FileInfo{}, Entropy: 6.5},
+					},
+				},
+			}
+			var buf bytes.Buffer
+			r.Compare(&buf)
+			if !strings.Contains(buf.String(), tc.want) {
+				t.Errorf("Compare() output doesn't contain %q:\n%s", tc.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestChangedFields(t *testing.T) {
+	diff := []string{
+		"  []*fswalker.Fingerprint{",
+		"- \t&{Method: s\"SHA256\", Value: \"abcd\"},",
+		"+ \t&{Method: s\"SHA256\", Value: \"efgh\"},",
+		"  }",
+		"ctime: 2018-12-06 10:01:02 UTC => 2018-12-07 10:01:02 UTC",
+		"mode: 644 => 744",
+		"owner: alice => bob (uid 1001)",
+		"privileges: setuid added",
+		"symlink target: \"/a\" => \"/b\"",
+		"xattr added: user.a: \"1\"",
+		"xattr removed: user.b",
+	}
+	want := []string{"fingerprint", "ctime", "mode", "owner", "privileges", "symlink_target", "xattrs"}
+	if diff := cmp.Diff(want, changedFields(diff)); diff != "" {
+		t.Errorf("changedFields(): diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestDiffChangeTypes(t *testing.T) {
+	testCases := []struct {
+		diff string
+		want []fspb.ReportConfig_ChangeType
+	}{
+		{
+			diff: "mtime: 2018-12-06 10:01:02 UTC => 2018-12-07 10:01:02 UTC",
+			want: []fspb.ReportConfig_ChangeType{fspb.ReportConfig_METADATA_CHANGED},
+		}, {
+			diff: "mode: 644 => 4755\nprivileges: setuid added\nsize: 1 => 2",
+			want: []fspb.ReportConfig_ChangeType{fspb.ReportConfig_PERMISSION_CHANGED, fspb.ReportConfig_CONTENT_CHANGED},
+		}, {
+			diff: "gid: 0 (root) => 1000 (alice)\nuid: 0 (root) => 1000 (alice)",
+			want: []fspb.ReportConfig_ChangeType{fspb.ReportConfig_OWNER_CHANGED},
+		},
+	}
+	for _, tc := range testCases {
+		if diff := cmp.Diff(tc.want, diffChangeTypes(tc.diff)); diff != "" {
+			t.Errorf("diffChangeTypes(%q): diff (-want +got):\n%s", tc.diff, diff)
+		}
+	}
+}
+
+func TestDiffMetrics(t *testing.T) {
+	r := &Reporter{
+		config: &fspb.ReportConfig{},
+		before: &fspb.Walk{File: []*fspb.File{
+			{Version: 1, Path: "/bin/su", Info: &fspb.FileInfo{Mode: 0755}},
+			{Version: 1, Path: "/etc/passwd", Info: &fspb.FileInfo{Size: 1, Mode: 0644}},
+			{Version: 1, Path: "/etc/shadow", Stat: &fspb.FileStat{Uid: 0}},
+			{Version: 1, Path: "/etc/old"},
+		}},
+		after: &fspb.Walk{File: []*fspb.File{
+			{Version: 1, Path: "/bin/su", Info: &fspb.FileInfo{Mode: 04755}},
+			{Version: 1, Path: "/etc/passwd", Info: &fspb.FileInfo{Size: 2, Mode: 0600}},
+			{Version: 1, Path: "/etc/shadow", Stat: &fspb.FileStat{Uid: 1000}},
+			{Version: 1, Path: "/etc/new"},
+		}},
+	}
+	var buf bytes.Buffer
+	r.Compare(&buf)
+	want := DiffMetrics{
+		Added:                 1,
+		Deleted:               1,
+		Modified:              3,
+		ContentChanges:        1,
+		PermissionChanges:     2,
+		OwnerChanges:          1,
+		PermissionOnlyChanges: 1,
+	}
+	if diff := cmp.Diff(want, r.Metrics()); diff != "" {
+		t.Errorf("Metrics(): diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseChangeTypes(t *testing.T) {
+	testCases := []struct {
+		in      string
+		want    []fspb.ReportConfig_ChangeType
+		wantErr bool
+	}{
+		{in: ""},
+		{in: "PERMISSION_CHANGED, owner_changed", want: []fspb.ReportConfig_ChangeType{fspb.ReportConfig_PERMISSION_CHANGED, fspb.ReportConfig_OWNER_CHANGED}},
+		{in: "MTIME_CHANGED", wantErr: true},
+		{in: "UNSPECIFIED", wantErr: true},
+	}
+	for _, tc := range testCases {
+		got, err := ParseChangeTypes(tc.in)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseChangeTypes(%q) error = %v; want error: %t", tc.in, err, tc.wantErr)
+			continue
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("ParseChangeTypes(%q): diff (-want +got):\n%s", tc.in, diff)
+		}
+	}
+}
+
+func TestCompareChangeTypeFilter(t *testing.T) {
+	before := &fspb.Walk{
+		Id: "before",
+		File: []*fspb.File{
+			{Version: 1, Path: "/var/log/syslog", Info: &fspb.FileInfo{Mode: 0644, Modified: &tspb.Timestamp{Seconds: 1}}},
+			{Version: 1, Path: "/usr/bin/tool", Info: &fspb.FileInfo{Mode: 0755}},
+			{Version: 1, Path: "/etc/shadow", Info: &fspb.FileInfo{Mode: 0600}, Stat: &fspb.FileStat{Uid: 0}},
+			{Version: 1, Path: "/etc/deleted", Info: &fspb.FileInfo{}},
+		},
+	}
+	after := &fspb.Walk{
+		Id: "after",
+		File: []*fspb.File{
+			{Version: 1, Path: "/var/log/syslog", Info: &fspb.FileInfo{Mode: 0644, Modified: &tspb.Timestamp{Seconds: 2}}},
+			{Version: 1, Path: "/usr/bin/tool", Info: &fspb.FileInfo{Mode: 04755}},
+			{Version: 1, Path: "/etc/shadow", Info: &fspb.FileInfo{Mode: 0600}, Stat: &fspb.FileStat{Uid: 1000}},
+			{Version: 1, Path: "/etc/added", Info: &fspb.FileInfo{}},
+		},
+	}
+	testCases := []struct {
+		desc         string
+		config       []fspb.ReportConfig_ChangeType
+		override     []fspb.ReportConfig_ChangeType
+		wantAdded    []string
+		wantDeleted  []string
+		wantModified []string
+	}{
+		{
+			desc:         "no filter",
+			wantAdded:    []string{"/etc/added"},
+			wantDeleted:  []string{"/etc/deleted"},
+			wantModified: []string{"/var/log/syslog", "/usr/bin/tool", "/etc/shadow"},
+		}, {
+			desc:         "permission and owner",
+			config:       []fspb.ReportConfig_ChangeType{fspb.ReportConfig_PERMISSION_CHANGED, fspb.ReportConfig_OWNER_CHANGED},
+			wantModified: []string{"/usr/bin/tool", "/etc/shadow"},
+		}, {
+			desc:        "flag overrides config",
+			config:      []fspb.ReportConfig_ChangeType{fspb.ReportConfig_PERMISSION_CHANGED},
+			override:    []fspb.ReportConfig_ChangeType{fspb.ReportConfig_FILE_ADDED, fspb.ReportConfig_FILE_DELETED},
+			wantAdded:   []string{"/etc/added"},
+			wantDeleted: []string{"/etc/deleted"},
+		},
+	}
+	paths := func(changes []FileChange) []string {
+		var p []string
+		for _, c := range changes {
+			if c.After != nil {
+				p = append(p, c.After.Path)
+			} else {
+				p = append(p, c.Before.Path)
+			}
+		}
+		return p
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			r := &Reporter{
+				config:           &fspb.ReportConfig{ChangeTypeFilter: tc.config},
+				before:           before,
+				after:            after,
+				ChangeTypeFilter: tc.override,
+			}
+			got := r.diffWalks()
+			if diff := cmp.Diff(tc.wantAdded, paths(got.Added)); diff != "" {
+				t.Errorf("diffWalks() added: diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantDeleted, paths(got.Deleted)); diff != "" {
+				t.Errorf("diffWalks() deleted: diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantModified, paths(got.Modified)); diff != "" {
+				t.Errorf("diffWalks() modified: diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCompareIgnoreMtimeOnly(t *testing.T) {
+	fp := func(v string) []*fspb.Fingerprint {
+		return []*fspb.Fingerprint{{Method: fspb.Fingerprint_SHA256, Value: v}}
+	}
+	r := &Reporter{
+		config: &fspb.ReportConfig{},
+		before: &fspb.Walk{
+			Id: "before",
+			File: []*fspb.File{
+				{Version: 1, Path: "/var/log/syslog", Info: &fspb.FileInfo{Modified: &tspb.Timestamp{Seconds: 1}}, Fingerprint: fp("aa")},
+				{Version: 1, Path: "/var/cache/db", Info: &fspb.FileInfo{Modified: &tspb.Timestamp{Seconds: 1}}, Fingerprint: fp("aa")},
+				{Version: 1, Path: "/etc/passwd", Info: &fspb.FileInfo{Mode: 0644, Modified: &tspb.Timestamp{Seconds: 1}}},
+			},
+		},
+		after: &fspb.Walk{
+			Id: "after",
+			File: []*fspb.File{
+				{Version: 1, Path: "/var/log/syslog", Info: &fspb.FileInfo{Modified: &tspb.Timestamp{Seconds: 2}}, Fingerprint: fp("aa")},
+				{Version: 1, Path: "/var/cache/db", Info: &fspb.FileInfo{Modified: &tspb.Timestamp{Seconds: 2}}, Fingerprint: fp("bb")},
+				{Version: 1, Path: "/etc/passwd", Info: &fspb.FileInfo{Mode: 0666, Modified: &tspb.Timestamp{Seconds: 2}}},
+			},
+		},
+		IgnoreMtimeOnly: true,
+	}
+	var got []string
+	for _, c := range r.diffWalks().Modified {
+		got = append(got, c.After.Path)
+	}
+	want := []string{"/var/cache/db", "/etc/passwd"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diffWalks() modified: diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestCompareToDiff(t *testing.T) {
+	ctx := context.Background()
+	r := &Reporter{
+		config:     &fspb.ReportConfig{},
+		beforeFile: "/walks/before.pb",
+		afterFile:  "/walks/after.pb",
+		before: &fspb.Walk{
+			Id:       "before",
+			Hostname: "host",
+			File: []*fspb.File{
+				{Version: 1, Path: "/etc/deleted", Info: &fspb.FileInfo{Size: 1}},
+				{Version: 1, Path: "/etc/link", Info: &fspb.FileInfo{Size: 1}, SymlinkTarget: "/opt/jdk8"},
+				{Version: 1, Path: "/etc/modified", Info: &fspb.FileInfo{Size: 1, Mode: 644}},
+			},
+		},
+		after: &fspb.Walk{
+			Id:       "after",
+			Hostname: "host",
+			Policy:   &fspb.Policy{HashAlgorithm: fspb.Fingerprint_SHA512},
+			File: []*fspb.File{
+				{Version: 1, Path: "/etc/added", Info: &fspb.FileInfo{Size: 1}, Entropy: 7.9},
+				{Version: 1, Path: "/etc/link", Info: &fspb.FileInfo{Size: 1}, SymlinkTarget: "/opt/jdk11"},
+				{Version: 1, Path: "/etc/modified", Info: &fspb.FileInfo{Size: 2, Mode: 644}},
+			},
+		},
+	}
+	want := &fspb.WalkDiff{
+		Hostname:            "host",
+		BeforeWalkId:        "before",
+		BeforeWalkReference: "/walks/before.pb",
+		AfterWalkId:         "after",
+		AfterWalkReference:  "/walks/after.pb",
+		Warning:             []string{"Walks used different hash algorithms (SHA256 => SHA512), content changes can't be detected."},
+		FileDiff: []*fspb.FileDiff{
+			{
+				DiffType:    fspb.FileDiff_ADDED,
+				Path:        "/etc/added",
+				After:       &fspb.FileInfo{Size: 1},
+				Entropy:     7.9,
+				HighEntropy: true,
+			}, {
+				DiffType: fspb.FileDiff_DELETED,
+				Path:     "/etc/deleted",
+				Before:   &fspb.FileInfo{Size: 1},
+			}, {
+				DiffType:     fspb.FileDiff_MODIFIED,
+				Path:         "/etc/modified",
+				Before:       &fspb.FileInfo{Size: 1, Mode: 644},
+				After:        &fspb.FileInfo{Size: 2, Mode: 644},
+				ChangedField: []string{"size"},
+				Diff:         []string{"size: 1 => 2"},
+			}, {
+				DiffType:            fspb.FileDiff_RETARGETED,
+				Path:                "/etc/link",
+				Before:              &fspb.FileInfo{Size: 1},
+				After:               &fspb.FileInfo{Size: 1},
+				ChangedField:        []string{"symlink_target"},
+				Diff:                []string{"symlink target: \"/opt/jdk8\" => \"/opt/jdk11\""},
+				BeforeSymlinkTarget: "/opt/jdk8",
+				AfterSymlinkTarget:  "/opt/jdk11",
+			},
+		},
+	}
+
+	got, err := r.CompareToDiff(ctx)
+	if err != nil {
+		t.Fatalf("CompareToDiff() error: %v", err)
+	}
+	if diff := cmp.Diff(want, got, cmp.Comparer(proto.Equal)); diff != "" {
+		t.Errorf("CompareToDiff(): diff (-want +got):\n%s", diff)
+	}
+	if _, err := (&Reporter{}).CompareToDiff(ctx); err == nil {
+		t.Error("CompareToDiff() no error without Walks")
+	}
+}
+
+func TestCompareRetyped(t *testing.T) {
+	r := &Reporter{
+		config: &fspb.ReportConfig{},
+		before: &fspb.Walk{
+			File: []*fspb.File{
+				{Version: 1, Path: "/usr/bin/tool", Info: &fspb.FileInfo{Size: 1}, MimeType: "text/plain; charset=utf-8"},
+				{Version: 1, Path: "/etc/config", Info: &fspb.FileInfo{Size: 1}, MimeType: "text/plain; charset=utf-8"},
+			},
+		},
+		after: &fspb.Walk{
+			File: []*fspb.File{
+				{Version: 1, Path: "/usr/bin/tool", Info: &fspb.FileInfo{Size: 2}, MimeType: "application/octet-stream"},
+				{Version: 1, Path: "/etc/config", Info: &fspb.FileInfo{Size: 2}, MimeType: "text/plain; charset=utf-8"},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	r.Compare(&buf)
+	for _, want := range []string{
+		"Modified (1):\n/etc/config\n",
+		"MIME Type Changed (1):\n/usr/bin/tool: \"text/plain; charset=utf-8\" => \"application/octet-stream\"\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Compare() output doesn't contain %q:\n%s", want, buf.String())
+		}
+	}
+	if n := r.ChangeCount(); n != 2 {
+		t.Errorf("ChangeCount() = %d; want 2", n)
+	}
+}
+
+func TestCompareDirectoriesOnly(t *testing.T) {
+	r := &Reporter{
+		config: &fspb.ReportConfig{},
+		before: &fspb.Walk{
+			Policy: &fspb.Policy{DirectoriesOnly: true},
+			File: []*fspb.File{
+				{Version: 1, Path: "/etc", Info: &fspb.FileInfo{IsDir: true, Mode: 0755}},
+				{Version: 1, Path: "/var", Info: &fspb.FileInfo{IsDir: true}},
+			},
+		},
+		after: &fspb.Walk{
+			File: []*fspb.File{
+				{Version: 1, Path: "/etc", Info: &fspb.FileInfo{IsDir: true, Mode: 0700}},
+				{Version: 1, Path: "/etc/passwd", Info: &fspb.FileInfo{Size: 1}},
+				{Version: 1, Path: "/opt", Info: &fspb.FileInfo{IsDir: true}},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	r.Compare(&buf)
+	for _, want := range []string{
+		"WARNING: Only one of the Walks recorded directories only",
+		"Added (1):\n/opt\n",
+		"Removed (1):\n/var\n",
+		"Modified (1):\n/etc\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Compare() output doesn't contain %q:\n%s", want, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "/etc/passwd") {
+		t.Errorf("Compare() output reports a file which isn't a directory:\n%s", buf.String())
+	}
+
+	// Walks which both recorded directories only are compared as usual.
+	r.after.Policy = &fspb.Policy{DirectoriesOnly: true}
+	buf.Reset()
+	r.Compare(&buf)
+	if strings.Contains(buf.String(), "WARNING") || !strings.Contains(buf.String(), "/etc/passwd") {
+		t.Errorf("Compare() of two directories only Walks = %q; want /etc/passwd added without warning", buf.String())
+	}
+}
+
+func TestComparePartial(t *testing.T) {
+	r := &Reporter{
+		config: &fspb.ReportConfig{},
+		before: &fspb.Walk{File: []*fspb.File{{Version: 1, Path: "/etc/passwd"}}},
+		after:  &fspb.Walk{Partial: true},
+	}
+	wd, err := r.CompareToDiff(context.Background())
+	if err != nil {
+		t.Fatalf("CompareToDiff() error: %v", err)
+	}
+	want := []string{"The after Walk is partial (it was stopped before completion), files it is missing are reported as removed."}
+	if diff := cmp.Diff(want, wd.Warning); diff != "" {
+		t.Errorf("CompareToDiff(): diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestCompareSampleRate(t *testing.T) {
+	fp := func(v string) []*fspb.Fingerprint {
+		return []*fspb.Fingerprint{{Method: fspb.Fingerprint_SHA256, Value: v}}
+	}
+	r := &Reporter{
+		config: &fspb.ReportConfig{},
+		before: &fspb.Walk{
+			File: []*fspb.File{
+				{Version: 1, Path: "/bin/sampled", Info: &fspb.FileInfo{}, Fingerprint: fp("a")},
+				{Version: 1, Path: "/bin/unsampled", Info: &fspb.FileInfo{}, Fingerprint: fp("b")},
+			},
+		},
+		after: &fspb.Walk{
+			Policy: &fspb.Policy{SampleRate: 0.1},
+			File: []*fspb.File{
+				{Version: 1, Path: "/bin/sampled", Info: &fspb.FileInfo{}, Fingerprint: fp("c")},
+				{Version: 1, Path: "/bin/unsampled", Info: &fspb.FileInfo{}},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	r.Compare(&buf)
+	for _, want := range []string{
+		"WARNING: Walks hashed different samples of files (sample rate 1 => 0.1)",
+		"Modified (1):\n/bin/sampled\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Compare() output doesn't contain %q:\n%s", want, buf.String())
+		}
+	}
+
+	// With the same sample rate, a missing fingerprint is a change.
+	r.before.Policy = &fspb.Policy{SampleRate: 0.1}
+	buf.Reset()
+	r.Compare(&buf)
+	if strings.Contains(buf.String(), "WARNING") || !strings.Contains(buf.String(), "Modified (2):") {
+		t.Errorf("Compare() of Walks with the same sample rate = %q; want 2 modified files without warning", buf.String())
+	}
+}
+
+func TestCompareJSON(t *testing.T) {
+	r := &Reporter{
+		config: &fspb.ReportConfig{
+			ExcludePfx: []string{"/tmp/"},
+		},
+		before: &fspb.Walk{
+			Id: "before",
+			File: []*fspb.File{
+				{Version: 1, Path: "/etc/deleted", Info: &fspb.FileInfo{Size: 1}},
+				{Version: 1, Path: "/etc/link", Info: &fspb.FileInfo{Size: 1}, SymlinkTarget: "/opt/jdk8"},
+				{Version: 1, Path: "/etc/modified", Info: &fspb.FileInfo{Size: 1, Mode: 644}},
+				{Version: 1, Path: "/etc/unchanged", Info: &fspb.FileInfo{Size: 1}},
+				{Version: 1, Path: "/tmp/ignored", Info: &fspb.FileInfo{Size: 1}},
+			},
+		},
+		after: &fspb.Walk{
+			Id: "after",
+			File: []*fspb.File{
+				{Version: 1, Path: "/etc/added", Info: &fspb.FileInfo{Size: 1}},
+				{Version: 1, Path: "/etc/link", Info: &fspb.FileInfo{Size: 1}, SymlinkTarget: "/opt/jdk11"},
+				{Version: 1, Path: "/etc/modified", Info: &fspb.FileInfo{Size: 1, Mode: 744}},
+				{Version: 1, Path: "/etc/unchanged", Info: &fspb.FileInfo{Size: 1}},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := r.CompareJSON(&buf); err != nil {
+		t.Fatalf("CompareJSON() error: %v", err)
+	}
+	if n := r.ChangeCount(); n != 4 {
+		t.Errorf("ChangeCount() = %d; want 4", n)
+	}
+	var got []jsonChange
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("CompareJSON() wrote invalid JSON: %v\n%s", err, buf.String())
+	}
+	want := []struct {
+		path, changeType string
+		diff             []string
+	}{
+		{path: "/etc/added", changeType: "added"},
+		{path: "/etc/deleted", changeType: "deleted"},
+		{path: "/etc/modified", changeType: "modified", diff: []string{"mode: 644 => 744"}},
+		{path: "/etc/link", changeType: "retargeted", diff: []string{`symlink target: "/opt/jdk8" => "/opt/jdk11"`}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("CompareJSON() returned %d changes; want %d:\n%s", len(got), len(want), buf.String())
+	}
+	for i, w := range want {
+		if got[i].Path != w.path || got[i].ChangeType != w.changeType {
+			t.Errorf("CompareJSON()[%d] = %s(%s); want %s(%s)", i, got[i].ChangeType, got[i].Path, w.changeType, w.path)
+		}
+		if diff := cmp.Diff(w.diff, got[i].Diff); diff != "" {
+			t.Errorf("CompareJSON()[%d].Diff: diff (-want +got):\n%s", i, diff)
+		}
+	}
+	after := &fspb.File{}
+	if err := jsonpb.UnmarshalString(string(got[2].After), after); err != nil {
+		t.Fatalf("CompareJSON()[2].After: %v", err)
+	}
+	if after.Info.Mode != 744 {
+		t.Errorf("CompareJSON()[2].After.Info.Mode = %d; want 744", after.Info.Mode)
+	}
+}
+
+func TestCompareDiffContext(t *testing.T) {
+	files := func(paths ...string) []*fspb.File {
+		var fs []*fspb.File
+		for _, p := range paths {
+			fs = append(fs, &fspb.File{Version: 1, Path: p, Info: &fspb.FileInfo{}})
+		}
+		return fs
+	}
+	testCases := []struct {
+		desc        string
+		diffContext int
+		want        string
+	}{
+		{
+			desc: "no context",
+			want: "Added (4):\n/etc/e\n/etc/b\n/etc/h\n/etc/l\n\n",
+		}, {
+			desc:        "separated",
+			diffContext: 1,
+			want:        "Added (4):\n  /etc/a\n/etc/b\n  /etc/c\n  /etc/d\n/etc/e\n  /etc/f\n  /etc/g\n/etc/h\n  /etc/i\n--\n  /etc/k\n/etc/l\n\n",
+		}, {
+			desc:        "overlapping",
+			diffContext: 2,
+			want:        "Added (4):\n  /etc/a\n/etc/b\n  /etc/c\n  /etc/d\n/etc/e\n  /etc/f\n  /etc/g\n/etc/h\n  /etc/i\n  /etc/j\n  /etc/k\n/etc/l\n\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			r := &Reporter{
+				config:      &fspb.ReportConfig{},
+				DiffContext: tc.diffContext,
+				before:      &fspb.Walk{File: files("/etc/a", "/etc/c", "/etc/d", "/etc/f", "/etc/g", "/etc/i", "/etc/j", "/etc/k")},
+				after:       &fspb.Walk{File: files("/etc/a", "/etc/e", "/etc/c", "/etc/d", "/etc/f", "/etc/b", "/etc/g", "/etc/h", "/etc/i", "/etc/j", "/etc/k", "/etc/l")},
+			}
+			var buf bytes.Buffer
+			r.Compare(&buf)
+			got := buf.String()
+			got = got[strings.Index(got, "Added"):]
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Compare(): diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReporterFromWalkDirectory(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "walks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+
+	now := time.Now()
+	walks := map[string]*fspb.Walk{
+		"old": {Id: "old", Version: 1, Hostname: "host", File: []*fspb.File{{Path: "/etc/passwd"}, {Path: "/tmp/a"}}},
+		"new": {Id: "new", Version: 1, Hostname: "host", File: []*fspb.File{{Path: "/etc/passwd"}, {Path: "/tmp/b"}}},
+	}
+	files := map[string]string{}
+	for id, ts := range map[string]time.Time{"old": now.Add(-time.Hour), "new": now} {
+		files[id] = filepath.Join(tmpdir, WalkFilename("host", ts))
+		if err := WriteWalk(ctx, files[id], walks[id]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reviewFile := filepath.Join(tmpdir, "reviews.asciipb")
+	if err := ioutil.WriteFile(reviewFile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, fp, err := (&Reporter{}).readWalk(ctx, files["old"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := ReviewManagerFromFile(reviewFile)
+	if err := m.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetBaseline("host", walks["old"], files["old"], fp); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Save(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		desc       string
+		hostname   string
+		opts       ReporterOptions
+		wantBefore string
+		wantFiles  int
+		wantErr    bool
+	}{
+		{
+			desc:       "review file",
+			hostname:   "host",
+			opts:       ReporterOptions{ReviewFile: reviewFile},
+			wantBefore: "old",
+			wantFiles:  2,
+		}, {
+			desc:       "filters",
+			hostname:   "host",
+			opts:       ReporterOptions{ReviewFile: reviewFile, Filters: []WalkFilter{PathPrefixFilter{Prefixes: []string{"/tmp/"}}}},
+			wantBefore: "old",
+			wantFiles:  1,
+		}, {
+			desc:      "no baseline",
+			hostname:  "host",
+			wantFiles: 2,
+		}, {
+			desc:     "no walks",
+			hostname: "other",
+			wantErr:  true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			r, err := ReporterFromWalkDirectory(ctx, tmpdir, tc.hostname, tc.opts)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ReporterFromWalkDirectory() error = %v; want error: %t", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := r.before.GetId(); got != tc.wantBefore {
+				t.Errorf("ReporterFromWalkDirectory() before Walk = %q; want %q", got, tc.wantBefore)
+			}
+			if r.after.GetId() != "new" || r.afterFile != files["new"] {
+				t.Errorf("ReporterFromWalkDirectory() after Walk = %q from %q; want %q from %q", r.after.GetId(), r.afterFile, "new", files["new"])
+			}
+			if got := len(r.after.File); got != tc.wantFiles {
+				t.Errorf("ReporterFromWalkDirectory() after Walk has %d files; want %d", got, tc.wantFiles)
 			}
 		})
 	}
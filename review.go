@@ -0,0 +1,234 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+// ReviewStore persists the last known good Walk of each host.
+// Implement it to keep reviews somewhere other than a review file, e.g. in a database.
+type ReviewStore interface {
+	// LoadReviews returns all stored reviews.
+	LoadReviews(ctx context.Context) (*fspb.Reviews, error)
+	// SaveReviews replaces the stored reviews.
+	SaveReviews(ctx context.Context, reviews *fspb.Reviews) error
+}
+
+// HostReviewStore is a ReviewStore which can also update the review of a single host, e.g. a
+// database table with one row per host. ReviewManager then only writes the baselines it changed,
+// so reporters for different hosts sharing the store don't overwrite each other's baselines.
+type HostReviewStore interface {
+	ReviewStore
+	// SaveReview stores the review of the host, replacing its previous one if any.
+	SaveReview(ctx context.Context, hostname string, review *fspb.Review) error
+	// DeleteReview removes the review of the host.
+	DeleteReview(ctx context.Context, hostname string) error
+}
+
+// FileReviewStore is a ReviewStore keeping reviews in a text format proto file.
+// The path may also be a gcs:// or s3:// URI.
+type FileReviewStore struct {
+	Path string
+}
+
+// LoadReviews reads the review file.
+func (s *FileReviewStore) LoadReviews(ctx context.Context) (*fspb.Reviews, error) {
+	reviews := &fspb.Reviews{}
+	if err := readTextProto(ctx, s.Path, reviews); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// SaveReviews overwrites the review file.
+func (s *FileReviewStore) SaveReviews(ctx context.Context, reviews *fspb.Reviews) error {
+	return writeTextProto(ctx, s.Path, reviews)
+}
+
+// String returns the path of the review file.
+func (s *FileReviewStore) String() string {
+	return s.Path
+}
+
+// errNoReviews is returned when modifying reviews which haven't been loaded.
+var errNoReviews = errors.New("reviews need to be loaded first")
+
+// ReviewManager keeps track of the last known good Walk (the baseline) of each host.
+// It is safe for concurrent use, e.g. by Reporters comparing several hosts in parallel.
+type ReviewManager struct {
+	store ReviewStore
+
+	mu      sync.Mutex
+	reviews *fspb.Reviews
+	// changed are the hosts whose baseline was set or deleted since the last Load or Save.
+	changed map[string]bool
+}
+
+// NewReviewManager creates a ReviewManager using the given store.
+// Load needs to be called before baselines can be retrieved.
+func NewReviewManager(store ReviewStore) *ReviewManager {
+	return &ReviewManager{store: store}
+}
+
+// ReviewManagerFromFile creates a ReviewManager for the given review file.
+func ReviewManagerFromFile(path string) *ReviewManager {
+	return NewReviewManager(&FileReviewStore{Path: path})
+}
+
+// Load (re-)reads all reviews from the store.
+func (m *ReviewManager) Load(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	reviews, err := m.store.LoadReviews(ctx)
+	if err != nil {
+		return &ReviewLoadError{Store: m.String(), Err: err}
+	}
+	if reviews.Review == nil {
+		reviews.Review = map[string]*fspb.Review{}
+	}
+	m.reviews = reviews
+	m.changed = map[string]bool{}
+	return nil
+}
+
+// GetBaseline returns the review of the last known good Walk of the given host.
+// It returns false if there is none.
+func (m *ReviewManager) GetBaseline(hostname string) (*fspb.Review, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.reviews == nil {
+		return nil, false
+	}
+	rvw, ok := m.reviews.Review[hostname]
+	return rvw, ok
+}
+
+// SetBaseline marks the given Walk as last known good for the host. reference is where the
+// Walk is stored and fp is the fingerprint of the stored Walk file.
+// The reviews need to be loaded first so Save doesn't drop the reviews of other hosts.
+// The change only takes effect in the store once Save is called.
+func (m *ReviewManager) SetBaseline(hostname string, wlk *fspb.Walk, reference string, fp *fspb.Fingerprint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.setBaseline(hostname, wlk, reference, fp)
+}
+
+func (m *ReviewManager) setBaseline(hostname string, wlk *fspb.Walk, reference string, fp *fspb.Fingerprint) error {
+	if m.reviews == nil {
+		return errNoReviews
+	}
+	m.reviews.Review[hostname] = &fspb.Review{
+		WalkId:        wlk.Id,
+		WalkReference: reference,
+		Fingerprint:   fp,
+	}
+	m.changed[hostname] = true
+	return nil
+}
+
+// ListHosts returns the sorted names of all hosts with a baseline.
+func (m *ReviewManager) ListHosts() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var hosts []string
+	for h := range m.reviews.GetReview() {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// DeleteBaseline removes the baseline of the host, e.g. once it has been decommissioned.
+// It fails if the host has no baseline. Like SetBaseline, the change only takes effect in the
+// store once Save is called.
+func (m *ReviewManager) DeleteBaseline(hostname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.reviews == nil {
+		return errNoReviews
+	}
+	if _, ok := m.reviews.Review[hostname]; !ok {
+		return fmt.Errorf("no baseline for %q", hostname)
+	}
+	delete(m.reviews.Review, hostname)
+	m.changed[hostname] = true
+	return nil
+}
+
+// Save writes all reviews to the store. If it is a HostReviewStore, only the baselines set or
+// deleted since Load are written.
+func (m *ReviewManager) Save(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.save(ctx)
+}
+
+func (m *ReviewManager) save(ctx context.Context) error {
+	if m.reviews == nil {
+		return errNoReviews
+	}
+	hs, ok := m.store.(HostReviewStore)
+	if !ok {
+		if err := m.store.SaveReviews(ctx, m.reviews); err != nil {
+			return err
+		}
+		m.changed = map[string]bool{}
+		return nil
+	}
+	hosts := make([]string, 0, len(m.changed))
+	for h := range m.changed {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+	for _, h := range hosts {
+		var err error
+		if rvw, ok := m.reviews.Review[h]; ok {
+			err = hs.SaveReview(ctx, h, rvw)
+		} else {
+			err = hs.DeleteReview(ctx, h)
+		}
+		if err != nil {
+			return err
+		}
+		delete(m.changed, h)
+	}
+	return nil
+}
+
+// saveBaseline is SetBaseline followed by Save without letting a concurrent Load drop the
+// new baseline in between.
+func (m *ReviewManager) saveBaseline(ctx context.Context, hostname string, wlk *fspb.Walk, reference string, fp *fspb.Fingerprint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.setBaseline(hostname, wlk, reference, fp); err != nil {
+		return err
+	}
+	return m.save(ctx)
+}
+
+// String describes the store of the ReviewManager if it implements fmt.Stringer.
+func (m *ReviewManager) String() string {
+	if s, ok := m.store.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return "review store"
+}
@@ -0,0 +1,242 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+// memReviewStore is a ReviewStore keeping reviews in memory.
+type memReviewStore struct {
+	reviews *fspb.Reviews
+}
+
+func (s *memReviewStore) LoadReviews(ctx context.Context) (*fspb.Reviews, error) {
+	return proto.Clone(s.reviews).(*fspb.Reviews), nil
+}
+
+func (s *memReviewStore) SaveReviews(ctx context.Context, reviews *fspb.Reviews) error {
+	s.reviews = proto.Clone(reviews).(*fspb.Reviews)
+	return nil
+}
+
+// memHostReviewStore is a HostReviewStore keeping reviews in memory.
+type memHostReviewStore struct {
+	memReviewStore
+	fullSaves int
+}
+
+func (s *memHostReviewStore) SaveReviews(ctx context.Context, reviews *fspb.Reviews) error {
+	s.fullSaves++
+	return s.memReviewStore.SaveReviews(ctx, reviews)
+}
+
+func (s *memHostReviewStore) SaveReview(ctx context.Context, hostname string, review *fspb.Review) error {
+	s.reviews.Review[hostname] = proto.Clone(review).(*fspb.Review)
+	return nil
+}
+
+func (s *memHostReviewStore) DeleteReview(ctx context.Context, hostname string) error {
+	delete(s.reviews.Review, hostname)
+	return nil
+}
+
+func TestReviewManagerFromFile(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "reviews")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+	b, err := ioutil.ReadFile(filepath.Join(testdataDir, "reviews.asciipb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reviewFile := filepath.Join(tmpdir, "reviews.asciipb")
+	if err := ioutil.WriteFile(reviewFile, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := ReviewManagerFromFile(reviewFile)
+	if err := m.Load(ctx); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	got, ok := m.GetBaseline("host-A.google.com")
+	if !ok {
+		t.Fatal("GetBaseline(\"host-A.google.com\") found no review")
+	}
+	if want := "debffdde-47f3-454b-adaa-d79d95945c69"; got.WalkId != want {
+		t.Errorf("GetBaseline(\"host-A.google.com\") walk ID = %q; want %q", got.WalkId, want)
+	}
+	if _, ok := m.GetBaseline("host-D.google.com"); ok {
+		t.Error("GetBaseline(\"host-D.google.com\") found a review for an unknown host")
+	}
+
+	fp := &fspb.Fingerprint{Method: fspb.Fingerprint_SHA256, Value: "deadbeef"}
+	if err := m.SetBaseline("host-D.google.com", &fspb.Walk{Id: "walk-D"}, "/walks/host-D.pb", fp); err != nil {
+		t.Fatalf("SetBaseline() error: %v", err)
+	}
+	if err := m.Save(ctx); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	reloaded := ReviewManagerFromFile(reviewFile)
+	if err := reloaded.Load(ctx); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if diff := cmp.Diff(m.reviews, reloaded.reviews, cmp.Comparer(proto.Equal)); diff != "" {
+		t.Errorf("Load() after Save(): diff (-want +got):\n%s", diff)
+	}
+	if len(reloaded.reviews.Review) != 4 {
+		t.Errorf("Load() after Save() got %d reviews; want 4", len(reloaded.reviews.Review))
+	}
+}
+
+func TestReviewManagerNotLoaded(t *testing.T) {
+	ctx := context.Background()
+	m := NewReviewManager(&memReviewStore{reviews: &fspb.Reviews{}})
+	if _, ok := m.GetBaseline("host"); ok {
+		t.Error("GetBaseline() found a review before Load()")
+	}
+	if err := m.SetBaseline("host", &fspb.Walk{}, "", nil); err == nil {
+		t.Error("SetBaseline() no error before Load()")
+	}
+	if err := m.Save(ctx); err == nil {
+		t.Error("Save() no error before Load()")
+	}
+}
+
+func TestUpdateReviewProto(t *testing.T) {
+	ctx := context.Background()
+	store := &memReviewStore{
+		reviews: &fspb.Reviews{
+			Review: map[string]*fspb.Review{
+				"other": {WalkId: "other-walk"},
+			},
+		},
+	}
+	fp := &fspb.Fingerprint{Method: fspb.Fingerprint_SHA256, Value: "deadbeef"}
+	r := &Reporter{
+		Reviews:   NewReviewManager(store),
+		after:     &fspb.Walk{Id: "new-walk", Hostname: "host"},
+		afterFile: "/walks/host.pb",
+		afterFp:   fp,
+	}
+	if err := r.Reviews.Load(ctx); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if err := r.UpdateReviewProto(ctx); err != nil {
+		t.Fatalf("UpdateReviewProto() error: %v", err)
+	}
+
+	want := &fspb.Reviews{
+		Review: map[string]*fspb.Review{
+			"other": {WalkId: "other-walk"},
+			"host": {
+				WalkId:        "new-walk",
+				WalkReference: "/walks/host.pb",
+				Fingerprint:   fp,
+			},
+		},
+	}
+	if diff := cmp.Diff(want, store.reviews, cmp.Comparer(proto.Equal)); diff != "" {
+		t.Errorf("UpdateReviewProto(): diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestReviewManagerListDeleteHosts(t *testing.T) {
+	ctx := context.Background()
+	store := &memReviewStore{
+		reviews: &fspb.Reviews{Review: map[string]*fspb.Review{
+			"b.example.com": {WalkId: "b"},
+			"a.example.com": {WalkId: "a"},
+		}},
+	}
+	m := NewReviewManager(store)
+	if got := m.ListHosts(); len(got) != 0 {
+		t.Errorf("ListHosts() before Load() = %q; want none", got)
+	}
+	if err := m.Load(ctx); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"a.example.com", "b.example.com"}, m.ListHosts()); diff != "" {
+		t.Errorf("ListHosts(): diff (-want +got):\n%s", diff)
+	}
+	if err := m.DeleteBaseline("a.example.com"); err != nil {
+		t.Fatalf("DeleteBaseline() error: %v", err)
+	}
+	if err := m.DeleteBaseline("c.example.com"); err == nil {
+		t.Error("DeleteBaseline() of a host without baseline: no error")
+	}
+	if err := m.Save(ctx); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	if _, ok := store.reviews.Review["a.example.com"]; ok {
+		t.Errorf("Save() after DeleteBaseline() kept the baseline: %v", store.reviews)
+	}
+	if diff := cmp.Diff([]string{"b.example.com"}, m.ListHosts()); diff != "" {
+		t.Errorf("ListHosts() after DeleteBaseline(): diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestReviewManagerHostReviewStore(t *testing.T) {
+	ctx := context.Background()
+	store := &memHostReviewStore{memReviewStore: memReviewStore{
+		reviews: &fspb.Reviews{Review: map[string]*fspb.Review{
+			"a.example.com": {WalkId: "a"},
+			"b.example.com": {WalkId: "b"},
+			"c.example.com": {WalkId: "c"},
+		}},
+	}}
+	// Reporters for different hosts load the reviews before either of them saves.
+	ma, mb := NewReviewManager(store), NewReviewManager(store)
+	for _, m := range []*ReviewManager{ma, mb} {
+		if err := m.Load(ctx); err != nil {
+			t.Fatalf("Load() error: %v", err)
+		}
+	}
+	if err := ma.saveBaseline(ctx, "a.example.com", &fspb.Walk{Id: "a2"}, "/walks/a2.pb", nil); err != nil {
+		t.Fatalf("saveBaseline() error: %v", err)
+	}
+	if err := mb.saveBaseline(ctx, "b.example.com", &fspb.Walk{Id: "b2"}, "/walks/b2.pb", nil); err != nil {
+		t.Fatalf("saveBaseline() error: %v", err)
+	}
+	if err := mb.DeleteBaseline("c.example.com"); err != nil {
+		t.Fatalf("DeleteBaseline() error: %v", err)
+	}
+	if err := mb.Save(ctx); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	want := &fspb.Reviews{Review: map[string]*fspb.Review{
+		"a.example.com": {WalkId: "a2", WalkReference: "/walks/a2.pb"},
+		"b.example.com": {WalkId: "b2", WalkReference: "/walks/b2.pb"},
+	}}
+	if diff := cmp.Diff(want, store.reviews, cmp.Comparer(proto.Equal)); diff != "" {
+		t.Errorf("Save() of both managers: diff (-want +got):\n%s", diff)
+	}
+	if store.fullSaves != 0 {
+		t.Errorf("Save() replaced all reviews %d times; want per host updates only", store.fullSaves)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// s3Scheme is the URI prefix of paths referring to objects in AWS S3.
+const s3Scheme = "s3://"
+
+// isS3Path returns whether the path refers to AWS S3.
+func isS3Path(p string) bool {
+	return strings.HasPrefix(p, s3Scheme)
+}
+
+// s3Client creates an S3 client using the standard AWS credential chain.
+func s3Client(ctx context.Context) (*s3.Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load AWS config: %v", err)
+	}
+	return s3.NewFromConfig(cfg), nil
+}
+
+// readS3 reads the content of an S3 object.
+func readS3(ctx context.Context, p string) ([]byte, error) {
+	bucket, key, err := splitBucketPath(s3Scheme, p)
+	if err != nil {
+		return nil, err
+	}
+	client, err := s3Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %q: %v", p, err)
+	}
+	defer out.Body.Close()
+	return ioutil.ReadAll(out.Body)
+}
+
+// writeS3 writes data to an S3 object.
+func writeS3(ctx context.Context, p string, data []byte) error {
+	bucket, key, err := splitBucketPath(s3Scheme, p)
+	if err != nil {
+		return err
+	}
+	client, err := s3Client(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	}); err != nil {
+		return fmt.Errorf("unable to write %q: %v", p, err)
+	}
+	return nil
+}
+
+// deleteS3 deletes an S3 object.
+func deleteS3(ctx context.Context, p string) error {
+	bucket, key, err := splitBucketPath(s3Scheme, p)
+	if err != nil {
+		return err
+	}
+	client, err := s3Client(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}); err != nil {
+		return fmt.Errorf("unable to delete %q: %v", p, err)
+	}
+	return nil
+}
+
+// S3WalkStore is a WalkStore keeping Walk files in AWS S3.
+// File names are s3://bucket/key URIs. The standard AWS credential chain is used.
+type S3WalkStore struct{}
+
+// Write writes an S3 object.
+func (S3WalkStore) Write(ctx context.Context, filename string, data []byte) error {
+	return writeS3(ctx, filename, data)
+}
+
+// Read reads an S3 object.
+func (S3WalkStore) Read(ctx context.Context, filename string) ([]byte, error) {
+	return readS3(ctx, filename)
+}
+
+// Delete deletes an S3 object.
+func (S3WalkStore) Delete(ctx context.Context, filename string) error {
+	return deleteS3(ctx, filename)
+}
+
+// List returns the matching objects of an S3 directory.
+func (S3WalkStore) List(ctx context.Context, prefix string) ([]string, error) {
+	return listS3(ctx, prefix, false)
+}
+
+// ListRecursive returns the matching objects of an S3 directory and all its subdirectories.
+func (S3WalkStore) ListRecursive(ctx context.Context, prefix string) ([]string, error) {
+	return listS3(ctx, prefix, true)
+}
+
+// listS3 lists the objects starting with prefix, only those directly below its directory
+// unless recursive is set.
+func listS3(ctx context.Context, prefix string, recursive bool) ([]string, error) {
+	bucket, key, err := splitBucketPath(s3Scheme, prefix)
+	if err != nil {
+		return nil, err
+	}
+	dir, _ := splitListPrefix(key)
+	client, err := s3Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	in := &s3.ListObjectsV2Input{
+		Bucket:    aws.String(bucket),
+		Prefix:    aws.String(key),
+		Delimiter: aws.String("/"),
+	}
+	if recursive {
+		in.Delimiter = nil
+	}
+	var objects []string
+	pages := s3.NewListObjectsV2Paginator(client, in)
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list %q: %v", prefix, err)
+		}
+		for _, o := range page.Contents {
+			objects = append(objects, aws.ToString(o.Key))
+		}
+	}
+	if recursive {
+		return objectPaths(s3Scheme, bucket, objects), nil
+	}
+	return matchObjects(s3Scheme, bucket, dir, "*", objects)
+}
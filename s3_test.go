@@ -0,0 +1,67 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"testing"
+)
+
+func TestSplitS3Path(t *testing.T) {
+	testCases := []struct {
+		path       string
+		wantBucket string
+		wantKey    string
+		wantErr    bool
+	}{
+		{
+			path:       "s3://bucket/walks/host-fswalker-state.pb",
+			wantBucket: "bucket",
+			wantKey:    "walks/host-fswalker-state.pb",
+		}, {
+			path:    "s3:///walks",
+			wantErr: true,
+		}, {
+			path:    "gcs://bucket/walks",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			bucket, key, err := splitBucketPath(s3Scheme, tc.path)
+			switch {
+			case tc.wantErr && err == nil:
+				t.Error("splitBucketPath() no error")
+			case !tc.wantErr && err != nil:
+				t.Errorf("splitBucketPath() error: %v", err)
+			case bucket != tc.wantBucket || key != tc.wantKey:
+				t.Errorf("splitBucketPath() = %q, %q; want %q, %q", bucket, key, tc.wantBucket, tc.wantKey)
+			}
+		})
+	}
+}
+
+func TestIsObjectPath(t *testing.T) {
+	for p, want := range map[string]bool{
+		"s3://bucket/walks":  true,
+		"gcs://bucket/walks": true,
+		"/tmp/walks":         false,
+		"s3:/bucket/walks":   false,
+	} {
+		if got := isObjectPath(p); got != want {
+			t.Errorf("isObjectPath(%q) = %t; want %t", p, got, want)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// WalkStore reads, writes and lists Walk files. Implement it to keep Walks somewhere other
+// than the local file system, GCS or S3, e.g. in memory or a database.
+// File names are the paths given to the Walker and Reporter, e.g. "/walks/host-...-fswalker-state.pb".
+type WalkStore interface {
+	// Write stores data under filename, replacing any existing file.
+	Write(ctx context.Context, filename string, data []byte) error
+	// Read returns the data stored under filename.
+	Read(ctx context.Context, filename string) ([]byte, error)
+	// List returns the sorted names of all files starting with prefix. Only files directly in the
+	// directory of prefix are returned, i.e. the part of the name after the last "/" of prefix
+	// doesn't contain another "/".
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// RecursiveWalkStore is a WalkStore which can also list files in subdirectories, e.g. Walks
+// written by the walker with an output directory layout.
+type RecursiveWalkStore interface {
+	WalkStore
+	// ListRecursive is like List but also returns files in any subdirectory below the directory
+	// of prefix. The part of the name after the last "/" of prefix has to start with the rest
+	// of prefix, e.g. "/walks/2018/" lists "/walks/2018/12/06/host-...-fswalker-state.pb".
+	ListRecursive(ctx context.Context, prefix string) ([]string, error)
+}
+
+// DeletableWalkStore is a WalkStore which can also delete files, e.g. to remove old Walks
+// beyond max_walk_retention of the policy.
+type DeletableWalkStore interface {
+	WalkStore
+	// Delete removes the file stored under filename.
+	Delete(ctx context.Context, filename string) error
+}
+
+// StdioPath is the Walk file name standing for stdout when writing and stdin when reading.
+const StdioPath = "-"
+
+// storeForPath returns the WalkStore handling the given path based on its URI scheme.
+func storeForPath(p string) WalkStore {
+	switch {
+	case p == StdioPath:
+		return stdioWalkStore{}
+	case isGCSPath(p):
+		return GCSWalkStore{}
+	case isS3Path(p):
+		return S3WalkStore{}
+	}
+	return LocalWalkStore{}
+}
+
+// splitListPrefix splits a List prefix into the directory to list (including the trailing
+// slash, if any) and the prefix of the file names to return.
+func splitListPrefix(prefix string) (string, string) {
+	i := strings.LastIndex(prefix, "/")
+	return prefix[:i+1], prefix[i+1:]
+}
+
+// LocalWalkStore is a WalkStore keeping Walk files on the local file system.
+// Written files are read-only.
+type LocalWalkStore struct{}
+
+// Write writes a read-only file atomically, see atomicWrite.
+func (LocalWalkStore) Write(ctx context.Context, filename string, data []byte) error {
+	return atomicWrite(filename, data, 0444)
+}
+
+// atomicWrite writes data to a temporary file in the directory of filename, syncs it to disk
+// and renames it to filename. If the process dies or the write fails midway, filename is
+// either missing or keeps its previous content, but it is never truncated or corrupt.
+func atomicWrite(filename string, data []byte, perm os.FileMode) error {
+	return atomicWriteFunc(filename, perm, func(f *os.File) error {
+		_, err := f.Write(data)
+		return err
+	})
+}
+
+// atomicWriteFunc is like atomicWrite but has write fill the temporary file.
+func atomicWriteFunc(filename string, perm os.FileMode, write func(f *os.File) error) (err error) {
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+	// The leading dot keeps the temporary file out of Walk file patterns.
+	f, err := ioutil.TempFile(dir, "."+base+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if err := write(f); err != nil {
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), filename); err != nil {
+		return err
+	}
+	// Sync the directory so the rename survives a crash as well.
+	d, err := os.Open(dir)
+	if err != nil {
+		return nil
+	}
+	defer d.Close()
+	d.Sync()
+	return nil
+}
+
+// Read reads a file.
+func (LocalWalkStore) Read(ctx context.Context, filename string) ([]byte, error) {
+	return ioutil.ReadFile(filename)
+}
+
+// Delete removes a file.
+func (LocalWalkStore) Delete(ctx context.Context, filename string) error {
+	return os.Remove(filename)
+}
+
+// List returns the matching files of a directory. Like filepath.Glob, the returned names are
+// cleaned and a missing directory results in no names rather than an error.
+func (LocalWalkStore) List(ctx context.Context, prefix string) ([]string, error) {
+	dir, pfx := splitListPrefix(prefix)
+	if dir == "" {
+		dir = "."
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasPrefix(info.Name(), pfx) {
+			names = append(names, filepath.Join(dir, info.Name()))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ListRecursive returns the matching files of a directory and all its subdirectories, sorted
+// and cleaned like List. A missing directory results in no names rather than an error.
+func (LocalWalkStore) ListRecursive(ctx context.Context, prefix string) ([]string, error) {
+	dir, pfx := splitListPrefix(prefix)
+	if dir == "" {
+		dir = "."
+	}
+	var names []string
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(filepath.ToSlash(rel), pfx) {
+			names = append(names, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// stdioWalkStore writes Walks to stdout and reads them from stdin, regardless of the file name.
+type stdioWalkStore struct{}
+
+func (stdioWalkStore) Write(ctx context.Context, filename string, data []byte) error {
+	_, err := os.Stdout.Write(data)
+	return err
+}
+
+func (stdioWalkStore) Read(ctx context.Context, filename string) ([]byte, error) {
+	return ioutil.ReadAll(os.Stdin)
+}
+
+func (stdioWalkStore) List(ctx context.Context, prefix string) ([]string, error) {
+	return nil, nil
+}
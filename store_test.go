@@ -0,0 +1,369 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+// memWalkStore is a WalkStore keeping Walk files in memory.
+type memWalkStore map[string][]byte
+
+func (s memWalkStore) Write(ctx context.Context, filename string, data []byte) error {
+	s[filename] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s memWalkStore) Read(ctx context.Context, filename string) ([]byte, error) {
+	b, ok := s[filename]
+	if !ok {
+		return nil, fmt.Errorf("%q: %v", filename, os.ErrNotExist)
+	}
+	return b, nil
+}
+
+func (s memWalkStore) Delete(ctx context.Context, filename string) error {
+	if _, ok := s[filename]; !ok {
+		return fmt.Errorf("%q: %v", filename, os.ErrNotExist)
+	}
+	delete(s, filename)
+	return nil
+}
+
+func (s memWalkStore) List(ctx context.Context, prefix string) ([]string, error) {
+	dir, _ := splitListPrefix(prefix)
+	var names []string
+	for n := range s {
+		if strings.HasPrefix(n, prefix) && !strings.Contains(strings.TrimPrefix(n, dir), "/") {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s memWalkStore) ListRecursive(ctx context.Context, prefix string) ([]string, error) {
+	var names []string
+	for n := range s {
+		if strings.HasPrefix(n, prefix) {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func TestSplitListPrefix(t *testing.T) {
+	testCases := []struct {
+		prefix  string
+		wantDir string
+		wantPfx string
+	}{
+		{prefix: "/walks/host-", wantDir: "/walks/", wantPfx: "host-"},
+		{prefix: "/walks/", wantDir: "/walks/"},
+		{prefix: "host-", wantPfx: "host-"},
+		{prefix: ""},
+	}
+	for _, tc := range testCases {
+		dir, pfx := splitListPrefix(tc.prefix)
+		if dir != tc.wantDir || pfx != tc.wantPfx {
+			t.Errorf("splitListPrefix(%q) = %q, %q; want %q, %q", tc.prefix, dir, pfx, tc.wantDir, tc.wantPfx)
+		}
+	}
+}
+
+func TestLocalWalkStore(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "walks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+	if err := os.Mkdir(filepath.Join(tmpdir, "host-archive"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var s LocalWalkStore
+	for _, n := range []string{"host-1.pb", "host-2.pb", "other-1.pb"} {
+		if err := s.Write(ctx, filepath.Join(tmpdir, n), []byte(n)); err != nil {
+			t.Fatalf("Write(%q) error: %v", n, err)
+		}
+	}
+	p := filepath.Join(tmpdir, "host-2.pb")
+	b, err := s.Read(ctx, p)
+	if err != nil {
+		t.Fatalf("Read(%q) error: %v", p, err)
+	}
+	if string(b) != "host-2.pb" {
+		t.Errorf("Read(%q) = %q; want %q", p, b, "host-2.pb")
+	}
+
+	got, err := s.List(ctx, filepath.Join(tmpdir, "host-"))
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	want := []string{filepath.Join(tmpdir, "host-1.pb"), filepath.Join(tmpdir, "host-2.pb")}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("List(): diff (-want +got):\n%s", diff)
+	}
+
+	got, err = s.List(ctx, filepath.Join(tmpdir, "missing")+"/")
+	if err != nil || len(got) != 0 {
+		t.Errorf("List() of missing directory = %q, %v; want no names and no error", got, err)
+	}
+
+	if err := s.Delete(ctx, p); err != nil {
+		t.Fatalf("Delete(%q) error: %v", p, err)
+	}
+	if _, err := s.Read(ctx, p); !os.IsNotExist(err) {
+		t.Errorf("Read(%q) after Delete() error = %v; want not exist", p, err)
+	}
+}
+
+func TestAtomicWrite(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "walks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+	p := filepath.Join(tmpdir, "host.pb")
+	ctx := context.Background()
+	var s LocalWalkStore
+	if err := s.Write(ctx, p, []byte("complete old Walk")); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+
+	// A write failing midway, e.g. because the disk is full, leaves the old file untouched.
+	errPartial := errors.New("partial write")
+	err = atomicWriteFunc(p, 0444, func(f *os.File) error {
+		if _, err := f.Write([]byte("incompl")); err != nil {
+			return err
+		}
+		return errPartial
+	})
+	if err != errPartial {
+		t.Errorf("atomicWriteFunc() error = %v; want %v", err, errPartial)
+	}
+	if b, err := s.Read(ctx, p); err != nil || string(b) != "complete old Walk" {
+		t.Errorf("Read() after failed write = %q, %v; want %q", b, err, "complete old Walk")
+	}
+	names, err := filepath.Glob(filepath.Join(tmpdir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hidden, _ := filepath.Glob(filepath.Join(tmpdir, ".*")); len(hidden) > 0 {
+		names = append(names, hidden...)
+	}
+	if diff := cmp.Diff([]string{p}, names); diff != "" {
+		t.Errorf("files after failed write: diff (-want +got):\n%s", diff)
+	}
+
+	// A successful write replaces the read-only file.
+	if err := s.Write(ctx, p, []byte("new Walk")); err != nil {
+		t.Fatalf("Write() over existing file error: %v", err)
+	}
+	if b, err := s.Read(ctx, p); err != nil || string(b) != "new Walk" {
+		t.Errorf("Read() after Write() = %q, %v; want %q", b, err, "new Walk")
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0444 {
+		t.Errorf("Write() created file with mode %v; want %v", info.Mode().Perm(), os.FileMode(0444))
+	}
+}
+
+func TestLocalWalkStoreListRecursive(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "walks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+
+	var s LocalWalkStore
+	for _, n := range []string{"host-1.pb", "2018/12/06/host/host-2.pb", "2018/12/07/other/other-1.pb", "archive/host-0.pb"} {
+		p := filepath.Join(tmpdir, n)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Write(ctx, p, []byte(n)); err != nil {
+			t.Fatalf("Write(%q) error: %v", n, err)
+		}
+	}
+
+	got, err := s.ListRecursive(ctx, filepath.Join(tmpdir, "2018")+"/")
+	if err != nil {
+		t.Fatalf("ListRecursive() error: %v", err)
+	}
+	want := []string{
+		filepath.Join(tmpdir, "2018/12/06/host/host-2.pb"),
+		filepath.Join(tmpdir, "2018/12/07/other/other-1.pb"),
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ListRecursive(): diff (-want +got):\n%s", diff)
+	}
+
+	got, err = s.ListRecursive(ctx, filepath.Join(tmpdir, "host-"))
+	if err != nil {
+		t.Fatalf("ListRecursive() error: %v", err)
+	}
+	want = []string{filepath.Join(tmpdir, "host-1.pb")}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ListRecursive(): diff (-want +got):\n%s", diff)
+	}
+
+	got, err = s.ListRecursive(ctx, filepath.Join(tmpdir, "missing")+"/")
+	if err != nil || len(got) != 0 {
+		t.Errorf("ListRecursive() of missing directory = %q, %v; want no names and no error", got, err)
+	}
+}
+
+func TestRunWalkStore(t *testing.T) {
+	ctx := context.Background()
+	store := memWalkStore{}
+	wlkr, err := WalkerFromPolicyBytes(ctx, []byte(fmt.Sprintf("include: %q", testdataDir)), "/walks/host.pb", false, WithWalkStore(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wlkr.Run(ctx); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if _, ok := store["/walks/host.pb"]; !ok {
+		t.Fatal("Run() didn't write the Walk to the store")
+	}
+
+	r := &Reporter{Store: store}
+	wlk, _, err := r.readWalk(ctx, "/walks/host.pb")
+	if err != nil {
+		t.Fatalf("readWalk() error: %v", err)
+	}
+	if wlk.Id != wlkr.walk.Id || len(wlk.File) != len(wlkr.walk.File) {
+		t.Errorf("readWalk() = Walk %q with %d files; want %q with %d files", wlk.Id, len(wlk.File), wlkr.walk.Id, len(wlkr.walk.File))
+	}
+}
+
+// withStdio replaces stdin and stdout by temporary files while fn runs. stdin contains in.
+// It returns what fn wrote to stdout.
+func withStdio(t *testing.T, in []byte, fn func()) []byte {
+	t.Helper()
+	tmpdir, err := ioutil.TempDir("", "stdio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+	if err := ioutil.WriteFile(filepath.Join(tmpdir, "stdin"), in, 0644); err != nil {
+		t.Fatal(err)
+	}
+	stdin, err := os.Open(filepath.Join(tmpdir, "stdin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	stdout, err := os.Create(filepath.Join(tmpdir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdout.Close()
+
+	origIn, origOut := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = stdin, stdout
+	defer func() { os.Stdin, os.Stdout = origIn, origOut }()
+	fn()
+
+	out, err := ioutil.ReadFile(stdout.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestStdioWalkStore(t *testing.T) {
+	ctx := context.Background()
+	want := &fspb.Walk{Id: "walk", Hostname: "host", File: []*fspb.File{{Path: "/etc/passwd"}}}
+	for _, tc := range []struct {
+		format   OutputFormat
+		compress bool
+	}{
+		{format: OutputFormatProto},
+		{format: OutputFormatProto, compress: true},
+		{format: OutputFormatJSON},
+		{format: OutputFormatJSON, compress: true},
+	} {
+		b, err := marshalWalk(want, tc.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.compress {
+			if b, err = gzipBytes(b); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var got *fspb.Walk
+		withStdio(t, b, func() {
+			got, err = ReadWalk(ctx, StdioPath)
+		})
+		if err != nil {
+			t.Errorf("ReadWalk() of %s (compressed: %t) error: %v", tc.format, tc.compress, err)
+			continue
+		}
+		if !proto.Equal(want, got) {
+			t.Errorf("ReadWalk() of %s (compressed: %t) = %v; want %v", tc.format, tc.compress, got, want)
+		}
+	}
+}
+
+func TestRunStdout(t *testing.T) {
+	ctx := context.Background()
+	wlkr, err := WalkerFromPolicyBytes(ctx, []byte(fmt.Sprintf("include: %q", testdataDir)), StdioPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := withStdio(t, nil, func() {
+		err = wlkr.Run(ctx)
+	})
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	got := &fspb.Walk{}
+	if err := proto.Unmarshal(out, got); err != nil {
+		t.Fatalf("Run() wrote no Walk to stdout: %v", err)
+	}
+	if got.Id != wlkr.walk.Id {
+		t.Errorf("Run() wrote Walk %q to stdout; want %q", got.Id, wlkr.walk.Id)
+	}
+
+	wlkr, err = WalkerFromPolicyBytes(ctx, []byte(fmt.Sprintf("include: %q delta_walk: true", testdataDir)), StdioPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wlkr.Run(ctx); err == nil {
+		t.Error("Run() of a delta Walk to stdout succeeded; want error")
+	}
+}
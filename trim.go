@@ -0,0 +1,65 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"fmt"
+	"path"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+// TrimWalk returns a copy of the Walk without the files matching any of the given glob patterns
+// (see path.Match), e.g. "/proc" or "/home/*/.cache". Files below a matching directory are removed
+// as well. Matching paths are also dropped from the deleted files of delta Walks.
+// This allows removing noisy entries from Walks generated elsewhere before comparing them.
+// The given Walk is not modified.
+func TrimWalk(w *fspb.Walk, excludePatterns []string) (*fspb.Walk, error) {
+	for _, p := range excludePatterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %v", p, err)
+		}
+	}
+	trimmed := *w
+	trimmed.Checksum = nil // no longer matches, WriteWalk sets a new one.
+	trimmed.Summary = nil  // counts the trimmed files.
+	trimmed.File = nil
+	for _, f := range w.File {
+		if !trimmedPath(f.Path, excludePatterns) {
+			trimmed.File = append(trimmed.File, f)
+		}
+	}
+	trimmed.Deleted = nil
+	for _, p := range w.Deleted {
+		if !trimmedPath(p, excludePatterns) {
+			trimmed.Deleted = append(trimmed.Deleted, p)
+		}
+	}
+	return &trimmed, nil
+}
+
+// trimmedPath determines whether p or any of its parent directories matches one of the patterns.
+func trimmedPath(p string, patterns []string) bool {
+	for ; ; p = path.Dir(p) {
+		for _, pat := range patterns {
+			if ok, _ := path.Match(pat, p); ok {
+				return true
+			}
+		}
+		if d := path.Dir(p); d == p || d == "." {
+			return false
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+func TestTrimWalk(t *testing.T) {
+	wlk := &fspb.Walk{
+		Id:       "walk",
+		Hostname: "host",
+		File: []*fspb.File{
+			{Path: "/"},
+			{Path: "/proc"},
+			{Path: "/proc/1/status"},
+			{Path: "/processes"},
+			{Path: "/home/alice/.cache/x"},
+			{Path: "/home/alice/notes"},
+		},
+		Deleted: []string{"/proc/2", "/etc/passwd"},
+		Summary: &fspb.WalkSummary{FileCount: 5, DirCount: 1},
+	}
+	testCases := []struct {
+		desc        string
+		patterns    []string
+		wantFiles   []string
+		wantDeleted []string
+		wantErr     bool
+	}{
+		{
+			desc:        "no patterns",
+			wantFiles:   []string{"/", "/proc", "/proc/1/status", "/processes", "/home/alice/.cache/x", "/home/alice/notes"},
+			wantDeleted: []string{"/proc/2", "/etc/passwd"},
+		}, {
+			desc:        "directory with contents",
+			patterns:    []string{"/proc"},
+			wantFiles:   []string{"/", "/processes", "/home/alice/.cache/x", "/home/alice/notes"},
+			wantDeleted: []string{"/etc/passwd"},
+		}, {
+			desc:        "glob",
+			patterns:    []string{"/home/*/.cache", "/etc/*"},
+			wantFiles:   []string{"/", "/proc", "/proc/1/status", "/processes", "/home/alice/notes"},
+			wantDeleted: []string{"/proc/2"},
+		}, {
+			desc:     "invalid pattern",
+			patterns: []string{"/home/["},
+			wantErr:  true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := TrimWalk(wlk, tc.patterns)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("TrimWalk() error = %v; want error: %t", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var gotFiles []string
+			for _, f := range got.File {
+				gotFiles = append(gotFiles, f.Path)
+			}
+			if diff := cmp.Diff(tc.wantFiles, gotFiles); diff != "" {
+				t.Errorf("TrimWalk() files: diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantDeleted, got.Deleted); diff != "" {
+				t.Errorf("TrimWalk() deleted: diff (-want +got):\n%s", diff)
+			}
+			if got.Id != wlk.Id || got.Hostname != wlk.Hostname {
+				t.Errorf("TrimWalk() = Walk %q of %q; want %q of %q", got.Id, got.Hostname, wlk.Id, wlk.Hostname)
+			}
+			if got.Summary != nil {
+				t.Errorf("TrimWalk() kept summary %v; want none as it no longer matches the files", got.Summary)
+			}
+		})
+	}
+	if len(wlk.File) != 6 || len(wlk.Deleted) != 2 {
+		t.Errorf("TrimWalk() modified the given Walk")
+	}
+}
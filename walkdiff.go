@@ -0,0 +1,104 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"context"
+	"time"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+// walkDiffFilenameFormat is the layout of WalkDiff file names, see WalkFilenameWithFormat.
+const walkDiffFilenameFormat = hostnamePlaceholder + "-" + tsFileFormat + "-fswalker-diff.pb"
+
+// WalkDiffFilename returns the filename for the WalkDiff of the given host and time, e.g. to
+// keep it alongside the Walk files. It doesn't match the patterns the Reporter uses to find
+// Walk files. If time is not provided, it returns a file pattern to glob by.
+func WalkDiffFilename(hostname string, t time.Time) string {
+	return WalkFilenameWithFormat(hostname, t, walkDiffFilenameFormat)
+}
+
+// WalkDiffStore reads and writes WalkDiff files to keep comparison results, e.g. to build a
+// history of changes for trend analysis. As for Walk files, the encoding and compression are
+// determined by the file extension.
+type WalkDiffStore struct {
+	// Store keeps the files. If nil, it is determined by the URI scheme of each path
+	// (local file system, GCS or S3).
+	Store WalkStore
+}
+
+// store returns the store keeping the file at path.
+func (s WalkDiffStore) store(path string) WalkStore {
+	if s.Store != nil {
+		return s.Store
+	}
+	return storeForPath(path)
+}
+
+// Write writes a WalkDiff to path.
+func (s WalkDiffStore) Write(ctx context.Context, path string, wd *fspb.WalkDiff) error {
+	b, err := marshalProto(wd, formatFromPath(path))
+	if err != nil {
+		return &WalkProtoError{Path: path, Err: err}
+	}
+	if isCompressed(path) {
+		if b, err = gzipBytes(b); err != nil {
+			return &WalkIOError{Path: path, Err: err}
+		}
+	}
+	if err := s.store(path).Write(ctx, path, b); err != nil {
+		return &WalkIOError{Path: path, Err: err}
+	}
+	return nil
+}
+
+// Read reads the WalkDiff at path.
+func (s WalkDiffStore) Read(ctx context.Context, path string) (*fspb.WalkDiff, error) {
+	b, err := s.store(path).Read(ctx, path)
+	if err != nil {
+		return nil, &WalkIOError{Path: path, Err: err}
+	}
+	if isCompressed(path) {
+		if b, err = gunzip(b); err != nil {
+			return nil, &WalkProtoError{Path: path, Err: err}
+		}
+	}
+	wd := &fspb.WalkDiff{}
+	if err := unmarshalProto(b, formatFromPath(path), wd); err != nil {
+		return nil, &WalkProtoError{Path: path, Err: err}
+	}
+	return wd, nil
+}
+
+// LoadDiff reads a WalkDiff written by Reporter.SaveDiff from a local path or a gcs:// or
+// s3:// URI.
+func LoadDiff(ctx context.Context, path string) (*fspb.WalkDiff, error) {
+	return WalkDiffStore{}.Read(ctx, path)
+}
+
+// SaveDiff compares the loaded Walks like CompareToDiff and writes the resulting WalkDiff to
+// outPath, using the Store of the Reporter if set. The comparison isn't counted in the metrics,
+// so SaveDiff can be called in addition to Compare.
+func (r *Reporter) SaveDiff(ctx context.Context, outPath string) error {
+	c := r.Clone()
+	c.Counter = nil
+	c.before, c.beforeFile, c.after, c.afterFile = r.before, r.beforeFile, r.after, r.afterFile
+	wd, err := c.CompareToDiff(ctx)
+	if err != nil {
+		return err
+	}
+	return WalkDiffStore{Store: r.Store}.Write(ctx, outPath, wd)
+}
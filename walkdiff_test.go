@@ -0,0 +1,123 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/google/fswalker/internal/metrics"
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+func TestWalkDiffFilename(t *testing.T) {
+	ts := time.Date(2018, 12, 06, 10, 01, 02, 0, time.UTC)
+	if got, want := WalkDiffFilename("host", ts), "host-20181206-100102-fswalker-diff.pb"; got != want {
+		t.Errorf("WalkDiffFilename() = %q; want %q", got, want)
+	}
+	if got, want := WalkDiffFilename("host", time.Time{}), "host-*-fswalker-diff.pb"; got != want {
+		t.Errorf("WalkDiffFilename() without time = %q; want %q", got, want)
+	}
+}
+
+func TestSaveDiff(t *testing.T) {
+	ctx := context.Background()
+	store := memWalkStore{}
+	r := &Reporter{
+		config:     &fspb.ReportConfig{},
+		Counter:    &metrics.Counter{},
+		Store:      store,
+		beforeFile: "/walks/before.pb",
+		before: &fspb.Walk{
+			Id:       "before",
+			Hostname: "host",
+			File: []*fspb.File{
+				{Version: 1, Path: "/etc/deleted", Info: &fspb.FileInfo{Size: 1}},
+				{Version: 1, Path: "/etc/modified", Info: &fspb.FileInfo{Size: 1}},
+			},
+		},
+		afterFile: "/walks/after.pb",
+		after: &fspb.Walk{
+			Id:       "after",
+			Hostname: "host",
+			File: []*fspb.File{
+				{Version: 1, Path: "/etc/added", Info: &fspb.FileInfo{Size: 1}},
+				{Version: 1, Path: "/etc/modified", Info: &fspb.FileInfo{Size: 2}},
+			},
+		},
+	}
+	want, err := r.CompareToDiff(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Counter.Reset()
+
+	for _, p := range []string{"/diffs/host.pb", "/diffs/host.json", "/diffs/host.textproto.gz"} {
+		if err := r.SaveDiff(ctx, p); err != nil {
+			t.Fatalf("SaveDiff(%q) error: %v", p, err)
+		}
+		got, err := WalkDiffStore{Store: store}.Read(ctx, p)
+		if err != nil {
+			t.Fatalf("Read(%q) error: %v", p, err)
+		}
+		if diff := cmp.Diff(want, got, cmp.Comparer(proto.Equal)); diff != "" {
+			t.Errorf("Read(%q): diff (-want +got):\n%s", p, diff)
+		}
+	}
+	if m := r.Counter.Snapshot(); len(m) != 0 {
+		t.Errorf("SaveDiff() counted metrics %v; want none", m)
+	}
+
+	if err := (&Reporter{}).SaveDiff(ctx, "/diffs/none.pb"); err == nil {
+		t.Error("SaveDiff() without loaded Walks succeeded; want error")
+	}
+}
+
+func TestLoadDiff(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "diffs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+
+	want := &fspb.WalkDiff{
+		Hostname: "host",
+		FileDiff: []*fspb.FileDiff{{DiffType: fspb.FileDiff_ADDED, Path: "/etc/added"}},
+	}
+	p := filepath.Join(tmpdir, WalkDiffFilename("host", time.Now()))
+	if err := (WalkDiffStore{}).Write(ctx, p, want); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	got, err := LoadDiff(ctx, p)
+	if err != nil {
+		t.Fatalf("LoadDiff() error: %v", err)
+	}
+	if !proto.Equal(want, got) {
+		t.Errorf("LoadDiff() = %v; want %v", got, want)
+	}
+
+	if _, err := LoadDiff(ctx, filepath.Join(tmpdir, "missing.pb")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadDiff() of missing file error = %v; want not exist", err)
+	}
+}
@@ -16,14 +16,25 @@ package fswalker
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"hash"
 	"io/ioutil"
 	"log"
+	"math"
 	"os"
+	"os/user"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/google/fswalker/internal/metrics"
 
@@ -36,8 +47,9 @@ import (
 )
 
 const (
-	// Number of root paths to walk in parallel.
-	parallelism = 1
+	// Number of directories which can be queued per walking routine before
+	// routines start reading newly discovered directories themselves.
+	dirQueueFactor = 64
 
 	// Versions for compatibility comparison.
 	fileVersion = 1
@@ -49,20 +61,134 @@ const (
 	countFileSizeSum = "file-size-sum"
 	countStatErr     = "file-stat-errors"
 	countHashes      = "file-hash-count"
+	countBrokenLinks = "symlink-broken-count"
+	countLinkLoops   = "symlink-loops"
+	countErrors      = "errors"
+	countExcluded    = "excluded-path-count"
+	countAgeFiltered = "age-filtered-count"
+	countSampledOut  = "hash-sampled-out-count"
+	countExtSkipped  = "hash-extension-skipped-count"
+	countWalksPurged = "walk-files-deleted"
+	countArchived    = "archive-entry-count"
+	countArchiveErr  = "archive-errors"
+	countRootMissing = "root-missing-count"
 )
 
+// defaultMaxHashFileSize is the size up to which files are hashed if the policy doesn't say.
+const defaultMaxHashFileSize = 1024 * 1024
+
+// WalkerOption configures optional aspects of a Walker created from a policy.
+type WalkerOption func(*Walker)
+
+// WithWalkStore makes the Walker write its Walk to the given store and look up base Walks
+// of delta walks there, rather than choosing the store by the URI scheme of the output path.
+func WithWalkStore(store WalkStore) WalkerOption {
+	return func(w *Walker) {
+		w.store = store
+	}
+}
+
+// RequireRootPaths makes creating the Walker fail with an InvalidPolicyError if any include path
+// of the policy doesn't exist on this host (see Policy.ValidateRoots), rather than skipping it.
+func RequireRootPaths() WalkerOption {
+	return func(w *Walker) {
+		w.requireRoots = true
+	}
+}
+
 // WalkerFromPolicyFile creates a new Walker based on a policy path.
-func WalkerFromPolicyFile(ctx context.Context, path, outpath string, verbose bool) (*Walker, error) {
-	pol := &fspb.Policy{}
-	if err := readTextProto(ctx, path, pol); err != nil {
+// The path may also be an HTTP(S) URL in which case the policy is fetched with PolicyHTTPClient.
+func WalkerFromPolicyFile(ctx context.Context, path, outpath string, verbose bool, opts ...WalkerOption) (*Walker, error) {
+	var b []byte
+	var err error
+	if isURL(path) {
+		b, err = fetchURL(ctx, PolicyHTTPClient, path)
+	} else {
+		b, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, &PolicyLoadError{Path: path, Err: err}
+	}
+	w, err := WalkerFromPolicyBytes(ctx, b, outpath, verbose, opts...)
+	if err != nil {
 		return nil, err
 	}
-	return &Walker{
-		pol:     pol,
-		Outpath: outpath,
-		Verbose: verbose,
-		Counter: &metrics.Counter{},
-	}, nil
+	w.PolicyName = path
+	return w, nil
+}
+
+// WalkerFromPolicyBytes creates a new Walker based on a text format or JSON encoded policy.
+func WalkerFromPolicyBytes(ctx context.Context, data []byte, outpath string, verbose bool, opts ...WalkerOption) (*Walker, error) {
+	pol := &fspb.Policy{}
+	if err := unmarshalConfig(data, pol); err != nil {
+		return nil, &InvalidPolicyError{Err: err}
+	}
+	return NewWalker(ctx, pol, outpath, verbose, opts...)
+}
+
+// NewWalker creates a new Walker for a policy built in code, e.g. from a Kubernetes ConfigMap.
+// The policy is validated like one read from a file (see Policy.Validate); if it is invalid,
+// the InvalidPolicyError wraps all problems found. It must not be modified afterwards.
+func NewWalker(ctx context.Context, pol *fspb.Policy, outpath string, verbose bool, opts ...WalkerOption) (*Walker, error) {
+	if pol == nil {
+		return nil, &InvalidPolicyError{Err: fmt.Errorf("no policy given")}
+	}
+	if errs := pol.Validate(); len(errs) > 0 {
+		return nil, &InvalidPolicyError{Err: errors.Join(errs...)}
+	}
+	excludeRegex, err := compileRegexps(pol.ExcludeRegex)
+	if err != nil {
+		return nil, &InvalidPolicyError{Err: fmt.Errorf("invalid exclude_regex: %w", err)}
+	}
+	w := &Walker{
+		pol:          pol,
+		excludeRegex: excludeRegex,
+		Outpath:      outpath,
+		Verbose:      verbose,
+		Counter:      &metrics.Counter{},
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.requireRoots {
+		if errs := pol.ValidateRoots(); len(errs) > 0 {
+			return nil, &InvalidPolicyError{Err: errors.Join(errs...)}
+		}
+	}
+	return w, nil
+}
+
+// mtimeWindow returns the range of modification times of files to record according to
+// min_mtime_age and max_mtime_age of the policy, relative to start. A zero time means unbounded.
+func mtimeWindow(pol *fspb.Policy, start time.Time) (oldest, newest time.Time, err error) {
+	if pol.MinMtimeAge != nil {
+		d, err := ptypes.Duration(pol.MinMtimeAge)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid min_mtime_age: %v", err)
+		}
+		newest = start.Add(-d)
+	}
+	if pol.MaxMtimeAge != nil {
+		d, err := ptypes.Duration(pol.MaxMtimeAge)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid max_mtime_age: %v", err)
+		}
+		oldest = start.Add(-d)
+	}
+	return oldest, newest, nil
+}
+
+// compileRegexps compiles all given regular expressions.
+func compileRegexps(exprs []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, e := range exprs {
+		re, err := regexp.Compile(e)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, re)
+	}
+	return res, nil
 }
 
 // Walker is able to walk a file structure starting with a list of given includes
@@ -73,6 +199,12 @@ func WalkerFromPolicyFile(ctx context.Context, path, outpath string, verbose boo
 type Walker struct {
 	// pol is the configuration defining which paths to include and exclude from the walk.
 	pol *fspb.Policy
+	// excludeRegex are the compiled exclude_regex expressions of the policy.
+	excludeRegex []*regexp.Regexp
+	// mtimeOldest and mtimeNewest bound the modification times of recorded files during a run.
+	// Zero values mean unbounded.
+	mtimeOldest time.Time
+	mtimeNewest time.Time
 
 	// walk collects all processed files during a run.
 	walk   *fspb.Walk
@@ -80,12 +212,134 @@ type Walker struct {
 
 	// Outpath, if non-empty, is where Walk will be written to.
 	Outpath string
+	// OutputRoot and OutputDirLayout, if both set, tell that Outpath is in the subdirectory of
+	// OutputRoot given by OutputDirLayout (see OutputDir), e.g. a new one every day. Old Walks
+	// beyond max_walk_retention are then searched in all subdirectories of OutputRoot rather
+	// than only next to Outpath, and so is the base Walk of a delta walk.
+	OutputRoot      string
+	OutputDirLayout string
+	// FilenameFormat, if non-empty, is the layout of the Walk file names (see
+	// WalkFilenameWithFormat) used to find the base Walk of a delta walk and old Walks beyond
+	// max_walk_retention. Defaults to DefaultWalkFilenameFormat.
+	FilenameFormat string
+	// store, if non-nil, is where the Walk is written to. Otherwise it is determined by the
+	// URI scheme of Outpath.
+	store WalkStore
+
+	// OutputFormat is the format in which Walk is written to Outpath.
+	// Defaults to binary proto if empty.
+	OutputFormat OutputFormat
+
+	// Compress, when true, makes Walker gzip compress the Walk written to Outpath.
+	Compress bool
 
 	// Verbose, when true, makes Walker print file metadata to stdout.
 	Verbose bool
 
+	// Hostname, if non-empty, is recorded in the Walk instead of the result of os.Hostname,
+	// e.g. the logical service name in a container. It is also used to find base Walks of
+	// delta walks and old Walks beyond max_walk_retention.
+	Hostname string
+
+	// PolicyName is recorded in the summary of the Walk to identify the policy used.
+	// WalkerFromPolicyFile sets it to the path or URL of the policy.
+	PolicyName string
+
 	// Counter records stats over all processed files, if non-nil.
+	// It is reset at the start of each run so it only reflects the latest Walk.
 	Counter *metrics.Counter
+
+	// MaxErrors, if non-zero, overrides max_errors of the policy.
+	MaxErrors uint32
+
+	// MaxHashFileSize, if non-zero, overrides max_hash_file_size of the policy.
+	MaxHashFileSize int64
+
+	// DryRun, when true, makes Walker collect all files as usual without writing the Walk
+	// to Outpath. Run then fails if any file or directory could not be walked.
+	DryRun bool
+
+	// ProbeDirs, if positive, is the number of directories Probe reads from each root path.
+	// Defaults to 100.
+	ProbeDirs int
+
+	// DryRunCleanup, when true, makes Walker only log the old Walks it would delete according
+	// to max_walk_retention of the policy instead of deleting them.
+	DryRunCleanup bool
+
+	// names caches the resolved owner and group names of files.
+	names idNameCache
+
+	// hasher, if non-nil, creates the hashes used for fingerprints of hasherMethod, see SetHasher.
+	hasher       func() hash.Hash
+	hasherMethod fspb.Fingerprint_Method
+
+	// requireRoots makes NewWalker fail on missing include paths, see RequireRootPaths.
+	requireRoots bool
+
+	// progress, if non-nil, receives progress updates during a run.
+	progress    chan<- WalkProgress
+	filesSeen   int64 // accessed atomically.
+	bytesHashed int64 // accessed atomically.
+	errCount    int64 // accessed atomically.
+	// started is set once Run has been called, freezing the root paths.
+	started int32 // accessed atomically.
+	// ownPolicy is whether pol is a copy owned by the Walker, which may be modified.
+	ownPolicy bool
+}
+
+// idNameCache caches user and group name lookups, as most files share a handful of owners.
+// IDs which don't resolve are cached with an empty name.
+type idNameCache struct {
+	mu     sync.Mutex
+	users  map[uint32]string
+	groups map[uint32]string
+}
+
+// userName returns the name of the user with the given ID.
+func (c *idNameCache) userName(uid uint32) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if name, ok := c.users[uid]; ok {
+		return name
+	}
+	if c.users == nil {
+		c.users = map[uint32]string{}
+	}
+	var name string
+	if u, err := user.LookupId(fmt.Sprint(uid)); err == nil {
+		name = u.Username
+	}
+	c.users[uid] = name
+	return name
+}
+
+// groupName returns the name of the group with the given ID.
+func (c *idNameCache) groupName(gid uint32) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if name, ok := c.groups[gid]; ok {
+		return name
+	}
+	if c.groups == nil {
+		c.groups = map[uint32]string{}
+	}
+	var name string
+	if g, err := user.LookupGroupId(fmt.Sprint(gid)); err == nil {
+		name = g.Name
+	}
+	c.groups[gid] = name
+	return name
+}
+
+// WalkProgress describes how far a running walk has come.
+type WalkProgress struct {
+	// Dir is the directory which is currently being read.
+	Dir string
+	// Files is the number of files (including directories) processed so far.
+	Files int64
+	// BytesHashed is the number of bytes of file content fingerprinted so far.
+	BytesHashed int64
 }
 
 // convert creates a File from the given information and if requested embeds the hash sum too.
@@ -99,23 +353,81 @@ func (w *Walker) convert(path string, info os.FileInfo) *fspb.File {
 		return f
 	}
 
-	var shaSum string
 	// Only build the hash sum if requested and if it is not a directory.
-	if w.wantHashing(path) && !info.IsDir() && info.Size() <= w.pol.MaxHashFileSize {
-		var err error
-		shaSum, err = sha256sum(path)
+	wantHash := w.wantHashing(path) && !info.IsDir() && info.Size() <= w.maxHashFileSize()
+	if wantHash && !w.hashedExtension(path) {
+		wantHash = false
+		if w.Counter != nil {
+			w.Counter.Add(1, countExtSkipped)
+		}
+	}
+	if wantHash && !w.inHashSample(path) {
+		wantHash = false
+		if w.Counter != nil {
+			w.Counter.Add(1, countSampledOut)
+		}
+	}
+	if wantHash {
+		method := w.hashMethod()
+		h, err := w.newHash()
+		var sum string
+		if err == nil {
+			sum, err = hashFile(path, h)
+		}
 		if err != nil {
 			log.Printf("unable to build hash for %s: %s", path, err)
 		} else {
+			atomic.AddInt64(&w.bytesHashed, info.Size())
 			f.Fingerprint = []*fspb.Fingerprint{
 				{
-					Method: fspb.Fingerprint_SHA256,
-					Value:  shaSum,
+					Method: method,
+					Value:  sum,
 				},
 			}
 		}
 	}
 
+	if w.pol.ComputeEntropy && info.Mode().IsRegular() && info.Size() <= w.maxHashFileSize() {
+		entropy, err := fileEntropy(path)
+		if err != nil {
+			log.Printf("unable to compute entropy for %s: %s", path, err)
+		} else {
+			f.Entropy = entropy
+		}
+	}
+
+	if w.pol.DetectMimeType && info.Mode().IsRegular() {
+		mt, err := detectMimeType(path)
+		if err != nil {
+			log.Printf("unable to detect MIME type for %s: %s", path, err)
+		} else {
+			f.MimeType = mt
+		}
+	}
+
+	// Symlinks are skipped as their extended attributes can't be read without following them.
+	if w.pol.CaptureXattrs && (info.Mode().IsRegular() || info.IsDir()) {
+		xattrs, err := listXattrs(path)
+		if err != nil {
+			log.Printf("unable to read extended attributes for %s: %s", path, err)
+		} else if len(xattrs) > 0 {
+			f.Xattrs = xattrs
+		}
+	}
+
+	if w.pol.CaptureCapabilities && info.Mode().IsRegular() {
+		caps, err := fileCapabilities(path)
+		if err != nil {
+			log.Printf("unable to read capabilities of %s: %s", path, err)
+		} else {
+			f.Capabilities = caps
+		}
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		w.readSymlink(f)
+	}
+
 	mts, _ := ptypes.TimestampProto(info.ModTime()) // ignoring the error and using default
 	f.Info = &fspb.FileInfo{
 		Name:     info.Name(),
@@ -150,11 +462,149 @@ func (w *Walker) convert(path string, info os.FileInfo) *fspb.File {
 				Nanos:   int32(stat.Ctim.Nsec),
 			},
 		}
+		f.OwnerName = w.names.userName(stat.Uid)
+		f.GroupName = w.names.groupName(stat.Gid)
+		if w.pol.CaptureBirthTime {
+			btime, err := birthTime(path)
+			if err != nil {
+				log.Printf("unable to read birth time of %s: %s", path, err)
+			}
+			f.Stat.Btime = btime
+		}
 	}
 
 	return f
 }
 
+// maxHashFileSize returns the size in bytes up to which files are hashed.
+func (w *Walker) maxHashFileSize() int64 {
+	switch {
+	case w.MaxHashFileSize != 0:
+		return w.MaxHashFileSize
+	case w.pol.MaxHashFileSize != 0:
+		return w.pol.MaxHashFileSize
+	}
+	return defaultMaxHashFileSize
+}
+
+// errWalkStarted is returned when changing the root paths of a Walker which has started running.
+var errWalkStarted = errors.New("root paths can't be changed after Run has started")
+
+// mutablePolicy returns the policy of the Walker, copying it first if it belongs to the caller.
+func (w *Walker) mutablePolicy() *fspb.Policy {
+	if !w.ownPolicy {
+		w.pol = proto.Clone(w.pol).(*fspb.Policy)
+		w.ownPolicy = true
+	}
+	return w.pol
+}
+
+// AddRootPath adds an existing path to walk in addition to the includes of the policy, as if it
+// were listed in include. The policy given to the Walker is not modified, but the policy
+// recorded in the Walk contains the path. It fails if Run has started already.
+func (w *Walker) AddRootPath(path string) error {
+	if atomic.LoadInt32(&w.started) != 0 {
+		return errWalkStarted
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("invalid root path: %w", err)
+	}
+	pol := w.mutablePolicy()
+	pol.Include = append(pol.Include, path)
+	return nil
+}
+
+// RemoveRootPath removes a path from the include and include_path entries of the policy, so it
+// is not walked. Paths are compared after cleaning them. The policy given to the Walker is not
+// modified. It fails if Run has started already or if the path is not walked.
+func (w *Walker) RemoveRootPath(path string) error {
+	if atomic.LoadInt32(&w.started) != 0 {
+		return errWalkStarted
+	}
+	path = filepath.Clean(path)
+	pol := w.mutablePolicy()
+	found := false
+	var include []string
+	for _, p := range pol.Include {
+		if filepath.Clean(p) == path {
+			found = true
+			continue
+		}
+		include = append(include, p)
+	}
+	var includePath []*fspb.PathConfig
+	for _, pc := range pol.IncludePath {
+		if filepath.Clean(pc.Path) == path {
+			found = true
+			continue
+		}
+		includePath = append(includePath, pc)
+	}
+	if !found {
+		return fmt.Errorf("%q is not a root path of the walk", path)
+	}
+	pol.Include, pol.IncludePath = include, includePath
+	return nil
+}
+
+// MissingRootPaths returns the include and include_path entries of the policy which don't exist
+// on this host. They are not an error as policies are often shared by many hosts, but they are
+// not walked either (see RequireRootPaths).
+func (w *Walker) MissingRootPaths() []string {
+	var missing []string
+	for _, pc := range w.pol.IncludePaths() {
+		if _, err := os.Lstat(pc.Path); os.IsNotExist(err) {
+			missing = append(missing, pc.Path)
+		}
+	}
+	return missing
+}
+
+// PolicyMaxHashFileSize returns max_hash_file_size as set in the policy, 0 if unset.
+func (w *Walker) PolicyMaxHashFileSize() int64 {
+	return w.pol.MaxHashFileSize
+}
+
+// SetHasher makes the Walker build fingerprints with hashes created by factory instead of the
+// implementation of hash_algorithm of the policy, e.g. a hardware-accelerated SHA-256. A new
+// hash is created for each file as files are hashed concurrently. Fingerprints are recorded
+// with method, which factory needs to implement for the Walks to be comparable with those of
+// other walkers; Run fails if the size of its sums doesn't match. It must be called before Run.
+func (w *Walker) SetHasher(method fspb.Fingerprint_Method, factory func() hash.Hash) {
+	w.hasher, w.hasherMethod = factory, method
+}
+
+// checkHasher verifies that the hasher set with SetHasher produces sums of its method's size.
+func (w *Walker) checkHasher() error {
+	if w.hasher == nil {
+		return nil
+	}
+	want, err := newHash(w.hasherMethod)
+	if err != nil {
+		return fmt.Errorf("invalid hasher: %v", err)
+	}
+	if got := w.hasher().Size(); got != want.Size() {
+		return fmt.Errorf("invalid hasher: it builds %d byte sums, %s sums have %d bytes", got, w.hasherMethod, want.Size())
+	}
+	return nil
+}
+
+// hashMethod returns the method fingerprints are built with, see SetHasher.
+func (w *Walker) hashMethod() fspb.Fingerprint_Method {
+	if w.hasher != nil {
+		return w.hasherMethod
+	}
+	return hashAlgorithm(w.pol)
+}
+
+// newHash returns a new hash for fingerprints of hashMethod, see SetHasher.
+func (w *Walker) newHash() (hash.Hash, error) {
+	if w.hasher != nil {
+		return w.hasher(), nil
+	}
+	return newHash(hashAlgorithm(w.pol))
+}
+
 // wantHashing determines whether the given path was asked to be hashed.
 func (w *Walker) wantHashing(path string) bool {
 	for _, p := range w.pol.HashPfx {
@@ -165,6 +615,38 @@ func (w *Walker) wantHashing(path string) bool {
 	return false
 }
 
+// hasExtension returns whether the file name of path ends with any of the given extensions,
+// ignoring case. Extensions may be given with or without leading dot.
+func hasExtension(path string, exts []string) bool {
+	name := strings.ToLower(filepath.Base(path))
+	for _, e := range exts {
+		if e = strings.ToLower(strings.TrimPrefix(e, ".")); e != "" && strings.HasSuffix(name, "."+e) {
+			return true
+		}
+	}
+	return false
+}
+
+// hashedExtension determines whether the extension of the given path allows hashing according
+// to no_hash_extensions and hash_only_extensions.
+func (w *Walker) hashedExtension(path string) bool {
+	if hasExtension(path, w.pol.NoHashExtensions) {
+		return false
+	}
+	return len(w.pol.HashOnlyExtensions) == 0 || hasExtension(path, w.pol.HashOnlyExtensions)
+}
+
+// inHashSample determines whether the given path is among the files to hash according to
+// sample_rate. The choice is based on a hash of the path so the same files are hashed in each run.
+func (w *Walker) inHashSample(path string) bool {
+	rate := w.pol.SampleRate
+	if rate <= 0 || rate >= 1 {
+		return true
+	}
+	sum := sha256.Sum256([]byte(path))
+	return float64(binary.BigEndian.Uint64(sum[:8])) < rate*math.MaxUint64
+}
+
 // isExcluded determines whether a given path was asked to be excluded from scanning.
 func (w *Walker) isExcluded(path string) bool {
 	for _, e := range w.pol.ExcludePfx {
@@ -175,6 +657,122 @@ func (w *Walker) isExcluded(path string) bool {
 	return false
 }
 
+// matchesExcludePath determines whether a given path matches any of the exclude_paths glob patterns.
+// Malformed patterns never match; they are rejected when the policy is loaded.
+func (w *Walker) matchesExcludePath(p string) bool {
+	for _, e := range w.pol.ExcludePaths {
+		if ok, _ := path.Match(e, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesExcludeRegex determines whether a given path matches any of the exclude_regex expressions.
+func (w *Walker) matchesExcludeRegex(p string) bool {
+	for _, re := range w.excludeRegex {
+		if re.MatchString(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// inMtimeWindow determines whether a file modified at mtime is within the age window of the policy.
+func (w *Walker) inMtimeWindow(mtime time.Time) bool {
+	if !w.mtimeOldest.IsZero() && mtime.Before(w.mtimeOldest) {
+		return false
+	}
+	if !w.mtimeNewest.IsZero() && mtime.After(w.mtimeNewest) {
+		return false
+	}
+	return true
+}
+
+// excludeRule returns the name of the policy field excluding the given path, or "" if the
+// path isn't excluded by a pattern.
+func (w *Walker) excludeRule(p string) string {
+	switch {
+	case w.matchesExcludePath(p):
+		return "exclude_paths"
+	case w.matchesExcludeRegex(p):
+		return "exclude_regex"
+	}
+	return ""
+}
+
+// ScanFile captures the metadata of a single file the same way a Walker does, without requiring
+// a policy or a full walk. The file content is fingerprinted with the default hash algorithm if
+// the file is not larger than maxHashFileSize. The returned File is the same as in a Walk.
+// Symlinks are not followed.
+func ScanFile(ctx context.Context, path string, maxHashFileSize int64) (*fspb.File, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &Walker{
+		pol: &fspb.Policy{
+			HashPfx:         []string{path},
+			MaxHashFileSize: maxHashFileSize,
+		},
+		walk: &fspb.Walk{},
+	}
+	return w.convert(path, info), nil
+}
+
+// WalkOptions configures NewWalkFromDirectory. The zero value walks and hashes all files.
+type WalkOptions struct {
+	// MaxHashFileSize is the size in bytes up to which files are hashed, see max_hash_file_size.
+	MaxHashFileSize int64
+	// NoHash disables hashing of file content altogether.
+	NoHash bool
+	// ExcludePatterns are glob patterns of paths to skip, see exclude_paths.
+	ExcludePatterns []string
+	// MaxDepth, if non-zero, limits the levels of directories walked into root.
+	MaxDepth uint32
+}
+
+// NewWalkFromDirectory walks root the same way a Walker does and returns the resulting Walk
+// without writing it anywhere. It is meant for tests and tooling which need a Walk of a
+// directory without setting up a policy. Files are sorted by path. A missing root is an error.
+func NewWalkFromDirectory(ctx context.Context, root string, opts WalkOptions) (*fspb.Walk, error) {
+	pol := &fspb.Policy{
+		Version:           1,
+		Include:           []string{root},
+		ExcludePaths:      opts.ExcludePatterns,
+		MaxHashFileSize:   opts.MaxHashFileSize,
+		MaxDirectoryDepth: opts.MaxDepth,
+		SortOrder:         fspb.Policy_PATH_LEXICAL,
+	}
+	if !opts.NoHash {
+		pol.HashPfx = []string{root}
+	}
+	w, err := NewWalker(ctx, pol, "", false, RequireRootPaths())
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Run(ctx); err != nil {
+		return nil, err
+	}
+	return w.walk, nil
+}
+
+// readSymlink records the target of a symlink and flags the link if the target does not exist.
+func (w *Walker) readSymlink(f *fspb.File) {
+	target, err := os.Readlink(f.Path)
+	if err != nil {
+		log.Printf("unable to read symlink %s: %s", f.Path, err)
+		return
+	}
+	f.SymlinkTarget = target
+	if _, err := os.Stat(f.Path); os.IsNotExist(err) {
+		w.addNotificationToWalk(fspb.Notification_INFO, f.Path, fmt.Sprintf("broken symlink %q: target %q does not exist", f.Path, target))
+		if w.Counter != nil {
+			w.Counter.Add(1, countBrokenLinks)
+		}
+	}
+}
+
 // process runs output functions for the given input File.
 func (w *Walker) process(ctx context.Context, f *fspb.File) error {
 	// Print a short overview if we're running in verbose mode.
@@ -197,6 +795,7 @@ func (w *Walker) process(ctx context.Context, f *fspb.File) error {
 
 	// Add file to the walk which will later be written out to disk.
 	w.addFileToWalk(f)
+	atomic.AddInt64(&w.filesSeen, 1)
 
 	// Collect some metrics.
 	if w.Counter != nil {
@@ -217,6 +816,39 @@ func (w *Walker) process(ctx context.Context, f *fspb.File) error {
 	return nil
 }
 
+// recordError counts a file or directory which could not be walked and returns an error
+// once the configured maximum number of errors is reached.
+func (w *Walker) recordError(err error) error {
+	n := atomic.AddInt64(&w.errCount, 1)
+	if w.Counter != nil {
+		w.Counter.Add(1, countErrors)
+	}
+	max := w.MaxErrors
+	if max == 0 {
+		max = w.pol.MaxErrors
+	}
+	if max > 0 && n >= int64(max) {
+		return &WalkIOError{Err: fmt.Errorf("reached maximum of %d errors, last error: %w", max, err)}
+	}
+	return nil
+}
+
+// reportProgress sends a progress update if a progress channel was given.
+// Updates are dropped rather than blocking the walk when the receiver is not ready.
+func (w *Walker) reportProgress(dir string) {
+	if w.progress == nil {
+		return
+	}
+	select {
+	case w.progress <- WalkProgress{
+		Dir:         dir,
+		Files:       atomic.LoadInt64(&w.filesSeen),
+		BytesHashed: atomic.LoadInt64(&w.bytesHashed),
+	}:
+	default:
+	}
+}
+
 func (w *Walker) addFileToWalk(f *fspb.File) {
 	w.walkMu.Lock()
 	w.walk.File = append(w.walk.File, f)
@@ -238,75 +870,377 @@ func (w *Walker) relDirDepth(origin, path string) uint32 {
 	return uint32(len(strings.Split(path, string(filepath.Separator))) - len(strings.Split(origin, string(filepath.Separator))))
 }
 
-// worker is a worker routine that reads paths from chPaths and walks all the files and
-// subdirectories until the channel is exhausted. All discovered files are converted to
-// File and processed with w.process().
-func (w *Walker) worker(ctx context.Context, chPaths <-chan string) error {
-	for path := range chPaths {
-		baseInfo, err := os.Stat(path)
+// walkFunc returns a filepath.WalkFunc which processes all files discovered under the include
+// path root, walking at most maxDepth levels of directories into it if non-zero.
+// Files are converted to File and processed with w.process().
+func (w *Walker) walkFunc(ctx context.Context, root string, maxDepth uint32, baseStat *syscall.Stat_t) filepath.WalkFunc {
+	return func(p string, info os.FileInfo, err error) error {
 		if err != nil {
-			return fmt.Errorf("unable to get file info for base path %q: %v", path, err)
-		}
-		baseStat, ok := baseInfo.Sys().(*syscall.Stat_t)
-		if !ok {
-			return fmt.Errorf("unable to get file stat on base path: %q", path)
+			msg := fmt.Sprintf("failed to walk %q: %s", p, err)
+			log.Print(msg)
+			w.addNotificationToWalk(fspb.Notification_WARNING, p, msg)
+			return w.recordError(err) // nil unless max_errors is reached
 		}
+		// Symlinks which would be followed are skipped like the directories they point to.
+		dirLike := info.IsDir() || w.followsSymlink(p, info)
 
-		if err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
-			if err != nil {
-				msg := fmt.Sprintf("failed to walk %q: %s", p, err)
-				log.Printf(msg)
-				w.addNotificationToWalk(fspb.Notification_WARNING, p, msg)
-				return nil // returning SkipDir on a file would skip the rest of the files in the dir
+		// Checking various exclusions based on flags in the walker policy.
+		if w.isExcluded(p) {
+			if w.Verbose {
+				w.addNotificationToWalk(fspb.Notification_INFO, p, fmt.Sprintf("skipping %q: excluded", p))
 			}
-
-			// Checking various exclusions based on flags in the walker policy.
-			if w.isExcluded(p) {
-				if w.Verbose {
-					w.addNotificationToWalk(fspb.Notification_INFO, p, fmt.Sprintf("skipping %q: excluded", p))
-				}
-				if info.IsDir() {
-					return filepath.SkipDir
-				}
-				return nil // returning SkipDir on a file would skip the rest of the files in the dir
+			if dirLike {
+				return filepath.SkipDir
 			}
-			if w.pol.IgnoreIrregularFiles && !info.Mode().IsRegular() && !info.IsDir() {
-				if w.Verbose {
-					w.addNotificationToWalk(fspb.Notification_INFO, p, fmt.Sprintf("skipping %q: irregular file (mode: %s)", p, info.Mode()))
-				}
-				return nil
+			return nil // returning SkipDir on a file would skip the rest of the files in the dir
+		}
+		if rule := w.excludeRule(p); rule != "" {
+			if w.Counter != nil {
+				w.Counter.Add(1, countExcluded)
+			}
+			if w.Verbose {
+				w.addNotificationToWalk(fspb.Notification_INFO, p, fmt.Sprintf("skipping %q: matches %s", p, rule))
 			}
-			f := w.convert(p, info)
-			if w.pol.MaxDirectoryDepth > 0 && info.IsDir() && w.relDirDepth(path, p) > w.pol.MaxDirectoryDepth {
-				w.addNotificationToWalk(fspb.Notification_WARNING, p, fmt.Sprintf("skipping %q: more than %d into base path %q", p, w.pol.MaxDirectoryDepth, path))
+			if dirLike {
 				return filepath.SkipDir
 			}
-			if !w.pol.WalkCrossDevice && f.Stat != nil && baseStat.Dev != f.Stat.Dev {
-				msg := fmt.Sprintf("skipping %q: file is on different device", p)
-				log.Printf(msg)
-				if w.Verbose {
-					w.addNotificationToWalk(fspb.Notification_INFO, p, msg)
-				}
-				if info.IsDir() {
-					return filepath.SkipDir
-				}
-				return nil // returning SkipDir on a file would skip the rest of the files in the dir
+			return nil
+		}
+		if w.pol.DirectoriesOnly && !info.IsDir() {
+			return nil
+		}
+		if w.pol.IgnoreIrregularFiles && !info.Mode().IsRegular() && !info.IsDir() {
+			if w.Verbose {
+				w.addNotificationToWalk(fspb.Notification_INFO, p, fmt.Sprintf("skipping %q: irregular file (mode: %s)", p, info.Mode()))
+			}
+			return nil
+		}
+		if !info.IsDir() && !w.inMtimeWindow(info.ModTime()) {
+			if w.Counter != nil {
+				w.Counter.Add(1, countAgeFiltered)
+			}
+			return nil
+		}
+		f := w.convert(p, info)
+		if maxDepth > 0 && dirLike && w.relDirDepth(root, p) > maxDepth {
+			w.addNotificationToWalk(fspb.Notification_WARNING, p, fmt.Sprintf("skipping %q: more than %d into base path %q", p, maxDepth, root))
+			return filepath.SkipDir
+		}
+		if !w.pol.WalkCrossDevice && f.Stat != nil && baseStat.Dev != f.Stat.Dev {
+			msg := fmt.Sprintf("skipping %q: file is on different device", p)
+			log.Print(msg)
+			if w.Verbose {
+				w.addNotificationToWalk(fspb.Notification_INFO, p, msg)
+			}
+			if info.IsDir() {
+				return filepath.SkipDir
 			}
+			return nil // returning SkipDir on a file would skip the rest of the files in the dir
+		}
 
-			return w.process(ctx, f)
-		}); err != nil {
-			return fmt.Errorf("error walking root include path %q: %v", path, err)
+		if err := w.process(ctx, f); err != nil {
+			return err
 		}
+		if format := w.archiveFormat(p, info); format != "" {
+			w.inspectArchive(p, format)
+		}
+		return nil
 	}
+}
+
+// followsSymlink determines whether p is a symlink to a directory which is followed according
+// to follow_symlinks of the policy.
+func (w *Walker) followsSymlink(p string, info os.FileInfo) bool {
+	if !w.pol.FollowSymlinks || info.Mode()&os.ModeSymlink == 0 {
+		return false
+	}
+	target, err := os.Stat(p)
+	return err == nil && target.IsDir()
+}
+
+// symlinkLoop notes a symlink which isn't followed as its target directory was walked already.
+func (w *Walker) symlinkLoop(p string) {
+	msg := fmt.Sprintf("not following symlink %q: its target directory was walked already (symlink loop)", p)
+	log.Print(msg)
+	w.addNotificationToWalk(fspb.Notification_WARNING, p, msg)
+	if w.Counter != nil {
+		w.Counter.Add(1, countLinkLoops)
+	}
+}
+
+// visitedDirs keeps track of the directories walked below an include path when following
+// symlinks, so symlinks leading back into them are not followed again.
+type visitedDirs struct {
+	mu   sync.Mutex
+	dirs map[[2]uint64]bool // device and inode numbers.
+}
+
+// add records the directory and returns whether it hadn't been visited before.
+func (v *visitedDirs) add(info os.FileInfo) bool {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return true
+	}
+	key := [2]uint64{uint64(st.Dev), uint64(st.Ino)}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.dirs[key] {
+		return false
+	}
+	v.dirs[key] = true
+	return true
+}
+
+// dirJob is a directory discovered during a walk which still needs to be read.
+type dirJob struct {
+	root string // the include path the directory was discovered under.
+	path string
+	fn   filepath.WalkFunc
+	// visited are the directories walked below root if symlinks are followed, nil otherwise.
+	visited *visitedDirs
+}
+
+// traversal walks the file system in parallel. Directories are read by a pool of
+// routines. Newly discovered directories are queued in a bounded channel and read
+// by the discovering routine itself when the queue is full, keeping memory usage
+// predictable.
+type traversal struct {
+	ctx     context.Context // stops the traversal once it is done.
+	jobs    chan dirJob
+	visit   func(dir string) // called for each directory before it is read, if non-nil.
+	loop    func(p string)   // called for symlinks not followed as their target was walked already, if non-nil.
+	pending sync.WaitGroup   // directories queued or being read.
+
+	errsMu sync.Mutex
+	errs   []string
+}
+
+// fail records a fatal error which aborts the walk.
+func (t *traversal) fail(err error) {
+	t.errsMu.Lock()
+	t.errs = append(t.errs, err.Error())
+	t.errsMu.Unlock()
+}
+
+// failed returns whether a fatal error was recorded.
+func (t *traversal) failed() bool {
+	t.errsMu.Lock()
+	defer t.errsMu.Unlock()
+	return len(t.errs) > 0
+}
+
+// enqueue queues a directory for reading or reads it right away if the queue is full.
+func (t *traversal) enqueue(job dirJob) {
+	t.pending.Add(1)
+	select {
+	case t.jobs <- job:
+	default:
+		t.readDir(job)
+	}
+}
+
+// worker reads queued directories until the channel is closed.
+func (t *traversal) worker() {
+	for job := range t.jobs {
+		t.readDir(job)
+	}
+}
+
+// stopped returns whether the traversal has been cancelled or has run past its deadline.
+func (t *traversal) stopped() bool {
+	return t.ctx.Err() != nil
+}
+
+// walkRoot starts walking the include path root, at most maxDepth levels deep if non-zero.
+func (t *traversal) walkRoot(ctx context.Context, w *Walker, root string, maxDepth uint32) {
+	if t.stopped() {
+		return
+	}
+	baseInfo, err := os.Stat(root)
+	if os.IsNotExist(err) {
+		// Policies are often shared by many hosts, see MissingRootPaths.
+		msg := fmt.Sprintf("include %q doesn't exist, skipping it", root)
+		log.Print(msg)
+		w.addNotificationToWalk(fspb.Notification_WARNING, root, msg)
+		if w.Counter != nil {
+			w.Counter.Add(1, countRootMissing)
+		}
+		return
+	}
+	if err != nil {
+		t.fail(fmt.Errorf("unable to get file info for base path %q: %v", root, err))
+		return
+	}
+	baseStat, ok := baseInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		t.fail(fmt.Errorf("unable to get file stat on base path: %q", root))
+		return
+	}
+
+	// Like filepath.Walk, the root itself is not followed if it is a symlink, unless the policy
+	// asks to follow symlinks.
+	fn := w.walkFunc(ctx, root, maxDepth, baseStat)
+	info, err := os.Lstat(root)
+	err = fn(root, info, err)
+	if err == filepath.SkipDir {
+		return
+	}
+	if err != nil {
+		t.fail(fmt.Errorf("error walking root include path %q: %v", root, err))
+		return
+	}
+	job := dirJob{root: root, path: root, fn: fn}
+	if w.pol.FollowSymlinks {
+		// Each include path has a set of its own, so include paths sharing directories
+		// aren't mistaken for symlink loops.
+		job.visited = &visitedDirs{dirs: map[[2]uint64]bool{}}
+	}
+	switch {
+	case info == nil:
+	case info.IsDir():
+		if job.visited != nil {
+			job.visited.add(info)
+		}
+		t.enqueue(job)
+	case job.visited != nil && info.Mode()&os.ModeSymlink != 0:
+		t.followSymlink(job)
+	}
+}
+
+// followSymlink queues the target of the symlink at job.path for reading below the symlink's
+// path if it is a directory which hasn't been walked from the same include path yet.
+func (t *traversal) followSymlink(job dirJob) {
+	target, err := os.Stat(job.path)
+	if err != nil || !target.IsDir() {
+		return // broken symlinks and symlinks to files are recorded as they are.
+	}
+	if !job.visited.add(target) {
+		if t.loop != nil {
+			t.loop(job.path)
+		}
+		return
+	}
+	t.enqueue(job)
+}
+
+// readDir processes all entries of a directory and queues its subdirectories.
+// Entries are processed in lexical order, same as filepath.Walk does.
+func (t *traversal) readDir(job dirJob) {
+	defer t.pending.Done()
+	if t.failed() || t.stopped() {
+		return
+	}
+	if t.visit != nil {
+		t.visit(job.path)
+	}
+
+	names, err := readDirNames(job.path)
+	if err != nil {
+		if err := job.fn(job.path, nil, err); err != nil && err != filepath.SkipDir {
+			t.fail(fmt.Errorf("error walking root include path %q: %v", job.root, err))
+		}
+		return
+	}
+	for _, name := range names {
+		if t.stopped() {
+			return
+		}
+		p := filepath.Join(job.path, name)
+		info, err := os.Lstat(p)
+		err = job.fn(p, info, err)
+		if err == filepath.SkipDir {
+			continue
+		}
+		if err != nil {
+			t.fail(fmt.Errorf("error walking root include path %q: %v", job.root, err))
+			return
+		}
+		switch {
+		case info == nil:
+		case info.IsDir():
+			if job.visited != nil {
+				job.visited.add(info)
+			}
+			t.enqueue(dirJob{root: job.root, path: p, fn: job.fn, visited: job.visited})
+		case job.visited != nil && info.Mode()&os.ModeSymlink != 0:
+			t.followSymlink(dirJob{root: job.root, path: p, fn: job.fn, visited: job.visited})
+		}
+	}
+}
+
+// readDirNames reads the directory and returns a sorted list of its entry names.
+func readDirNames(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	names, err := f.Readdirnames(-1)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// compileExcludeRegex compiles the exclude_regex expressions of the policy unless NewWalker already did.
+func (w *Walker) compileExcludeRegex() error {
+	if w.excludeRegex != nil || len(w.pol.ExcludeRegex) == 0 {
+		return nil
+	}
+	excludeRegex, err := compileRegexps(w.pol.ExcludeRegex)
+	if err != nil {
+		return fmt.Errorf("invalid exclude_regex: %v", err)
+	}
+	w.excludeRegex = excludeRegex
 	return nil
 }
 
 // Run is the main function of Walker. It discovers all files under included paths
 // (minus excluded ones) and processes them.
-// This does NOT follow symlinks - fortunately we don't need it either.
+// Symlinks are not followed unless follow_symlinks of the policy is set, in which case symlink
+// loops are detected separately for each include path.
 func (w *Walker) Run(ctx context.Context) error {
+	return w.RunWithProgress(ctx, nil)
+}
+
+// RunWithProgress is like Run but additionally sends progress updates on the given channel
+// whenever a directory is read. Updates are dropped if the channel is not ready to receive,
+// so the walk is never slowed down by the receiver. The channel is not closed by the Walker.
+// A nil channel disables progress updates.
+func (w *Walker) RunWithProgress(ctx context.Context, progress chan<- WalkProgress) error {
+	atomic.StoreInt32(&w.started, 1)
+	w.progress = progress
+	atomic.StoreInt64(&w.filesSeen, 0)
+	atomic.StoreInt64(&w.bytesHashed, 0)
+	atomic.StoreInt64(&w.errCount, 0)
+	if w.Counter != nil {
+		w.Counter.Reset()
+	}
+	defer func() { w.progress = nil }()
+	if err := w.compileExcludeRegex(); err != nil {
+		return err
+	}
+	if err := w.checkHasher(); err != nil {
+		return err
+	}
+
+	if w.pol.DeltaWalk && w.Outpath == StdioPath {
+		return fmt.Errorf("delta_walk requires an output file, not stdout")
+	}
 	walkID := uuid.New().String()
-	hn, err := os.Hostname()
+	hn := w.Hostname
+	var err error
+	if hn == "" {
+		if hn, err = os.Hostname(); err != nil {
+			return err
+		}
+	}
+	start := time.Now()
+	if w.mtimeOldest, w.mtimeNewest, err = mtimeWindow(w.pol, start); err != nil {
+		return err
+	}
+	startTs, err := ptypes.TimestampProto(start)
 	if err != nil {
 		return err
 	}
@@ -315,49 +1249,250 @@ func (w *Walker) Run(ctx context.Context) error {
 		Id:        walkID,
 		Policy:    w.pol,
 		Hostname:  hn,
-		StartWalk: ptypes.TimestampNow(),
+		StartWalk: startTs,
 	}
 
-	chPaths := make(chan string, 10)
-	var wg sync.WaitGroup
-	var errs []string
-	var errsMu sync.Mutex
+	parallelism := int(w.pol.Parallelism)
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	t := &traversal{
+		ctx:   ctx,
+		jobs:  make(chan dirJob, parallelism*dirQueueFactor),
+		visit: w.reportProgress,
+		loop:  w.symlinkLoop,
+	}
+	var workers sync.WaitGroup
 	for i := 0; i < parallelism; i++ {
-		wg.Add(1)
+		workers.Add(1)
 		go func() {
-			defer wg.Done()
-			if err := w.worker(ctx, chPaths); err != nil {
-				errsMu.Lock()
-				errs = append(errs, err.Error())
-				errsMu.Unlock()
-			}
+			defer workers.Done()
+			t.worker()
 		}()
 	}
 
 	includes := map[string]bool{}
-	for _, p := range w.pol.Include {
-		p := filepath.Clean(p)
+	for _, pc := range w.pol.IncludePaths() {
+		if ctx.Err() != nil {
+			break
+		}
+		p := filepath.Clean(pc.Path)
 		if _, ok := includes[p]; ok {
 			continue
 		}
 		includes[p] = true
-		chPaths <- p
+		maxDepth := pc.MaxDepth
+		if maxDepth == 0 {
+			maxDepth = w.pol.MaxDirectoryDepth
+		}
+		t.walkRoot(ctx, w, p, maxDepth)
 	}
-	close(chPaths)
-	wg.Wait()
-	if len(errs) != 0 {
-		return fmt.Errorf("unable to complete Walk:\n%s", strings.Join(errs, "\n"))
+	t.pending.Wait()
+	close(t.jobs)
+	workers.Wait()
+	if len(t.errs) != 0 {
+		return &WalkIOError{Err: fmt.Errorf("unable to complete Walk:\n%s", strings.Join(t.errs, "\n"))}
 	}
 
 	// Finishing work by writing out the report.
 	w.walk.StopWalk = ptypes.TimestampNow()
+	sortFiles(w.walk.File, w.pol.SortOrder)
+	w.walk.Summary = w.summary()
+	if err := ctx.Err(); err != nil {
+		return w.writePartialWalk(err)
+	}
+	if w.DryRun {
+		if n := atomic.LoadInt64(&w.errCount); n > 0 {
+			return &WalkIOError{Err: fmt.Errorf("dry run encountered %d errors", n)}
+		}
+		return nil
+	}
 	if w.Outpath == "" {
 		return nil
 	}
-	// Serialize and write out the walk file.
-	walkBytes, err := proto.Marshal(w.walk)
+	if w.pol.DeltaWalk {
+		if err := w.reduceToDelta(ctx); err != nil {
+			return err
+		}
+	}
+	if err := w.writeWalk(ctx); err != nil {
+		return err
+	}
+	return w.purgeWalks(ctx)
+}
+
+// summary computes the aggregate statistics of the Walk. It needs to be called before the Walk
+// is reduced to a delta.
+func (w *Walker) summary() *fspb.WalkSummary {
+	s := &fspb.WalkSummary{
+		WalkStartTime: w.walk.StartWalk,
+		WalkEndTime:   w.walk.StopWalk,
+		Hostname:      w.walk.Hostname,
+		PolicyName:    w.PolicyName,
+	}
+	for _, f := range w.walk.File {
+		if f.GetInfo().GetIsDir() {
+			s.DirCount++
+		} else {
+			s.FileCount++
+			s.TotalSizeBytes += f.GetInfo().GetSize()
+		}
+		if len(f.Fingerprint) > 0 {
+			s.HashCount++
+		}
+	}
+	return s
+}
+
+// writePartialWalk flags the Walk as partial and writes it after the walk was stopped by its
+// context, so the files discovered until then aren't lost. Partial Walks are never reduced to
+// deltas as the files not walked would show up as deleted. It returns an error wrapping
+// ctxErr in any case.
+func (w *Walker) writePartialWalk(ctxErr error) error {
+	w.walk.Partial = true
+	w.addNotificationToWalk(fspb.Notification_ERROR, "", fmt.Sprintf("walk stopped before completion: %v", ctxErr))
+	if w.DryRun || w.Outpath == "" {
+		return &WalkIOError{Err: fmt.Errorf("walk stopped before completion: %w", ctxErr)}
+	}
+	// The context of the walk is done already, writing needs a fresh one.
+	if err := w.writeWalk(context.Background()); err != nil {
+		return err
+	}
+	return &WalkIOError{Path: w.Outpath, Err: fmt.Errorf("walk stopped before completion, partial Walk written: %w", ctxErr)}
+}
+
+// sortFiles sorts the files of a Walk in the given order. Files with the same inode
+// (i.e. hard links) are sorted by path to keep the order deterministic.
+func sortFiles(files []*fspb.File, order fspb.Policy_SortOrder) {
+	switch order {
+	case fspb.Policy_PATH_LEXICAL:
+		sort.Slice(files, func(i, j int) bool {
+			return files[i].Path < files[j].Path
+		})
+	case fspb.Policy_INODE_ORDER:
+		sort.Slice(files, func(i, j int) bool {
+			a, b := files[i].GetStat(), files[j].GetStat()
+			switch {
+			case a.GetDev() != b.GetDev():
+				return a.GetDev() < b.GetDev()
+			case a.GetInode() != b.GetInode():
+				return a.GetInode() < b.GetInode()
+			}
+			return files[i].Path < files[j].Path
+		})
+	}
+}
+
+// reduceToDelta turns the Walk into a delta to the latest Walk of the host, see ownWalkFiles.
+// If there is no previous Walk yet, the full Walk is kept.
+func (w *Walker) reduceToDelta(ctx context.Context) error {
+	store := w.walkStore()
+	names, err := w.ownWalkFiles(ctx, store)
+	if err != nil {
+		return fmt.Errorf("unable to find base Walk: %w", err)
+	}
+	if len(names) > 0 && names[len(names)-1] == w.Outpath {
+		names = names[:len(names)-1] // a previous run with the same Outpath is about to be overwritten.
+	}
+	if len(names) == 0 {
+		w.addNotificationToWalk(fspb.Notification_INFO, w.Outpath, "no base Walk found, writing a full Walk")
+		return nil
+	}
+	latest := names[len(names)-1]
+	base, _, err := loadWalk(ctx, store, latest)
 	if err != nil {
+		return fmt.Errorf("unable to load base Walk %q: %w", latest, err)
+	}
+	if base, err = resolveWalk(ctx, store, latest, base); err != nil {
 		return err
 	}
-	return ioutil.WriteFile(w.Outpath, walkBytes, 0444)
+	baseName, err := relWalkPath(walkPathDir(w.Outpath), latest)
+	if err != nil {
+		return fmt.Errorf("unable to refer to base Walk %q: %w", latest, err)
+	}
+	makeDelta(w.walk, base, baseName)
+	return nil
+}
+
+// ownWalkFiles returns the Walk files of the host next to Outpath, or in all subdirectories of
+// OutputRoot if an output directory layout is used, ordered by the time in their names.
+func (w *Walker) ownWalkFiles(ctx context.Context, store WalkStore) ([]string, error) {
+	dir, recursive := walkPathDir(w.Outpath), false
+	if w.OutputRoot != "" && w.OutputDirLayout != "" {
+		dir, recursive = w.OutputRoot, true
+	}
+	names, err := findWalkFiles(ctx, store, w.walk.Hostname, w.FilenameFormat, dir, recursive)
+	if err != nil {
+		return nil, err
+	}
+	sortWalkFiles(w.walk.Hostname, w.FilenameFormat, names)
+	return names, nil
+}
+
+// purgeWalks deletes the oldest Walks of the host beyond max_walk_retention of the policy, see
+// ownWalkFiles.
+func (w *Walker) purgeWalks(ctx context.Context) error {
+	keep := int(w.pol.MaxWalkRetention)
+	if keep <= 0 || w.Outpath == StdioPath {
+		return nil
+	}
+	store := w.walkStore()
+	names, err := w.ownWalkFiles(ctx, store)
+	if err != nil {
+		return &WalkIOError{Path: w.Outpath, Err: fmt.Errorf("unable to list old Walks: %w", err)}
+	}
+	if len(names) <= keep {
+		return nil
+	}
+	ds, ok := store.(DeletableWalkStore)
+	if !ok && !w.DryRunCleanup {
+		return &WalkIOError{Path: w.Outpath, Err: fmt.Errorf("deleting old Walks is not supported by the Walk store")}
+	}
+	for _, n := range names[:len(names)-keep] {
+		if n == w.Outpath {
+			continue // written just now, despite its name sorting first.
+		}
+		if w.DryRunCleanup {
+			log.Printf("max_walk_retention: would delete old Walk %s", n)
+			continue
+		}
+		if err := ds.Delete(ctx, n); err != nil {
+			return &WalkIOError{Path: n, Err: fmt.Errorf("unable to delete old Walk: %w", err)}
+		}
+		if w.Counter != nil {
+			w.Counter.Add(1, countWalksPurged)
+		}
+	}
+	return nil
+}
+
+// walkStore returns the store the Walk is written to.
+func (w *Walker) walkStore() WalkStore {
+	if w.store != nil {
+		return w.store
+	}
+	return storeForPath(w.Outpath)
+}
+
+// writeWalk serializes the Walk and writes it to Outpath in the Walk store, gzip compressing
+// it if requested.
+func (w *Walker) writeWalk(ctx context.Context) error {
+	sum, err := walkChecksum(w.walk)
+	if err != nil {
+		return &WalkProtoError{Path: w.Outpath, Err: err}
+	}
+	w.walk.Checksum = sum
+	walkBytes, err := marshalWalk(w.walk, w.OutputFormat)
+	if err != nil {
+		return &WalkProtoError{Path: w.Outpath, Err: err}
+	}
+	if w.Compress {
+		if walkBytes, err = gzipBytes(walkBytes); err != nil {
+			return &WalkIOError{Path: w.Outpath, Err: err}
+		}
+	}
+	if err := w.walkStore().Write(ctx, w.Outpath, walkBytes); err != nil {
+		return &WalkIOError{Path: w.Outpath, Err: err}
+	}
+	return nil
 }
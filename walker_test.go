@@ -16,11 +16,21 @@ package fswalker
 
 import (
 	"context"
+	"crypto/md5"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/user"
 	"path/filepath"
 	"reflect"
 	"sort"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
@@ -88,6 +98,261 @@ func TestWalkerFromPolicyFile(t *testing.T) {
 	}
 }
 
+func TestWalkerFromPolicyBytes(t *testing.T) {
+	wantPol := &fspb.Policy{
+		Version:         1,
+		MaxHashFileSize: 1048576,
+		Include: []string{
+			"/",
+		},
+		ExcludePfx: []string{
+			"/tmp/",
+		},
+	}
+	testCases := []struct {
+		desc    string
+		data    string
+		wantErr bool
+	}{
+		{
+			desc: "text format",
+			data: "version: 1\nmax_hash_file_size: 1048576\ninclude: \"/\"\nexclude_pfx: \"/tmp/\"\n",
+		}, {
+			desc: "JSON encoding",
+			data: ` {"version": 1, "maxHashFileSize": "1048576", "include": ["/"], "excludePfx": ["/tmp/"]}`,
+		}, {
+			desc:    "invalid policy",
+			data:    "unknown_field: 1",
+			wantErr: true,
+		}, {
+			desc:    "malformed exclude_paths pattern",
+			data:    "include: \"/\"\nexclude_paths: \"/var/log/[\"\n",
+			wantErr: true,
+		}, {
+			desc:    "invalid exclude_regex",
+			data:    "include: \"/\"\nexclude_regex: \"/__pycache__(\"\n",
+			wantErr: true,
+		}, {
+			desc:    "invalid max_mtime_age",
+			data:    "include: \"/\"\nmax_mtime_age: { seconds: 1 nanos: -1 }\n",
+			wantErr: true,
+		}, {
+			desc:    "invalid sample_rate",
+			data:    "include: \"/\"\nsample_rate: 1.5\n",
+			wantErr: true,
+		},
+	}
+
+	ctx := context.Background()
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			wlkr, err := WalkerFromPolicyBytes(ctx, []byte(tc.data), "", false)
+			switch {
+			case tc.wantErr && err == nil:
+				t.Error("WalkerFromPolicyBytes() no error")
+			case !tc.wantErr && err != nil:
+				t.Errorf("WalkerFromPolicyBytes() error: %v", err)
+			case !tc.wantErr:
+				if diff := cmp.Diff(wlkr.pol, wantPol); diff != "" {
+					t.Errorf("WalkerFromPolicyBytes() policy: diff (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestMissingRootPaths(t *testing.T) {
+	pol := &fspb.Policy{
+		Include:     []string{testdataDir, "/nonexistent"},
+		IncludePath: []*fspb.PathConfig{{Path: "/nonexistent2", MaxDepth: 1}},
+	}
+	wlkr, err := NewWalker(context.Background(), pol, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"/nonexistent", "/nonexistent2"}, wlkr.MissingRootPaths()); diff != "" {
+		t.Errorf("MissingRootPaths(): diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestRequireRootPaths(t *testing.T) {
+	ctx := context.Background()
+	pol := &fspb.Policy{Include: []string{testdataDir, "/nonexistent"}}
+	_, err := NewWalker(ctx, pol, "", false, RequireRootPaths())
+	var ipe *InvalidPolicyError
+	if !errors.As(err, &ipe) {
+		t.Fatalf("NewWalker() with RequireRootPaths() error: %v; want InvalidPolicyError", err)
+	}
+	if !strings.Contains(err.Error(), `"/nonexistent"`) {
+		t.Errorf("NewWalker() error %q doesn't name the missing include", err)
+	}
+	if _, err := NewWalker(ctx, &fspb.Policy{Include: []string{testdataDir}}, "", false, RequireRootPaths()); err != nil {
+		t.Errorf("NewWalker() with RequireRootPaths() and existing includes error: %v", err)
+	}
+}
+
+func TestRunMissingRootPath(t *testing.T) {
+	ctx := context.Background()
+	wlkr, err := NewWalker(ctx, &fspb.Policy{Include: []string{"/nonexistent", testdataDir}}, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wlkr.DryRun = true
+	if err := wlkr.Run(ctx); err != nil {
+		t.Fatalf("Run() with a missing include error: %v", err)
+	}
+	if len(wlkr.walk.File) == 0 {
+		t.Error("Run() with a missing include didn't walk the other include")
+	}
+	if n, _ := wlkr.Counter.Get(countRootMissing); n != 1 {
+		t.Errorf("Run() counted %d missing include paths; want 1", n)
+	}
+	var found bool
+	for _, n := range wlkr.walk.Notification {
+		found = found || (n.Path == "/nonexistent" && n.Severity == fspb.Notification_WARNING)
+	}
+	if !found {
+		t.Errorf("Run() notifications %v don't warn about the missing include", wlkr.walk.Notification)
+	}
+}
+
+func TestAddRemoveRootPath(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+	pol := &fspb.Policy{Include: []string{testdataDir, "/nonexistent"}}
+	wlkr, err := NewWalker(ctx, pol, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := wlkr.AddRootPath(filepath.Join(tmpdir, "missing")); err == nil {
+		t.Error("AddRootPath() of a nonexistent path succeeded; want error")
+	}
+	if err := wlkr.AddRootPath(tmpdir); err != nil {
+		t.Errorf("AddRootPath() error: %v", err)
+	}
+	if err := wlkr.RemoveRootPath("/nonexistent/"); err != nil {
+		t.Errorf("RemoveRootPath() error: %v", err)
+	}
+	if err := wlkr.RemoveRootPath("/not/walked"); err == nil {
+		t.Error("RemoveRootPath() of a path which isn't walked succeeded; want error")
+	}
+	if diff := cmp.Diff([]string{testdataDir, "/nonexistent"}, pol.Include); diff != "" {
+		t.Errorf("AddRootPath() modified the given policy: diff (-want +got):\n%s", diff)
+	}
+
+	if err := wlkr.Run(ctx); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if diff := cmp.Diff([]string{testdataDir, tmpdir}, wlkr.walk.Policy.Include); diff != "" {
+		t.Errorf("Run() recorded includes: diff (-want +got):\n%s", diff)
+	}
+	walked := false
+	for _, f := range wlkr.walk.File {
+		if f.Path == tmpdir {
+			walked = true
+		}
+	}
+	if !walked {
+		t.Errorf("Run() didn't walk the added root path %q", tmpdir)
+	}
+
+	if err := wlkr.AddRootPath(tmpdir); err == nil {
+		t.Error("AddRootPath() after Run() succeeded; want error")
+	}
+	if err := wlkr.RemoveRootPath(tmpdir); err == nil {
+		t.Error("RemoveRootPath() after Run() succeeded; want error")
+	}
+}
+
+func TestNewWalker(t *testing.T) {
+	ctx := context.Background()
+	pol := &fspb.Policy{Include: []string{"/"}, ExcludePaths: []string{"/home/*/.cache"}}
+	store := memWalkStore{}
+	wlkr, err := NewWalker(ctx, pol, "/walks/host.pb", true, WithWalkStore(store))
+	if err != nil {
+		t.Fatalf("NewWalker() error: %v", err)
+	}
+	if wlkr.pol != pol || wlkr.Outpath != "/walks/host.pb" || !wlkr.Verbose || wlkr.Counter == nil {
+		t.Errorf("NewWalker() = %+v; want a verbose Walker for the given policy and output path", wlkr)
+	}
+	if _, ok := wlkr.walkStore().(memWalkStore); !ok {
+		t.Errorf("NewWalker() didn't apply WithWalkStore")
+	}
+
+	for _, pol := range []*fspb.Policy{
+		nil,
+		{},
+		{Include: []string{"/"}, ExcludePaths: []string{"/home/["}},
+		{Include: []string{"/"}, SampleRate: -1},
+		{Include: []string{"/"}, MaxWalkRetention: -1},
+		{Include: []string{"/"}, MaxWalkRetention: 3, DeltaWalk: true},
+	} {
+		var e *InvalidPolicyError
+		if _, err := NewWalker(ctx, pol, "", false); !errors.As(err, &e) {
+			t.Errorf("NewWalker(%v) error = %v; want InvalidPolicyError", pol, err)
+		}
+	}
+
+	// All problems of the policy are reported at once.
+	_, err = NewWalker(ctx, &fspb.Policy{Include: []string{"/"}, SampleRate: -1, MaxWalkRetention: -1}, "", false)
+	if err == nil || !strings.Contains(err.Error(), "sample_rate") || !strings.Contains(err.Error(), "max_walk_retention") {
+		t.Errorf("NewWalker() error = %v; want errors for sample_rate and max_walk_retention", err)
+	}
+}
+
+func TestWalkerFromPolicyFileURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/policy.textpb" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "version: 1\ninclude: \"/\"\n")
+	}))
+	defer srv.Close()
+
+	testCases := []struct {
+		desc    string
+		url     string
+		wantPol *fspb.Policy
+		wantErr bool
+	}{
+		{
+			desc: "existing policy",
+			url:  srv.URL + "/policy.textpb",
+			wantPol: &fspb.Policy{
+				Version: 1,
+				Include: []string{"/"},
+			},
+		}, {
+			desc:    "missing policy",
+			url:     srv.URL + "/missing.textpb",
+			wantErr: true,
+		},
+	}
+
+	ctx := context.Background()
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			wlkr, err := WalkerFromPolicyFile(ctx, tc.url, "", false)
+			switch {
+			case tc.wantErr && err == nil:
+				t.Error("WalkerFromPolicyFile() no error")
+			case !tc.wantErr && err != nil:
+				t.Errorf("WalkerFromPolicyFile() error: %v", err)
+			case !tc.wantErr:
+				if diff := cmp.Diff(tc.wantPol, wlkr.pol); diff != "" {
+					t.Errorf("WalkerFromPolicyFile() policy: diff (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
 func TestProcess(t *testing.T) {
 	ctx := context.Background()
 	wlkr := &Walker{
@@ -162,6 +427,341 @@ func TestIsExcluded(t *testing.T) {
 	}
 }
 
+func TestMatchesExcludePath(t *testing.T) {
+	testCases := []struct {
+		path     string
+		wantExcl bool
+	}{
+		{path: "/var/log/syslog.1.gz", wantExcl: true},
+		{path: "/var/log/syslog"},
+		{path: "/var/log/apt/history.log.1.gz"}, // * doesn't match the separator.
+		{path: "/home/user/.cache", wantExcl: true},
+		{path: "/home/user/cache"},
+	}
+
+	wlkr := &Walker{
+		pol: &fspb.Policy{
+			ExcludePaths: []string{
+				"/var/log/*.gz",
+				"/home/*/.cache",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		if gotExcl := wlkr.matchesExcludePath(tc.path); gotExcl != tc.wantExcl {
+			t.Errorf("matchesExcludePath(%q) = %v; want %v", tc.path, gotExcl, tc.wantExcl)
+		}
+	}
+}
+
+func TestMatchesExcludeRegex(t *testing.T) {
+	testCases := []struct {
+		path     string
+		wantExcl bool
+	}{
+		{path: "/src/app/__pycache__", wantExcl: true},
+		{path: "/src/app/__pycache__/mod.cpython-37.pyc", wantExcl: true},
+		{path: "/src/app/not__pycache__"},
+		{path: "/src/app/mod.py"},
+		{path: "/home/user/.bash_history", wantExcl: true},
+	}
+
+	wlkr, err := WalkerFromPolicyBytes(context.Background(), []byte(`
+		include: "/"
+		exclude_regex: "/__pycache__(/|$)"
+		exclude_regex: "^/home/[^/]+/\\.[a-z_]+_history$"
+	`), "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range testCases {
+		if gotExcl := wlkr.matchesExcludeRegex(tc.path); gotExcl != tc.wantExcl {
+			t.Errorf("matchesExcludeRegex(%q) = %v; want %v", tc.path, gotExcl, tc.wantExcl)
+		}
+	}
+}
+
+func TestRunExcludePaths(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+	for _, p := range []string{
+		"keep/file.log",
+		"keep/file.log.gz",
+		"keep/nested/file.gz",
+		"skip/file.log",
+		"skip/nested/file.log",
+		"keep/__pycache__/file.pyc",
+	} {
+		p = filepath.Join(tmpdir, p)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte("content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wlkr := &Walker{
+		pol: &fspb.Policy{
+			Include: []string{tmpdir},
+			ExcludePaths: []string{
+				filepath.Join(tmpdir, "*", "*.gz"),
+				filepath.Join(tmpdir, "sk*"),
+			},
+			ExcludeRegex: []string{"/__pycache__(/|$)"},
+		},
+		Counter: &metrics.Counter{},
+	}
+	if err := wlkr.Run(ctx); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	var got []string
+	for _, f := range wlkr.walk.File {
+		rel, err := filepath.Rel(tmpdir, f.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, rel)
+	}
+	sort.Strings(got)
+	want := []string{".", "keep", "keep/file.log", "keep/nested", "keep/nested/file.gz"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Run() walked files: diff (-want +got):\n%s", diff)
+	}
+	// The skipped directory counts once as its content is never visited.
+	if n, _ := wlkr.Counter.Get(countExcluded); n != 3 {
+		t.Errorf("Run() counted %d excluded paths; want 3", n)
+	}
+}
+
+func TestRunFollowSymlinks(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+	root, outside := filepath.Join(tmpdir, "root"), filepath.Join(tmpdir, "outside")
+	for _, p := range []string{"root/dir/file", "root/skip/file", "outside/file"} {
+		p = filepath.Join(tmpdir, p)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte("content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for link, target := range map[string]string{
+		"root/dir/up":   root,                       // loop back to the include path.
+		"root/ext":      outside,                    // directory outside of the include path.
+		"root/excluded": outside,                    // excluded symlinks are not followed.
+		"root/broken":   filepath.Join(tmpdir, "x"), // broken symlinks are recorded as they are.
+		"root/tofile":   filepath.Join(outside, "file"),
+	} {
+		if err := os.Symlink(target, filepath.Join(tmpdir, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		desc      string
+		follow    bool
+		include   []string
+		want      []string
+		wantLoops int64
+	}{
+		{
+			desc:    "not following",
+			include: []string{root},
+			want: []string{
+				"root", "root/broken", "root/dir", "root/dir/file", "root/dir/up", "root/ext", "root/tofile",
+			},
+		}, {
+			desc:    "following",
+			follow:  true,
+			include: []string{root},
+			want: []string{
+				"root", "root/broken", "root/dir", "root/dir/file", "root/dir/up", "root/ext", "root/ext/file", "root/tofile",
+			},
+			wantLoops: 1,
+		}, {
+			desc:    "following with include paths sharing directories",
+			follow:  true,
+			include: []string{root, outside},
+			want: []string{
+				"outside", "outside/file",
+				"root", "root/broken", "root/dir", "root/dir/file", "root/dir/up", "root/ext", "root/ext/file", "root/tofile",
+			},
+			wantLoops: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			wlkr := &Walker{
+				pol: &fspb.Policy{
+					Include:        tc.include,
+					ExcludePfx:     []string{filepath.Join(root, "skip"), filepath.Join(root, "excluded")},
+					FollowSymlinks: tc.follow,
+				},
+				Counter: &metrics.Counter{},
+			}
+			if err := wlkr.Run(ctx); err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+			var got []string
+			for _, f := range wlkr.walk.File {
+				rel, err := filepath.Rel(tmpdir, f.Path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, rel)
+			}
+			sort.Strings(got)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Run() walked files: diff (-want +got):\n%s", diff)
+			}
+			if n, _ := wlkr.Counter.Get(countLinkLoops); n != tc.wantLoops {
+				t.Errorf("Run() counted %d symlink loops; want %d", n, tc.wantLoops)
+			}
+		})
+	}
+}
+
+func TestRunMtimeAge(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+	now := time.Now()
+	for p, mtime := range map[string]time.Time{
+		"old":        now.Add(-48 * time.Hour),
+		"recent":     now.Add(-2 * time.Hour),
+		"fresh":      now,
+		"dir/recent": now.Add(-2 * time.Hour),
+	} {
+		p = filepath.Join(tmpdir, p)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte("content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Directories are walked regardless of their own modification time.
+	old := now.Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(tmpdir, "dir"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	wlkr := &Walker{
+		pol: &fspb.Policy{
+			Include:     []string{tmpdir},
+			MinMtimeAge: ptypes.DurationProto(time.Hour),
+			MaxMtimeAge: ptypes.DurationProto(24 * time.Hour),
+		},
+		Counter: &metrics.Counter{},
+	}
+	if err := wlkr.Run(ctx); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	var got []string
+	for _, f := range wlkr.walk.File {
+		rel, err := filepath.Rel(tmpdir, f.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, rel)
+	}
+	sort.Strings(got)
+	want := []string{".", "dir", "dir/recent", "recent"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Run() walked files: diff (-want +got):\n%s", diff)
+	}
+	if n, _ := wlkr.Counter.Get(countAgeFiltered); n != 2 {
+		t.Errorf("Run() counted %d age filtered files; want 2", n)
+	}
+}
+
+func TestRunMaxHashFileSize(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+	for name, size := range map[string]int{"small": 10, "big": 100} {
+		if err := ioutil.WriteFile(filepath.Join(tmpdir, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		desc      string
+		polMax    int64
+		walkerMax int64
+		wantSmall bool // Whether the small file is hashed.
+		wantBig   bool // Whether the big file is hashed.
+	}{
+		{
+			desc:      "default",
+			wantSmall: true,
+			wantBig:   true,
+		}, {
+			desc:      "policy limit",
+			polMax:    50,
+			wantSmall: true,
+		}, {
+			desc:      "walker overrides policy",
+			polMax:    50,
+			walkerMax: 5,
+		}, {
+			desc:      "walker raises policy limit",
+			polMax:    5,
+			walkerMax: 100,
+			wantSmall: true,
+			wantBig:   true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			wlkr := &Walker{
+				pol: &fspb.Policy{
+					Include:         []string{tmpdir},
+					HashPfx:         []string{tmpdir},
+					MaxHashFileSize: tc.polMax,
+				},
+				MaxHashFileSize: tc.walkerMax,
+				Counter:         &metrics.Counter{},
+			}
+			if err := wlkr.Run(ctx); err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+			hashed := map[string]bool{}
+			for _, f := range wlkr.walk.File {
+				if f.Info.IsDir {
+					continue
+				}
+				hashed[f.Info.Name] = len(f.Fingerprint) > 0
+			}
+			want := map[string]bool{"small": tc.wantSmall, "big": tc.wantBig}
+			if diff := cmp.Diff(want, hashed); diff != "" {
+				t.Errorf("Run() hashed files: diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestWantHashing(t *testing.T) {
 	testCases := []struct {
 		desc      string
@@ -214,6 +814,70 @@ func TestWantHashing(t *testing.T) {
 	}
 }
 
+func TestHashedExtension(t *testing.T) {
+	testCases := []struct {
+		desc string
+		pol  *fspb.Policy
+		path string
+		want bool
+	}{
+		{desc: "no rules", pol: &fspb.Policy{}, path: "/home/a/movie.mp4", want: true},
+		{desc: "no hash", pol: &fspb.Policy{NoHashExtensions: []string{"mp4", ".iso"}}, path: "/home/a/movie.MP4", want: false},
+		{desc: "no hash with dot", pol: &fspb.Policy{NoHashExtensions: []string{"mp4", ".iso"}}, path: "/home/a/disk.iso", want: false},
+		{desc: "no hash other", pol: &fspb.Policy{NoHashExtensions: []string{"mp4"}}, path: "/home/a/mp4", want: true},
+		{desc: "hash only", pol: &fspb.Policy{HashOnlyExtensions: []string{"conf", "tar.gz"}}, path: "/etc/app.conf", want: true},
+		{desc: "hash only multiple dots", pol: &fspb.Policy{HashOnlyExtensions: []string{"conf", "tar.gz"}}, path: "/srv/backup.TAR.GZ", want: true},
+		{desc: "hash only other", pol: &fspb.Policy{HashOnlyExtensions: []string{"conf"}}, path: "/bin/ls", want: false},
+		{
+			desc: "no hash takes precedence",
+			pol:  &fspb.Policy{NoHashExtensions: []string{"gz"}, HashOnlyExtensions: []string{"tar.gz"}},
+			path: "/srv/backup.tar.gz",
+			want: false,
+		},
+	}
+	for _, tc := range testCases {
+		wlkr := &Walker{pol: tc.pol}
+		if got := wlkr.hashedExtension(tc.path); got != tc.want {
+			t.Errorf("hashedExtension(%q) %s = %t; want %t", tc.path, tc.desc, got, tc.want)
+		}
+	}
+}
+
+func TestInHashSample(t *testing.T) {
+	paths := make([]string, 1000)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("/data/file%d", i)
+	}
+	testCases := []struct {
+		rate    float64
+		wantMin int
+		wantMax int
+	}{
+		{rate: 0, wantMin: 1000, wantMax: 1000},
+		{rate: 1, wantMin: 1000, wantMax: 1000},
+		{rate: 0.1, wantMin: 50, wantMax: 150},
+		{rate: 0.5, wantMin: 400, wantMax: 600},
+	}
+	for _, tc := range testCases {
+		wlkr := &Walker{pol: &fspb.Policy{SampleRate: tc.rate}}
+		var sampled []string
+		for _, p := range paths {
+			if wlkr.inHashSample(p) {
+				sampled = append(sampled, p)
+			}
+		}
+		if n := len(sampled); n < tc.wantMin || n > tc.wantMax {
+			t.Errorf("inHashSample() with rate %v sampled %d of %d paths; want %d to %d", tc.rate, n, len(paths), tc.wantMin, tc.wantMax)
+		}
+		// The same paths are sampled in each run.
+		for _, p := range sampled {
+			if !wlkr.inHashSample(p) {
+				t.Errorf("inHashSample(%q) with rate %v is not deterministic", p, tc.rate)
+			}
+		}
+	}
+}
+
 func TestConvert(t *testing.T) {
 	wlkr := &Walker{
 		pol: &fspb.Policy{
@@ -293,16 +957,381 @@ func TestConvert(t *testing.T) {
 	}
 }
 
-func TestRun(t *testing.T) {
-	ctx := context.Background()
-	tmpfile, err := ioutil.TempFile("", "walk.pb")
+func TestConvertXattrs(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "xattrs")
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer os.Remove(tmpfile.Name()) // clean up
+	tmpfile.Close()
+	if err := syscall.Setxattr(tmpfile.Name(), "user.fswalker", []byte("test"), 0); err != nil {
+		t.Skipf("extended attributes not supported: %v", err)
+	}
+	info, err := os.Stat(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	wlkr := &Walker{
-		pol: &fspb.Policy{
+	for _, capture := range []bool{false, true} {
+		wlkr := &Walker{
+			pol: &fspb.Policy{
+				CaptureXattrs: capture,
+			},
+		}
+		f := wlkr.convert(tmpfile.Name(), info)
+		var want map[string][]byte
+		if capture {
+			want = map[string][]byte{"user.fswalker": []byte("test")}
+		}
+		if diff := cmp.Diff(want, f.Xattrs); diff != "" {
+			t.Errorf("convert() with capture_xattrs %t: diff (-want +got):\n%s", capture, diff)
+		}
+	}
+}
+
+func TestConvertBirthTime(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "btime")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name()) // clean up
+	tmpfile.Close()
+	btime, err := birthTime(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("birthTime() error: %v", err)
+	}
+	if btime == nil {
+		t.Skip("birth time not supported")
+	}
+	info, err := os.Lstat(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, capture := range []bool{false, true} {
+		wlkr := &Walker{
+			pol: &fspb.Policy{
+				CaptureBirthTime: capture,
+			},
+		}
+		f := wlkr.convert(tmpfile.Name(), info)
+		var want *tspb.Timestamp
+		if capture {
+			want = btime
+		}
+		if diff := cmp.Diff(want, f.Stat.Btime, cmp.Comparer(proto.Equal)); diff != "" {
+			t.Errorf("convert() with capture_birth_time %t: diff (-want +got):\n%s", capture, diff)
+		}
+	}
+}
+
+func TestDecodeCapabilities(t *testing.T) {
+	vfsCapData := func(words ...uint32) []byte {
+		b := make([]byte, 4*len(words))
+		for i, w := range words {
+			binary.LittleEndian.PutUint32(b[4*i:], w)
+		}
+		return b
+	}
+	testCases := []struct {
+		desc    string
+		data    []byte
+		want    string
+		wantErr bool
+	}{
+		{
+			desc: "revision 2 effective",
+			data: vfsCapData(0x02000001, 1<<13, 0, 0, 0),
+			want: "cap_net_raw=ep",
+		}, {
+			desc: "revision 3 with root ID",
+			data: vfsCapData(0x03000001, 1<<12|1<<13, 0, 0, 0, 1000),
+			want: "cap_net_admin,cap_net_raw=ep",
+		}, {
+			desc: "revision 1 without effective flag",
+			data: vfsCapData(0x01000000, 1<<7, 1<<7),
+			want: "cap_setuid=ip",
+		}, {
+			desc: "upper capabilities and different flags",
+			data: vfsCapData(0x02000000, 1<<0, 0, 1<<(38-32), 1<<(38-32)),
+			want: "cap_chown=p cap_perfmon=ip",
+		}, {
+			desc: "unknown capability",
+			data: vfsCapData(0x02000001, 0, 0, 1<<31, 0),
+			want: "cap_63=ep",
+		}, {
+			desc:    "unknown revision",
+			data:    vfsCapData(0x04000000, 1, 0, 0, 0),
+			wantErr: true,
+		}, {
+			desc:    "truncated",
+			data:    vfsCapData(0x02000000, 1),
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		got, err := decodeCapabilities(tc.data)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("decodeCapabilities() of %s error = %v; want error %t", tc.desc, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("decodeCapabilities() of %s = %q; want %q", tc.desc, got, tc.want)
+		}
+	}
+
+	tmpfile, err := ioutil.TempFile("", "caps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name()) // clean up
+	tmpfile.Close()
+	if got, err := fileCapabilities(tmpfile.Name()); got != "" || err != nil {
+		t.Errorf("fileCapabilities() of a file without capabilities = %q, %v; want none and no error", got, err)
+	}
+}
+
+func TestConvertSymlink(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "symlinks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+	target := filepath.Join(tmpdir, "target")
+	if err := ioutil.WriteFile(target, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		desc       string
+		target     string
+		wantBroken int64
+	}{
+		{
+			desc:   "valid symlink",
+			target: target,
+		}, {
+			desc:       "broken symlink",
+			target:     filepath.Join(tmpdir, "missing"),
+			wantBroken: 1,
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			link := filepath.Join(tmpdir, fmt.Sprintf("link%d", i))
+			if err := os.Symlink(tc.target, link); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Lstat(link)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wlkr := &Walker{
+				pol:     &fspb.Policy{},
+				walk:    &fspb.Walk{},
+				Counter: &metrics.Counter{},
+			}
+			f := wlkr.convert(link, info)
+			if f.SymlinkTarget != tc.target {
+				t.Errorf("convert() symlink target = %q; want %q", f.SymlinkTarget, tc.target)
+			}
+			if n, _ := wlkr.Counter.Get(countBrokenLinks); n != tc.wantBroken {
+				t.Errorf("convert() counted %d broken symlinks; want %d", n, tc.wantBroken)
+			}
+			if n := int64(len(wlkr.walk.Notification)); n != tc.wantBroken {
+				t.Errorf("convert() added %d notifications; want %d", n, tc.wantBroken)
+			}
+		})
+	}
+}
+
+func TestConvertEntropy(t *testing.T) {
+	path := filepath.Join(testdataDir, "hashSumTest")
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		desc        string
+		pol         *fspb.Policy
+		wantEntropy bool
+	}{
+		{
+			desc: "disabled",
+			pol:  &fspb.Policy{MaxHashFileSize: 1024},
+		}, {
+			desc:        "enabled",
+			pol:         &fspb.Policy{MaxHashFileSize: 1024, ComputeEntropy: true},
+			wantEntropy: true,
+		}, {
+			desc: "file too large",
+			pol:  &fspb.Policy{MaxHashFileSize: 1, ComputeEntropy: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			wlkr := &Walker{pol: tc.pol}
+			f := wlkr.convert(path, info)
+			if got := f.Entropy > 0; got != tc.wantEntropy {
+				t.Errorf("convert() entropy = %f; want set: %t", f.Entropy, tc.wantEntropy)
+			}
+		})
+	}
+}
+
+func TestConvertMimeType(t *testing.T) {
+	path := filepath.Join(testdataDir, "hashSumTest")
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wlkr := &Walker{pol: &fspb.Policy{DetectMimeType: true}}
+	if f := wlkr.convert(path, info); f.MimeType != "text/plain; charset=utf-8" {
+		t.Errorf("convert() MIME type = %q; want %q", f.MimeType, "text/plain; charset=utf-8")
+	}
+	dirInfo, err := os.Lstat(testdataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := wlkr.convert(testdataDir, dirInfo); f.MimeType != "" {
+		t.Errorf("convert() MIME type of directory = %q; want none", f.MimeType)
+	}
+}
+
+func TestConvertOwnerNames(t *testing.T) {
+	path := filepath.Join(testdataDir, "hashSumTest")
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat := info.Sys().(*syscall.Stat_t)
+	wantOwner, wantGroup := "", ""
+	if u, err := user.LookupId(fmt.Sprint(stat.Uid)); err == nil {
+		wantOwner = u.Username
+	}
+	if g, err := user.LookupGroupId(fmt.Sprint(stat.Gid)); err == nil {
+		wantGroup = g.Name
+	}
+
+	wlkr := &Walker{pol: &fspb.Policy{}}
+	for i := 0; i < 2; i++ { // the second conversion uses the cached names.
+		f := wlkr.convert(path, info)
+		if f.OwnerName != wantOwner || f.GroupName != wantGroup {
+			t.Errorf("convert() owner, group = %q, %q; want %q, %q", f.OwnerName, f.GroupName, wantOwner, wantGroup)
+		}
+	}
+}
+
+func TestNewWalkFromDirectory(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+	for _, p := range []string{"a/file", "skip/file", "z"} {
+		p = filepath.Join(tmpdir, p)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte("content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wlk, err := NewWalkFromDirectory(ctx, tmpdir, WalkOptions{ExcludePatterns: []string{filepath.Join(tmpdir, "skip")}})
+	if err != nil {
+		t.Fatalf("NewWalkFromDirectory() error: %v", err)
+	}
+	var got []string
+	for _, f := range wlk.File {
+		rel, err := filepath.Rel(tmpdir, f.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, rel)
+		if !f.Info.IsDir && len(f.Fingerprint) == 0 {
+			t.Errorf("NewWalkFromDirectory() didn't hash %q", f.Path)
+		}
+	}
+	want := []string{".", "a", "a/file", "z"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("NewWalkFromDirectory(): diff (-want +got):\n%s", diff)
+	}
+	if wlk.Id == "" || wlk.StartWalk == nil || wlk.StopWalk == nil {
+		t.Errorf("NewWalkFromDirectory() = Walk %q from %v to %v; want ID and timestamps", wlk.Id, wlk.StartWalk, wlk.StopWalk)
+	}
+
+	wlk, err = NewWalkFromDirectory(ctx, tmpdir, WalkOptions{NoHash: true, MaxDepth: 1})
+	if err != nil {
+		t.Fatalf("NewWalkFromDirectory() error: %v", err)
+	}
+	for _, f := range wlk.File {
+		if len(f.Fingerprint) > 0 {
+			t.Errorf("NewWalkFromDirectory() with NoHash hashed %q", f.Path)
+		}
+	}
+
+	if _, err := NewWalkFromDirectory(ctx, filepath.Join(tmpdir, "missing"), WalkOptions{}); err == nil {
+		t.Error("NewWalkFromDirectory() of a missing directory succeeded; want error")
+	}
+}
+
+func TestScanFile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(testdataDir, "hashSumTest")
+	testCases := []struct {
+		desc            string
+		maxHashFileSize int64
+		wantFp          []*fspb.Fingerprint
+	}{
+		{
+			desc:            "hashed",
+			maxHashFileSize: 1024,
+			wantFp: []*fspb.Fingerprint{
+				{
+					Method: fspb.Fingerprint_SHA256,
+					Value:  "aeb02544df0ef515b21cab81ad5c0609b774f86879bf7e2e42c88efdaab2c75f",
+				},
+			},
+		}, {
+			desc:            "too large to hash",
+			maxHashFileSize: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			f, err := ScanFile(ctx, path, tc.maxHashFileSize)
+			if err != nil {
+				t.Fatalf("ScanFile() error: %v", err)
+			}
+			if f.Path != path || f.Info == nil || f.Info.Name != "hashSumTest" || f.Stat == nil {
+				t.Errorf("ScanFile() = %v; want metadata of %q", f, path)
+			}
+			if diff := cmp.Diff(tc.wantFp, f.Fingerprint); diff != "" {
+				t.Errorf("ScanFile() fingerprint: diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := ScanFile(ctx, filepath.Join(testdataDir, "missing"), 1024); err == nil {
+		t.Error("ScanFile() no error for missing file")
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	tmpfile, err := ioutil.TempFile("", "walk.pb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name()) // clean up
+
+	wlkr := &Walker{
+		pol: &fspb.Policy{
 			Include: []string{
 				testdataDir,
 			},
@@ -366,4 +1395,780 @@ func TestRun(t *testing.T) {
 	if walk.Id == "" {
 		t.Error("walk.Id is empty")
 	}
+
+	// Metrics of a second run don't add up with those of the first one.
+	first := wlkr.Counter.Snapshot()
+	if err := wlkr.Run(ctx); err != nil {
+		t.Fatalf("Run() error on second run: %v", err)
+	}
+	if diff := cmp.Diff(first, wlkr.Counter.Snapshot()); diff != "" {
+		t.Errorf("wlkr.Counter.Snapshot() after second Run(): diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	store := memWalkStore{}
+	wlkr, err := WalkerFromPolicyBytes(ctx, []byte(fmt.Sprintf("include: %q delta_walk: true", testdataDir)), "/walks/host.pb", false, WithWalkStore(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = wlkr.Run(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() of a cancelled walk error: %v; want %v", err, context.Canceled)
+	}
+	var ioErr *WalkIOError
+	if !errors.As(err, &ioErr) {
+		t.Errorf("Run() error %T; want *WalkIOError", err)
+	}
+	got, _, err := loadWalk(ctx, store, "/walks/host.pb")
+	if err != nil {
+		t.Fatalf("loadWalk() of the partial Walk error: %v", err)
+	}
+	if !got.Partial || got.Id != wlkr.walk.Id || got.BaseWalk != "" {
+		t.Errorf("loadWalk() = Walk %q (partial: %t, base: %q); want partial full Walk %q", got.Id, got.Partial, got.BaseWalk, wlkr.walk.Id)
+	}
+
+	// A deadline which already passed stops the walk too.
+	ctx, cancel = context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	wlkr.DryRun = true
+	if err := wlkr.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run() past its deadline error: %v; want %v", err, context.DeadlineExceeded)
+	}
+	if !wlkr.walk.Partial {
+		t.Error("Run() past its deadline didn't flag the Walk as partial")
+	}
+}
+
+func TestRunIncludePath(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+	for _, p := range []string{"a/x/y/file", "b/x/y/file"} {
+		p = filepath.Join(tmpdir, p)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte("content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wlkr := &Walker{
+		pol: &fspb.Policy{
+			Include: []string{filepath.Join(tmpdir, "b")},
+			IncludePath: []*fspb.PathConfig{
+				{Path: filepath.Join(tmpdir, "a"), MaxDepth: 1},
+				{Path: filepath.Join(tmpdir, "b"), MaxDepth: 1}, // already included without limit.
+			},
+		},
+	}
+	if err := wlkr.Run(ctx); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	var got []string
+	for _, f := range wlkr.walk.File {
+		rel, err := filepath.Rel(tmpdir, f.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, rel)
+	}
+	sort.Strings(got)
+	want := []string{"a", "a/x", "b", "b/x", "b/x/y", "b/x/y/file"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Run(): diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunDirectoriesOnly(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+	for _, p := range []string{"a/file", "a/b/file", "file"} {
+		p = filepath.Join(tmpdir, p)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte("content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("file", filepath.Join(tmpdir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	wlkr := &Walker{
+		pol: &fspb.Policy{
+			Include:         []string{tmpdir},
+			HashPfx:         []string{tmpdir},
+			DirectoriesOnly: true,
+		},
+		Counter: &metrics.Counter{},
+	}
+	if err := wlkr.Run(ctx); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	var got []string
+	for _, f := range wlkr.walk.File {
+		rel, err := filepath.Rel(tmpdir, f.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, rel)
+	}
+	sort.Strings(got)
+	want := []string{".", "a", "a/b"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Run() walked files: diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunDryRun(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "walks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+	outpath := filepath.Join(tmpdir, "walk.pb")
+
+	wlkr := &Walker{
+		pol: &fspb.Policy{
+			Include:         []string{testdataDir},
+			HashPfx:         []string{testdataDir},
+			MaxHashFileSize: 1048576,
+		},
+		Outpath: outpath,
+		Counter: &metrics.Counter{},
+		DryRun:  true,
+	}
+	if err := wlkr.Run(ctx); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if _, err := os.Stat(outpath); !os.IsNotExist(err) {
+		t.Errorf("Run() wrote %q in dry run mode: %v", outpath, err)
+	}
+	if len(wlkr.walk.File) == 0 {
+		t.Error("Run() collected no files in dry run mode")
+	}
+	if n, _ := wlkr.Counter.Get("file-hash-count"); n == 0 {
+		t.Error("Run() hashed no files in dry run mode")
+	}
+
+	if os.Geteuid() == 0 {
+		t.Skip("unreadable directories can't be tested as root")
+	}
+	unreadable := filepath.Join(tmpdir, "unreadable")
+	if err := os.Mkdir(unreadable, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(unreadable, 0755)
+	wlkr.pol.Include = []string{tmpdir}
+	if err := wlkr.Run(ctx); err == nil {
+		t.Error("Run() no error for unreadable directory in dry run mode")
+	}
+}
+
+func TestRunHostname(t *testing.T) {
+	ctx := context.Background()
+	hn, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		hostname string
+		want     string
+	}{
+		{want: hn},
+		{hostname: "service.example.com", want: "service.example.com"},
+	} {
+		wlkr := &Walker{
+			pol:      &fspb.Policy{Include: []string{testdataDir}},
+			Hostname: tc.hostname,
+			DryRun:   true,
+		}
+		if err := wlkr.Run(ctx); err != nil {
+			t.Fatalf("Run() error: %v", err)
+		}
+		if wlkr.walk.Hostname != tc.want {
+			t.Errorf("Run() with Hostname %q recorded hostname %q; want %q", tc.hostname, wlkr.walk.Hostname, tc.want)
+		}
+	}
+}
+
+func TestRunSummary(t *testing.T) {
+	ctx := context.Background()
+	wlkr := &Walker{
+		pol: &fspb.Policy{
+			Include:         []string{testdataDir},
+			HashPfx:         []string{testdataDir},
+			MaxHashFileSize: 1048576,
+		},
+		Hostname:   "host",
+		PolicyName: "policy.asciipb",
+		DryRun:     true,
+	}
+	if err := wlkr.Run(ctx); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	want := &fspb.WalkSummary{
+		WalkStartTime: wlkr.walk.StartWalk,
+		WalkEndTime:   wlkr.walk.StopWalk,
+		Hostname:      "host",
+		PolicyName:    "policy.asciipb",
+	}
+	for _, f := range wlkr.walk.File {
+		if f.Info.IsDir {
+			want.DirCount++
+			continue
+		}
+		want.FileCount++
+		want.TotalSizeBytes += f.Info.Size
+		if len(f.Fingerprint) > 0 {
+			want.HashCount++
+		}
+	}
+	if want.FileCount == 0 || want.DirCount == 0 || want.HashCount == 0 {
+		t.Fatalf("Run() walked %d files (%d hashed) and %d directories; want some of each", want.FileCount, want.HashCount, want.DirCount)
+	}
+	if diff := cmp.Diff(want, wlkr.walk.Summary, cmp.Comparer(proto.Equal)); diff != "" {
+		t.Errorf("Run() summary: diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunOutputFormats(t *testing.T) {
+	testCases := []struct {
+		format   OutputFormat
+		compress bool
+	}{
+		{format: OutputFormatProto, compress: true},
+		{format: OutputFormatJSON},
+		{format: OutputFormatJSON, compress: true},
+		{format: OutputFormatTextProto},
+	}
+
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "walk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+
+	for _, tc := range testCases {
+		name := WalkFilenameForFormat("testhost", time.Now(), tc.format, tc.compress)
+		t.Run(name, func(t *testing.T) {
+			outpath := filepath.Join(tmpdir, name)
+			wlkr := &Walker{
+				pol: &fspb.Policy{
+					Include: []string{
+						testdataDir,
+					},
+					HashPfx: []string{
+						testdataDir,
+					},
+					MaxHashFileSize: 1048576,
+				},
+				Outpath:      outpath,
+				OutputFormat: tc.format,
+				Compress:     tc.compress,
+			}
+
+			if err := wlkr.Run(ctx); err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+
+			r := &Reporter{}
+			gotWalk, _, err := r.readWalk(ctx, outpath)
+			if err != nil {
+				t.Fatalf("readWalk(): %v", err)
+			}
+			diff := cmp.Diff(gotWalk, wlkr.walk, cmp.FilterPath(func(p cmp.Path) bool {
+				return strings.Contains(p.String(), "XXX_")
+			}, cmp.Ignore()))
+			if diff != "" {
+				t.Errorf("readWalk(): round trip diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRunMaxWalkRetention(t *testing.T) {
+	ctx := context.Background()
+	hn, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	walkPath := func(age time.Duration) string {
+		return "/walks/" + WalkFilename(hn, now.Add(-age))
+	}
+	for _, dryRun := range []bool{false, true} {
+		store := memWalkStore{
+			walkPath(3 * time.Hour):                []byte("oldest"),
+			walkPath(2 * time.Hour):                []byte("older"),
+			walkPath(time.Hour):                    []byte("old"),
+			"/walks/" + WalkFilename("other", now): []byte("other host"),
+		}
+		pol := &fspb.Policy{Include: []string{testdataDir}, MaxWalkRetention: 2}
+		wlkr, err := NewWalker(ctx, pol, walkPath(0), false, WithWalkStore(store))
+		if err != nil {
+			t.Fatal(err)
+		}
+		wlkr.DryRunCleanup = dryRun
+		if err := wlkr.Run(ctx); err != nil {
+			t.Fatalf("Run() error: %v", err)
+		}
+
+		var got []string
+		for n := range store {
+			got = append(got, n)
+		}
+		sort.Strings(got)
+		want := []string{walkPath(time.Hour), walkPath(0), "/walks/" + WalkFilename("other", now)}
+		if dryRun {
+			want = append(want, walkPath(3*time.Hour), walkPath(2*time.Hour))
+		}
+		sort.Strings(want)
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Run() with max_walk_retention (dry run: %t): diff (-want +got):\n%s", dryRun, diff)
+		}
+	}
+}
+
+func TestRunMaxWalkRetentionOutputDirLayout(t *testing.T) {
+	ctx := context.Background()
+	hn, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const layout = "{year}/{month}/{day}"
+	now := time.Now()
+	walkPath := func(age time.Duration) string {
+		dir, err := OutputDir(layout, hn, now.Add(-age))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "/walks/" + dir + "/" + WalkFilename(hn, now.Add(-age))
+	}
+	day := 24 * time.Hour
+	store := memWalkStore{
+		walkPath(2*day + time.Hour): []byte("oldest"),
+		walkPath(2 * day):           []byte("older"),
+		walkPath(day):               []byte("old"),
+	}
+	pol := &fspb.Policy{Include: []string{testdataDir}, MaxWalkRetention: 2}
+	wlkr, err := NewWalker(ctx, pol, walkPath(0), false, WithWalkStore(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wlkr.OutputRoot = "/walks"
+	wlkr.OutputDirLayout = layout
+	if err := wlkr.Run(ctx); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	var got []string
+	for n := range store {
+		got = append(got, n)
+	}
+	sort.Strings(got)
+	want := []string{walkPath(day), walkPath(0)}
+	sort.Strings(want)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Run() with max_walk_retention and output directory layout: diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestRunDeltaWalk(t *testing.T) {
+	ctx := context.Background()
+	treeDir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(treeDir) // clean up
+	walkDir, err := ioutil.TempDir("", "walks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(walkDir) // clean up
+	makeTree(t, treeDir, 2, 3)
+	hn, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pol := &fspb.Policy{
+		Include:         []string{treeDir},
+		HashPfx:         []string{treeDir},
+		MaxHashFileSize: 1024,
+		DeltaWalk:       true,
+	}
+	now := time.Now()
+	basePath := filepath.Join(walkDir, WalkFilename(hn, now.Add(-time.Hour)))
+	base := &Walker{pol: pol, Outpath: basePath}
+	if err := base.Run(ctx); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if base.walk.BaseWalk != "" {
+		t.Fatalf("Run() without previous Walk: base_walk = %q; want a full Walk", base.walk.BaseWalk)
+	}
+
+	changed := filepath.Join(treeDir, "d0", "d0", "f0")
+	removed := filepath.Join(treeDir, "d1", "d1", "f2")
+	if err := ioutil.WriteFile(changed, []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(removed); err != nil {
+		t.Fatal(err)
+	}
+	deltaPath := filepath.Join(walkDir, WalkFilename(hn, now))
+	delta := &Walker{pol: pol, Outpath: deltaPath}
+	if err := delta.Run(ctx); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if delta.walk.BaseWalk != filepath.Base(basePath) {
+		t.Errorf("Run() base_walk = %q; want %q", delta.walk.BaseWalk, filepath.Base(basePath))
+	}
+	if diff := cmp.Diff([]string{removed}, delta.walk.Deleted); diff != "" {
+		t.Errorf("Run() deleted: diff (-want +got):\n%s", diff)
+	}
+	var paths []string
+	for _, f := range delta.walk.File {
+		paths = append(paths, f.Path)
+	}
+	// The parent directory of the removed file changed as well.
+	wantPaths := []string{changed, filepath.Dir(removed)}
+	sort.Strings(paths)
+	sort.Strings(wantPaths)
+	if diff := cmp.Diff(wantPaths, paths); diff != "" {
+		t.Errorf("Run() delta files: diff (-want +got):\n%s", diff)
+	}
+
+	r := &Reporter{}
+	full, _, err := r.readWalk(ctx, deltaPath)
+	if err != nil {
+		t.Fatalf("readWalk(): %v", err)
+	}
+	if len(full.File) != len(base.walk.File)-1 {
+		t.Errorf("readWalk() merged Walk has %d files; want %d", len(full.File), len(base.walk.File)-1)
+	}
+}
+
+func TestRunDeltaWalkOutputDirLayout(t *testing.T) {
+	ctx := context.Background()
+	treeDir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(treeDir) // clean up
+	walkDir, err := ioutil.TempDir("", "walks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(walkDir) // clean up
+	makeTree(t, treeDir, 2, 3)
+	hn, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const layout = "{year}/{month}/{day}"
+	pol := &fspb.Policy{Include: []string{treeDir}, DeltaWalk: true}
+	now := time.Now()
+	run := func(ts time.Time) *Walker {
+		dir, err := OutputDir(layout, hn, ts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := filepath.Join(walkDir, dir, WalkFilename(hn, ts))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		w := &Walker{pol: pol, Outpath: p, OutputRoot: walkDir, OutputDirLayout: layout}
+		if err := w.Run(ctx); err != nil {
+			t.Fatalf("Run() error: %v", err)
+		}
+		return w
+	}
+	base := run(now.Add(-24 * time.Hour))
+	if err := os.Remove(filepath.Join(treeDir, "d1", "d1", "f2")); err != nil {
+		t.Fatal(err)
+	}
+	delta := run(now)
+
+	want, err := filepath.Rel(filepath.Dir(delta.Outpath), base.Outpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delta.walk.BaseWalk != want {
+		t.Fatalf("Run() on the next day: base_walk = %q; want %q", delta.walk.BaseWalk, want)
+	}
+	full, _, err := (&Reporter{}).readWalk(ctx, delta.Outpath)
+	if err != nil {
+		t.Fatalf("readWalk(): %v", err)
+	}
+	if len(full.File) != len(base.walk.File)-1 {
+		t.Errorf("readWalk() merged Walk has %d files; want %d", len(full.File), len(base.walk.File)-1)
+	}
+}
+
+// makeTree creates dirs directories below root with files files each, spread over two levels.
+func makeTree(tb testing.TB, root string, dirs, files int) {
+	for d := 0; d < dirs; d++ {
+		dir := filepath.Join(root, fmt.Sprintf("d%d", d%10), fmt.Sprintf("d%d", d))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			tb.Fatal(err)
+		}
+		for f := 0; f < files; f++ {
+			if err := ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("f%d", f)), []byte("content"), 0644); err != nil {
+				tb.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestRunParallel(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+	makeTree(t, tmpdir, 50, 20)
+
+	paths := map[uint32][]string{}
+	for _, parallelism := range []uint32{1, 8} {
+		wlkr := &Walker{
+			pol: &fspb.Policy{
+				Include: []string{
+					tmpdir,
+				},
+				ExcludePfx: []string{
+					filepath.Join(tmpdir, "d3"),
+				},
+				Parallelism: parallelism,
+			},
+			Counter: &metrics.Counter{},
+		}
+		if err := wlkr.Run(ctx); err != nil {
+			t.Fatalf("Run() with parallelism %d: %v", parallelism, err)
+		}
+		// 45 directories with 20 files each remain after excluding d3/.
+		if n, _ := wlkr.Counter.Get(countFiles); n != 45*20 {
+			t.Errorf("Run() with parallelism %d: counted %d files; want %d", parallelism, n, 45*20)
+		}
+		for _, f := range wlkr.walk.File {
+			paths[parallelism] = append(paths[parallelism], f.Path)
+		}
+		sort.Strings(paths[parallelism])
+	}
+	if diff := cmp.Diff(paths[1], paths[8]); diff != "" {
+		t.Errorf("Run() walked different paths in parallel: diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestSortFiles(t *testing.T) {
+	files := func() []*fspb.File {
+		return []*fspb.File{
+			{Path: "/b", Stat: &fspb.FileStat{Dev: 1, Inode: 5}},
+			{Path: "/c", Stat: &fspb.FileStat{Dev: 2, Inode: 1}},
+			{Path: "/a/z", Stat: &fspb.FileStat{Dev: 1, Inode: 7}},
+			{Path: "/a", Stat: &fspb.FileStat{Dev: 1, Inode: 7}},
+		}
+	}
+	testCases := []struct {
+		order fspb.Policy_SortOrder
+		want  []string
+	}{
+		{order: fspb.Policy_NONE, want: []string{"/b", "/c", "/a/z", "/a"}},
+		{order: fspb.Policy_PATH_LEXICAL, want: []string{"/a", "/a/z", "/b", "/c"}},
+		{order: fspb.Policy_INODE_ORDER, want: []string{"/b", "/a", "/a/z", "/c"}},
+	}
+	for _, tc := range testCases {
+		f := files()
+		sortFiles(f, tc.order)
+		var got []string
+		for _, file := range f {
+			got = append(got, file.Path)
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("sortFiles(%s): diff (-want +got):\n%s", tc.order, diff)
+		}
+	}
+}
+
+func TestWalkFuncMaxErrors(t *testing.T) {
+	ctx := context.Background()
+	testCases := []struct {
+		desc      string
+		polMax    uint32
+		walkerMax uint32
+		wantFail  int // 1-based index of the error which aborts the walk, 0 for none.
+	}{
+		{
+			desc: "unlimited",
+		}, {
+			desc:     "policy limit",
+			polMax:   3,
+			wantFail: 3,
+		}, {
+			desc:      "walker overrides policy",
+			polMax:    3,
+			walkerMax: 1,
+			wantFail:  1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			wlkr := &Walker{
+				pol:       &fspb.Policy{MaxErrors: tc.polMax},
+				walk:      &fspb.Walk{},
+				MaxErrors: tc.walkerMax,
+				Counter:   &metrics.Counter{},
+			}
+			fn := wlkr.walkFunc(ctx, "/", 0, nil)
+			failedAt := 0
+			for i := 1; i <= 5 && failedAt == 0; i++ {
+				if err := fn(fmt.Sprintf("/file%d", i), nil, os.ErrPermission); err != nil {
+					failedAt = i
+				}
+			}
+			if failedAt != tc.wantFail {
+				t.Errorf("walkFunc() failed at error %d; want %d", failedAt, tc.wantFail)
+			}
+			wantCount := int64(tc.wantFail)
+			if wantCount == 0 {
+				wantCount = 5
+			}
+			if n, _ := wlkr.Counter.Get(countErrors); n != wantCount {
+				t.Errorf("walkFunc() counted %d errors; want %d", n, wantCount)
+			}
+			if len(wlkr.walk.Notification) != int(wantCount) {
+				t.Errorf("walkFunc() added %d notifications; want %d", len(wlkr.walk.Notification), wantCount)
+			}
+		})
+	}
+}
+
+func TestRunWithProgress(t *testing.T) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+	makeTree(t, tmpdir, 50, 20)
+
+	wlkr := &Walker{
+		pol: &fspb.Policy{
+			Include:         []string{tmpdir},
+			HashPfx:         []string{tmpdir},
+			MaxHashFileSize: 1024,
+		},
+	}
+	// The root, 10 intermediate and 50 leaf directories each produce one update.
+	progress := make(chan WalkProgress, 61)
+	if err := wlkr.RunWithProgress(ctx, progress); err != nil {
+		t.Fatalf("RunWithProgress(): %v", err)
+	}
+	close(progress)
+
+	var updates []WalkProgress
+	for p := range progress {
+		updates = append(updates, p)
+	}
+	if len(updates) != 61 {
+		t.Fatalf("RunWithProgress() sent %d updates; want 61", len(updates))
+	}
+	if updates[0].Dir != tmpdir {
+		t.Errorf("RunWithProgress() first update for %q; want %q", updates[0].Dir, tmpdir)
+	}
+	for i := 1; i < len(updates); i++ {
+		if updates[i].Files < updates[i-1].Files || updates[i].BytesHashed < updates[i-1].BytesHashed {
+			t.Errorf("RunWithProgress() progress went backwards: %+v => %+v", updates[i-1], updates[i])
+		}
+	}
+	if last := updates[len(updates)-1]; last.BytesHashed == 0 || last.BytesHashed > 50*20*int64(len("content")) {
+		t.Errorf("RunWithProgress() last update hashed %d bytes; want between 1 and %d", last.BytesHashed, 50*20*len("content"))
+	}
+
+	// Run must work without a progress channel.
+	if err := wlkr.Run(ctx); err != nil {
+		t.Errorf("Run(): %v", err)
+	}
+}
+
+func BenchmarkRun(b *testing.B) {
+	ctx := context.Background()
+	tmpdir, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+	makeTree(b, tmpdir, 100, 1000)
+
+	for _, parallelism := range []uint32{1, 8} {
+		b.Run(fmt.Sprintf("parallelism-%d", parallelism), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				wlkr := &Walker{
+					pol: &fspb.Policy{
+						Include: []string{
+							tmpdir,
+						},
+						Parallelism: parallelism,
+					},
+				}
+				if err := wlkr.Run(ctx); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestSetHasher(t *testing.T) {
+	ctx := context.Background()
+	wlkr := &Walker{
+		pol: &fspb.Policy{
+			Include:         []string{testdataDir},
+			HashPfx:         []string{testdataDir},
+			MaxHashFileSize: 1048576,
+		},
+		DryRun: true,
+	}
+	wlkr.SetHasher(fspb.Fingerprint_SHA256, md5.New)
+	if err := wlkr.Run(ctx); err == nil {
+		t.Error("Run() with an MD5 hasher for SHA256 succeeded; want error")
+	}
+
+	wlkr.SetHasher(fspb.Fingerprint_SHA512, sha512.New)
+	if err := wlkr.Run(ctx); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	p := filepath.Join(testdataDir, "hashSumTest")
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha512.Sum512(b)
+	want := []*fspb.Fingerprint{{Method: fspb.Fingerprint_SHA512, Value: hex.EncodeToString(sum[:])}}
+	for _, f := range wlkr.walk.File {
+		if f.Path != p {
+			continue
+		}
+		if diff := cmp.Diff(want, f.Fingerprint, cmp.Comparer(proto.Equal)); diff != "" {
+			t.Errorf("Run() fingerprint of %s: diff (-want +got):\n%s", p, diff)
+		}
+		return
+	}
+	t.Errorf("Run() didn't record %s", p)
 }
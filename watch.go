@@ -0,0 +1,164 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+
+	fspb "github.com/google/fswalker/proto/fswalker"
+)
+
+// WatchEventType is the kind of change reported by Walker.Watch.
+type WatchEventType int
+
+// Types of changes reported by Walker.Watch.
+const (
+	WatchCreate WatchEventType = iota + 1
+	WatchModify
+	WatchDelete
+	WatchRename
+)
+
+func (t WatchEventType) String() string {
+	switch t {
+	case WatchCreate:
+		return "create"
+	case WatchModify:
+		return "modify"
+	case WatchDelete:
+		return "delete"
+	case WatchRename:
+		return "rename"
+	}
+	return fmt.Sprintf("WatchEventType(%d)", int(t))
+}
+
+// WatchEvent is a change of a file below one of the include paths of the policy.
+type WatchEvent struct {
+	Path string
+	Type WatchEventType
+	// File is a snapshot of the new state of the file, captured the same way as in a Walk.
+	// It is nil for deleted and renamed files, as well as files which vanished before they
+	// could be read.
+	File *fspb.File
+}
+
+// watchOp maps fsnotify operations onto event types. Permission and owner changes count as
+// modifications, same as in a report.
+func watchOp(op fsnotify.Op) WatchEventType {
+	switch {
+	case op&fsnotify.Remove != 0:
+		return WatchDelete
+	case op&fsnotify.Rename != 0:
+		return WatchRename
+	case op&fsnotify.Create != 0:
+		return WatchCreate
+	}
+	return WatchModify
+}
+
+// watchExcluded determines whether the policy excludes a path from being watched.
+func (w *Walker) watchExcluded(p string) bool {
+	return w.isExcluded(p) || w.excludeRule(p) != ""
+}
+
+// watchTree adds the directory dir and all its subdirectories which aren't excluded to watcher.
+func (w *Walker) watchTree(watcher *fsnotify.Watcher, dir string) error {
+	return filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if p != dir && os.IsNotExist(err) {
+				return nil // removed while being added
+			}
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if w.watchExcluded(p) {
+			return filepath.SkipDir
+		}
+		return watcher.Add(p)
+	})
+}
+
+// Watch continuously monitors the include paths of the policy and sends an event on the given
+// channel for each created, modified, deleted or renamed file below them. Paths excluded by the
+// policy are not reported. Directories created while watching are watched as well. Unlike Run,
+// no Walk is written: Watch complements periodic walks with real-time alerting.
+// Watch blocks until ctx is done and then returns nil. The channel is not closed by the Walker.
+func (w *Walker) Watch(ctx context.Context, events chan<- WatchEvent) error {
+	if err := w.compileExcludeRegex(); err != nil {
+		return err
+	}
+	// Notifications about broken symlinks end up in this Walk rather than being reported.
+	w.walk = &fspb.Walk{Policy: w.pol}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("unable to start watching: %v", err)
+	}
+	defer watcher.Close()
+	for _, inc := range w.pol.IncludePaths() {
+		root := filepath.Clean(inc.Path)
+		info, err := os.Stat(root)
+		if err != nil {
+			return fmt.Errorf("unable to get file info for base path %q: %v", root, err)
+		}
+		if !info.IsDir() {
+			err = watcher.Add(root)
+		} else {
+			err = w.watchTree(watcher, root)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to watch base path %q: %v", root, err)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-watcher.Errors:
+			log.Printf("error watching include paths: %s", err)
+		case ev := <-watcher.Events:
+			if w.watchExcluded(ev.Name) {
+				continue
+			}
+			we := WatchEvent{Path: ev.Name, Type: watchOp(ev.Op)}
+			if we.Type == WatchCreate || we.Type == WatchModify {
+				info, err := os.Lstat(ev.Name)
+				if err == nil {
+					we.File = w.convert(ev.Name, info)
+					if we.Type == WatchCreate && info.IsDir() {
+						if err := w.watchTree(watcher, ev.Name); err != nil {
+							log.Printf("unable to watch new directory %q: %s", ev.Name, err)
+						}
+					}
+				}
+			}
+			select {
+			case events <- we:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
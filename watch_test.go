@@ -0,0 +1,113 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestWatchOp(t *testing.T) {
+	testCases := []struct {
+		op   fsnotify.Op
+		want WatchEventType
+	}{
+		{op: fsnotify.Create, want: WatchCreate},
+		{op: fsnotify.Write, want: WatchModify},
+		{op: fsnotify.Chmod, want: WatchModify},
+		{op: fsnotify.Remove, want: WatchDelete},
+		{op: fsnotify.Rename, want: WatchRename},
+		{op: fsnotify.Create | fsnotify.Remove, want: WatchDelete},
+	}
+	for _, tc := range testCases {
+		if got := watchOp(tc.op); got != tc.want {
+			t.Errorf("watchOp(%v) = %v; want %v", tc.op, got, tc.want)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir) // clean up
+	excluded := filepath.Join(tmpdir, "excluded")
+	if err := os.Mkdir(excluded, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pol := fmt.Sprintf("include: %q exclude_pfx: %q", tmpdir, excluded)
+	wlkr, err := WalkerFromPolicyBytes(ctx, []byte(pol), "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan WatchEvent, 100)
+	done := make(chan error)
+	go func() { done <- wlkr.Watch(ctx, events) }()
+
+	// Keep writing until the watch is set up and reports the change.
+	p := filepath.Join(tmpdir, "new")
+	wait := func(typ WatchEventType, change func()) WatchEvent {
+		t.Helper()
+		tick := time.NewTicker(50 * time.Millisecond)
+		defer tick.Stop()
+		timeout := time.After(10 * time.Second)
+		change()
+		for {
+			select {
+			case ev := <-events:
+				if strings.HasPrefix(ev.Path, excluded) {
+					t.Errorf("Watch() sent event %v for excluded path %q", ev.Type, ev.Path)
+				}
+				if ev.Path == p && ev.Type == typ {
+					return ev
+				}
+			case <-tick.C:
+				change()
+			case <-timeout:
+				t.Fatalf("Watch() sent no %v event for %q", typ, p)
+			}
+		}
+	}
+	ev := wait(WatchModify, func() {
+		if err := ioutil.WriteFile(filepath.Join(excluded, "file"), []byte("excluded"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte("content"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if ev.File == nil || ev.File.Info.GetName() != "new" || ev.File.Info.GetSize() != 7 {
+		t.Errorf("Watch() event File = %v; want a snapshot of %q with 7 bytes", ev.File, p)
+	}
+
+	wait(WatchDelete, func() { os.Remove(p) })
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Watch() error: %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fswalker
+
+import (
+	"bytes"
+	"syscall"
+)
+
+// listXattrs returns the extended attributes of the given path keyed by name.
+// Symlinks are followed. If the file system doesn't support extended attributes,
+// no attributes and no error are returned.
+func listXattrs(path string) (map[string][]byte, error) {
+	size, err := syscall.Listxattr(path, nil)
+	if err == syscall.ENOTSUP {
+		return nil, nil
+	}
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	if size, err = syscall.Listxattr(path, buf); err != nil {
+		return nil, err
+	}
+
+	xattrs := map[string][]byte{}
+	for _, name := range bytes.Split(buf[:size], []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		vsize, err := syscall.Getxattr(path, string(name), nil)
+		if err == syscall.ENODATA {
+			continue // the attribute was removed in the meantime
+		}
+		if err != nil {
+			return nil, err
+		}
+		val := make([]byte, vsize)
+		if vsize, err = syscall.Getxattr(path, string(name), val); err != nil {
+			return nil, err
+		}
+		xattrs[string(name)] = val[:vsize]
+	}
+	return xattrs, nil
+}